@@ -0,0 +1,20 @@
+package main
+
+import (
+	"log"
+)
+
+//advanceUsage is printed when the advance command is called wrongly
+const advanceUsage = `usage: challengr advance
+
+Starts the scheduled challenges whose starts_at has come and closes the active challenges whose
+ends_at has passed. It is the advance_challenges job, which the server schedules.`
+
+//runAdvance func handles the `challengr advance` sub command, a shorthand of `challengr jobs run advance_challenges`
+func runAdvance(args []string) {
+	if len(args) != 0 {
+		log.Fatal(advanceUsage)
+	}
+
+	runJobs([]string{"run", "advance_challenges"})
+}
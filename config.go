@@ -0,0 +1,35 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/challengr/config"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//configUsage is printed when the config command is called wrongly
+const configUsage = `usage: challengr config print [--redacted]
+
+Loads the config the same way the server does (defaults, $` + config.FileEnv + `, env and *_FILE secrets),
+validates it and prints it as yaml. --redacted hides the secrets.`
+
+//runConfig func handles the `challengr config` sub command
+func runConfig(args []string) {
+	if len(args) == 0 || args[0] != "print" || len(args) > 2 || (len(args) == 2 && args[1] != "--redacted") {
+		log.Fatal(configUsage)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config error: %v", err)
+	}
+
+	if len(args) == 2 {
+		cfg = cfg.Redacted()
+	}
+
+	if err = yaml.NewEncoder(os.Stdout).Encode(cfg); err != nil {
+		log.Fatalf("config print error: %v", err)
+	}
+}
@@ -0,0 +1,541 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/challengr/cron"
+	"github.com/challengr/ratelimit"
+	yaml "gopkg.in/yaml.v2"
+)
+
+//FileEnv is the env variable which points to the optional yaml or toml config file
+const FileEnv = "CHALLENGR_CONFIG"
+
+//redacted is printed instead of the secret values
+const redacted = "[redacted]"
+
+//Config struct holds every setting of challengr. It is loaded once at startup and passed down explicitly.
+type Config struct {
+	Port string `yaml:"port" toml:"port" env:"PORT"`
+
+	//TrustedProxies is the comma separated list of the ips and cidrs of the proxies whose X-Forwarded-For and X-Real-IP
+	//headers tell the client ip. Empty trusts none, the client ip is the remote address then.
+	TrustedProxies string `yaml:"trusted_proxies" toml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+
+	DB  DB  `yaml:"db" toml:"db"`
+	AWS AWS `yaml:"aws" toml:"aws"`
+	JWT JWT `yaml:"jwt" toml:"jwt"`
+
+	Identity Identity `yaml:"identity" toml:"identity"`
+	Privacy  Privacy  `yaml:"privacy" toml:"privacy"`
+
+	RateLimit RateLimit `yaml:"rate_limit" toml:"rate_limit"`
+	Jobs      Jobs      `yaml:"jobs" toml:"jobs"`
+	Trending  Trending  `yaml:"trending" toml:"trending"`
+	Rewards   Rewards   `yaml:"rewards" toml:"rewards"`
+
+	Leaderboard Leaderboard `yaml:"leaderboard" toml:"leaderboard"`
+	Invites     Invites     `yaml:"invites" toml:"invites"`
+}
+
+//DB struct holds the postgres settings
+type DB struct {
+	Host     string `yaml:"host" toml:"host" env:"DB_HOST"`
+	Name     string `yaml:"name" toml:"name" env:"DB_NAME"`
+	User     string `yaml:"user" toml:"user" env:"DB_USER"`
+	Password string `yaml:"password" toml:"password" env:"DB_PASSWORD" secret:"true"`
+	SSLMode  string `yaml:"sslmode" toml:"sslmode" env:"DB_SSLMODE"`
+}
+
+//DataSource func returns the postgres connection url, the user and password are escaped so any secret can be used
+func (d DB) DataSource() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(d.User, d.Password),
+		Host:     d.Host,
+		Path:     "/" + d.Name,
+		RawQuery: url.Values{"sslmode": {d.SSLMode}}.Encode(),
+	}
+	return u.String()
+}
+
+//AWS struct holds the s3 settings. Empty keys make the sdk fall back to its default credential chain.
+type AWS struct {
+	Region          string        `yaml:"region" toml:"region" env:"AWS_REGION"`
+	AccessKeyID     string        `yaml:"access_key_id" toml:"access_key_id" env:"AWS_ACCESS_KEY_ID"`
+	SecretAccessKey string        `yaml:"secret_access_key" toml:"secret_access_key" env:"AWS_SECRET_ACCESS_KEY" secret:"true"`
+	Bucket          string        `yaml:"bucket" toml:"bucket" env:"S3_BUCKET"`
+	PresignTTL      time.Duration `yaml:"presign_ttl" toml:"presign_ttl" env:"S3_PRESIGN_TTL"`
+}
+
+//JWT struct holds the token settings. HS256 signs with the secret, RS256 and ES256 with the PEM private key of key_file.
+//The previous secret and the PEM public keys of verify_keys_dir still verify tokens while the keys are rotated.
+type JWT struct {
+	Algorithm      string        `yaml:"algorithm" toml:"algorithm" env:"JWT_ALGORITHM"`
+	Secret         string        `yaml:"secret" toml:"secret" env:"JWT_SECRET" secret:"true"`
+	PreviousSecret string        `yaml:"previous_secret" toml:"previous_secret" env:"JWT_PREVIOUS_SECRET" secret:"true"`
+	KeyFile        string        `yaml:"key_file" toml:"key_file" env:"JWT_KEY_FILE"`
+	VerifyKeysDir  string        `yaml:"verify_keys_dir" toml:"verify_keys_dir" env:"JWT_VERIFY_KEYS_DIR"`
+	AccessTTL      time.Duration `yaml:"access_ttl" toml:"access_ttl" env:"JWT_ACCESS_TTL"`
+	RefreshTTL     time.Duration `yaml:"refresh_ttl" toml:"refresh_ttl" env:"JWT_REFRESH_TTL"`
+}
+
+//Identity struct holds the settings of the sign in providers. The urls are configurable so the providers can be stubbed.
+//Google and Apple sign in are enabled by setting their comma separated client ids, which the id tokens must be issued for.
+type Identity struct {
+	FacebookGraphURL string `yaml:"facebook_graph_url" toml:"facebook_graph_url" env:"FACEBOOK_GRAPH_URL"`
+
+	GoogleJWKSURL   string `yaml:"google_jwks_url" toml:"google_jwks_url" env:"GOOGLE_JWKS_URL"`
+	GoogleIssuer    string `yaml:"google_issuer" toml:"google_issuer" env:"GOOGLE_ISSUER"`
+	GoogleClientIDs string `yaml:"google_client_ids" toml:"google_client_ids" env:"GOOGLE_CLIENT_IDS"`
+
+	AppleJWKSURL   string `yaml:"apple_jwks_url" toml:"apple_jwks_url" env:"APPLE_JWKS_URL"`
+	AppleIssuer    string `yaml:"apple_issuer" toml:"apple_issuer" env:"APPLE_ISSUER"`
+	AppleClientIDs string `yaml:"apple_client_ids" toml:"apple_client_ids" env:"APPLE_CLIENT_IDS"`
+}
+
+//Privacy struct holds the personal data settings. A requested account deletion is carried out after the grace period,
+//until then the user can cancel it.
+type Privacy struct {
+	DeletionGrace time.Duration `yaml:"deletion_grace" toml:"deletion_grace" env:"DELETION_GRACE"`
+}
+
+//RateLimit struct holds the token bucket limits of the route groups, written as "<requests>/<period>", e.g. "10/1m".
+//Default applies to every route, the others are stricter limits of the costly routes on top of it. An empty limit is disabled.
+type RateLimit struct {
+	Default string `yaml:"default" toml:"default" env:"RATE_LIMIT_DEFAULT"`
+	Auth    string `yaml:"auth" toml:"auth" env:"RATE_LIMIT_AUTH"`
+	Like    string `yaml:"like" toml:"like" env:"RATE_LIMIT_LIKE"`
+	Upload  string `yaml:"upload" toml:"upload" env:"RATE_LIMIT_UPLOAD"`
+}
+
+//Jobs struct holds the background job settings. The specs are cron expressions in UTC, an empty spec leaves the job to
+//`challengr jobs run`. Enabled runs the scheduler in the server; every replica may enable it, a job runs on one at a time.
+type Jobs struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" env:"JOBS_ENABLED"`
+
+	AdvanceChallenges   string `yaml:"advance_challenges" toml:"advance_challenges" env:"JOB_ADVANCE_CHALLENGES"`
+	RefillLikes         string `yaml:"refill_likes" toml:"refill_likes" env:"JOB_REFILL_LIKES"`
+	CloseIdleChallenges string `yaml:"close_idle_challenges" toml:"close_idle_challenges" env:"JOB_CLOSE_IDLE_CHALLENGES"`
+	PurgeDeleted        string `yaml:"purge_deleted" toml:"purge_deleted" env:"JOB_PURGE_DELETED"`
+	EraseUsers          string `yaml:"erase_users" toml:"erase_users" env:"JOB_ERASE_USERS"`
+	RefreshTrending     string `yaml:"refresh_trending" toml:"refresh_trending" env:"JOB_REFRESH_TRENDING"`
+
+	//IdleAfter is how long an active challenge goes without posts before it is closed
+	IdleAfter time.Duration `yaml:"idle_after" toml:"idle_after" env:"CHALLENGE_IDLE_AFTER"`
+	//Retention is how long deleted posts, challenges and the job runs are kept before they are purged
+	Retention time.Duration `yaml:"retention" toml:"retention" env:"DELETED_RETENTION"`
+}
+
+//Trending struct holds the formula of the trending score of the active challenges, which the refresh_trending job keeps:
+//(posts*Posts + liked posts*LikedPosts + velocity*Velocity) * (1 + challenge weight). The velocity sums the posts and
+//likes of the last Window, each one decaying linearly from 1 to 0 over it.
+type Trending struct {
+	Posts      float64       `yaml:"posts" toml:"posts" env:"TRENDING_POSTS"`
+	LikedPosts float64       `yaml:"liked_posts" toml:"liked_posts" env:"TRENDING_LIKED_POSTS"`
+	Velocity   float64       `yaml:"velocity" toml:"velocity" env:"TRENDING_VELOCITY"`
+	Window     time.Duration `yaml:"window" toml:"window" env:"TRENDING_WINDOW"`
+}
+
+//Rewards struct holds what completing a challenge earns. A post completes when its likes reach its likes_needed, its owner
+//is credited once per challenge with Exp and Coins times the challenge weight and the user weight.
+type Rewards struct {
+	Exp   int `yaml:"exp" toml:"exp" env:"REWARD_EXP"`
+	Coins int `yaml:"coins" toml:"coins" env:"REWARD_COINS"`
+}
+
+//Leaderboard struct holds the cache of the challenge leaderboards. A server drops its cached ranking of a challenge when
+//it takes a like, post or post deletion of the challenge, TTL bounds how stale the rankings of other servers can get.
+type Leaderboard struct {
+	TTL time.Duration `yaml:"ttl" toml:"ttl" env:"LEADERBOARD_TTL"`
+}
+
+//Invites struct holds the deep links of the challenge invites. The link of an invite is LinkBase followed by its code.
+type Invites struct {
+	LinkBase string `yaml:"link_base" toml:"link_base" env:"INVITE_LINK_BASE"`
+}
+
+//defaults func returns the settings which are safe to default. Credentials never have a default.
+func defaults() *Config {
+	return &Config{
+		Port: "8080",
+		DB: DB{
+			Name:    "challengrdb",
+			User:    "challengr",
+			SSLMode: "disable",
+		},
+		AWS: AWS{
+			Region:     "us-east-1",
+			Bucket:     "challengrPost",
+			PresignTTL: time.Minute,
+		},
+		JWT: JWT{
+			Algorithm:  "HS256",
+			AccessTTL:  15 * time.Minute,
+			RefreshTTL: 30 * 24 * time.Hour,
+		},
+		Identity: Identity{
+			FacebookGraphURL: "https://graph.facebook.com",
+			GoogleJWKSURL:    "https://www.googleapis.com/oauth2/v3/certs",
+			GoogleIssuer:     "https://accounts.google.com",
+			AppleJWKSURL:     "https://appleid.apple.com/auth/keys",
+			AppleIssuer:      "https://appleid.apple.com",
+		},
+		Privacy: Privacy{
+			DeletionGrace: 30 * 24 * time.Hour,
+		},
+		RateLimit: RateLimit{
+			Default: "300/1m",
+			Auth:    "10/1m",
+			Like:    "60/1m",
+			Upload:  "30/1m",
+		},
+		Jobs: Jobs{
+			Enabled:             true,
+			AdvanceChallenges:   "* * * * *",
+			RefillLikes:         "*/5 * * * *",
+			CloseIdleChallenges: "0 * * * *",
+			PurgeDeleted:        "30 3 * * *",
+			EraseUsers:          "0 4 * * *",
+			RefreshTrending:     "* * * * *",
+			IdleAfter:           30 * 24 * time.Hour,
+			Retention:           90 * 24 * time.Hour,
+		},
+		Trending: Trending{
+			LikedPosts: 0.01,
+			Velocity:   0.01,
+			Window:     72 * time.Hour,
+		},
+		Rewards: Rewards{
+			Exp:   100,
+			Coins: 10,
+		},
+		Leaderboard: Leaderboard{
+			TTL: time.Minute,
+		},
+		Invites: Invites{
+			LinkBase: "challengr://invite/",
+		},
+	}
+}
+
+//Load func builds the config from the defaults, the optional file of CHALLENGR_CONFIG, the env and the *_FILE secret files, then validates it
+func Load() (*Config, error) {
+	cfg := defaults()
+
+	if path := os.Getenv(FileEnv); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := loadEnv(reflect.ValueOf(cfg).Elem()); err != nil {
+		return nil, err
+	}
+
+	if errSlice := cfg.Validate(); len(errSlice) > 0 {
+		return nil, fmt.Errorf("invalid config: %s", strings.Join(errSlice, ", "))
+	}
+
+	return cfg, nil
+}
+
+//loadFile func decodes a yaml or toml file on top of the current values
+func (c *Config) loadFile(path string) error {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %v", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(body, c)
+	case ".toml":
+		var meta toml.MetaData
+		meta, err = toml.Decode(string(body), c)
+		if err == nil && len(meta.Undecoded()) > 0 {
+			err = fmt.Errorf("unknown keys %v", meta.Undecoded())
+		}
+	default:
+		err = errors.New("must be .yaml, .yml or .toml")
+	}
+
+	if err != nil {
+		return fmt.Errorf("config file %s: %v", path, err)
+	}
+	return nil
+}
+
+//loadEnv func overrides the tagged fields from the env. NAME_FILE wins over NAME and holds the path of a file with the value.
+func loadEnv(v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		tag := v.Type().Field(i).Tag
+
+		if field.Kind() == reflect.Struct {
+			if err := loadEnv(field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := tag.Get("env")
+		if name == "" {
+			continue
+		}
+
+		value, ok := os.LookupEnv(name)
+		if path := os.Getenv(name + "_FILE"); path != "" {
+			body, err := ioutil.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("%s_FILE: %v", name, err)
+			}
+			value, ok = strings.TrimSpace(string(body)), true
+		}
+		if !ok {
+			continue
+		}
+
+		if err := set(field, value); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+//set func parses the string value into the field
+func set(field reflect.Value, value string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(value)
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported config type %v", field.Type())
+	}
+
+	return nil
+}
+
+//TrustedProxyList func returns the trusted proxies, nil when no proxy is trusted
+func (c *Config) TrustedProxyList() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(c.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+//Validate func checks the config and returns the names of the invalid settings
+func (c *Config) Validate() []string {
+	errSlice := []string{}
+
+	if _, err := strconv.Atoi(c.Port); err != nil {
+		errSlice = append(errSlice, "port")
+	}
+
+	for _, proxy := range c.TrustedProxyList() {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			errSlice = append(errSlice, "trusted_proxies")
+			break
+		}
+	}
+
+	if c.DB.Host == "" {
+		errSlice = append(errSlice, "db.host")
+	}
+
+	if c.DB.Name == "" {
+		errSlice = append(errSlice, "db.name")
+	}
+
+	if c.DB.User == "" {
+		errSlice = append(errSlice, "db.user")
+	}
+
+	if c.DB.Password == "" {
+		errSlice = append(errSlice, "db.password")
+	}
+
+	if c.AWS.Region == "" {
+		errSlice = append(errSlice, "aws.region")
+	}
+
+	if c.AWS.Bucket == "" {
+		errSlice = append(errSlice, "aws.bucket")
+	}
+
+	if (c.AWS.AccessKeyID == "") != (c.AWS.SecretAccessKey == "") {
+		errSlice = append(errSlice, "aws.access_key_id/aws.secret_access_key")
+	}
+
+	if c.AWS.PresignTTL <= 0 {
+		errSlice = append(errSlice, "aws.presign_ttl")
+	}
+
+	switch c.JWT.Algorithm {
+	case "HS256":
+		if len(c.JWT.Secret) < 16 {
+			errSlice = append(errSlice, "jwt.secret")
+		}
+	case "RS256", "ES256":
+		if c.JWT.KeyFile == "" {
+			errSlice = append(errSlice, "jwt.key_file")
+		}
+	default:
+		errSlice = append(errSlice, "jwt.algorithm")
+	}
+
+	if c.JWT.PreviousSecret != "" && len(c.JWT.PreviousSecret) < 16 {
+		errSlice = append(errSlice, "jwt.previous_secret")
+	}
+
+	if c.Identity.FacebookGraphURL == "" {
+		errSlice = append(errSlice, "identity.facebook_graph_url")
+	}
+
+	if c.Identity.GoogleClientIDs != "" && (c.Identity.GoogleJWKSURL == "" || c.Identity.GoogleIssuer == "") {
+		errSlice = append(errSlice, "identity.google_jwks_url/identity.google_issuer")
+	}
+
+	if c.Identity.AppleClientIDs != "" && (c.Identity.AppleJWKSURL == "" || c.Identity.AppleIssuer == "") {
+		errSlice = append(errSlice, "identity.apple_jwks_url/identity.apple_issuer")
+	}
+
+	if c.JWT.AccessTTL <= 0 {
+		errSlice = append(errSlice, "jwt.access_ttl")
+	}
+
+	if c.JWT.RefreshTTL <= c.JWT.AccessTTL {
+		errSlice = append(errSlice, "jwt.refresh_ttl")
+	}
+
+	if c.Privacy.DeletionGrace <= 0 {
+		errSlice = append(errSlice, "privacy.deletion_grace")
+	}
+
+	rates := []struct{ name, rate string }{
+		{"default", c.RateLimit.Default},
+		{"auth", c.RateLimit.Auth},
+		{"like", c.RateLimit.Like},
+		{"upload", c.RateLimit.Upload},
+	}
+	for _, r := range rates {
+		if _, err := ratelimit.ParseRate(r.rate); err != nil {
+			errSlice = append(errSlice, "rate_limit."+r.name)
+		}
+	}
+
+	specs := []struct{ name, spec string }{
+		{"advance_challenges", c.Jobs.AdvanceChallenges},
+		{"refill_likes", c.Jobs.RefillLikes},
+		{"close_idle_challenges", c.Jobs.CloseIdleChallenges},
+		{"purge_deleted", c.Jobs.PurgeDeleted},
+		{"erase_users", c.Jobs.EraseUsers},
+		{"refresh_trending", c.Jobs.RefreshTrending},
+	}
+	for _, s := range specs {
+		if _, err := cron.Parse(s.spec); s.spec != "" && err != nil {
+			errSlice = append(errSlice, "jobs."+s.name)
+		}
+	}
+
+	if c.Jobs.IdleAfter <= 0 {
+		errSlice = append(errSlice, "jobs.idle_after")
+	}
+
+	if c.Jobs.Retention <= 0 {
+		errSlice = append(errSlice, "jobs.retention")
+	}
+
+	weights := []struct {
+		name   string
+		weight float64
+	}{{"posts", c.Trending.Posts}, {"liked_posts", c.Trending.LikedPosts}, {"velocity", c.Trending.Velocity}}
+	for _, w := range weights {
+		if w.weight < 0 || math.IsNaN(w.weight) || math.IsInf(w.weight, 0) {
+			errSlice = append(errSlice, "trending."+w.name)
+		}
+	}
+
+	if c.Trending.Window <= 0 {
+		errSlice = append(errSlice, "trending.window")
+	}
+
+	if c.Rewards.Exp < 0 {
+		errSlice = append(errSlice, "rewards.exp")
+	}
+
+	if c.Rewards.Coins < 0 {
+		errSlice = append(errSlice, "rewards.coins")
+	}
+
+	if c.Leaderboard.TTL <= 0 {
+		errSlice = append(errSlice, "leaderboard.ttl")
+	}
+
+	if c.Invites.LinkBase == "" {
+		errSlice = append(errSlice, "invites.link_base")
+	}
+
+	return errSlice
+}
+
+//Redacted func returns a copy of the config whose secrets are replaced, so it can be printed
+func (c *Config) Redacted() *Config {
+	cp := *c
+	redact(reflect.ValueOf(&cp).Elem())
+	return &cp
+}
+
+//redact func blanks the fields tagged as secret
+func redact(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			redact(field)
+			continue
+		}
+
+		if v.Type().Field(i).Tag.Get("secret") == "true" && field.Kind() == reflect.String && field.String() != "" {
+			field.SetString(redacted)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+//testEnv is the env of the smallest valid config, without a config file
+var testEnv = map[string]string{
+	FileEnv:       "",
+	"DB_HOST":     "localhost",
+	"DB_PASSWORD": "challengr",
+	"JWT_SECRET":  "0123456789abcdef",
+}
+
+//setEnv func sets the env of the test on top of testEnv
+func setEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for name, value := range testEnv {
+		t.Setenv(name, value)
+	}
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+}
+
+//writeFile func writes the body to a file of the test dir and returns its path
+func writeFile(t *testing.T, name, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(body), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	setEnv(t, nil)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Port != "8080" || cfg.DB.Name != "challengrdb" || cfg.JWT.AccessTTL != 15*time.Minute {
+		t.Errorf("config = %+v, want the defaults", cfg)
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	setEnv(t, map[string]string{
+		"PORT":           "9090",
+		"JWT_ACCESS_TTL": "5m",
+		"TRENDING_POSTS": "0.5",
+		"REWARD_COINS":   "20",
+		"JOBS_ENABLED":   "false",
+	})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Port != "9090" || cfg.JWT.AccessTTL != 5*time.Minute || cfg.Trending.Posts != 0.5 || cfg.Rewards.Coins != 20 || cfg.Jobs.Enabled {
+		t.Errorf("config = %+v, want the env values", cfg)
+	}
+}
+
+func TestLoadSecretFile(t *testing.T) {
+	setEnv(t, map[string]string{
+		"DB_PASSWORD":      "from env",
+		"DB_PASSWORD_FILE": writeFile(t, "db_password", "from file\n"),
+	})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.DB.Password != "from file" {
+		t.Errorf("password = %q, want %q", cfg.DB.Password, "from file")
+	}
+
+	t.Setenv("DB_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	if _, err := Load(); err == nil {
+		t.Error("missing secret file loaded")
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		ok   bool
+	}{
+		{"config.yaml", "port: \"9090\"\ndb:\n  name: filedb\n", true},
+		{"config.toml", "port = \"9090\"\n[db]\nname = \"filedb\"\n", true},
+		{"unknown.yaml", "prot: \"9090\"\n", false},
+		{"unknown.toml", "prot = \"9090\"\n", false},
+		{"config.json", "{}", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnv(t, map[string]string{FileEnv: writeFile(t, tc.name, tc.body)})
+
+			cfg, err := Load()
+			if !tc.ok {
+				if err == nil {
+					t.Error("invalid file loaded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if cfg.Port != "9090" || cfg.DB.Name != "filedb" {
+				t.Errorf("config = %+v, want the file values", cfg)
+			}
+		})
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	cases := []map[string]string{
+		{"DB_HOST": ""},
+		{"JWT_ALGORITHM": "none"},
+		{"JWT_SECRET": "short"},
+		{"JWT_ACCESS_TTL": "soon"},
+		{"JOBS_ENABLED": "sometimes"},
+		{"TRUSTED_PROXIES": "proxy.internal"},
+		{"RATE_LIMIT_AUTH": "10 per minute"},
+		{"JOB_REFILL_LIKES": "61 * * * *"},
+	}
+
+	for _, env := range cases {
+		t.Run(fmt.Sprint(env), func(t *testing.T) {
+			setEnv(t, env)
+			if _, err := Load(); err == nil {
+				t.Error("invalid config loaded")
+			}
+		})
+	}
+}
+
+//TestDataSource checks that a password of any characters survives the connection url
+func TestDataSource(t *testing.T) {
+	password := "p@ss:w/rd#?%&= x"
+	setEnv(t, map[string]string{
+		"DB_HOST":          "db.internal:5432",
+		"DB_USER":          "chall@ngr",
+		"DB_PASSWORD_FILE": writeFile(t, "db_password", password),
+		"DB_SSLMODE":       "verify-full",
+	})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	u, err := url.Parse(cfg.DB.DataSource())
+	if err != nil {
+		t.Fatalf("data source %q: %v", cfg.DB.DataSource(), err)
+	}
+	if got, _ := u.User.Password(); got != password {
+		t.Errorf("password = %q, want %q", got, password)
+	}
+	if u.Scheme != "postgres" || u.User.Username() != "chall@ngr" || u.Host != "db.internal:5432" || u.Path != "/challengrdb" {
+		t.Errorf("data source = %q, want user chall@ngr on db.internal:5432/challengrdb", u)
+	}
+	if sslMode := u.Query().Get("sslmode"); sslMode != "verify-full" {
+		t.Errorf("sslmode = %q, want verify-full", sslMode)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	setEnv(t, map[string]string{"AWS_ACCESS_KEY_ID": "key", "AWS_SECRET_ACCESS_KEY": "secret"})
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	redactedCfg := cfg.Redacted()
+	if redactedCfg.DB.Password != redacted || redactedCfg.JWT.Secret != redacted || redactedCfg.AWS.SecretAccessKey != redacted {
+		t.Errorf("redacted = %+v, want the secrets replaced", redactedCfg)
+	}
+	if redactedCfg.AWS.AccessKeyID != "key" || cfg.DB.Password != "challengr" {
+		t.Error("redacted changed the other values or the config")
+	}
+}
@@ -0,0 +1,156 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//descriptors are the shorthands of the common schedules
+var descriptors = map[string]string{
+	"@yearly":  "0 0 1 1 *",
+	"@monthly": "0 0 1 * *",
+	"@weekly":  "0 0 * * 0",
+	"@daily":   "0 0 * * *",
+	"@hourly":  "0 * * * *",
+}
+
+//field struct is the range of the values of one of the five fields
+type field struct {
+	name     string
+	min, max int
+}
+
+//fields are the minute, hour, day of month, month and day of week fields in the order of a spec. Sunday is 0 or 7.
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+//Schedule struct holds the allowed values of each field as bits
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	//anyDay tells that one of the day fields is *, then a day matches when both do, otherwise when either does
+	anyDay bool
+}
+
+//Parse func parses a five field cron spec like "*/5 * * * *", or one of @yearly, @monthly, @weekly, @daily and @hourly.
+//Fields are lists of values, ranges and steps, e.g. "0,30", "9-17", "*/15" or "1-31/2".
+func Parse(spec string) (*Schedule, error) {
+	if d, ok := descriptors[strings.TrimSpace(spec)]; ok {
+		spec = d
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron spec %q must have %d fields", spec, len(fields))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, part := range parts {
+		b, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron spec %q: %v", spec, err)
+		}
+		bits[i] = b
+	}
+
+	//sunday is both 0 and 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4] | 1
+	}
+
+	return &Schedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		anyDay: strings.HasPrefix(parts[2], "*") || strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+//parseField func turns a comma separated list of values, ranges and steps into bits
+func parseField(s string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %s %q", f.name, item)
+			}
+			rangePart, step = item[:i], n
+		}
+
+		low, high := f.min, f.max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			n, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s %q", f.name, item)
+			}
+			low, high = n, n
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid %s %q", f.name, item)
+				}
+			} else if step > 1 {
+				high = f.max
+			}
+		}
+
+		if low < f.min || high > f.max || low > high {
+			return 0, fmt.Errorf("%s %q out of range %d-%d", f.name, item, f.min, f.max)
+		}
+
+		for v := low; v <= high; v = v + step {
+			bits = bits | 1<<uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+//has func checks if the bit of v is set
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
+
+//day func checks if the day of t matches the day of month and day of week fields
+func (s *Schedule) day(t time.Time) bool {
+	dom, dow := has(s.dom, t.Day()), has(s.dow, int(t.Weekday()))
+	if s.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+//Next func returns the first matching minute after t, in the location of t. It returns the zero time when nothing
+//matches within five years, e.g. for the 31st of February.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location()).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case !has(s.month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.day(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !has(s.hour, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !has(s.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
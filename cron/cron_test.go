@@ -0,0 +1,160 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+//bitsOf func returns the bits of the values
+func bitsOf(values ...int) uint64 {
+	var bits uint64
+	for _, v := range values {
+		bits = bits | 1<<uint(v)
+	}
+	return bits
+}
+
+//rangeOf func returns the bits of low to high by step
+func rangeOf(low, high, step int) uint64 {
+	var bits uint64
+	for v := low; v <= high; v = v + step {
+		bits = bits | 1<<uint(v)
+	}
+	return bits
+}
+
+func TestParse(t *testing.T) {
+	every := struct{ minute, hour, dom, month, dow uint64 }{rangeOf(0, 59, 1), rangeOf(0, 23, 1), rangeOf(1, 31, 1), rangeOf(1, 12, 1), rangeOf(0, 7, 1)}
+
+	cases := []struct {
+		spec string
+		want Schedule
+	}{
+		{"* * * * *", Schedule{every.minute, every.hour, every.dom, every.month, every.dow, true}},
+		{"@yearly", Schedule{bitsOf(0), bitsOf(0), bitsOf(1), bitsOf(1), every.dow, true}},
+		{"@monthly", Schedule{bitsOf(0), bitsOf(0), bitsOf(1), every.month, every.dow, true}},
+		{" @weekly ", Schedule{bitsOf(0), bitsOf(0), every.dom, every.month, bitsOf(0), true}},
+		{"@daily", Schedule{bitsOf(0), bitsOf(0), every.dom, every.month, every.dow, true}},
+		{"@hourly", Schedule{bitsOf(0), every.hour, every.dom, every.month, every.dow, true}},
+		{"0,15,45 * * * *", Schedule{bitsOf(0, 15, 45), every.hour, every.dom, every.month, every.dow, true}},
+		{"0 9-17 * * 1-5", Schedule{bitsOf(0), rangeOf(9, 17, 1), every.dom, every.month, rangeOf(1, 5, 1), true}},
+		{"*/15 */6 * * *", Schedule{rangeOf(0, 59, 15), rangeOf(0, 23, 6), every.dom, every.month, every.dow, true}},
+		{"5/20 * 1-31/2 * *", Schedule{bitsOf(5, 25, 45), every.hour, rangeOf(1, 31, 2), every.month, every.dow, true}},
+		{"0 0 1,15 1-6/2 *", Schedule{bitsOf(0), bitsOf(0), bitsOf(1, 15), bitsOf(1, 3, 5), every.dow, true}},
+		{"0 0 13 * 5", Schedule{bitsOf(0), bitsOf(0), bitsOf(13), every.month, bitsOf(5), false}},
+		{"0 0 */2 * 1", Schedule{bitsOf(0), bitsOf(0), rangeOf(1, 31, 2), every.month, bitsOf(1), true}},
+		{"59 23 31 12 0", Schedule{bitsOf(59), bitsOf(23), bitsOf(31), bitsOf(12), bitsOf(0), false}},
+		{"0 0 1 1 7", Schedule{bitsOf(0), bitsOf(0), bitsOf(1), bitsOf(1), bitsOf(0, 7), false}},
+		{"0 0 1 1 5-7", Schedule{bitsOf(0), bitsOf(0), bitsOf(1), bitsOf(1), bitsOf(0, 5, 6, 7), false}},
+	}
+
+	for _, tc := range cases {
+		s, err := Parse(tc.spec)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.spec, err)
+			continue
+		}
+		if *s != tc.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tc.spec, *s, tc.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"@every 5m",
+		"@annually",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 0 *",
+		"* * * 13 *",
+		"* * * * 8",
+		"-1 * * * *",
+		"5-1 * * * *",
+		"1-2-3 * * * *",
+		"*/0 * * * *",
+		"*/-5 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+		"1, * * * *",
+		"* * * JAN *",
+		"* * * * MON",
+		"0-60/5 * * * *",
+	}
+
+	for _, spec := range specs {
+		if s, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) = %+v, want an error", spec, *s)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	at := func(year int, month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+	}
+
+	cases := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"next step", "*/5 * * * *", at(2020, 3, 4, 10, 2, 30), at(2020, 3, 4, 10, 5, 0)},
+		{"strictly after", "*/5 * * * *", at(2020, 3, 4, 10, 5, 0), at(2020, 3, 4, 10, 10, 0)},
+		{"next hour", "0 * * * *", at(2020, 3, 4, 10, 0, 1), at(2020, 3, 4, 11, 0, 0)},
+		{"next day", "30 3 * * *", at(2020, 3, 4, 3, 30, 0), at(2020, 3, 5, 3, 30, 0)},
+		{"month boundary", "30 3 * * *", at(2020, 1, 31, 4, 0, 0), at(2020, 2, 1, 3, 30, 0)},
+		{"year boundary", "@yearly", at(2020, 12, 31, 23, 59, 0), at(2021, 1, 1, 0, 0, 0)},
+		{"last minute of the year", "59 23 31 12 *", at(2020, 6, 1, 0, 0, 0), at(2020, 12, 31, 23, 59, 0)},
+		{"skips the short months", "0 0 31 * *", at(2020, 4, 1, 0, 0, 0), at(2020, 5, 31, 0, 0, 0)},
+		{"leap day", "0 0 29 2 *", at(2021, 3, 1, 0, 0, 0), at(2024, 2, 29, 0, 0, 0)},
+		{"never", "0 0 30 2 *", at(2020, 1, 1, 0, 0, 0), time.Time{}},
+		{"monthly across the year", "@monthly", at(2020, 12, 15, 0, 0, 0), at(2021, 1, 1, 0, 0, 0)},
+
+		//2020-03-01 is a sunday
+		{"sunday as 0", "0 12 * * 0", at(2020, 3, 2, 0, 0, 0), at(2020, 3, 8, 12, 0, 0)},
+		{"sunday as 7", "0 12 * * 7", at(2020, 3, 2, 0, 0, 0), at(2020, 3, 8, 12, 0, 0)},
+		{"weekend range to 7", "0 12 * * 6-7", at(2020, 3, 2, 0, 0, 0), at(2020, 3, 7, 12, 0, 0)},
+		{"weekly", "@weekly", at(2020, 3, 1, 0, 0, 0), at(2020, 3, 8, 0, 0, 0)},
+
+		//both day fields restricted: the 13th or a friday, whichever comes first
+		{"day of month or week, week first", "0 0 13 * 5", at(2020, 3, 1, 0, 0, 0), at(2020, 3, 6, 0, 0, 0)},
+		{"day of month or week, month first", "0 0 13 * 5", at(2020, 3, 7, 0, 0, 0), at(2020, 3, 13, 0, 0, 0)},
+		{"day of month or week, after the 13th", "0 0 13 * 5", at(2020, 3, 13, 0, 0, 0), at(2020, 3, 20, 0, 0, 0)},
+		//a * day field: both must match, an odd day which is a monday
+		{"starred day of month and week", "0 0 */2 * 1", at(2020, 3, 1, 0, 0, 0), at(2020, 3, 9, 0, 0, 0)},
+		{"any day of month", "0 0 * * 5", at(2020, 3, 1, 0, 0, 0), at(2020, 3, 6, 0, 0, 0)},
+		{"any day of week", "0 0 13 * *", at(2020, 3, 1, 0, 0, 0), at(2020, 3, 13, 0, 0, 0)},
+	}
+
+	for _, tc := range cases {
+		s, err := Parse(tc.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tc.spec, err)
+		}
+		if got := s.Next(tc.from); !got.Equal(tc.want) {
+			t.Errorf("%s: Next(%q, %v) = %v, want %v", tc.name, tc.spec, tc.from, got, tc.want)
+		}
+	}
+}
+
+//TestNextLocation checks that the schedule runs on the wall clock of the location of the time
+func TestNextLocation(t *testing.T) {
+	s, err := Parse("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	got := s.Next(time.Date(2020, 1, 1, 10, 0, 0, 0, zone))
+	if want := time.Date(2020, 1, 2, 9, 0, 0, 0, zone); !got.Equal(want) || got.Location() != zone {
+		t.Errorf("next = %v, want %v", got, want)
+	}
+}
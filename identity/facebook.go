@@ -0,0 +1,59 @@
+package identity
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+//facebookUser struct is the response of the graph api me endpoint
+type facebookUser struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+//facebook struct verifies facebook access tokens against the graph api
+type facebook struct {
+	client   *http.Client
+	graphURL string
+}
+
+//NewFacebook func creates the facebook provider which asks the graph api at graphURL
+func NewFacebook(client *http.Client, graphURL string) Provider {
+	return &facebook{client: client, graphURL: strings.TrimSuffix(graphURL, "/")}
+}
+
+//Name func returns the name of the provider
+func (f *facebook) Name() string {
+	return Facebook
+}
+
+//Verify func fetches the user of the access token from the graph api
+func (f *facebook) Verify(credential string) (*Identity, error) {
+	res, err := f.client.Get(f.graphURL + "/me?fields=id,name,email&access_token=" + url.QueryEscape(credential))
+	if err != nil {
+		return nil, fmt.Errorf("facebook req err: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusBadRequest || res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("%w: facebook status %v", ErrInvalidCredential, res.StatusCode)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("facebook status %v", res.StatusCode)
+	}
+
+	var user facebookUser
+	if err := json.NewDecoder(res.Body).Decode(&user); err != nil {
+		return nil, fmt.Errorf("facebook body unmarshalling err: %v", err)
+	}
+
+	if user.ID == "" {
+		return nil, fmt.Errorf("%w: facebook user without id", ErrInvalidCredential)
+	}
+
+	return &Identity{Provider: Facebook, Subject: user.ID, Email: user.Email, Name: user.Name}, nil
+}
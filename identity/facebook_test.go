@@ -0,0 +1,71 @@
+package identity
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+//newGraphServer func serves the graph api me endpoint, the responses are keyed by access token
+func newGraphServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/me" || r.URL.Query().Get("fields") != "id,name,email" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		switch r.URL.Query().Get("access_token") {
+		case "token of ada":
+			w.Write([]byte(`{"id":"10","name":"Ada","email":"ada@example.com"}`))
+		case "token of grace":
+			w.Write([]byte(`{"id":"11","name":"Grace","email":"grace@example.com"}`))
+		case "token without id":
+			w.Write([]byte(`{"name":"Nobody"}`))
+		case "token of a down graph":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"type":"OAuthException","code":190}}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFacebookVerify(t *testing.T) {
+	srv := newGraphServer(t)
+	provider := NewFacebook(srv.Client(), srv.URL+"/")
+
+	cases := []struct {
+		name    string
+		token   string
+		want    *Identity
+		invalid bool
+	}{
+		{"user of the token", "token of ada", &Identity{Provider: Facebook, Subject: "10", Email: "ada@example.com", Name: "Ada"}, false},
+		{"other user of the token", "token of grace", &Identity{Provider: Facebook, Subject: "11", Email: "grace@example.com", Name: "Grace"}, false},
+		{"user without id", "token without id", nil, true},
+		{"unauthorized", "expired token", nil, true},
+		{"graph down", "token of a down graph", nil, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			id, err := provider.Verify(tc.token)
+			if tc.want == nil {
+				if err == nil || errors.Is(err, ErrInvalidCredential) != tc.invalid {
+					t.Errorf("error = %v, want an invalid credential %v", err, tc.invalid)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if *id != *tc.want {
+				t.Errorf("identity = %+v, want %+v", *id, *tc.want)
+			}
+		})
+	}
+}
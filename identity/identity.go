@@ -0,0 +1,69 @@
+package identity
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/challengr/config"
+)
+
+//Names of the supported providers, they are stored with the linked identities
+const (
+	Facebook = "facebook"
+	Google   = "google"
+	Apple    = "apple"
+)
+
+//ErrInvalidCredential is wrapped by the errors of the credentials which the provider does not vouch for.
+//Every other error means the provider could not be asked.
+var ErrInvalidCredential = errors.New("invalid identity credential")
+
+//Identity struct is the account of a provider which a credential proves
+type Identity struct {
+	Provider string
+	Subject  string
+	Email    string
+	Name     string
+}
+
+//Provider interface is implemented by the sign in providers. Verify checks the credential the client got from the provider,
+//a facebook access token or an id token, and returns the identity it proves.
+type Provider interface {
+	Name() string
+	Verify(credential string) (*Identity, error)
+}
+
+//Providers type maps the enabled providers by name
+type Providers map[string]Provider
+
+//New func creates the providers enabled in the config. Facebook is always enabled.
+func New(cfg config.Identity) Providers {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	providers := Providers{Facebook: NewFacebook(client, cfg.FacebookGraphURL)}
+
+	if ids := split(cfg.GoogleClientIDs); len(ids) > 0 {
+		//google issues with and without the scheme
+		issuers := []string{cfg.GoogleIssuer, strings.TrimPrefix(cfg.GoogleIssuer, "https://")}
+		providers[Google] = NewIDToken(Google, client, cfg.GoogleJWKSURL, issuers, ids)
+	}
+
+	if ids := split(cfg.AppleClientIDs); len(ids) > 0 {
+		providers[Apple] = NewIDToken(Apple, client, cfg.AppleJWKSURL, []string{cfg.AppleIssuer}, ids)
+	}
+
+	return providers
+}
+
+//split func splits a comma separated setting, dropping the blanks
+func split(value string) []string {
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
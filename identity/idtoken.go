@@ -0,0 +1,187 @@
+package identity
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/challengr/keyset"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+//jwksMaxAge is how long fetched keys are trusted, jwksMinRefresh throttles the refetches on unknown kids
+const (
+	jwksMaxAge     = time.Hour
+	jwksMinRefresh = time.Minute
+)
+
+//idTokenClaims struct holds the claims of an openid connect id token which we use. Apple sends email_verified as a string.
+type idTokenClaims struct {
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"`
+	Name          string      `json:"name"`
+
+	jwt.StandardClaims
+}
+
+//verifiedEmail func returns the email if the provider verified it
+func (c *idTokenClaims) verifiedEmail() string {
+	if c.EmailVerified == true || c.EmailVerified == "true" {
+		return c.Email
+	}
+	return ""
+}
+
+//idToken struct verifies the openid connect id tokens of a provider against its JWKS
+type idToken struct {
+	name      string
+	client    *http.Client
+	jwksURL   string
+	issuers   []string
+	clientIDs []string
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+//NewIDToken func creates a provider which accepts the id tokens signed by a key of jwksURL, issued by one of the issuers for one of the client ids
+func NewIDToken(name string, client *http.Client, jwksURL string, issuers, clientIDs []string) Provider {
+	return &idToken{name: name, client: client, jwksURL: jwksURL, issuers: issuers, clientIDs: clientIDs}
+}
+
+//Name func returns the name of the provider
+func (p *idToken) Name() string {
+	return p.name
+}
+
+//Verify func checks the signature, expiry, issuer and audience of the id token
+func (p *idToken) Verify(credential string) (*Identity, error) {
+	claims := idTokenClaims{}
+	_, err := jwt.ParseWithClaims(credential, &claims, p.keyfunc)
+	if err != nil {
+		if ve, ok := err.(*jwt.ValidationError); ok && ve.Inner != nil && ve.Errors&jwt.ValidationErrorUnverifiable != 0 {
+			if _, invalid := ve.Inner.(invalidKidError); !invalid {
+				return nil, ve.Inner
+			}
+		}
+		return nil, fmt.Errorf("%w: %s id token: %v", ErrInvalidCredential, p.name, err)
+	}
+
+	if !contains(p.issuers, claims.Issuer) {
+		return nil, fmt.Errorf("%w: %s id token issued by %q", ErrInvalidCredential, p.name, claims.Issuer)
+	}
+
+	if !contains(p.clientIDs, claims.Audience) {
+		return nil, fmt.Errorf("%w: %s id token issued for %q", ErrInvalidCredential, p.name, claims.Audience)
+	}
+
+	if claims.Subject == "" || claims.ExpiresAt == 0 {
+		return nil, fmt.Errorf("%w: %s id token without sub or exp", ErrInvalidCredential, p.name)
+	}
+
+	return &Identity{Provider: p.name, Subject: claims.Subject, Email: claims.verifiedEmail(), Name: claims.Name}, nil
+}
+
+//invalidKidError type is returned by keyfunc when the token names a key the provider does not have
+type invalidKidError string
+
+func (e invalidKidError) Error() string {
+	return string(e)
+}
+
+//keyfunc func picks the key of the kid, the algorithm must match the type of the key
+func (p *idToken) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, invalidKidError("id token without kid")
+	}
+
+	key, err := p.key(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey:
+		if token.Method.Alg() != keyset.RS256 {
+			return nil, invalidKidError("unexpected signing method " + token.Method.Alg())
+		}
+	case *ecdsa.PublicKey:
+		if token.Method.Alg() != keyset.ES256 {
+			return nil, invalidKidError("unexpected signing method " + token.Method.Alg())
+		}
+	}
+
+	return key, nil
+}
+
+//key func returns the cached key of the kid. The JWKS is refetched once it is old or, at most every jwksMinRefresh, on an unknown kid.
+func (p *idToken) key(kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	age := time.Since(p.fetchedAt)
+	if key, ok := p.keys[kid]; ok && age < jwksMaxAge {
+		return key, nil
+	}
+
+	if p.keys == nil || age >= jwksMinRefresh {
+		if err := p.fetch(); err != nil {
+			return nil, err
+		}
+	}
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, invalidKidError("unknown kid " + kid)
+}
+
+//fetch func downloads the JWKS of the provider. Caller must hold the lock.
+func (p *idToken) fetch() error {
+	res, err := p.client.Get(p.jwksURL)
+	if err != nil {
+		return fmt.Errorf("%s jwks req err: %v", p.name, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s jwks status %v", p.name, res.StatusCode)
+	}
+
+	var jwks keyset.JWKS
+	if err := json.NewDecoder(res.Body).Decode(&jwks); err != nil {
+		return fmt.Errorf("%s jwks unmarshalling err: %v", p.name, err)
+	}
+
+	keys := map[string]interface{}{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	p.keys = keys
+	p.fetchedAt = time.Now()
+	return nil
+}
+
+//contains func tells if the value is one of the values
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
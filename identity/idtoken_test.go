@@ -0,0 +1,242 @@
+package identity
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/challengr/keyset"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const (
+	testIssuer   = "https://accounts.example.com"
+	testClientID = "client-1"
+)
+
+//testKey struct is a private key of the provider with its kid
+type testKey struct {
+	private interface{}
+	kid     string
+	key     *keyset.Key
+}
+
+//newRSAKey func generates an RS256 key of the provider
+func newRSAKey(t *testing.T) *testKey {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parseKey(t, private, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)}))
+}
+
+//newECKey func generates an ES256 key of the provider
+func newECKey(t *testing.T) *testKey {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parseKey(t, private, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+}
+
+//parseKey func reads the kid of the key the way the keyset does
+func parseKey(t *testing.T, private interface{}, data []byte) *testKey {
+	t.Helper()
+	key, err := keyset.ParsePrivateKeyPEM(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKey{private: private, kid: key.ID, key: key}
+}
+
+//sign func signs the claims with the key under the kid, an empty kid header is left out
+func (k *testKey) sign(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(k.private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+//newJWKSServer func serves the public keys as the JWKS of the provider and counts the fetches
+func newJWKSServer(t *testing.T, fetches *int, keys ...*testKey) *httptest.Server {
+	t.Helper()
+	ks, err := keyset.New(keys[0].key, keysOf(keys[1:])...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches != nil {
+			*fetches++
+		}
+		json.NewEncoder(w).Encode(ks.JWKS())
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+//keysOf func returns the keyset keys of the test keys
+func keysOf(keys []*testKey) []*keyset.Key {
+	keySlice := []*keyset.Key{}
+	for _, k := range keys {
+		keySlice = append(keySlice, k.key)
+	}
+	return keySlice
+}
+
+//validClaims func returns the claims of an id token the provider accepts
+func validClaims() *idTokenClaims {
+	return &idTokenClaims{
+		Email:         "ada@example.com",
+		EmailVerified: true,
+		Name:          "Ada",
+		StandardClaims: jwt.StandardClaims{
+			Subject:   "108",
+			Issuer:    testIssuer,
+			Audience:  testClientID,
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+	}
+}
+
+func TestIDTokenVerify(t *testing.T) {
+	rsaKey, ecKey, otherKey := newRSAKey(t), newECKey(t), newRSAKey(t)
+	srv := newJWKSServer(t, nil, rsaKey, ecKey)
+	provider := NewIDToken(Google, srv.Client(), srv.URL, []string{testIssuer}, []string{"client-0", testClientID})
+
+	withClaims := func(change func(*idTokenClaims)) *idTokenClaims {
+		claims := validClaims()
+		change(claims)
+		return claims
+	}
+
+	cases := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"rs256", rsaKey.sign(t, jwt.SigningMethodRS256, rsaKey.kid, validClaims()), true},
+		{"es256", ecKey.sign(t, jwt.SigningMethodES256, ecKey.kid, validClaims()), true},
+		{"bad signature", otherKey.sign(t, jwt.SigningMethodRS256, rsaKey.kid, validClaims()), false},
+		{"unknown kid", otherKey.sign(t, jwt.SigningMethodRS256, otherKey.kid, validClaims()), false},
+		{"no kid", rsaKey.sign(t, jwt.SigningMethodRS256, "", validClaims()), false},
+		{"algorithm of other key", ecKey.sign(t, jwt.SigningMethodES256, rsaKey.kid, validClaims()), false},
+		{"wrong aud", rsaKey.sign(t, jwt.SigningMethodRS256, rsaKey.kid, withClaims(func(c *idTokenClaims) { c.Audience = "client-2" })), false},
+		{"wrong iss", rsaKey.sign(t, jwt.SigningMethodRS256, rsaKey.kid, withClaims(func(c *idTokenClaims) { c.Issuer = "https://evil.example.com" })), false},
+		{"expired", rsaKey.sign(t, jwt.SigningMethodRS256, rsaKey.kid, withClaims(func(c *idTokenClaims) { c.ExpiresAt = time.Now().Add(-time.Minute).Unix() })), false},
+		{"no exp", rsaKey.sign(t, jwt.SigningMethodRS256, rsaKey.kid, withClaims(func(c *idTokenClaims) { c.ExpiresAt = 0 })), false},
+		{"no sub", rsaKey.sign(t, jwt.SigningMethodRS256, rsaKey.kid, withClaims(func(c *idTokenClaims) { c.Subject = "" })), false},
+		{"malformed", "not.a.token", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			id, err := provider.Verify(tc.token)
+			if !tc.valid {
+				if !errors.Is(err, ErrInvalidCredential) {
+					t.Errorf("error = %v, want an invalid credential", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			want := Identity{Provider: Google, Subject: "108", Email: "ada@example.com", Name: "Ada"}
+			if *id != want {
+				t.Errorf("identity = %+v, want %+v", *id, want)
+			}
+		})
+	}
+}
+
+//TestIDTokenUnverifiedEmail checks that an email the provider did not verify is dropped, apple sends the flag as a string
+func TestIDTokenUnverifiedEmail(t *testing.T) {
+	key := newRSAKey(t)
+	srv := newJWKSServer(t, nil, key)
+	provider := NewIDToken(Apple, srv.Client(), srv.URL, []string{testIssuer}, []string{testClientID})
+
+	cases := []struct {
+		verified interface{}
+		email    string
+	}{
+		{nil, ""},
+		{false, ""},
+		{"false", ""},
+		{"true", "ada@example.com"},
+	}
+
+	for _, tc := range cases {
+		claims := validClaims()
+		claims.EmailVerified = tc.verified
+
+		id, err := provider.Verify(key.sign(t, jwt.SigningMethodRS256, key.kid, claims))
+		if err != nil {
+			t.Fatalf("verify: %v", err)
+		}
+		if id.Email != tc.email {
+			t.Errorf("email of email_verified %v = %q, want %q", tc.verified, id.Email, tc.email)
+		}
+	}
+}
+
+//TestIDTokenJWKSCache checks that the JWKS is fetched once and refetched on an unknown kid at most every jwksMinRefresh
+func TestIDTokenJWKSCache(t *testing.T) {
+	key, otherKey := newRSAKey(t), newRSAKey(t)
+	fetches := 0
+	srv := newJWKSServer(t, &fetches, key)
+	provider := NewIDToken(Google, srv.Client(), srv.URL, []string{testIssuer}, []string{testClientID})
+
+	token := key.sign(t, jwt.SigningMethodRS256, key.kid, validClaims())
+	for i := 0; i < 3; i++ {
+		if _, err := provider.Verify(token); err != nil {
+			t.Fatalf("verify: %v", err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("fetches = %v, want 1", fetches)
+	}
+
+	if _, err := provider.Verify(otherKey.sign(t, jwt.SigningMethodRS256, otherKey.kid, validClaims())); !errors.Is(err, ErrInvalidCredential) {
+		t.Errorf("unknown kid error = %v, want an invalid credential", err)
+	}
+	if fetches != 1 {
+		t.Errorf("fetches after unknown kid = %v, want 1 within jwksMinRefresh", fetches)
+	}
+}
+
+//TestIDTokenJWKSDown checks that a provider which cannot be asked is not mistaken for an invalid credential
+func TestIDTokenJWKSDown(t *testing.T) {
+	key := newRSAKey(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	provider := NewIDToken(Google, srv.Client(), srv.URL, []string{testIssuer}, []string{testClientID})
+
+	_, err := provider.Verify(key.sign(t, jwt.SigningMethodRS256, key.kid, validClaims()))
+	if err == nil || errors.Is(err, ErrInvalidCredential) {
+		t.Errorf("error = %v, want a jwks error", err)
+	}
+}
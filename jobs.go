@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/challengr/config"
+	"github.com/challengr/model"
+	"github.com/challengr/service"
+)
+
+//jobsUsage is printed when the jobs command is called wrongly
+const jobsUsage = `usage: challengr jobs <command>
+
+    list        list the jobs with their schedule, last run and last failure
+    run <name>  run a job now, unless it is running elsewhere`
+
+//runJobs func handles the `challengr jobs` sub commands
+func runJobs(args []string) {
+	if len(args) == 0 {
+		log.Fatal(jobsUsage)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config error: %v", err)
+	}
+
+	db, err := model.Connect(cfg.DB)
+	if err != nil {
+		log.Fatalf("DB connection error: %v", err)
+	}
+	defer db.Close()
+
+	//no tokens are signed or verified, the service needs no keyset
+	scheduler := service.New(model.NewPostgresStore(db), cfg, nil).Jobs()
+
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		statusList, err := scheduler.Status()
+		if err != nil {
+			log.Fatalf("jobs list error: %v", err)
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(statusList); err != nil {
+			log.Fatalf("jobs list error: %v", err)
+		}
+	case args[0] == "run" && len(args) == 2:
+		run, err := scheduler.Run(args[1])
+		if err != nil {
+			log.Fatalf("job %v error: %v", args[1], err)
+		}
+		if run.Error != "" {
+			log.Fatalf("job %v failed: %v", args[1], run.Error)
+		}
+
+		log.Printf("job %v done", args[1])
+	default:
+		log.Fatal(jobsUsage)
+	}
+}
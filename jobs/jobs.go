@@ -0,0 +1,258 @@
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/challengr/cron"
+	"github.com/challengr/model"
+)
+
+//triggers of the runs
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+//Job struct is a named maintenance task. The spec is a cron expression in UTC, an empty spec only runs the job on demand.
+type Job struct {
+	Name string
+	Spec string
+	Run  func() error
+}
+
+//Status struct is the state of a job: its next scheduled run, its last run and its last failed run
+type Status struct {
+	Name        string        `json:"name"`
+	Spec        string        `json:"spec"`
+	NextRunAt   *time.Time    `json:"next_run_at"`
+	LastRun     *model.JobRun `json:"last_run"`
+	LastFailure *model.JobRun `json:"last_failure"`
+}
+
+//entry struct is a registered job with its parsed schedule, which is nil for on demand jobs
+type entry struct {
+	job      Job
+	schedule *cron.Schedule
+	next     time.Time
+}
+
+//Scheduler struct runs the jobs on their schedule. Every run takes the lock of its job first, so when several replicas
+//schedule the same job only one of them runs it; the runs are recorded in the job runs history.
+type Scheduler struct {
+	runs model.JobRunRepository
+
+	mu      sync.Mutex
+	entries []*entry
+	stop    chan struct{}
+}
+
+//New func creates a scheduler which locks and records the runs through the given repository
+func New(runs model.JobRunRepository) *Scheduler {
+	return &Scheduler{runs: runs}
+}
+
+//Add func registers a job, its name must be unique
+func (s *Scheduler) Add(job Job) error {
+	e := &entry{job: job}
+	if job.Spec != "" {
+		schedule, err := cron.Parse(job.Spec)
+		if err != nil {
+			return err
+		}
+		e.schedule = schedule
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, other := range s.entries {
+		if other.job.Name == job.Name {
+			return fmt.Errorf("job %q added twice", job.Name)
+		}
+	}
+	s.entries = append(s.entries, e)
+
+	return nil
+}
+
+//Start func runs the due jobs at the start of every minute until Stop is called
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+
+	now := time.Now().UTC()
+	for _, e := range s.entries {
+		if e.schedule != nil {
+			e.next = e.schedule.Next(now)
+		}
+	}
+
+	go s.loop(s.stop)
+	log.Printf("job scheduler started with %d jobs", len(s.entries))
+}
+
+//Stop func stops scheduling new runs. Runs which already started go on.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+//loop func wakes up at every minute and starts the due jobs
+func (s *Scheduler) loop(stop chan struct{}) {
+	for {
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		for _, job := range s.due(time.Now().UTC()) {
+			go func(job Job) {
+				if _, err := s.run(job, TriggerSchedule); err != nil {
+					log.Printf("job %v not run: %v", job.Name, err)
+				}
+			}(job)
+		}
+	}
+}
+
+//due func returns the jobs whose next run has come and schedules their following run
+func (s *Scheduler) due(now time.Time) []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobList := []Job{}
+	for _, e := range s.entries {
+		if e.schedule == nil || e.next.IsZero() || e.next.After(now) {
+			continue
+		}
+		jobList = append(jobList, e.job)
+		e.next = e.schedule.Next(now)
+	}
+
+	return jobList
+}
+
+//find func returns the entry of the named job
+func (s *Scheduler) find(name string) (*entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.entries {
+		if e.job.Name == name {
+			return e, nil
+		}
+	}
+
+	return nil, model.NotFound(model.CodeNotFound, "Job not found")
+}
+
+//Run func runs the named job right away and returns its run. A failure of the job is recorded in the error of the run,
+//the error is for runs which could not start, e.g. because the job is already running.
+func (s *Scheduler) Run(name string) (*model.JobRun, error) {
+	e, err := s.find(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.run(e.job, TriggerManual)
+}
+
+//run func runs the job under its lock and records the run
+func (s *Scheduler) run(job Job, trigger string) (*model.JobRun, error) {
+	unlock, ok, err := s.runs.Lock(job.Name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, model.Conflict(model.CodeJobRunning, "Job is already running")
+	}
+	defer unlock()
+
+	run := model.JobRun{Name: job.Name, Trigger: trigger}
+	if err = s.runs.Start(&run); err != nil {
+		return nil, err
+	}
+
+	log.Printf("job %v started", job.Name)
+	if err := safeRun(job.Run); err != nil {
+		log.Printf("job %v failed: %v", job.Name, err)
+		run.Error = err.Error()
+	}
+
+	if err = s.runs.Finish(&run); err != nil {
+		return &run, err
+	}
+	log.Printf("job %v finished in %v", job.Name, run.FinishedAt.Sub(run.StartedAt))
+
+	return &run, nil
+}
+
+//safeRun func calls fn and turns its panic into an error, so a broken job does not take the server down
+func safeRun(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return fn()
+}
+
+//Status func returns the state of every job in the order they were added
+func (s *Scheduler) Status() ([]*Status, error) {
+	s.mu.Lock()
+	statusList := []*Status{}
+	now := time.Now().UTC()
+	for _, e := range s.entries {
+		status := Status{Name: e.job.Name, Spec: e.job.Spec}
+		if e.schedule != nil {
+			next := e.next
+			if next.IsZero() || s.stop == nil {
+				next = e.schedule.Next(now)
+			}
+			if !next.IsZero() {
+				status.NextRunAt = &next
+			}
+		}
+		statusList = append(statusList, &status)
+	}
+	s.mu.Unlock()
+
+	for _, status := range statusList {
+		runList, err := s.runs.Get(model.JobRunFilter{Name: status.Name, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(runList) > 0 {
+			status.LastRun = runList[0]
+		}
+
+		runList, err = s.runs.Get(model.JobRunFilter{Name: status.Name, Failed: true, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(runList) > 0 {
+			status.LastFailure = runList[0]
+		}
+	}
+
+	return statusList, nil
+}
@@ -0,0 +1,341 @@
+package keyset
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"sort"
+
+	"github.com/challengr/config"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+//Supported signing algorithms
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+//Key struct is a signing or verification key with its id, which is sent as the kid header of the tokens
+type Key struct {
+	ID        string
+	Algorithm string
+
+	//signKey is nil for verification only keys
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+//Keyset struct signs tokens with one key and verifies them with every key it holds, so tokens of the previous key keep
+//working while keys are rotated
+type Keyset struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+//New func creates a keyset which signs with signing and verifies with signing and the other keys
+func New(signing *Key, verify ...*Key) (*Keyset, error) {
+	if signing == nil || signing.signKey == nil {
+		return nil, errors.New("keyset: signing key has no private part")
+	}
+
+	k := &Keyset{signing: signing, keys: map[string]*Key{signing.ID: signing}}
+	for _, key := range verify {
+		if existing, ok := k.keys[key.ID]; ok && existing.Algorithm != key.Algorithm {
+			return nil, fmt.Errorf("keyset: duplicate kid %s", key.ID)
+		}
+		if _, ok := k.keys[key.ID]; !ok {
+			k.keys[key.ID] = key
+		}
+	}
+
+	return k, nil
+}
+
+//Load func builds the keyset of the config. HS256 signs with the secret, RS256 and ES256 with the PEM private key of key_file.
+//The previous secret and the PEM public keys of verify_keys_dir are accepted for verification only.
+func Load(cfg config.JWT) (*Keyset, error) {
+	var (
+		signing *Key
+		err     error
+	)
+
+	switch cfg.Algorithm {
+	case HS256:
+		signing = NewHMAC([]byte(cfg.Secret))
+	case RS256, ES256:
+		signing, err = readKey(cfg.KeyFile, ParsePrivateKeyPEM)
+		if err != nil {
+			return nil, err
+		}
+		if signing.Algorithm != cfg.Algorithm {
+			return nil, fmt.Errorf("keyset: %s holds a %s key, %s configured", cfg.KeyFile, signing.Algorithm, cfg.Algorithm)
+		}
+	default:
+		return nil, fmt.Errorf("keyset: unsupported algorithm %q", cfg.Algorithm)
+	}
+
+	verify := []*Key{}
+	if cfg.PreviousSecret != "" {
+		previous := NewHMAC([]byte(cfg.PreviousSecret))
+		previous.signKey = nil
+		verify = append(verify, previous)
+	}
+
+	if cfg.VerifyKeysDir != "" {
+		paths, err := filepath.Glob(filepath.Join(cfg.VerifyKeysDir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			key, err := readKey(path, ParsePublicKeyPEM)
+			if err != nil {
+				return nil, err
+			}
+			verify = append(verify, key)
+		}
+	}
+
+	return New(signing, verify...)
+}
+
+//readKey func parses the PEM file at path
+func readKey(path string, parse func([]byte) (*Key, error)) (*Key, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("keyset: %v", err)
+	}
+
+	key, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("keyset: %s: %v", path, err)
+	}
+
+	return key, nil
+}
+
+//NewHMAC func creates an HS256 key from the secret. The kid is derived from the hash of the secret.
+func NewHMAC(secret []byte) *Key {
+	sum := sha256.Sum256(secret)
+	return &Key{ID: "hs-" + hex.EncodeToString(sum[:8]), Algorithm: HS256, signKey: secret, verifyKey: secret}
+}
+
+//ParsePrivateKeyPEM func parses an RSA or P-256 EC private key in PKCS #1, SEC 1 or PKCS #8 form.
+//The kid is the RFC 7638 thumbprint of the public key.
+func ParsePrivateKeyPEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var private interface{}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		private = key
+	} else if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		private = key
+	} else if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		private = key
+	}
+
+	switch key := private.(type) {
+	case *rsa.PrivateKey:
+		return newRSA(key, &key.PublicKey)
+	case *ecdsa.PrivateKey:
+		return newEC(key, &key.PublicKey)
+	}
+
+	return nil, errors.New("not an RSA or EC private key")
+}
+
+//ParsePublicKeyPEM func parses an RSA or P-256 EC public key or certificate. The kid is the RFC 7638 thumbprint of the key.
+func ParsePublicKeyPEM(data []byte) (*Key, error) {
+	if rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return newRSA(nil, rsaKey)
+	}
+
+	if ecKey, err := jwt.ParseECPublicKeyFromPEM(data); err == nil {
+		return newEC(nil, ecKey)
+	}
+
+	return nil, errors.New("not an RSA or EC public key")
+}
+
+//newRSA func creates an RS256 key, private may be nil
+func newRSA(private *rsa.PrivateKey, public *rsa.PublicKey) (*Key, error) {
+	if public.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must have at least 2048 bits")
+	}
+
+	key := &Key{Algorithm: RS256, verifyKey: public}
+	if private != nil {
+		key.signKey = private
+	}
+	key.ID = thumbprint(key.JWK())
+
+	return key, nil
+}
+
+//newEC func creates an ES256 key, private may be nil
+func newEC(private *ecdsa.PrivateKey, public *ecdsa.PublicKey) (*Key, error) {
+	if public.Curve != elliptic.P256() {
+		return nil, errors.New("ES256 needs a P-256 key")
+	}
+
+	key := &Key{Algorithm: ES256, verifyKey: public}
+	if private != nil {
+		key.signKey = private
+	}
+	key.ID = thumbprint(key.JWK())
+
+	return key, nil
+}
+
+//Sign func signs the claims with the signing key and sets its kid header
+func (k *Keyset) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(k.signing.Algorithm), claims)
+	token.Header["kid"] = k.signing.ID
+
+	return token.SignedString(k.signing.signKey)
+}
+
+//Keyfunc func is the jwt.Keyfunc which picks the verification key of a token by its kid.
+//Tokens without a kid predate the keyset and are checked against the signing key.
+func (k *Keyset) Keyfunc(token *jwt.Token) (interface{}, error) {
+	key := k.signing
+	if kid, ok := token.Header["kid"]; ok {
+		id, _ := kid.(string)
+		if key, ok = k.keys[id]; !ok {
+			return nil, fmt.Errorf("unknown kid %v", kid)
+		}
+	}
+
+	//the algorithm of the key wins over the one of the header, an RSA public key must never be used as an HMAC secret
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method %v for kid %v", token.Method.Alg(), key.ID)
+	}
+
+	return key.verifyKey, nil
+}
+
+//JWK struct is a public key in the JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+//JWKS struct is the JSON Web Key Set document
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+//JWK func returns the public key in the JWK format. HMAC keys have no public part and return nil.
+func (k *Key) JWK() *JWK {
+	switch public := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		return &JWK{Kty: "RSA", Use: "sig", Alg: k.Algorithm, Kid: k.ID, N: encode(public.N.Bytes()), E: encode(big.NewInt(int64(public.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		return &JWK{Kty: "EC", Use: "sig", Alg: k.Algorithm, Kid: k.ID, Crv: public.Curve.Params().Name, X: encode(pad(public.X.Bytes(), size)), Y: encode(pad(public.Y.Bytes(), size))}
+	}
+	return nil
+}
+
+//PublicKey func decodes the RSA or EC public key of the JWK, e.g. of the JWKS of another issuer
+func (j *JWK) PublicKey() (interface{}, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %s: invalid n", j.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("jwk %s: invalid e", j.Kid)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if j.Crv != "P-256" {
+			return nil, fmt.Errorf("jwk %s: unsupported curve %s", j.Kid, j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %s: invalid x", j.Kid)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("jwk %s: invalid y", j.Kid)
+		}
+		public := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !public.Curve.IsOnCurve(public.X, public.Y) {
+			return nil, fmt.Errorf("jwk %s: point not on curve", j.Kid)
+		}
+		return public, nil
+	}
+	return nil, fmt.Errorf("jwk %s: unsupported key type %s", j.Kid, j.Kty)
+}
+
+//JWKS func returns the public keys of the keyset, ordered by kid. HMAC keys are never published.
+func (k *Keyset) JWKS() JWKS {
+	ids := []string{}
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, id := range ids {
+		if jwk := k.keys[id].JWK(); jwk != nil {
+			jwks.Keys = append(jwks.Keys, *jwk)
+		}
+	}
+
+	return jwks
+}
+
+//thumbprint func returns the RFC 7638 thumbprint of the key, the required members in lexical order
+func thumbprint(jwk *JWK) string {
+	var canonical string
+	if jwk.Kty == "RSA" {
+		canonical = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	} else {
+		canonical = fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`, jwk.Crv, jwk.X, jwk.Y)
+	}
+
+	sum := sha256.Sum256([]byte(canonical))
+	return encode(sum[:])
+}
+
+//encode func encodes bytes as unpadded base64url
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+//pad func left pads b with zeros to size bytes
+func pad(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
@@ -0,0 +1,371 @@
+package keyset
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/challengr/config"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+//rsaPEM func generates an RSA private key of the bits and returns it with its PEM
+func rsaPEM(t *testing.T, bits int) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return private, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)})
+}
+
+//ecPEM func generates an EC private key of the curve and returns it with its PEM
+func ecPEM(t *testing.T, curve elliptic.Curve) (*ecdsa.PrivateKey, []byte) {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return private, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+//publicPEM func returns the PKIX PEM of the public key
+func publicPEM(t *testing.T, public interface{}) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+//writeFile func writes the data to a file of the dir and returns its path
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+//testClaims func returns claims which expire in an hour
+func testClaims() jwt.Claims {
+	return &jwt.StandardClaims{Subject: "42", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+}
+
+//verify func parses the token with the keyfunc of the keyset
+func verify(k *Keyset, token string) error {
+	_, err := jwt.ParseWithClaims(token, &jwt.StandardClaims{}, k.Keyfunc)
+	return err
+}
+
+func TestSignVerify(t *testing.T) {
+	_, rsaData := rsaPEM(t, 2048)
+	_, ecData := ecPEM(t, elliptic.P256())
+
+	cases := []struct {
+		algorithm string
+		key       func() (*Key, error)
+	}{
+		{HS256, func() (*Key, error) { return NewHMAC([]byte("0123456789abcdef")), nil }},
+		{RS256, func() (*Key, error) { return ParsePrivateKeyPEM(rsaData) }},
+		{ES256, func() (*Key, error) { return ParsePrivateKeyPEM(ecData) }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.algorithm, func(t *testing.T) {
+			key, err := tc.key()
+			if err != nil {
+				t.Fatal(err)
+			}
+			k, err := New(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := k.Sign(testClaims())
+			if err != nil {
+				t.Fatalf("sign: %v", err)
+			}
+
+			parsed, err := jwt.ParseWithClaims(token, &jwt.StandardClaims{}, k.Keyfunc)
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if parsed.Header["alg"] != tc.algorithm || parsed.Header["kid"] != key.ID || parsed.Claims.(*jwt.StandardClaims).Subject != "42" {
+				t.Errorf("token = %+v, want %s under kid %s", parsed, tc.algorithm, key.ID)
+			}
+
+			//a token without kid predates the keyset and is checked against the signing key
+			unnamed := jwt.NewWithClaims(jwt.GetSigningMethod(tc.algorithm), testClaims())
+			signed, err := unnamed.SignedString(key.signKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := verify(k, signed); err != nil {
+				t.Errorf("token without kid: %v", err)
+			}
+
+			tampered := token[:len(token)-4] + strings.Repeat("A", 4)
+			if tampered == token {
+				tampered = token[:len(token)-4] + strings.Repeat("B", 4)
+			}
+			if err := verify(k, tampered); err == nil {
+				t.Error("tampered token verified")
+			}
+		})
+	}
+}
+
+//TestRotation checks that the tokens of the previous keys verify after the signing key is rotated, and only those
+func TestRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldRSA, oldRSAData := rsaPEM(t, 2048)
+	oldEC, oldECData := ecPEM(t, elliptic.P256())
+	_, newData := rsaPEM(t, 2048)
+
+	writeFile(t, dir, "old-rsa.pem", publicPEM(t, &oldRSA.PublicKey))
+	writeFile(t, dir, "old-ec.pem", publicPEM(t, &oldEC.PublicKey))
+
+	rotated, err := Load(config.JWT{
+		Algorithm:      RS256,
+		KeyFile:        writeFile(t, t.TempDir(), "signing.pem", newData),
+		VerifyKeysDir:  dir,
+		PreviousSecret: "previous-secret-0123",
+	})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	previous := []struct {
+		name string
+		key  func() (*Key, error)
+	}{
+		{"previous secret", func() (*Key, error) { return NewHMAC([]byte("previous-secret-0123")), nil }},
+		{"old rsa key", func() (*Key, error) { return ParsePrivateKeyPEM(oldRSAData) }},
+		{"old ec key", func() (*Key, error) { return ParsePrivateKeyPEM(oldECData) }},
+	}
+
+	for _, p := range previous {
+		key, err := p.key()
+		if err != nil {
+			t.Fatal(err)
+		}
+		old, err := New(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		token, err := old.Sign(testClaims())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := verify(rotated, token); err != nil {
+			t.Errorf("token of the %s: %v", p.name, err)
+		}
+	}
+
+	//the keys which only verify can not sign
+	for id, key := range rotated.keys {
+		if id != rotated.signing.ID && key.signKey != nil {
+			t.Errorf("verification key %s has a private part", id)
+		}
+	}
+
+	retired, err := New(NewHMAC([]byte("retired-secret-01234")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := retired.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(rotated, token); err == nil {
+		t.Error("token of a retired secret verified")
+	}
+}
+
+func TestKeyfuncRejects(t *testing.T) {
+	rsaPrivate, rsaData := rsaPEM(t, 2048)
+	rsaKey, err := ParsePrivateKeyPEM(rsaData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := New(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(method jwt.SigningMethod, kid interface{}, key interface{}) string {
+		token := jwt.NewWithClaims(method, testClaims())
+		if kid != nil {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	_, otherData := rsaPEM(t, 2048)
+	otherKey, err := ParsePrivateKeyPEM(otherData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"unknown kid", sign(jwt.SigningMethodRS256, otherKey.ID, otherKey.signKey)},
+		{"kid of other key", sign(jwt.SigningMethodRS256, rsaKey.ID, otherKey.signKey)},
+		{"kid not a string", sign(jwt.SigningMethodRS256, 7, rsaPrivate)},
+		//the public key must never be taken as an HMAC secret
+		{"hs256 with the public key pem", sign(jwt.SigningMethodHS256, rsaKey.ID, publicPEM(t, &rsaPrivate.PublicKey))},
+		{"hs256 with the public key der", sign(jwt.SigningMethodHS256, rsaKey.ID, x509.MarshalPKCS1PublicKey(&rsaPrivate.PublicKey))},
+		{"hs256 without kid", sign(jwt.SigningMethodHS256, nil, publicPEM(t, &rsaPrivate.PublicKey))},
+		{"none", sign(jwt.SigningMethodNone, rsaKey.ID, jwt.UnsafeAllowNoneSignatureType)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := verify(k, tc.token); err == nil {
+				t.Error("token verified")
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaPrivate, rsaData := rsaPEM(t, 2048)
+	ecPrivate, ecData := ecPEM(t, elliptic.P256())
+
+	rsaKey, err := ParsePrivateKeyPEM(rsaData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ParsePrivateKeyPEM(ecData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := New(rsaKey, ecKey, NewHMAC([]byte("previous-secret-0123")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := k.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("jwks = %+v, want the rsa and ec keys without the hmac secret", jwks)
+	}
+
+	for _, jwk := range jwks.Keys {
+		switch jwk.Kid {
+		case rsaKey.ID:
+			if jwk.Kty != "RSA" || jwk.Alg != RS256 || jwk.Use != "sig" || jwk.X != "" || jwk.Y != "" {
+				t.Errorf("rsa jwk = %+v", jwk)
+			}
+			if jwk.N != encode(rsaPrivate.N.Bytes()) || jwk.E != encode(big.NewInt(int64(rsaPrivate.E)).Bytes()) {
+				t.Errorf("rsa jwk n/e = %s/%s, want the modulus and exponent", jwk.N, jwk.E)
+			}
+		case ecKey.ID:
+			if jwk.Kty != "EC" || jwk.Alg != ES256 || jwk.Use != "sig" || jwk.Crv != "P-256" || jwk.N != "" || jwk.E != "" {
+				t.Errorf("ec jwk = %+v", jwk)
+			}
+			if jwk.X != encode(pad(ecPrivate.X.Bytes(), 32)) || jwk.Y != encode(pad(ecPrivate.Y.Bytes(), 32)) {
+				t.Errorf("ec jwk x/y = %s/%s, want the point", jwk.X, jwk.Y)
+			}
+		default:
+			t.Errorf("jwk of unknown kid %s", jwk.Kid)
+		}
+
+		public, err := jwk.PublicKey()
+		if err != nil {
+			t.Errorf("jwk %s public key: %v", jwk.Kid, err)
+		}
+		if kid := thumbprint(&jwk); kid != jwk.Kid {
+			t.Errorf("kid = %s, want the thumbprint %s", jwk.Kid, kid)
+		}
+		switch public := public.(type) {
+		case *rsa.PublicKey:
+			if public.N.Cmp(rsaPrivate.N) != 0 || public.E != rsaPrivate.E {
+				t.Error("rsa jwk does not decode to the public key")
+			}
+		case *ecdsa.PublicKey:
+			if public.X.Cmp(ecPrivate.X) != 0 || public.Y.Cmp(ecPrivate.Y) != 0 {
+				t.Error("ec jwk does not decode to the public key")
+			}
+		}
+	}
+
+	body, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var members struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &members); err != nil {
+		t.Fatal(err)
+	}
+	for _, jwk := range members.Keys {
+		for _, private := range []string{"d", "p", "q", "dp", "dq", "qi", "k"} {
+			if _, ok := jwk[private]; ok {
+				t.Errorf("jwk %v publishes the private member %s", jwk["kid"], private)
+			}
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	_, smallRSA := rsaPEM(t, 1024)
+	_, rsaData := rsaPEM(t, 2048)
+	_, p384 := ecPEM(t, elliptic.P384())
+	ecPrivate, ecData := ecPEM(t, elliptic.P256())
+
+	notPEM := writeFile(t, dir, "not.pem", []byte("not a key"))
+	publicOnly := writeFile(t, dir, "public.pem", publicPEM(t, &ecPrivate.PublicKey))
+
+	badDir := t.TempDir()
+	writeFile(t, badDir, "garbage.pem", []byte("-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----\n"))
+
+	cases := []struct {
+		name string
+		cfg  config.JWT
+		err  string
+	}{
+		{"unsupported algorithm", config.JWT{Algorithm: "none"}, "unsupported algorithm"},
+		{"missing key file", config.JWT{Algorithm: RS256, KeyFile: filepath.Join(dir, "missing.pem")}, "no such file"},
+		{"not pem", config.JWT{Algorithm: RS256, KeyFile: notPEM}, "no PEM block"},
+		{"public key as signing key", config.JWT{Algorithm: ES256, KeyFile: publicOnly}, "not an RSA or EC private key"},
+		{"small rsa key", config.JWT{Algorithm: RS256, KeyFile: writeFile(t, dir, "small.pem", smallRSA)}, "at least 2048 bits"},
+		{"p-384 key", config.JWT{Algorithm: ES256, KeyFile: writeFile(t, dir, "p384.pem", p384)}, "P-256"},
+		{"key of other algorithm", config.JWT{Algorithm: ES256, KeyFile: writeFile(t, dir, "rsa.pem", rsaData)}, "RS256 key, ES256 configured"},
+		{"bad verify key", config.JWT{Algorithm: ES256, KeyFile: writeFile(t, dir, "ec.pem", ecData), VerifyKeysDir: badDir}, "not an RSA or EC public key"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Load(tc.cfg)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("error = %v, want %q", err, tc.err)
+			}
+		})
+	}
+}
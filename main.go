@@ -4,49 +4,54 @@ import (
 	"log"
 	"os"
 
+	"github.com/challengr/config"
+	"github.com/challengr/keyset"
+	"github.com/challengr/model"
 	"github.com/challengr/service"
-	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	port := os.Getenv("PORT")
-
-	if port == "" {
-		port = "8080"
-	}
-
-	router := gin.New()
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
-
 	log.SetFlags(log.Ldate | log.Ltime | log.LUTC | log.Llongfile)
 
-	router.GET("/s3Sign", service.PreSignS3)
-
-	router.PUT("/onesignal", service.UpdateOneSignal)
-
-	router.POST("/login", service.LogIn)
-	router.POST("/logout", service.LogOut)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		case "config":
+			runConfig(os.Args[2:])
+			return
+		case "purge":
+			runPurge(os.Args[2:])
+			return
+		case "advance":
+			runAdvance(os.Args[2:])
+			return
+		case "jobs":
+			runJobs(os.Args[2:])
+			return
+		default:
+			log.Fatalf("unknown command %q", os.Args[1])
+		}
+	}
 
-	router.GET("/user", service.GetUser)
-	router.PUT("/user/:user_id/weight", service.UpdateUserWeight)
-	router.PUT("/user/:user_id/level", service.UpdateUserLevel)
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config error: %v", err)
+	}
 
-	router.PUT("/user/:user_id/score/:score_id/add_coins", service.AddCoins)
-	router.PUT("/user/:user_id/score/:score_id/add_exp", service.AddExp)
-	router.PUT("/user/:user_id/score/:score_id/add_likes", service.AddLikes)
+	keys, err := keyset.Load(cfg.JWT)
+	if err != nil {
+		log.Fatalf("jwt keyset error: %v", err)
+	}
 
-	router.GET("/challenge", service.GetChellenge)
-	router.POST("/challenge", service.PostChallenge)
-	router.PUT("/challenge/:challenge_id", service.PutChallenge)
-	router.DELETE("/challenge/:challenge_id", service.DeleteChallenge)
+	db, err := model.Connect(cfg.DB)
+	if err != nil {
+		log.Fatalf("DB connection error: %v", err)
+	}
+	defer db.Close()
 
-	router.GET("/challenge/:challenge_id/post", service.GetPost)
-	router.POST("/challenge/:challenge_id/post", service.PostPost)
-	router.PUT("/challenge/:challenge_id/post/:post_id/like", service.LikePost)
-	router.PUT("/challenge/:challenge_id/post/:post_id/flag", service.FlagPost)
-	router.PUT("/challenge/:challenge_id/post/:post_id/unflag", service.UnFlagPost)
-	router.DELETE("/challenge/:challenge_id/post/:post_id", service.DeletePost)
+	router := service.NewRouter(service.Deps{Store: model.NewPostgresStore(db), Config: cfg, Keys: keys})
 
-	router.Run(":" + port)
+	router.Run(":" + cfg.Port)
 }
@@ -0,0 +1,11 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+//adminRole const is the role string of an admin in the token
+const adminRole = "admin"
+
+//RequireAdmin func middleware lets only admins through. It must run after Authenticate.
+func RequireAdmin() gin.HandlerFunc {
+	return RequireRole(adminRole)
+}
@@ -5,23 +5,31 @@ import (
 
 	"net/http"
 
+	"github.com/challengr/keyset"
+	"github.com/challengr/model"
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 )
 
-//JWTSecret is user for encrypting and decrypting jwt
-const JWTSecret = "challengr app secret"
-
-//respondWithError func responds with error
-func respondWithError(code int, message string, c *gin.Context) {
-	resp := map[string]string{"error": message}
+//respondWithError func responds with error. errCode is one of the stable model.Code* error codes, fields the offending fields.
+func respondWithError(code int, errCode, message string, c *gin.Context, fields ...string) {
+	resp := map[string]interface{}{"error": message, "code": errCode}
+	if len(fields) > 0 {
+		resp["fields"] = fields
+	}
 
 	c.JSON(code, resp)
 	c.AbortWithStatus(code)
 }
 
-//Authenticate func middleware authenticates incoming request
-func Authenticate() gin.HandlerFunc {
+//SessionChecker interface tells whether the session of an access token has been revoked, e.g. by a logout
+type SessionChecker interface {
+	Revoked(sessionID string) (bool, error)
+}
+
+//Authenticate func middleware authenticates incoming request with the verification keys of the keyset.
+//Tokens must carry an expiry and a session which is still active in sessions.
+func Authenticate(keys *keyset.Keyset, sessions SessionChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenstring := c.Query("token")
 		if tokenstring == "" {
@@ -29,24 +37,45 @@ func Authenticate() gin.HandlerFunc {
 		}
 
 		if tokenstring == "" {
-			respondWithError(http.StatusForbidden, "token required", c)
+			respondWithError(http.StatusForbidden, model.CodeInvalidToken, "token required", c)
 			return
 		}
 
-		user := JWTUser{}
-		token, err := jwt.ParseWithClaims(tokenstring, &user, func(token *jwt.Token) (interface{}, error) {
-			return []byte(JWTSecret), nil
-		})
+		user := model.JWTUser{}
+		token, err := jwt.ParseWithClaims(tokenstring, &user, keys.Keyfunc)
+
+		if ve, ok := err.(*jwt.ValidationError); ok && ve.Errors&jwt.ValidationErrorExpired != 0 {
+			respondWithError(http.StatusForbidden, model.CodeTokenExpired, "token expired", c)
+			return
+		}
 
 		if err != nil {
 			log.Printf("token parse err: %v", err)
-			respondWithError(http.StatusForbidden, "invalid  token", c)
+			respondWithError(http.StatusForbidden, model.CodeInvalidToken, "invalid  token", c)
 			return
 		}
 
 		if !token.Valid {
 			log.Println("token.Valid false")
-			respondWithError(http.StatusForbidden, "invalid  token", c)
+			respondWithError(http.StatusForbidden, model.CodeInvalidToken, "invalid  token", c)
+			return
+		}
+
+		if user.ExpiresAt == 0 || user.SessionID == "" {
+			log.Printf("token without expiry or session, user_id: %v", user.ID)
+			respondWithError(http.StatusForbidden, model.CodeInvalidToken, "invalid  token", c)
+			return
+		}
+
+		revoked, err := sessions.Revoked(user.SessionID)
+		if err != nil {
+			log.Printf("session revoked check err: %v", err)
+			respondWithError(http.StatusInternalServerError, model.CodeInternal, "Server error", c)
+			return
+		}
+
+		if revoked {
+			respondWithError(http.StatusForbidden, model.CodeTokenRevoked, "token revoked", c)
 			return
 		}
 
@@ -55,6 +84,7 @@ func Authenticate() gin.HandlerFunc {
 		c.Set("facebook_user_id", user.FacebookUserID)
 		c.Set("weight", user.Weight)
 		c.Set("role", user.Role)
+		c.Set("session_id", user.SessionID)
 
 		// before request
 
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+)
+
+//Policies decide who may reach a handler. They run after Authenticate, read the claims it set on the context and abort the
+//request when it is not allowed. They never call c.Next, so they can be chained in front of a handler or mounted as middleware.
+
+//OwnerLoader func type loads the id of the user owning the resource of the request. Its errors are attached with c.Error
+//and rendered by the error middleware, e.g. a not found error of the model layer.
+type OwnerLoader func(c *gin.Context) (int64, error)
+
+//IsAdmin func tells if the token of the request has the admin role
+func IsAdmin(c *gin.Context) bool {
+	role, _ := c.Get("role")
+	return role == adminRole
+}
+
+//RequireRole func policy lets only the tokens with one of the roles through
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		for _, r := range roles {
+			if role == r {
+				return
+			}
+		}
+
+		respondWithError(http.StatusForbidden, model.CodeNotAllowed, strings.Join(roles, "/")+" role required", c)
+	}
+}
+
+//RequireSelf func policy lets the request through only if the user id path param, e.g. ":user_id", is the user of the token
+func RequireSelf(param string) gin.HandlerFunc {
+	return requireUser(param, false)
+}
+
+//RequireSelfOrAdmin func policy lets the request through if the user id path param, e.g. ":user_id", is the user of the token
+//or the token has the admin role
+func RequireSelfOrAdmin(param string) gin.HandlerFunc {
+	return requireUser(param, true)
+}
+
+//requireUser func compares the user id path param with the user of the token
+func requireUser(param string, allowAdmin bool) gin.HandlerFunc {
+	name := strings.TrimPrefix(param, ":")
+
+	return func(c *gin.Context) {
+		paramUserID, err := strconv.ParseInt(c.Param(name), 10, 64)
+		if err != nil {
+			log.Printf("path parm %v err: %v", name, err)
+			respondWithError(http.StatusBadRequest, model.CodeInvalidPathParam, "Invalid path param", c, name)
+			return
+		}
+
+		if userID, _ := c.Get("user_id"); userID == paramUserID {
+			return
+		}
+
+		if allowAdmin && IsAdmin(c) {
+			return
+		}
+
+		respondWithError(http.StatusForbidden, model.CodeNotAllowed, "Not allowed please check token", c)
+	}
+}
+
+//RequireOwner func policy lets the request through if the user of the token owns the resource which load finds,
+//admins always pass and the resource is not loaded for them
+func RequireOwner(load OwnerLoader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if IsAdmin(c) {
+			return
+		}
+
+		ownerID, err := load(c)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		if userID, _ := c.Get("user_id"); userID != ownerID {
+			respondWithError(http.StatusForbidden, model.CodeNotAllowed, "Not allowed, not the owner", c)
+			return
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+)
+
+//policyCase struct is a request through a policy: the claims of the token, the path and what the policy must do with it
+type policyCase struct {
+	name    string
+	policy  gin.HandlerFunc
+	role    string
+	userID  int64
+	path    string
+	status  int
+	code    string
+	reached bool
+	err     error
+}
+
+var (
+	errOwnerNotFound = model.NotFound(model.CodeChallengeNotFound, "Challenge not found")
+	errOwnerLoad     = errors.New("database down")
+)
+
+//ownerOf func returns a loader which finds the resource owned by ownerID, or fails with err
+func ownerOf(ownerID int64, err error) OwnerLoader {
+	return func(c *gin.Context) (int64, error) {
+		return ownerID, err
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []policyCase{
+		{name: "role allows matching role", policy: RequireRole(adminRole), role: adminRole, userID: 1, path: "/user/2", status: http.StatusOK, reached: true},
+		{name: "role allows any of the roles", policy: RequireRole("moderator", adminRole), role: "moderator", userID: 1, path: "/user/2", status: http.StatusOK, reached: true},
+		{name: "role denies other role", policy: RequireRole(adminRole), role: "user", userID: 1, path: "/user/2", status: http.StatusForbidden, code: model.CodeNotAllowed},
+		{name: "role denies missing role", policy: RequireRole(adminRole), userID: 1, path: "/user/2", status: http.StatusForbidden, code: model.CodeNotAllowed},
+		{name: "admin allows admin", policy: RequireAdmin(), role: adminRole, userID: 1, path: "/user/2", status: http.StatusOK, reached: true},
+		{name: "admin denies user", policy: RequireAdmin(), role: "user", userID: 1, path: "/user/2", status: http.StatusForbidden, code: model.CodeNotAllowed},
+
+		{name: "self allows own user", policy: RequireSelf(":user_id"), role: "user", userID: 2, path: "/user/2", status: http.StatusOK, reached: true},
+		{name: "self denies other user", policy: RequireSelf(":user_id"), role: "user", userID: 1, path: "/user/2", status: http.StatusForbidden, code: model.CodeNotAllowed},
+		{name: "self denies admin of other user", policy: RequireSelf(":user_id"), role: adminRole, userID: 1, path: "/user/2", status: http.StatusForbidden, code: model.CodeNotAllowed},
+		{name: "self denies missing user", policy: RequireSelf(":user_id"), path: "/user/2", status: http.StatusForbidden, code: model.CodeNotAllowed},
+		{name: "self rejects invalid param", policy: RequireSelf(":user_id"), role: "user", userID: 2, path: "/user/abc", status: http.StatusBadRequest, code: model.CodeInvalidPathParam},
+
+		{name: "self or admin allows own user", policy: RequireSelfOrAdmin(":user_id"), role: "user", userID: 2, path: "/user/2", status: http.StatusOK, reached: true},
+		{name: "self or admin allows admin", policy: RequireSelfOrAdmin(":user_id"), role: adminRole, userID: 1, path: "/user/2", status: http.StatusOK, reached: true},
+		{name: "self or admin denies other user", policy: RequireSelfOrAdmin(":user_id"), role: "user", userID: 1, path: "/user/2", status: http.StatusForbidden, code: model.CodeNotAllowed},
+		{name: "self or admin rejects invalid param", policy: RequireSelfOrAdmin(":user_id"), role: adminRole, userID: 1, path: "/user/abc", status: http.StatusBadRequest, code: model.CodeInvalidPathParam},
+
+		{name: "owner allows owner", policy: RequireOwner(ownerOf(1, nil)), role: "user", userID: 1, path: "/user/2", status: http.StatusOK, reached: true},
+		{name: "owner denies other user", policy: RequireOwner(ownerOf(3, nil)), role: "user", userID: 1, path: "/user/2", status: http.StatusForbidden, code: model.CodeNotAllowed},
+		{name: "owner allows admin", policy: RequireOwner(ownerOf(3, nil)), role: adminRole, userID: 1, path: "/user/2", status: http.StatusOK, reached: true},
+		{name: "owner attaches not found", policy: RequireOwner(ownerOf(0, errOwnerNotFound)), role: "user", userID: 1, path: "/user/2", status: http.StatusOK, err: errOwnerNotFound},
+		{name: "owner attaches load error", policy: RequireOwner(ownerOf(0, errOwnerLoad)), role: "user", userID: 1, path: "/user/2", status: http.StatusOK, err: errOwnerLoad},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var attached error
+			reached := false
+
+			router := gin.New()
+			router.GET("/user/:user_id", func(c *gin.Context) {
+				c.Next()
+				if last := c.Errors.Last(); last != nil {
+					attached = last.Err
+				}
+			}, func(c *gin.Context) {
+				if tc.role != "" {
+					c.Set("role", tc.role)
+				}
+				if tc.userID != 0 {
+					c.Set("user_id", tc.userID)
+				}
+			}, tc.policy, func(c *gin.Context) {
+				reached = true
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+
+			if w.Code != tc.status {
+				t.Errorf("status = %v, want %v", w.Code, tc.status)
+			}
+			if reached != tc.reached {
+				t.Errorf("handler reached = %v, want %v", reached, tc.reached)
+			}
+			if attached != tc.err {
+				t.Errorf("attached error = %v, want %v", attached, tc.err)
+			}
+
+			if tc.code != "" {
+				var resp struct {
+					Code string `json:"code"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("response body %q: %v", w.Body.String(), err)
+				}
+				if resp.Code != tc.code {
+					t.Errorf("code = %q, want %q", resp.Code, tc.code)
+				}
+			}
+		})
+	}
+}
+
+//TestRequireOwnerSkipsLoaderForAdmins checks that the resource is not loaded for admins
+func TestRequireOwnerSkipsLoaderForAdmins(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	loaded := false
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		c.Set("role", adminRole)
+		c.Set("user_id", int64(1))
+	}, RequireOwner(func(c *gin.Context) (int64, error) {
+		loaded = true
+		return 0, errOwnerLoad
+	}), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if loaded {
+		t.Error("loader called for admin")
+	}
+	if w.Code != http.StatusNoContent {
+		t.Errorf("status = %v, want %v", w.Code, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/challengr/model"
+	"github.com/challengr/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+//RateLimit func middleware takes a token from the buckets of the caller in the named group. Every request is keyed by the
+//client ip, authenticated requests by the user of the token as well, and are throttled when either bucket is empty.
+//A disabled rate lets everything through. The RateLimit-* headers tell the client the budget of its tighter bucket,
+//a throttled request gets 429 with Retry-After.
+func RateLimit(store ratelimit.Store, group string, rate ratelimit.Rate) gin.HandlerFunc {
+	if rate.Disabled() {
+		return func(c *gin.Context) {}
+	}
+
+	policy := fmt.Sprintf("%d;w=%d", rate.Limit, seconds(rate.Period))
+
+	return func(c *gin.Context) {
+		keys := []string{group + ":ip:" + c.ClientIP()}
+		if userID, ok := c.Get("user_id"); ok {
+			keys = append(keys, fmt.Sprintf("%s:user:%v", group, userID))
+		}
+
+		var res ratelimit.Result
+		for i, key := range keys {
+			keyRes, err := store.Take(key, rate)
+			if err != nil {
+				//a broken backend must not take the api down, the request goes through unlimited
+				log.Printf("rate limit store error: %v", err)
+				return
+			}
+			if i == 0 || tighter(keyRes, res) {
+				res = keyRes
+			}
+		}
+
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+			respondWithError(http.StatusTooManyRequests, model.CodeRateLimited, "Too many requests, retry later", c)
+			return
+		}
+	}
+}
+
+//tighter func tells if the result a leaves the caller less budget than b: a throttled result, the later retry of two
+//throttled ones, or the fewer remaining requests of two allowed ones
+func tighter(a, b ratelimit.Result) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+	return a.Remaining < b.Remaining
+}
+
+//seconds func rounds the duration up to whole seconds, the unit of the rate limit headers
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/challengr/model"
+	"github.com/challengr/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+//fakeClock struct is a clock of the rate limit buckets which only moves when it is advanced
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+//limiter struct is a router behind RateLimit on a fake clock. The user_id header stands in for the token.
+type limiter struct {
+	clock  *fakeClock
+	router *gin.Engine
+}
+
+//newLimiter func creates the router limited to rate in the group
+func newLimiter(rate ratelimit.Rate) *limiter {
+	gin.SetMode(gin.TestMode)
+
+	clock := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) {
+		if userID, err := strconv.ParseInt(c.GetHeader("user_id"), 10, 64); err == nil {
+			c.Set("user_id", userID)
+		}
+	}, RateLimit(ratelimit.NewMemoryClock(clock.Now), "default", rate), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	return &limiter{clock: clock, router: router}
+}
+
+//get func sends a request from the ip as the user, 0 for none
+func (l *limiter) get(ip string, userID int64) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = ip + ":40000"
+	if userID != 0 {
+		req.Header.Set("user_id", strconv.FormatInt(userID, 10))
+	}
+
+	w := httptest.NewRecorder()
+	l.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	l := newLimiter(ratelimit.Rate{Limit: 2, Period: time.Minute})
+
+	for remaining := 1; remaining >= 0; remaining-- {
+		w := l.get("192.0.2.1", 0)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %v, want %v", w.Code, http.StatusOK)
+		}
+		headers := map[string]string{
+			"RateLimit-Policy":    "2;w=60",
+			"RateLimit-Limit":     "2",
+			"RateLimit-Remaining": strconv.Itoa(remaining),
+			"RateLimit-Reset":     strconv.Itoa((2 - remaining) * 30),
+			"Retry-After":         "",
+		}
+		for name, want := range headers {
+			if got := w.Header().Get(name); got != want {
+				t.Errorf("%s = %q, want %q", name, got, want)
+			}
+		}
+	}
+
+	w := l.get("192.0.2.1", 0)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %v, want %v", w.Code, http.StatusTooManyRequests)
+	}
+	if w.Header().Get("Retry-After") != "30" || w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("RateLimit-Reset") != "60" {
+		t.Errorf("throttled headers = %v, want a retry in 30s", w.Header())
+	}
+	var resp model.ErrResp
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Code != model.CodeRateLimited {
+		t.Errorf("throttled body = %q, want code %q", w.Body.String(), model.CodeRateLimited)
+	}
+
+	//a part of the token is not enough, the retry counts down
+	l.clock.now = l.clock.now.Add(20 * time.Second)
+	if w := l.get("192.0.2.1", 0); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "10" {
+		t.Errorf("early retry = %v after %q, want %v after 10s", w.Code, w.Header().Get("Retry-After"), http.StatusTooManyRequests)
+	}
+
+	l.clock.now = l.clock.now.Add(10 * time.Second)
+	if w := l.get("192.0.2.1", 0); w.Code != http.StatusOK {
+		t.Errorf("retry after refill status = %v, want %v", w.Code, http.StatusOK)
+	}
+
+	if w := l.get("192.0.2.2", 0); w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "1" {
+		t.Errorf("other ip = %v with %q remaining, want its own bucket", w.Code, w.Header().Get("RateLimit-Remaining"))
+	}
+}
+
+//TestRateLimitUserAndIP checks that an authenticated request is throttled by either of its user and ip buckets
+func TestRateLimitUserAndIP(t *testing.T) {
+	type request struct {
+		ip     string
+		userID int64
+	}
+	cases := []struct {
+		name     string
+		requests []request
+	}{
+		{"one user rotating ips", []request{{"192.0.2.1", 1}, {"192.0.2.2", 1}, {"192.0.2.3", 1}}},
+		{"many users behind one ip", []request{{"192.0.2.1", 1}, {"192.0.2.1", 2}, {"192.0.2.1", 3}}},
+		{"anonymous and user behind one ip", []request{{"192.0.2.1", 0}, {"192.0.2.1", 1}, {"192.0.2.1", 0}}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := newLimiter(ratelimit.Rate{Limit: 2, Period: time.Minute})
+
+			for i, r := range tc.requests {
+				want := http.StatusOK
+				if i >= 2 {
+					want = http.StatusTooManyRequests
+				}
+				if w := l.get(r.ip, r.userID); w.Code != want {
+					t.Errorf("request %d from %v as %v = %v, want %v", i+1, r.ip, r.userID, w.Code, want)
+				}
+			}
+		})
+	}
+
+	//the headers tell the budget of the tighter bucket
+	l := newLimiter(ratelimit.Rate{Limit: 3, Period: time.Minute})
+	l.get("192.0.2.1", 1)
+	l.get("192.0.2.1", 1)
+	if w := l.get("192.0.2.2", 1); w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("remaining = %q, want 0 of the user bucket", w.Header().Get("RateLimit-Remaining"))
+	}
+}
+
+//errStore struct is a broken rate limit backend
+type errStore struct{}
+
+func (errStore) Take(key string, rate ratelimit.Rate) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("backend down")
+}
+
+func TestRateLimitPassThrough(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name  string
+		store ratelimit.Store
+		rate  ratelimit.Rate
+	}{
+		{"disabled rate", ratelimit.NewMemory(), ratelimit.Rate{}},
+		{"broken store", errStore{}, ratelimit.Rate{Limit: 1, Period: time.Minute}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/", RateLimit(tc.store, "default", tc.rate), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			for i := 0; i < 3; i++ {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+				if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+					t.Fatalf("request %d = %v %v, want an unlimited pass", i+1, w.Code, w.Header())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+//RequestIDHeader is the header carrying the id of a request
+const RequestIDHeader = "X-Request-ID"
+
+//validRequestID matches the request ids accepted from clients and proxies, anything else is replaced
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+//RequestID func middleware tags every request with an id, taken from the X-Request-ID header or generated. It is set as
+//"request_id" on the context, e.g. for the audit events, and echoed in the response header.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Request.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.NewV4().String()
+		}
+
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+	}
+}
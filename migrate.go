@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/challengr/config"
+	"github.com/challengr/migration"
+	"github.com/challengr/model"
+)
+
+//migrateUsage is printed when the migrate command is called wrongly
+const migrateUsage = `usage: challengr migrate <command>
+
+commands:
+  up [n]         apply all pending migrations or only the next n
+  down [n]       roll back the latest migration or the latest n
+  status         list the migrations and when they were applied
+  create <name>  write empty up/down files into ` + migration.SourceDir + ` (rebuild to embed them)`
+
+//runMigrate func handles the `challengr migrate` sub command
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	steps := 0
+	if len(args) > 1 && (args[0] == "up" || args[0] == "down") {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid number of steps %q\n%s", args[1], migrateUsage)
+		}
+		steps = n
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			log.Fatal(migrateUsage)
+		}
+
+		paths, err := migration.Create(migration.SourceDir, args[1])
+		if err != nil {
+			log.Fatalf("migration create error: %v", err)
+		}
+		for _, path := range paths {
+			fmt.Println(path)
+		}
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config error: %v", err)
+	}
+
+	db, err := model.Connect(cfg.DB)
+	if err != nil {
+		log.Fatalf("DB connection error: %v", err)
+	}
+	defer db.Close()
+
+	migrator := migration.New(db)
+
+	switch args[0] {
+	case "up":
+		err = migrator.Up(steps)
+	case "down":
+		err = migrator.Down(steps)
+	case "status":
+		var statusList []*migration.Status
+		statusList, err = migrator.Status()
+		for _, status := range statusList {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%04d  %-30s  %s\n", status.Version, status.Name, appliedAt)
+		}
+	default:
+		log.Fatal(migrateUsage)
+	}
+
+	if err != nil {
+		log.Fatalf("migrate %s error: %v", args[0], err)
+	}
+}
@@ -0,0 +1,317 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//lockKey is the postgres advisory lock key which is held while migrating
+const lockKey = 7303162017
+
+//SourceDir is the directory of the migration files relative to the repository root
+const SourceDir = "migration/sql"
+
+//files holds the migrations compiled into the binary
+//go:embed sql/*.sql
+var files embed.FS
+
+//fileRegexp matches names like 0001_create_users.up.sql
+var fileRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+//Migration struct is a versioned schema change with its up and down sql
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+//Status struct tells if a migration is applied to the database
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+//Migrations func returns the embedded migrations ordered by version
+func Migrations() ([]*Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		body, err := files.ReadFile("sql/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := []*Migration{}
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+//Migrator struct applies the embedded migrations to a database
+type Migrator struct {
+	db *sql.DB
+}
+
+//New func creates a migrator for the given database
+func New(db *sql.DB) *Migrator {
+	return &Migrator{db: db}
+}
+
+//withLock func runs fn on a single connection which holds the migration advisory lock
+func (m *Migrator) withLock(fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	log.Println("waiting for the migration lock...")
+	if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1);", lockKey); err != nil {
+		return err
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1);", lockKey); err != nil {
+			log.Printf("migration unlock error: %v", err)
+		}
+	}()
+
+	if _, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+//applied func fetches the applied versions with their time
+func applied(conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(context.Background(), "SELECT version, applied_at FROM schema_migrations;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		versions[version] = appliedAt
+	}
+
+	return versions, rows.Err()
+}
+
+//run func executes the sql of one migration and records it in a single transaction
+func run(conn *sql.Conn, m *Migration, up bool) error {
+	ctx := context.Background()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	query := m.Down
+	record := "DELETE FROM schema_migrations WHERE version=$1;"
+	args := []interface{}{m.Version}
+	if up {
+		query = m.Up
+		record = "INSERT INTO schema_migrations (version, name) VALUES ($1, $2);"
+		args = append(args, m.Name)
+	}
+
+	if _, err = tx.ExecContext(ctx, query); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %d_%s: %v", m.Version, m.Name, err)
+	}
+
+	if _, err = tx.ExecContext(ctx, record, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+//Up func applies the pending migrations in order. A steps value of 0 applies all of them.
+func (m *Migrator) Up(steps int) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+
+	return m.withLock(func(conn *sql.Conn) error {
+		versions, err := applied(conn)
+		if err != nil {
+			return err
+		}
+
+		done := 0
+		for _, migration := range migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+			if steps > 0 && done == steps {
+				break
+			}
+
+			log.Printf("applying migration %d_%s", migration.Version, migration.Name)
+			if err = run(conn, migration, true); err != nil {
+				return err
+			}
+			done = done + 1
+		}
+
+		log.Printf("%d migration(s) applied", done)
+		return nil
+	})
+}
+
+//Down func rolls back the latest applied migrations. A steps value of 0 rolls back one.
+func (m *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+
+	return m.withLock(func(conn *sql.Conn) error {
+		versions, err := applied(conn)
+		if err != nil {
+			return err
+		}
+
+		done := 0
+		for i := len(migrations) - 1; i >= 0 && done < steps; i-- {
+			if _, ok := versions[migrations[i].Version]; !ok {
+				continue
+			}
+
+			log.Printf("rolling back migration %d_%s", migrations[i].Version, migrations[i].Name)
+			if err = run(conn, migrations[i], false); err != nil {
+				return err
+			}
+			done = done + 1
+		}
+
+		log.Printf("%d migration(s) rolled back", done)
+		return nil
+	})
+}
+
+//Status func lists every embedded migration with the time it was applied
+func (m *Migrator) Status() ([]*Status, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statusList := []*Status{}
+	err = m.withLock(func(conn *sql.Conn) error {
+		versions, err := applied(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if appliedAt, ok := versions[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statusList = append(statusList, &status)
+		}
+		return nil
+	})
+
+	return statusList, err
+}
+
+//Create func writes an empty up and down file for the next version into dir and returns their paths
+func Create(dir, name string) ([]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = regexp.MustCompile(`\W+`).ReplaceAllString(name, "_")
+	if name == "" || name == "_" {
+		return nil, errors.New("migration name required")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var last int64
+	for _, entry := range entries {
+		if match := fileRegexp.FindStringSubmatch(entry.Name()); match != nil {
+			if version, _ := strconv.ParseInt(match[1], 10, 64); version > last {
+				last = version
+			}
+		}
+	}
+
+	paths := []string{}
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%04d_%s.%s.sql", last+1, name, direction))
+		if err = os.WriteFile(path, []byte("-- "+direction+" migration of "+name+"\n"), 0644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS scores;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS levels;
@@ -0,0 +1,37 @@
+CREATE TABLE levels (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL
+);
+
+CREATE TABLE users (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL DEFAULT '',
+	email TEXT NOT NULL DEFAULT '',
+	facebook_user_id TEXT NOT NULL,
+	role TEXT NOT NULL DEFAULT 'user',
+	gender TEXT NOT NULL DEFAULT '',
+	date_of_birth TEXT NOT NULL DEFAULT '',
+	weight REAL NOT NULL DEFAULT 1,
+	level_id BIGINT REFERENCES levels(id),
+	geometry GEOMETRY(Point, 4326),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX users_facebook_user_id_idx ON users (facebook_user_id) WHERE deleted_at IS NULL;
+CREATE INDEX users_geometry_idx ON users USING GIST (geometry);
+
+CREATE TABLE scores (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	exp INTEGER NOT NULL DEFAULT 0,
+	coins BIGINT NOT NULL DEFAULT 0,
+	likes_remaining INTEGER NOT NULL DEFAULT 0,
+	likes_updated_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX scores_user_id_idx ON scores (user_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS flags;
+DROP TABLE IF EXISTS likes;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS challenges;
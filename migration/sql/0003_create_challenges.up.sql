@@ -0,0 +1,53 @@
+CREATE TABLE challenges (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	name TEXT NOT NULL,
+	description TEXT,
+	likes_needed_per_post INTEGER NOT NULL DEFAULT 0,
+	status TEXT NOT NULL DEFAULT 'active',
+	weight REAL NOT NULL DEFAULT 1,
+	geometry GEOMETRY(Geometry, 4326),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX challenges_user_id_idx ON challenges (user_id);
+CREATE INDEX challenges_status_idx ON challenges (status) WHERE deleted_at IS NULL;
+CREATE INDEX challenges_geometry_idx ON challenges USING GIST (geometry);
+
+CREATE TABLE posts (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	challenge_id BIGINT NOT NULL REFERENCES challenges(id),
+	likes_needed INTEGER NOT NULL DEFAULT 0,
+	file_url TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	content_size BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX posts_challenge_id_idx ON posts (challenge_id);
+CREATE INDEX posts_user_id_idx ON posts (user_id);
+
+CREATE TABLE likes (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	post_id BIGINT NOT NULL REFERENCES posts(id),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (user_id, post_id)
+);
+
+CREATE INDEX likes_post_id_idx ON likes (post_id);
+
+CREATE TABLE flags (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	post_id BIGINT NOT NULL REFERENCES posts(id),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (user_id, post_id)
+);
+
+CREATE INDEX flags_post_id_idx ON flags (post_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS bought_items;
+DROP TABLE IF EXISTS vanity_items;
@@ -0,0 +1,20 @@
+CREATE TABLE vanity_items (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	amount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+	currency TEXT NOT NULL DEFAULT '',
+	coins BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE bought_items (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	vanity_item_id BIGINT REFERENCES vanity_items(id),
+	level_id BIGINT REFERENCES levels(id),
+	amount NUMERIC(10, 2),
+	currency TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX bought_items_user_id_idx ON bought_items (user_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS group_challenge_requests;
+DROP TABLE IF EXISTS challenge_requests;
+DROP TABLE IF EXISTS onesignal;
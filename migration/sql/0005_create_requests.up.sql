@@ -0,0 +1,31 @@
+CREATE TABLE onesignal (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	imei TEXT NOT NULL,
+	player_id TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ,
+	UNIQUE (user_id, imei)
+);
+
+CREATE TABLE challenge_requests (
+	id BIGSERIAL PRIMARY KEY,
+	from_id BIGINT NOT NULL REFERENCES users(id),
+	to_id BIGINT NOT NULL REFERENCES users(id),
+	challenge_id BIGINT NOT NULL REFERENCES challenges(id),
+	message TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'open',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX challenge_requests_from_id_idx ON challenge_requests (from_id);
+CREATE INDEX challenge_requests_to_id_idx ON challenge_requests (to_id);
+
+CREATE TABLE group_challenge_requests (
+	id BIGSERIAL PRIMARY KEY,
+	from_id BIGINT NOT NULL REFERENCES users(id),
+	to_ids BIGINT[] NOT NULL DEFAULT '{}',
+	accepted_ids BIGINT[] NOT NULL DEFAULT '{}',
+	message TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+-- the users who signed in through google or apple have an empty facebook user id, so the index stays partial on them
+DROP INDEX IF EXISTS users_facebook_user_id_idx;
+CREATE UNIQUE INDEX users_facebook_user_id_idx ON users (facebook_user_id) WHERE deleted_at IS NULL AND facebook_user_id <> '';
+
+DROP TABLE IF EXISTS user_identities;
@@ -0,0 +1,18 @@
+CREATE TABLE user_identities (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	provider TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	email TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (provider, subject)
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
+
+INSERT INTO user_identities (user_id, provider, subject, email, created_at)
+	SELECT id, 'facebook', facebook_user_id, email, created_at FROM users WHERE deleted_at IS NULL AND facebook_user_id <> '';
+
+-- users signing in through google or apple have no facebook user id
+DROP INDEX users_facebook_user_id_idx;
+CREATE UNIQUE INDEX users_facebook_user_id_idx ON users (facebook_user_id) WHERE deleted_at IS NULL AND facebook_user_id <> '';
@@ -0,0 +1 @@
+ALTER TABLE onesignal DROP COLUMN IF EXISTS last_seen_at;
@@ -0,0 +1,3 @@
+ALTER TABLE onesignal ADD COLUMN last_seen_at TIMESTAMPTZ;
+
+UPDATE onesignal SET last_seen_at = COALESCE(updated_at, created_at);
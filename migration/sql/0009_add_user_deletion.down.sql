@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS users_deletion_due_at_idx;
+
+ALTER TABLE users DROP COLUMN IF EXISTS erased_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deletion_due_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deletion_requested_at;
@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN deletion_requested_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN deletion_due_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN erased_at TIMESTAMPTZ;
+
+CREATE INDEX users_deletion_due_at_idx ON users (deletion_due_at) WHERE deletion_due_at IS NOT NULL;
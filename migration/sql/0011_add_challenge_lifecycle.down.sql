@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS challenges_ends_at_idx;
+DROP INDEX IF EXISTS challenges_starts_at_idx;
+
+ALTER TABLE challenges DROP CONSTRAINT IF EXISTS challenges_dates_check;
+ALTER TABLE challenges DROP CONSTRAINT IF EXISTS challenges_status_check;
+
+UPDATE challenges SET status='inactive' WHERE status<>'active';
+
+ALTER TABLE challenges DROP COLUMN IF EXISTS ends_at;
+ALTER TABLE challenges DROP COLUMN IF EXISTS starts_at;
@@ -0,0 +1,10 @@
+ALTER TABLE challenges ADD COLUMN starts_at TIMESTAMPTZ;
+ALTER TABLE challenges ADD COLUMN ends_at TIMESTAMPTZ;
+
+UPDATE challenges SET status='closed' WHERE status NOT IN ('draft', 'scheduled', 'active', 'closed', 'archived');
+
+ALTER TABLE challenges ADD CONSTRAINT challenges_status_check CHECK (status IN ('draft', 'scheduled', 'active', 'closed', 'archived'));
+ALTER TABLE challenges ADD CONSTRAINT challenges_dates_check CHECK (starts_at IS NULL OR ends_at IS NULL OR ends_at > starts_at);
+
+CREATE INDEX challenges_starts_at_idx ON challenges (starts_at) WHERE status='scheduled' AND deleted_at IS NULL;
+CREATE INDEX challenges_ends_at_idx ON challenges (ends_at) WHERE status='active' AND deleted_at IS NULL;
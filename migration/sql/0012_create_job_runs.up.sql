@@ -0,0 +1,11 @@
+CREATE TABLE job_runs (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	trigger TEXT NOT NULL DEFAULT '',
+	started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	finished_at TIMESTAMPTZ,
+	error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX job_runs_name_idx ON job_runs (name, id);
+CREATE INDEX job_runs_started_at_idx ON job_runs (started_at);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS challenges_tags_idx;
+DROP INDEX IF EXISTS challenges_category_id_idx;
+
+ALTER TABLE challenges DROP COLUMN IF EXISTS tags;
+ALTER TABLE challenges DROP COLUMN IF EXISTS category_id;
+
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,13 @@
+CREATE TABLE categories (
+	id BIGSERIAL PRIMARY KEY,
+	slug TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ
+);
+
+ALTER TABLE challenges ADD COLUMN category_id BIGINT REFERENCES categories(id) ON DELETE SET NULL;
+ALTER TABLE challenges ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX challenges_category_id_idx ON challenges (category_id) WHERE deleted_at IS NULL;
+CREATE INDEX challenges_tags_idx ON challenges USING GIN (tags);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS users_name_trgm_idx;
+DROP INDEX IF EXISTS users_search_vector_idx;
+DROP INDEX IF EXISTS challenges_name_trgm_idx;
+DROP INDEX IF EXISTS challenges_search_vector_idx;
+
+ALTER TABLE users DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE challenges DROP COLUMN IF EXISTS search_vector;
+
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,11 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE challenges ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+	setweight(to_tsvector('simple', name), 'A') || setweight(to_tsvector('simple', COALESCE(description, '')), 'B')
+) STORED;
+ALTER TABLE users ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', name)) STORED;
+
+CREATE INDEX challenges_search_vector_idx ON challenges USING GIN (search_vector) WHERE deleted_at IS NULL;
+CREATE INDEX challenges_name_trgm_idx ON challenges USING GIN (name gin_trgm_ops) WHERE deleted_at IS NULL;
+CREATE INDEX users_search_vector_idx ON users USING GIN (search_vector) WHERE deleted_at IS NULL;
+CREATE INDEX users_name_trgm_idx ON users USING GIN (name gin_trgm_ops) WHERE deleted_at IS NULL;
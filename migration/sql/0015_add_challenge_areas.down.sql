@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS challenges_geography_idx;
+
+ALTER TABLE challenges DROP CONSTRAINT IF EXISTS challenges_geometry_check;
@@ -0,0 +1,4 @@
+ALTER TABLE challenges ADD CONSTRAINT challenges_geometry_check
+	CHECK (geometry IS NULL OR (GeometryType(geometry) IN ('POINT', 'POLYGON', 'MULTIPOLYGON') AND ST_IsValid(geometry)));
+
+CREATE INDEX challenges_geography_idx ON challenges USING GIST ((geometry::geography)) WHERE deleted_at IS NULL;
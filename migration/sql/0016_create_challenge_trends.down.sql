@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS likes_created_at_idx;
+DROP INDEX IF EXISTS posts_created_at_idx;
+DROP INDEX IF EXISTS challenges_trending_score_idx;
+
+ALTER TABLE challenges DROP COLUMN IF EXISTS trending_score;
+
+DROP TABLE IF EXISTS challenge_trends;
@@ -0,0 +1,14 @@
+CREATE TABLE challenge_trends (
+	challenge_id BIGINT PRIMARY KEY REFERENCES challenges(id) ON DELETE CASCADE,
+	posts INTEGER NOT NULL DEFAULT 0,
+	liked_posts INTEGER NOT NULL DEFAULT 0,
+	velocity DOUBLE PRECISION NOT NULL DEFAULT 0,
+	score DOUBLE PRECISION NOT NULL DEFAULT 0,
+	refreshed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE challenges ADD COLUMN trending_score DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+CREATE INDEX challenges_trending_score_idx ON challenges (status, trending_score DESC) WHERE deleted_at IS NULL;
+CREATE INDEX posts_created_at_idx ON posts (created_at) WHERE deleted_at IS NULL;
+CREATE INDEX likes_created_at_idx ON likes (created_at);
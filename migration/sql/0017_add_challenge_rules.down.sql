@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS posts_challenge_id_user_id_idx;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS geometry;
+ALTER TABLE posts DROP COLUMN IF EXISTS duration;
+
+ALTER TABLE challenges DROP COLUMN IF EXISTS rules;
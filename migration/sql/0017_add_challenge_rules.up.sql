@@ -0,0 +1,6 @@
+ALTER TABLE challenges ADD COLUMN rules JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE posts ADD COLUMN duration REAL;
+ALTER TABLE posts ADD COLUMN geometry GEOMETRY(Point, 4326);
+
+CREATE INDEX posts_challenge_id_user_id_idx ON posts (challenge_id, user_id) WHERE deleted_at IS NULL;
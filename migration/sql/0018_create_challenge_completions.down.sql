@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS challenge_completions;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS completed_at;
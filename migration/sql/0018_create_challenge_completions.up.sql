@@ -0,0 +1,14 @@
+ALTER TABLE posts ADD COLUMN completed_at TIMESTAMPTZ;
+
+CREATE TABLE challenge_completions (
+	id BIGSERIAL PRIMARY KEY,
+	challenge_id BIGINT NOT NULL REFERENCES challenges(id) ON DELETE CASCADE,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	post_id BIGINT REFERENCES posts(id) ON DELETE SET NULL,
+	exp INTEGER NOT NULL DEFAULT 0,
+	coins BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (challenge_id, user_id)
+);
+
+CREATE INDEX challenge_completions_user_id_idx ON challenge_completions (user_id, id);
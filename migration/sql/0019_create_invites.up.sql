@@ -0,0 +1,23 @@
+CREATE TABLE invites (
+	id BIGSERIAL PRIMARY KEY,
+	code TEXT NOT NULL UNIQUE,
+	challenge_id BIGINT NOT NULL REFERENCES challenges(id) ON DELETE CASCADE,
+	inviter_id BIGINT NOT NULL REFERENCES users(id),
+	expires_at TIMESTAMPTZ,
+	max_uses INTEGER,
+	uses INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX invites_challenge_id_idx ON invites (challenge_id, id);
+
+CREATE TABLE invite_redemptions (
+	id BIGSERIAL PRIMARY KEY,
+	invite_id BIGINT NOT NULL REFERENCES invites(id) ON DELETE CASCADE,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	challenge_request_id BIGINT REFERENCES challenge_requests(id) ON DELETE SET NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (invite_id, user_id)
+);
+
+CREATE INDEX invite_redemptions_user_id_idx ON invite_redemptions (user_id);
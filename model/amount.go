@@ -2,5 +2,13 @@ package model
 
 //Amount struct to bind the incoming payload
 type Amount struct {
-	Amount int `json:"amount"`
+	Amount int `json:"amount" bind:"update"`
+}
+
+//Validate func checks that there is an amount to add
+func (a *Amount) Validate(op string) []string {
+	if a.Amount == 0 {
+		return []string{"amount"}
+	}
+	return []string{}
 }
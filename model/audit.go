@@ -0,0 +1,169 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+//AuditEvent struct is a model/schema for audit_events table. It records who changed what, with the target row before and after.
+//The handler fills the actor, action and request id, the repository the target and the snapshots.
+type AuditEvent struct {
+	ID         int64           `json:"id" sql:"id"`
+	ActorID    int64           `json:"actor_id" sql:"actor_id"`
+	ActorRole  string          `json:"actor_role" sql:"actor_role"`
+	Action     string          `json:"action" sql:"action"`
+	TargetType string          `json:"target_type" sql:"target_type"`
+	TargetID   int64           `json:"target_id" sql:"target_id"`
+	Before     json.RawMessage `json:"before" sql:"before"`
+	After      json.RawMessage `json:"after" sql:"after"`
+	RequestID  string          `json:"request_id" sql:"request_id"`
+	CreatedAt  time.Time       `json:"created_at" sql:"created_at"`
+}
+
+//audit targets, the target_type of the events
+const (
+	auditChallenge = "challenge"
+	auditPost      = "post"
+	auditUser      = "user"
+	auditScore     = "score"
+)
+
+//auditSnapshots select the audited columns of a target row, $1 is the target id
+var auditSnapshots = map[string]string{
+	auditChallenge: "SELECT id, user_id, name, description, status, starts_at, ends_at, category_id, tags, rules, weight, ST_AsGeoJSON(geometry)::json AS geometry, updated_at, deleted_at FROM challenges WHERE id=$1",
+	auditPost:      "SELECT id, user_id, challenge_id, file_url, deleted_at FROM posts WHERE id=$1",
+	auditUser:      "SELECT id, role, level_id, weight, updated_at FROM users WHERE id=$1",
+	auditScore:     "SELECT id, user_id, exp, coins, likes_remaining, updated_at FROM scores WHERE id=$1",
+}
+
+//dbtx interface is satisfied by both *sql.DB and *sql.Tx, so a change can run inside the transaction of its audit event
+type dbtx interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+//withAudit func runs change in a transaction. Given an event, the transaction also locks and snapshots the target row before
+//and after the change and appends the event, so a change is never committed without its event nor the other way round.
+func withAudit(db *sql.DB, e *AuditEvent, target string, targetID int64, change func(q dbtx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Audit: begin error: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if e == nil {
+		if err = change(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	e.TargetType = target
+	e.TargetID = targetID
+
+	if e.Before, err = snapshot(tx, auditSnapshots[target]+" FOR UPDATE", targetID); err != nil {
+		return err
+	}
+
+	if err = change(tx); err != nil {
+		return err
+	}
+
+	if e.After, err = snapshot(tx, auditSnapshots[target], targetID); err != nil {
+		return err
+	}
+
+	e.CreatedAt = time.Now()
+	err = tx.QueryRow("INSERT INTO audit_events(actor_id, actor_role, action, target_type, target_id, before, after, request_id, created_at) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9) RETURNING id;",
+		e.ActorID, e.ActorRole, e.Action, e.TargetType, e.TargetID, []byte(e.Before), []byte(e.After), e.RequestID, e.CreatedAt).Scan(&e.ID)
+	if err != nil {
+		log.Printf("Audit: insert error: %v", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Audit: commit error: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+//snapshot func returns the row of the query as JSON, null when there is none
+func snapshot(q dbtx, query string, id int64) (json.RawMessage, error) {
+	var row []byte
+	err := q.QueryRow("SELECT row_to_json(t) FROM ("+query+") t;", id).Scan(&row)
+	if err == sql.ErrNoRows {
+		return json.RawMessage("null"), nil
+	}
+	if err != nil {
+		log.Printf("Audit: snapshot error: %v", err)
+		return nil, err
+	}
+
+	return row, nil
+}
+
+//pgAuditEventRepository struct is the postgres implementation of AuditEventRepository
+type pgAuditEventRepository struct {
+	db *sql.DB
+}
+
+//query func turns the filter into the where clause, newest events first
+func (r *pgAuditEventRepository) query(filter AuditEventFilter) *Query {
+	q := NewQuery()
+	if filter.ActorID != 0 {
+		q.Where(Eq("actor_id", filter.ActorID))
+	}
+	if filter.Action != "" {
+		q.Where(Eq("action", filter.Action))
+	}
+	if filter.TargetType != "" {
+		q.Where(Eq("target_type", filter.TargetType))
+	}
+	if filter.TargetID != 0 {
+		q.Where(Eq("target_id", filter.TargetID))
+	}
+	if filter.From != nil {
+		q.Where(Gte("created_at", *filter.From))
+	}
+	if filter.To != nil {
+		q.Where(Lt("created_at", *filter.To))
+	}
+	if filter.BeforeID != 0 {
+		q.Where(Lt("id", filter.BeforeID))
+	}
+
+	return q.Sort(SortKeys{"newest": "id DESC"}, "newest", "newest").Page(0, filter.Limit)
+}
+
+//Get func fetches the audit events passing the filter, newest first
+func (r *pgAuditEventRepository) Get(filter AuditEventFilter) ([]*AuditEvent, error) {
+	clause, args := r.query(filter).Build()
+
+	eventList := []*AuditEvent{}
+	rows, err := r.db.Query("SELECT id, actor_id, actor_role, action, target_type, target_id, COALESCE(before, 'null'), COALESCE(after, 'null'), request_id, created_at FROM audit_events "+clause+";", args...)
+	if err != nil {
+		log.Printf("Get audit events: sql error %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event := AuditEvent{}
+		var before, after []byte
+		if err = rows.Scan(&event.ID, &event.ActorID, &event.ActorRole, &event.Action, &event.TargetType, &event.TargetID, &before, &after, &event.RequestID, &event.CreatedAt); err != nil {
+			log.Printf("scanning row to struct error: %v", err)
+			return nil, err
+		}
+		event.Before, event.After = before, after
+
+		eventList = append(eventList, &event)
+	}
+	return eventList, nil
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//Operations which decide the writable fields of a payload. A field lists them in its bind tag, e.g. `bind:"create,update"`.
+//Models may define their own operations next to their struct when an endpoint writes a single field.
+const (
+	OpCreate      = "create"
+	OpUpdate      = "update"
+	OpAdminUpdate = "admin_update"
+)
+
+//Validator interface is implemented by the payloads which check their values once they are bound
+type Validator interface {
+	Validate(op string) []string
+}
+
+//writableKey is the cache key of the writable fields of a struct type for an operation
+type writableKey struct {
+	t  reflect.Type
+	op string
+}
+
+//writableCache holds the parsed bind tags, they never change at runtime
+var writableCache sync.Map
+
+//writableFields func maps the json names of the fields writable in op onto their field index
+func writableFields(t reflect.Type, op string) map[string]int {
+	key := writableKey{t: t, op: op}
+	if fields, ok := writableCache.Load(key); ok {
+		return fields.(map[string]int)
+	}
+
+	fields := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		writable := false
+		for _, fieldOp := range strings.Split(field.Tag.Get("bind"), ",") {
+			if strings.TrimSpace(fieldOp) == op {
+				writable = true
+			}
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !writable || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = i
+	}
+
+	writableCache.Store(key, fields)
+	return fields
+}
+
+//Bind func decodes a json object into dst in a single pass. Keys which are not writable in op are rejected with their names,
+//values of the wrong type are reported per field and at last dst validates itself if it is a Validator.
+func Bind(body io.Reader, dst interface{}, op string) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return Internal(&json.InvalidUnmarshalError{Type: reflect.TypeOf(dst)})
+	}
+	v = v.Elem()
+
+	raw := map[string]json.RawMessage{}
+	decoder := json.NewDecoder(body)
+	if err := decoder.Decode(&raw); err != nil {
+		return Validation(CodeInvalidPayload, "Invalid JSON payload")
+	}
+	if decoder.More() {
+		return Validation(CodeInvalidPayload, "Invalid JSON payload")
+	}
+
+	writable := writableFields(v.Type(), op)
+
+	notAllowed := []string{}
+	for key := range raw {
+		if _, ok := writable[key]; !ok {
+			notAllowed = append(notAllowed, key)
+		}
+	}
+	if len(notAllowed) > 0 {
+		sort.Strings(notAllowed)
+		return Validation(CodeFieldsNotAllowed, "Some fields are not allowed", notAllowed...)
+	}
+
+	invalid := []string{}
+	for key, value := range raw {
+		if err := json.Unmarshal(value, v.Field(writable[key]).Addr().Interface()); err != nil {
+			invalid = append(invalid, key)
+		}
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return Validation(CodeInvalidFields, "Invalid fields detected", invalid...)
+	}
+
+	if validator, ok := dst.(Validator); ok {
+		if errSlice := validator.Validate(op); len(errSlice) > 0 {
+			return Validation(CodeInvalidFields, "Invalid fields detected", errSlice...)
+		}
+	}
+
+	return nil
+}
@@ -1,8 +1,8 @@
 package model
 
 import (
+	"database/sql"
 	"encoding/json"
-	"errors"
 	"log"
 	"time"
 )
@@ -10,77 +10,68 @@ import (
 //BoughtItem is a model/schema for a bought_item table
 type BoughtItem struct {
 	ID           int64       `json:"id" sql:"id"`
-	VanityItemID *int64      `json:"vanity_item_id,omitempty" sql:"vanity_item_id"`
+	VanityItemID *int64      `json:"vanity_item_id,omitempty" sql:"vanity_item_id" bind:"create"`
 	UserID       int64       `json:"-" sql:"user_id"`
 	VanityItem   *VanityItem `json:"vanity_item" sql:"-"`
-	LevelID      *int64      `json:"level_id,omitempty" sql:"level_id"`
+	LevelID      *int64      `json:"level_id,omitempty" sql:"level_id" bind:"create"`
 	Level        *Level      `json:"level" sql:"-"`
-	Amount       *string     `json:"amount" sql:"amount"`
-	Currency     *string     `json:"currency" sql:"currency"`
+	Amount       *string     `json:"amount" sql:"amount" bind:"create"`
+	Currency     *string     `json:"currency" sql:"currency" bind:"create"`
 	CreatedAt    time.Time   `json:"created_at" sql:"created_at"`
-
-	Payload map[string]interface{} `json:"-"`
 }
 
-//ParseNotAllowedJSON unmarshalls JSON payload to struct payload and fields. Plus parses the JSON payload.
-func (b *BoughtItem) ParseNotAllowedJSON() []string {
+//Validate func validates incoming post payload fields. Exactly one of vanity_item_id and level_id is bought.
+func (b *BoughtItem) Validate(op string) []string {
 	errSlice := []string{}
 
-	delete(b.Payload, "amount")
-	delete(b.Payload, "currency")
-	delete(b.Payload, "level_id")
-	delete(b.Payload, "vanity_item_id")
-
-	for key := range b.Payload {
-		errSlice = append(errSlice, key)
-	}
-
-	return errSlice
-}
-
-//PostValidate func validates incoming post payload fields
-func (b *BoughtItem) PostValidate() []string {
-	errSlice := []string{}
-
-	if *b.Amount == "" {
+	if b.Amount == nil || *b.Amount == "" {
 		errSlice = append(errSlice, "amount")
 	}
 
-	if *b.Currency == "" {
+	if b.Currency == nil || *b.Currency == "" {
 		errSlice = append(errSlice, "currency")
 	}
 
-	if *b.LevelID <= 0 && *b.VanityItemID <= 0 {
+	hasLevel := b.LevelID != nil && *b.LevelID > 0
+	hasVanityItem := b.VanityItemID != nil && *b.VanityItemID > 0
+	if hasLevel == hasVanityItem {
 		errSlice = append(errSlice, "vanity_item_id/level_id")
 	}
 
 	return errSlice
 }
 
-//Get func fetches the vanity items from the db
-func (b *BoughtItem) Get(whereClause string, args ...interface{}) ([]*BoughtItem, error) {
+//pgBoughtItemRepository struct is the postgres implementation of BoughtItemRepository
+type pgBoughtItemRepository struct {
+	db *sql.DB
+}
+
+//Get func fetches the bought items of an user from the db
+func (r *pgBoughtItemRepository) Get(filter BoughtItemFilter) ([]*BoughtItem, error) {
 	boughtItemList := []*BoughtItem{}
-	rows, err := db.Query(`SELECT id, (SELECT row_to_json(vanity_items) FROM vanity_items WHERE vanity_items.id=bought_items.vanity_item_id) as vanity_items, 
-	(SELECT row_to_json(levels) FROM levels WHERE levels.id=bought_item.level_id) as level, amount, currency, created_at FROM bought_items `+whereClause+" ORDER BY created_at DESC;", args...)
+	rows, err := r.db.Query(`SELECT id, vanity_item_id, level_id, COALESCE((SELECT row_to_json(vanity_items) FROM vanity_items WHERE vanity_items.id=bought_items.vanity_item_id), 'null') as vanity_item, 
+	COALESCE((SELECT row_to_json(levels) FROM levels WHERE levels.id=bought_items.level_id), 'null') as level, amount, currency, created_at FROM bought_items WHERE user_id=$1 ORDER BY created_at DESC;`, filter.UserID)
 	if err != nil {
-		log.Printf("Get users: sql error %v", err)
+		log.Printf("Get bought items: sql error %v", err)
 		return nil, err
 	}
+	defer rows.Close()
+
 	for rows.Next() {
-		boughtItem := BoughtItem{}
-		vanityItemsStr := ""
+		boughtItem := BoughtItem{UserID: filter.UserID}
+		vanityItemStr := ""
 		levelStr := ""
-		if err = rows.Scan(&boughtItem.ID, &vanityItemsStr, &levelStr, &boughtItem.Amount, &boughtItem.Currency, &boughtItem.CreatedAt); err != nil {
+		if err = rows.Scan(&boughtItem.ID, &boughtItem.VanityItemID, &boughtItem.LevelID, &vanityItemStr, &levelStr, &boughtItem.Amount, &boughtItem.Currency, &boughtItem.CreatedAt); err != nil {
 			log.Printf("scanning row to struct error: %v", err)
 			return nil, err
 		}
 
-		if err = json.Unmarshal([]byte(vanityItemsStr), &boughtItem.VanityItem); err != nil {
+		if err = json.Unmarshal([]byte(vanityItemStr), &boughtItem.VanityItem); err != nil {
 			log.Printf("Unmarshaling of subquery error: %v", err)
 			return nil, err
 		}
 
-		if err = json.Unmarshal([]byte(vanityItemsStr), &boughtItem.Level); err != nil {
+		if err = json.Unmarshal([]byte(levelStr), &boughtItem.Level); err != nil {
 			log.Printf("Unmarshaling of subquery error: %v", err)
 			return nil, err
 		}
@@ -91,39 +82,17 @@ func (b *BoughtItem) Get(whereClause string, args ...interface{}) ([]*BoughtItem
 }
 
 //Create func adds an item to the table
-func (b *BoughtItem) Create() error {
-	now := time.Now()
-	b.CreatedAt = now
+func (r *pgBoughtItemRepository) Create(b *BoughtItem) error {
+	b.CreatedAt = time.Now()
 
-	stmt, err := db.Prepare("INSERT INTO bought_items (user_id, vanity_item_id, level_id, amount, currency, created_at) VALUES($1,$2,$3,$4,$5,$6);")
+	err := r.db.QueryRow("INSERT INTO bought_items (user_id, vanity_item_id, level_id, amount, currency, created_at) VALUES($1,$2,$3,$4,$5,$6) RETURNING id;",
+		b.UserID, b.VanityItemID, b.LevelID, b.Amount, b.Currency, b.CreatedAt).Scan(&b.ID)
 	if err != nil {
-		log.Printf("create prepare statement error: %v", err)
-		return err
-	}
-
-	res, err := stmt.Exec(b.UserID, b.VanityItemID, b.LevelID, b.Amount, b.Currency, b.CreatedAt)
-	if err != nil {
-		log.Printf("exec statement error: %v", err)
-		return err
-	}
-
-	b.ID, err = res.LastInsertId()
-	if err != nil {
-		log.Printf("last insert id error: %v", err)
+		log.Printf("Create bought item: insert error: %v", err)
 		return err
 	}
 
 	log.Printf("boughtitem successfully created with id %v", b.ID)
 
-	affected, err := res.RowsAffected()
-	if err != nil {
-		log.Printf("rows effected error: %v", err)
-		return err
-	}
-	if affected == 0 {
-		log.Printf("rows effected -> %v", affected)
-		return errors.New("Server error")
-	}
-
 	return nil
 }
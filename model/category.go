@@ -0,0 +1,171 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+//Category struct is a model/schema for categories table. Admins manage the categories, clients filter the challenges by the slug.
+type Category struct {
+	ID        int64      `json:"id" sql:"id"`
+	Slug      string     `json:"slug" sql:"slug" bind:"create,update"`
+	Name      string     `json:"name" sql:"name" bind:"create,update"`
+	CreatedAt time.Time  `json:"created_at" sql:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" sql:"updated_at"`
+}
+
+//slugRegexp matches lower case words joined by dashes, e.g. street-art
+var slugRegexp = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+//tagRegexp matches a normalized tag
+var tagRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+//limits of the tags of a challenge
+const (
+	maxTags      = 10
+	maxTagLength = 32
+)
+
+//Validate func validates incoming payload fields. Updates need at least one field.
+func (c *Category) Validate(op string) []string {
+	errSlice := []string{}
+
+	if op != OpCreate && c.Slug == "" && c.Name == "" {
+		return append(errSlice, "slug/name")
+	}
+
+	if (op == OpCreate || c.Slug != "") && (len(c.Slug) > 32 || !slugRegexp.MatchString(c.Slug)) {
+		errSlice = append(errSlice, "slug")
+	}
+
+	if op == OpCreate && strings.TrimSpace(c.Name) == "" {
+		errSlice = append(errSlice, "name")
+	}
+
+	return errSlice
+}
+
+//NormalizeTags func lower cases the tags, drops a leading # and the duplicates. ok is false when a tag is invalid or there are too many.
+func NormalizeTags(tags []string) ([]string, bool) {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(tag)), "#")
+		if len(tag) > maxTagLength || !tagRegexp.MatchString(tag) {
+			return nil, false
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	return normalized, len(normalized) <= maxTags
+}
+
+//categoryError func turns the foreign key violation of an unknown category into a validation error
+func categoryError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "foreign_key_violation" {
+		return Validation(CodeInvalidFields, "Unknown category", "category_id")
+	}
+	return err
+}
+
+//pgCategoryRepository struct is the postgres implementation of CategoryRepository
+type pgCategoryRepository struct {
+	db *sql.DB
+}
+
+//Create func inserts a new category in the db
+func (r *pgCategoryRepository) Create(c *Category) error {
+	c.CreatedAt = time.Now()
+
+	err := r.db.QueryRow("INSERT INTO categories (slug, name, created_at) VALUES($1,$2,$3) RETURNING id;", c.Slug, c.Name, c.CreatedAt).Scan(&c.ID)
+	if err != nil {
+		log.Printf("Create category: insert error: %v", err)
+		return dbError(err)
+	}
+
+	return nil
+}
+
+//Get func fetches the categories passing the filter, ordered by name
+func (r *pgCategoryRepository) Get(filter CategoryFilter) ([]*Category, error) {
+	q := NewQuery()
+	if filter.ID > 0 {
+		q.Where(Eq("id", filter.ID))
+	}
+	if filter.Slug != "" {
+		q.Where(Eq("slug", filter.Slug))
+	}
+	clause, args := q.Sort(SortKeys{"name": "name, id"}, "name", "name").Build()
+
+	categoryList := []*Category{}
+	rows, err := r.db.Query("SELECT id, slug, name, created_at, updated_at FROM categories "+clause+";", args...)
+	if err != nil {
+		log.Printf("Get categories: sql error %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		category := Category{}
+		if err = rows.Scan(&category.ID, &category.Slug, &category.Name, &category.CreatedAt, &category.UpdatedAt); err != nil {
+			log.Printf("scanning row to struct error: %v", err)
+			return nil, err
+		}
+		categoryList = append(categoryList, &category)
+	}
+	return categoryList, nil
+}
+
+//Update func changes the slug and/or the name of the category
+func (r *pgCategoryRepository) Update(c *Category) error {
+	now := time.Now()
+	c.UpdatedAt = &now
+
+	res, err := r.db.Exec("UPDATE categories SET slug=COALESCE(NULLIF($1, ''), slug), name=COALESCE(NULLIF($2, ''), name), updated_at=$3 WHERE id=$4;",
+		c.Slug, c.Name, c.UpdatedAt, c.ID)
+	if err != nil {
+		log.Printf("Update category: sql error %v", err)
+		return dbError(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Printf("rows effected error: %v", err)
+		return err
+	}
+	if affected == 0 {
+		return NotFound(CodeCategoryNotFound, "Category not found")
+	}
+
+	return nil
+}
+
+//Delete func deletes the category, its challenges are left without a category
+func (r *pgCategoryRepository) Delete(c *Category) error {
+	res, err := r.db.Exec("DELETE FROM categories WHERE id=$1;", c.ID)
+	if err != nil {
+		log.Printf("Delete category: sql error %v", err)
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Printf("rows effected error: %v", err)
+		return err
+	}
+	if affected == 0 {
+		return NotFound(CodeCategoryNotFound, "Category not found")
+	}
+
+	return nil
+}
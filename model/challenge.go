@@ -1,52 +1,77 @@
 package model
 
 import (
+	"database/sql"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"log"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 //Challenge struct is a model/schema for a challenge table
 type Challenge struct {
-	ID                 int64      `json:"id" sql:"id"`
-	UserID             int64      `json:"user_id" sql:"user_id"`
-	Name               string     `json:"name" sql:"name"`
-	LikesNeededPerPost int        `json:"likes_needed_per_post" sql:"likes_needed_per_post"`
-	Description        *string    `json:"description" sql:"description"`
-	Status             string     `json:"status" sql:"status"`
-	Weight             *float32   `json:"weight" sql:"weight"`
-	CreatedAt          time.Time  `json:"created_at" sql:"created_at"`
-	UpdatedAt          *time.Time `json:"updated_at" sql:"updated_at"`
+	ID                 int64           `json:"id" sql:"id"`
+	UserID             int64           `json:"user_id" sql:"user_id"`
+	Name               string          `json:"name" sql:"name" bind:"create"`
+	LikesNeededPerPost int             `json:"likes_needed_per_post" sql:"likes_needed_per_post" bind:"create"`
+	Description        *string         `json:"description" sql:"description" bind:"create,update,admin_update"`
+	Status             string          `json:"status" sql:"status" bind:"create,update,admin_update"`
+	Weight             *float32        `json:"weight" sql:"weight" bind:"admin_update"`
+	StartsAt           *time.Time      `json:"starts_at" sql:"starts_at" bind:"create,update,admin_update"`
+	EndsAt             *time.Time      `json:"ends_at" sql:"ends_at" bind:"create,update,admin_update"`
+	CategoryID         *int64          `json:"category_id" sql:"category_id" bind:"create,update,admin_update"`
+	Tags               []string        `json:"tags" sql:"tags" bind:"create,update,admin_update"`
+	Rules              *ChallengeRules `json:"rules" sql:"rules" bind:"create,update,admin_update"`
+	TrendingScore      float64         `json:"trending_score" sql:"trending_score"`
+	CreatedAt          time.Time       `json:"created_at" sql:"created_at"`
+	UpdatedAt          *time.Time      `json:"updated_at" sql:"updated_at"`
+	DeletedAt          *time.Time      `json:"-" sql:"deleted_at"`
 
 	TotalPost int64     `json:"total_post" sql:"-"`
-	Location  *geometry `json:"geo_coords" sql:"-"`
-
-	Payload map[string]interface{} `json:"-"`
+	Location  *geometry `json:"geo_coords" sql:"-" bind:"create,update,admin_update"`
+	Category  *Category `json:"category" sql:"-"`
+	Distance  *float64  `json:"distance,omitempty" sql:"-"`
 }
 
-//ParseNotAllowedJSON unmarshalls JSON payload to struct payload and fields. Plus parses the JSON payload.
-func (c *Challenge) ParseNotAllowedJSON() []string {
+//Validate func validates incoming payload fields. Updates need at least one field. The tags are normalized, a category_id
+//of 0 removes the category.
+func (c *Challenge) Validate(op string) []string {
 	errSlice := []string{}
 
-	delete(c.Payload, "name")
-	delete(c.Payload, "description")
-	delete(c.Payload, "geo_coords")
-	delete(c.Payload, "likes_needed_per_post")
+	if c.Status != "" && !IsChallengeState(c.Status) {
+		errSlice = append(errSlice, "status")
+	}
 
-	for key := range c.Payload {
-		errSlice = append(errSlice, key)
+	if c.CategoryID != nil && *c.CategoryID < 0 {
+		errSlice = append(errSlice, "category_id")
 	}
 
-	return errSlice
-}
+	if c.Tags != nil {
+		if tags, ok := NormalizeTags(c.Tags); ok {
+			c.Tags = tags
+		} else {
+			errSlice = append(errSlice, "tags")
+		}
+	}
 
-//PostValidate func validates incoming post payload fields
-func (c *Challenge) PostValidate() []string {
-	errSlice := []string{}
+	if c.Rules != nil {
+		errSlice = append(errSlice, c.Rules.validate()...)
+	}
+
+	if c.Location != nil && !c.Location.valid() {
+		errSlice = append(errSlice, "geo_coords")
+	}
+
+	if op != OpCreate {
+		if c.Description == nil && c.Location == nil && c.Status == "" && c.Weight == nil && c.StartsAt == nil && c.EndsAt == nil &&
+			c.CategoryID == nil && c.Tags == nil && c.Rules == nil {
+			errSlice = append(errSlice, "description/geo_coords")
+		}
+		return errSlice
+	}
 
 	if c.Name == "" {
 		errSlice = append(errSlice, "name")
@@ -59,118 +84,118 @@ func (c *Challenge) PostValidate() []string {
 	return errSlice
 }
 
-//Create func inserts a new challenge in the db
-func (c *Challenge) Create() error {
-	c.CreatedAt = time.Now()
+//pgChallengeRepository struct is the postgres implementation of ChallengeRepository
+type pgChallengeRepository struct {
+	db *sql.DB
+}
 
-	geomStr, err := json.Marshal(c.Location)
-	if err != nil {
-		log.Printf("Bad location value err: %v\n", err)
-		return err
+//ChallengeSorts is the whitelist of the orderings clients can ask for through the type query string. Trending, and hot
+//which older clients ask for, read the score kept by RefreshTrending. Every ordering ends with the id, which keeps the
+//pages of challengeCursor apart.
+var ChallengeSorts = SortKeys{
+	"trending": "challenges.trending_score DESC, challenges.id",
+	"hot":      "challenges.trending_score DESC, challenges.id",
+	"fresh":    "challenges.created_at DESC, challenges.id",
+	"id":       "challenges.id",
+}
+
+//challengeCursor func returns the keyset predicate of the sort of the filter, the challenges after the last one of the
+//previous page
+func challengeCursor(filter ChallengeFilter) Predicate {
+	switch filter.Sort {
+	case "trending", "hot":
+		return AfterDesc("trending_score", filter.LastTrendingScore, filter.LastID)
+	case "fresh":
+		return AfterDesc("created_at", filter.LastCreatedAt, filter.LastID)
 	}
+	return Gt("id", filter.LastID)
+}
 
-	geometryValue := "ST_GeomFromGeoJSON('" + string(geomStr) + "')"
+//query func translates the filter into a typed query. It returns the expression of the distance to the near point too,
+//which is NULL without one.
+func (r *pgChallengeRepository) query(filter ChallengeFilter) (*Query, string) {
+	q := NewQuery().Where(IsNull("deleted_at"))
+	distance := "NULL::float8"
 
-	stmt, err := db.Prepare("INSERT INTO challenges (user_id, name, description, likes_needed_per_post, status, weight, geometry, created_at) VALUES($1,$2,$3,$4,$5" + geometryValue + ",$6);")
-	if err != nil {
-		log.Printf("create prepare statement error: %v", err)
-		return err
+	if filter.ID > 0 {
+		q.Where(Eq("id", filter.ID))
 	}
 
-	res, err := stmt.Exec(c.UserID, c.Name, c.Description, c.LikesNeededPerPost, c.Status, c.Weight, c.CreatedAt)
-	if err != nil {
-		log.Printf("exec statement error: %v", err)
-		return err
+	if filter.UserID > 0 {
+		q.Where(Eq("user_id", filter.UserID))
 	}
 
-	c.ID, err = res.LastInsertId()
-	if err != nil {
-		log.Printf("last insert id error: %v", err)
-		return err
+	if filter.Status != "" {
+		q.Where(Eq("status", filter.Status))
 	}
 
-	log.Printf("onesignal successfully created with id %v", c.ID)
-
-	affected, err := res.RowsAffected()
-	if err != nil {
-		log.Printf("rows effected error: %v", err)
-		return err
-	}
-	if affected == 0 {
-		log.Printf("rows effected -> %v", affected)
-		return errors.New("Server error")
+	if filter.CategoryID > 0 {
+		q.Where(Eq("category_id", filter.CategoryID))
 	}
 
-	return nil
-}
-
-//Update func updates a challenge in the db
-func (c *Challenge) Update() (int, error) {
-	sets := []string{}
-	values := make(map[int]interface{})
-	index := 0
+	if len(filter.Tags) > 0 && filter.AllTags {
+		q.Where(ArrayContains("tags", filter.Tags))
+	} else if len(filter.Tags) > 0 {
+		q.Where(ArrayOverlaps("tags", filter.Tags))
+	}
 
-	if c.Description != nil {
-		values[index] = *c.Description
-		index = index + 1
-		sets = append(sets, "description=$"+strconv.Itoa(index))
+	if filter.Box != nil {
+		q.Where(WithinBox("geometry", *filter.Box))
 	}
 
-	if c.Status != "" {
-		values[index] = c.Status
-		index = index + 1
-		sets = append(sets, "status=$"+strconv.Itoa(index))
+	if filter.Near == nil {
+		if filter.LastID > 0 {
+			q.Where(challengeCursor(filter))
+		}
+		return q.Sort(ChallengeSorts, filter.Sort, "id").Page(0, filter.Limit), distance
 	}
 
-	if c.UpdatedAt != nil {
-		values[index] = c.UpdatedAt
-		index = index + 1
-		sets = append(sets, "updated_at=$"+strconv.Itoa(index))
+	point := q.GeographyPoint(filter.Near.Long, filter.Near.Lat)
+	distance = "ST_Distance(geometry::geography, " + point + ")"
+	q.Where(WithinDistance("geometry", point, filter.Near.Radius))
+	if filter.LastID > 0 {
+		q.Where(AfterDistance(distance, filter.LastDistance, filter.LastID))
 	}
 
-	if c.Location != nil {
-		geomStr, err := json.Marshal(c.Location)
-		if err != nil {
-			log.Printf("Bad location value err: %v\n", err)
-			return 0, err
-		}
+	return q.Sort(SortKeys{"distance": distance + ", id"}, "distance", "distance").Page(0, filter.Limit), distance
+}
 
-		geometryValue := "ST_GeomFromGeoJSON('" + string(geomStr) + "')"
-		sets = append(sets, "updated_at="+geometryValue)
-	}
+//Create func inserts a new challenge in the db
+func (r *pgChallengeRepository) Create(c *Challenge) error {
+	c.CreatedAt = time.Now()
 
-	stmt, err := db.Prepare("UPDATE challenges SET " + strings.Join(sets, ", ") + " WHERE deleted_at IS NULL AND id=" + fmt.Sprintf("%v", c.ID) + " AND user_id=" + fmt.Sprintf("%v", c.UserID) + ";")
-	if err != nil {
-		log.Printf("UPDATE challegne prepare statement error: %v", err)
-		return 500, errors.New("Server error")
+	if err := c.initState(c.CreatedAt); err != nil {
+		return err
 	}
 
-	argsValues := make([]interface{}, len(values))
-	for k, v := range values {
-		argsValues[k] = v
+	if c.Tags == nil {
+		c.Tags = []string{}
+	}
+	if c.Rules == nil {
+		c.Rules = &ChallengeRules{MediaTypes: []string{}}
 	}
 
-	res, err := stmt.Exec(argsValues...)
+	geomStr, err := json.Marshal(c.Location)
 	if err != nil {
-		log.Printf("exec statement error: %v", err)
-		return 500, errors.New("Server error")
+		log.Printf("Bad location value err: %v\n", err)
+		return err
 	}
 
-	affected, err := res.RowsAffected()
+	err = r.db.QueryRow("INSERT INTO challenges (user_id, name, description, likes_needed_per_post, status, starts_at, ends_at, category_id, tags, rules, weight, geometry, created_at) VALUES($1,$2,$3,$4,$5,$6,$7,NULLIF($8, 0),$9,$10,$11,ST_Force2D(ST_GeomFromGeoJSON($12)),$13) RETURNING id;",
+		c.UserID, c.Name, c.Description, c.LikesNeededPerPost, c.Status, c.StartsAt, c.EndsAt, c.CategoryID, pq.Array(c.Tags), *c.Rules, c.Weight, string(geomStr), c.CreatedAt).Scan(&c.ID)
 	if err != nil {
-		log.Printf("rows effected error: %v", err)
-		return 500, errors.New("Server error")
-	}
-	if affected == 0 {
-		log.Printf("rows effected -> %v", affected)
-		return 404, errors.New("Challenge not found")
+		log.Printf("Create challenge: insert error: %v", err)
+		return geometryError(categoryError(err))
 	}
 
-	return 0, nil
+	log.Printf("challenge successfully created with id %v", c.ID)
+
+	return nil
 }
 
-//AdminUpdate func updates a challenge in the db
-func (c *Challenge) AdminUpdate() (int, error) {
+//update func runs the update of the given fields. The ownerID is checked only when it is bigger than zero.
+//The change is checked against the current state of the challenge, which is locked meanwhile.
+func (r *pgChallengeRepository) update(c *Challenge, ownerID int64, withWeight bool, e *AuditEvent) error {
 	sets := []string{}
 	values := make(map[int]interface{})
 	index := 0
@@ -187,7 +212,37 @@ func (c *Challenge) AdminUpdate() (int, error) {
 		sets = append(sets, "status=$"+strconv.Itoa(index))
 	}
 
-	if c.Weight != nil {
+	if c.StartsAt != nil {
+		values[index] = *c.StartsAt
+		index = index + 1
+		sets = append(sets, "starts_at=$"+strconv.Itoa(index))
+	}
+
+	if c.EndsAt != nil {
+		values[index] = *c.EndsAt
+		index = index + 1
+		sets = append(sets, "ends_at=$"+strconv.Itoa(index))
+	}
+
+	if c.CategoryID != nil {
+		values[index] = *c.CategoryID
+		index = index + 1
+		sets = append(sets, "category_id=NULLIF($"+strconv.Itoa(index)+", 0)")
+	}
+
+	if c.Tags != nil {
+		values[index] = pq.Array(c.Tags)
+		index = index + 1
+		sets = append(sets, "tags=$"+strconv.Itoa(index))
+	}
+
+	if c.Rules != nil {
+		values[index] = *c.Rules
+		index = index + 1
+		sets = append(sets, "rules=$"+strconv.Itoa(index))
+	}
+
+	if withWeight && c.Weight != nil {
 		values[index] = *c.Weight
 		index = index + 1
 		sets = append(sets, "weight=$"+strconv.Itoa(index))
@@ -199,49 +254,110 @@ func (c *Challenge) AdminUpdate() (int, error) {
 		sets = append(sets, "updated_at=$"+strconv.Itoa(index))
 	}
 
-	stmt, err := db.Prepare("UPDATE challenges SET " + strings.Join(sets, ", ") + " deleted_at IS NULL AND WHERE id=" + fmt.Sprintf("%v", c.ID) + ";")
-	if err != nil {
-		log.Printf("UPDATE challegne prepare statement error: %v", err)
-		return 500, errors.New("Server error")
-	}
+	if c.Location != nil {
+		geomStr, err := json.Marshal(c.Location)
+		if err != nil {
+			log.Printf("Bad location value err: %v\n", err)
+			return Validation(CodeInvalidFields, "Invalid geo_coords", "geo_coords")
+		}
 
-	argsValues := make([]interface{}, len(values))
-	for k, v := range values {
-		argsValues[k] = v
+		values[index] = string(geomStr)
+		index = index + 1
+		sets = append(sets, "geometry=ST_Force2D(ST_GeomFromGeoJSON($"+strconv.Itoa(index)+"))")
 	}
 
-	res, err := stmt.Exec(argsValues...)
-	if err != nil {
-		log.Printf("exec statement error: %v", err)
-		return 500, errors.New("Server error")
-	}
+	values[index] = c.ID
+	index = index + 1
+	whereClause := " WHERE deleted_at IS NULL AND id=$" + strconv.Itoa(index)
 
-	affected, err := res.RowsAffected()
-	if err != nil {
-		log.Printf("rows effected error: %v", err)
-		return 500, errors.New("Server error")
-	}
-	if affected == 0 {
-		log.Printf("rows effected -> %v", affected)
-		return 404, errors.New("Challenge not found")
+	if ownerID > 0 {
+		values[index] = ownerID
+		index = index + 1
+		whereClause = whereClause + " AND user_id=$" + strconv.Itoa(index)
 	}
 
-	return 0, nil
+	return withAudit(r.db, e, auditChallenge, c.ID, func(q dbtx) error {
+		current := Challenge{}
+		err := q.QueryRow("SELECT status, starts_at, ends_at FROM challenges WHERE deleted_at IS NULL AND id=$1 AND ($2=0 OR user_id=$2) FOR UPDATE;", c.ID, ownerID).
+			Scan(&current.Status, &current.StartsAt, &current.EndsAt)
+		if err == sql.ErrNoRows {
+			return NotFound(CodeChallengeNotFound, "Challenge not found")
+		}
+		if err != nil {
+			log.Printf("UPDATE challenge current state error: %v", err)
+			return Internal(err)
+		}
+
+		if err = current.checkChange(c, time.Now()); err != nil {
+			return err
+		}
+
+		stmt, err := q.Prepare("UPDATE challenges SET " + strings.Join(sets, ", ") + whereClause + ";")
+		if err != nil {
+			log.Printf("UPDATE challegne prepare statement error: %v", err)
+			return Internal(err)
+		}
+		defer stmt.Close()
+
+		argsValues := make([]interface{}, len(values))
+		for k, v := range values {
+			argsValues[k] = v
+		}
+
+		res, err := stmt.Exec(argsValues...)
+		if err != nil {
+			log.Printf("exec statement error: %v", err)
+			if err = geometryError(categoryError(err)); AsError(err).Kind == KindValidation {
+				return err
+			}
+			return Internal(err)
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			log.Printf("rows effected error: %v", err)
+			return Internal(err)
+		}
+		if affected == 0 {
+			log.Printf("rows effected -> %v", affected)
+			return NotFound(CodeChallengeNotFound, "Challenge not found")
+		}
+
+		return nil
+	})
+}
+
+//Update func updates a challenge of its owner in the db
+func (r *pgChallengeRepository) Update(c *Challenge) error {
+	return r.update(c, c.UserID, false, nil)
 }
 
-//Get func fetches the challenges from the db based on the query
-func (c *Challenge) Get(whereClause string, args ...interface{}) ([]*Challenge, error) {
+//AdminUpdate func updates any challenge in the db, recording the audit event
+func (r *pgChallengeRepository) AdminUpdate(c *Challenge, e *AuditEvent) error {
+	return r.update(c, 0, true, e)
+}
+
+//Get func fetches the challenges from the db based on the filter
+func (r *pgChallengeRepository) Get(filter ChallengeFilter) ([]*Challenge, error) {
 	challengeList := []*Challenge{}
 
-	rows, err := db.Query("SELECT id, name, description, likes_needed_per_post, ST_AsGeoJSON(geometry) AS location, (SELECT COUNT(id) FROM posts WHERE posts.challenge_id=challenges.id) AS total_post, created_at, updated_at FROM challenges "+whereClause, args...)
+	q, distance := r.query(filter)
+	whereClause, args := q.Build()
+
+	rows, err := r.db.Query("SELECT id, user_id, name, description, likes_needed_per_post, status, starts_at, ends_at, category_id, tags, rules, COALESCE((SELECT row_to_json(c) FROM (SELECT id, slug, name, created_at, updated_at FROM categories WHERE categories.id=challenges.category_id) c), 'null') AS category, weight, ST_AsGeoJSON(geometry) AS location, "+distance+" AS distance, (SELECT COUNT(id) FROM posts WHERE posts.challenge_id=challenges.id AND posts.deleted_at IS NULL) AS total_post, trending_score, created_at, updated_at FROM challenges "+whereClause+";", args...)
 	if err != nil {
-		log.Printf("Get users: sql error %v", err)
+		log.Printf("Get challenges: sql error %v", err)
 		return nil, err
 	}
+	defer rows.Close()
+
 	for rows.Next() {
-		challenge := Challenge{}
+		challenge := Challenge{Rules: &ChallengeRules{}}
 		geomStr := ""
-		if err = rows.Scan(&challenge.ID, &challenge.Name, &challenge.Description, &challenge.LikesNeededPerPost, &geomStr, &challenge.TotalPost, &challenge.CreatedAt, &challenge.UpdatedAt); err != nil {
+		categoryStr := ""
+		tags := pq.StringArray{}
+		if err = rows.Scan(&challenge.ID, &challenge.UserID, &challenge.Name, &challenge.Description, &challenge.LikesNeededPerPost, &challenge.Status, &challenge.StartsAt, &challenge.EndsAt,
+			&challenge.CategoryID, &tags, challenge.Rules, &categoryStr, &challenge.Weight, &geomStr, &challenge.Distance, &challenge.TotalPost, &challenge.TrendingScore, &challenge.CreatedAt, &challenge.UpdatedAt); err != nil {
 			log.Printf("scanning row to struct error: %v", err)
 			return nil, err
 		}
@@ -251,6 +367,12 @@ func (c *Challenge) Get(whereClause string, args ...interface{}) ([]*Challenge,
 			return nil, err
 		}
 
+		if err = json.Unmarshal([]byte(categoryStr), &challenge.Category); err != nil {
+			log.Printf("Unmarshaling of category subquery error: %v", err)
+			return nil, err
+		}
+		challenge.Tags = []string(tags)
+
 		challengeList = append(challengeList, &challenge)
 	}
 
@@ -258,99 +380,140 @@ func (c *Challenge) Get(whereClause string, args ...interface{}) ([]*Challenge,
 }
 
 //Count func counts the total challenges in db
-func (c *Challenge) Count(whereClause string, args ...interface{}) (int64, error) {
+func (r *pgChallengeRepository) Count(filter ChallengeFilter) (int64, error) {
 	var count int64
 
-	if err := db.QueryRow("SELECT COUNT(id) FROM challenges "+whereClause+";", args...).Scan(&count); err != nil {
-		log.Printf("Count onesignal: sql error %v", err)
+	q, _ := r.query(filter)
+	whereClause, args := q.WhereClause()
+	if err := r.db.QueryRow("SELECT COUNT(id) FROM challenges "+whereClause+";", args...).Scan(&count); err != nil {
+		log.Printf("Count challenges: sql error %v", err)
 		return count, err
 	}
 
 	return count, nil
 }
 
-//Delete func deletes the post record of the user. Delete meaning it doesnt purge it. Just hides it.
-func (c *Challenge) Delete() (int, error) {
-	count, err := c.Count("WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL", c.ID, c.UserID)
+//delete func hides the challenge. The ownerID is checked only when it is bigger than zero.
+func (r *pgChallengeRepository) delete(c *Challenge, ownerID int64, e *AuditEvent) error {
+	count, err := r.Count(ChallengeFilter{ID: c.ID, UserID: ownerID})
 	if err != nil {
-		log.Printf("challenge delete: error on fetching Post record count: %v", err)
-		return 500, errors.New("Server error")
+		log.Printf("challenge delete: error on fetching challenge record count: %v", err)
+		return Internal(err)
 	}
 
 	if count == 0 {
-		err = fmt.Errorf("challenge not found-> id %v, user_id %v, total found %v", c.ID, c.UserID, count)
-		log.Printf("%v", err)
-		return 405, errors.New("Not allowed")
-	} else if count == 1 {
-		stmt, err := db.Prepare("UPDATE challenges SET deleted_at=$1 WHERE id=$2 AND user_id=$3;")
+		log.Printf("challenge not found-> id %v, user_id %v, total found %v", c.ID, ownerID, count)
+		return NotFound(CodeChallengeNotFound, "Challenge not found")
+	} else if count > 1 {
+		log.Printf("multiple challenges found-> id %v, total found %v", c.ID, count)
+		return Conflict(CodeMultipleFound, "Multiple challenges detected")
+	}
+
+	return withAudit(r.db, e, auditChallenge, c.ID, func(q dbtx) error {
+		stmt, err := q.Prepare("UPDATE challenges SET deleted_at=$1 WHERE id=$2 AND ($3=0 OR user_id=$3);")
 		if err != nil {
 			log.Printf("create prepare statement error: %v", err)
-			return 500, errors.New("Server error")
+			return Internal(err)
 		}
+		defer stmt.Close()
 
-		res, err := stmt.Exec(time.Now(), c.ID, c.UserID)
+		res, err := stmt.Exec(time.Now(), c.ID, ownerID)
 		if err != nil {
 			log.Printf("exec statement error: %v", err)
-			return 500, errors.New("Server error")
+			return Internal(err)
 		}
 
 		affected, err := res.RowsAffected()
 		if err != nil {
 			log.Printf("rows effected error: %v", err)
-			return 500, errors.New("Server error")
+			return Internal(err)
 		}
 		if affected == 0 {
 			log.Printf("rows effected -> %v", affected)
-			return 500, errors.New("Server error")
+			return NotFound(CodeChallengeNotFound, "Challenge not found")
 		}
-	} else {
-		err = fmt.Errorf("multiple challenges found-> id %v, total found %v", c.ID, count)
-		log.Printf("%v", err)
-		return 409, errors.New("Multiple challenges detected")
-	}
 
-	return 0, nil
+		return nil
+	})
 }
 
-//AdminDelete func deletes the post record of the user. Delete meaning it doesnt purge it. Just hides it.
-func (c *Challenge) AdminDelete() (int, error) {
-	count, err := c.Count("WHERE id=$1 AND deleted_at IS NULL", c.ID)
-	if err != nil {
-		log.Printf("challenge delete: error on fetching Post record count: %v", err)
-		return 500, errors.New("Server error")
-	}
+//Delete func deletes the challenge of its owner. Delete meaning it doesnt purge it. Just hides it.
+func (r *pgChallengeRepository) Delete(c *Challenge) error {
+	return r.delete(c, c.UserID, nil)
+}
 
-	if count == 0 {
-		err = fmt.Errorf("challenge not found-> id %v, total found %v", c.ID, count)
-		log.Printf("%v", err)
-		return 404, errors.New("challenge not found")
-	} else if count == 1 {
-		stmt, err := db.Prepare("UPDATE challenges SET deleted_at=$1 WHERE id=$2;")
-		if err != nil {
-			log.Printf("create prepare statement error: %v", err)
-			return 500, errors.New("Server error")
-		}
+//AdminDelete func deletes any challenge, recording the audit event. Delete meaning it doesnt purge it. Just hides it.
+func (r *pgChallengeRepository) AdminDelete(c *Challenge, e *AuditEvent) error {
+	return r.delete(c, 0, e)
+}
 
-		res, err := stmt.Exec(time.Now(), c.ID)
+//Advance func moves the scheduled challenges whose start has come to active and the active ones whose end has passed to closed.
+//It returns the number of moved challenges.
+func (r *pgChallengeRepository) Advance(now time.Time) (int64, error) {
+	var moved int64
+	for _, stmt := range challengeAdvances {
+		res, err := r.db.Exec(stmt, now)
 		if err != nil {
-			log.Printf("exec statement error: %v", err)
-			return 500, errors.New("Server error")
+			log.Printf("Advance challenges: sql error %v", err)
+			return moved, err
 		}
 
 		affected, err := res.RowsAffected()
 		if err != nil {
 			log.Printf("rows effected error: %v", err)
-			return 500, errors.New("Server error")
-		}
-		if affected == 0 {
-			log.Printf("rows effected -> %v", affected)
-			return 404, errors.New("challenge not found")
+			return moved, err
 		}
-	} else {
-		err = fmt.Errorf("multiple challenges found-> id %v, total found %v", c.ID, count)
-		log.Printf("%v", err)
-		return 409, errors.New("Multiple challenges detected")
+		moved = moved + affected
+	}
+
+	return moved, nil
+}
+
+//CloseIdle func closes the active challenges which got no post since the given time, unless they were created after it.
+//It returns the number of closed challenges.
+func (r *pgChallengeRepository) CloseIdle(since time.Time) (int64, error) {
+	res, err := r.db.Exec(`UPDATE challenges SET status='closed', updated_at=NOW() WHERE status='active' AND deleted_at IS NULL AND created_at<$1
+	AND NOT EXISTS (SELECT 1 FROM posts WHERE posts.challenge_id=challenges.id AND posts.deleted_at IS NULL AND posts.created_at>=$1);`, since)
+	if err != nil {
+		log.Printf("Close idle challenges: sql error %v", err)
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+//Purge func removes the challenges deleted before the given time for good, with their challenge requests.
+//Challenges which still hold posts are kept. It returns the number of purged challenges.
+func (r *pgChallengeRepository) Purge(before time.Time) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Purge challenges: begin error: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	purgeable := "SELECT id FROM challenges WHERE deleted_at<$1 AND NOT EXISTS (SELECT 1 FROM posts WHERE posts.challenge_id=challenges.id)"
+	if _, err = tx.Exec("DELETE FROM challenge_requests WHERE challenge_id IN ("+purgeable+");", before); err != nil {
+		log.Printf("Purge challenges: requests error: %v", err)
+		return 0, err
+	}
+
+	res, err := tx.Exec("DELETE FROM challenges WHERE id IN ("+purgeable+");", before)
+	if err != nil {
+		log.Printf("Purge challenges: sql error %v", err)
+		return 0, err
+	}
+
+	purged, err := res.RowsAffected()
+	if err != nil {
+		log.Printf("rows effected error: %v", err)
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Purge challenges: commit error: %v", err)
+		return 0, err
 	}
 
-	return 0, nil
+	return purged, nil
 }
@@ -1,10 +1,9 @@
 package model
 
 import (
+	"database/sql"
 	"encoding/json"
-	"errors"
 	"log"
-	"net/http"
 	"time"
 )
 
@@ -13,220 +12,158 @@ type ChallengeRequest struct {
 	ID          int64      `json:"id" sql:"id"`
 	FromID      int64      `json:"from_id,omitempty" sql:"from_id"`
 	From        *User      `json:"from" sql:"-"`
-	ToID        int64      `json:"to_id,omitempty" sql:"to_id"`
+	ToID        int64      `json:"to_id,omitempty" sql:"to_id" bind:"create"`
 	To          *User      `json:"to" sql:"-"`
-	ChallengeID int64      `json:"challenge_id,omitempty" sql:"challenge_id"`
+	ChallengeID int64      `json:"challenge_id,omitempty" sql:"challenge_id" bind:"create"`
 	Challenge   *Challenge `json:"challenge" sql:"-"`
-	Message     string     `json:"message" sql:"message"`
+	Message     string     `json:"message" sql:"message" bind:"create"`
 	Status      string     `json:"status" sql:"status"` //rejected, accepted and completed
 	CreatedAt   time.Time  `json:"created_at" sql:"created_at"`
-
-	Payload map[string]interface{} `json:"-"`
-}
-
-//ParseNotAllowedJSON unmarshalls JSON payload to struct payload and fields. Plus parses the JSON payload.
-func (c *ChallengeRequest) ParseNotAllowedJSON() []string {
-	errSlice := []string{}
-
-	delete(c.Payload, "to_id")
-	delete(c.Payload, "message")
-	delete(c.Payload, "challenge_id")
-	delete(c.Payload, "status")
-
-	for key := range c.Payload {
-		errSlice = append(errSlice, key)
-	}
-
-	return errSlice
 }
 
-//PostValidate func validates incoming post payload fields
-func (c *ChallengeRequest) PostValidate() []string {
+//Validate func validates incoming post payload fields. The sender is taken from the token.
+func (c *ChallengeRequest) Validate(op string) []string {
 	errSlice := []string{}
 
-	if c.FromID <= 0 {
-		errSlice = append(errSlice, "from_id")
-	}
-
 	if c.ToID <= 0 {
 		errSlice = append(errSlice, "to_id")
 	}
 
 	if c.ChallengeID <= 0 {
-		errSlice = append(errSlice, "cahllenge_id")
+		errSlice = append(errSlice, "challenge_id")
 	}
 
 	return errSlice
 }
 
-//Count func counts the users from db
-func (c *ChallengeRequest) Count(whereClause string, args ...interface{}) (int64, error) {
-	var count int64
+//pgChallengeRequestRepository struct is the postgres implementation of ChallengeRequestRepository
+type pgChallengeRequestRepository struct {
+	db *sql.DB
+}
+
+//challengeRequestSorts is the whitelist of the orderings of the challenge requests
+var challengeRequestSorts = SortKeys{
+	"newest": "created_at DESC",
+}
+
+//Get func fetches the challenge requests from the db
+func (r *pgChallengeRequestRepository) Get(filter ChallengeRequestFilter) ([]*ChallengeRequest, error) {
+	q := NewQuery()
 
-	if err := db.QueryRow("SELECT COUNT(id) FROM challenge_requests "+whereClause+";", args...).Scan(&count); err != nil {
-		log.Printf("Count levels: sql error %v", err)
-		return count, err
+	if filter.FromID > 0 {
+		q.Where(Eq("from_id", filter.FromID))
 	}
 
-	return count, nil
-}
+	if filter.ToID > 0 {
+		q.Where(Eq("to_id", filter.ToID))
+	}
+
+	whereClause, args := q.Sort(challengeRequestSorts, "newest", "newest").Page(filter.LastID, filter.Limit).Build()
 
-//Get func fetches the vanity items from the db
-func (c *ChallengeRequest) Get(whereClause string, args ...interface{}) ([]*ChallengeRequest, error) {
-	ChallengeRequestList := []*ChallengeRequest{}
-	rows, err := db.Query(`SELECT id, (SELECT row_to_json(users) FROM users WHERE users.id=challenge_requests.to_id) as to, 
-	(SELECT row_to_json(users) FROM users WHERE users.id=challenge_requests.from_id) as from, 
-	(SELECT row_to_json(challenges) FROM challenges WHERE challenges.id=challenge_requests.from_challenge_id) as challenge, message, created_at FROM bought_items `+whereClause+" ORDER BY created_at DESC;", args...)
+	challengeRequestList := []*ChallengeRequest{}
+	rows, err := r.db.Query(`SELECT id, to_id, from_id, challenge_id, COALESCE((SELECT row_to_json(users) FROM users WHERE users.id=challenge_requests.to_id), 'null') as to_user, 
+	COALESCE((SELECT row_to_json(users) FROM users WHERE users.id=challenge_requests.from_id), 'null') as from_user, 
+	COALESCE((SELECT row_to_json(challenges) FROM challenges WHERE challenges.id=challenge_requests.challenge_id), 'null') as challenge, message, status, created_at FROM challenge_requests `+whereClause+";", args...)
 	if err != nil {
-		log.Printf("Get users: sql error %v", err)
+		log.Printf("Get challenge requests: sql error %v", err)
 		return nil, err
 	}
+	defer rows.Close()
+
 	for rows.Next() {
-		ChallengeRequest := ChallengeRequest{}
+		challengeRequest := ChallengeRequest{}
 		toStr := ""
 		fromStr := ""
 		challengeStr := ""
-		if err = rows.Scan(&ChallengeRequest.ID, &toStr, &fromStr, &challengeStr, &ChallengeRequest.Message, &ChallengeRequest.CreatedAt); err != nil {
+		if err = rows.Scan(&challengeRequest.ID, &challengeRequest.ToID, &challengeRequest.FromID, &challengeRequest.ChallengeID, &toStr, &fromStr, &challengeStr, &challengeRequest.Message, &challengeRequest.Status, &challengeRequest.CreatedAt); err != nil {
 			log.Printf("scanning row to struct error: %v", err)
 			return nil, err
 		}
 
-		if err = json.Unmarshal([]byte(toStr), &ChallengeRequest.To); err != nil {
+		if err = json.Unmarshal([]byte(toStr), &challengeRequest.To); err != nil {
 			log.Printf("Unmarshaling of subquery error: %v", err)
 			return nil, err
 		}
 
-		if err = json.Unmarshal([]byte(fromStr), &ChallengeRequest.From); err != nil {
+		if err = json.Unmarshal([]byte(fromStr), &challengeRequest.From); err != nil {
 			log.Printf("Unmarshaling of subquery error: %v", err)
 			return nil, err
 		}
 
-		if err = json.Unmarshal([]byte(challengeStr), &ChallengeRequest.Challenge); err != nil {
+		if err = json.Unmarshal([]byte(challengeStr), &challengeRequest.Challenge); err != nil {
 			log.Printf("Unmarshaling of subquery error: %v", err)
 			return nil, err
 		}
 
-		ChallengeRequestList = append(ChallengeRequestList, &ChallengeRequest)
+		challengeRequestList = append(challengeRequestList, &challengeRequest)
 	}
-	return ChallengeRequestList, nil
+	return challengeRequestList, nil
 }
 
-//Create func adds an item to the table
-func (c *ChallengeRequest) Create() error {
-	now := time.Now()
-	c.CreatedAt = now
+//Create func adds a challenge request to the table
+func (r *pgChallengeRequestRepository) Create(c *ChallengeRequest) error {
+	c.CreatedAt = time.Now()
 
-	stmt, err := db.Prepare("INSERT INTO bought_items (to_id, from_id, challenge_id, message, created_at) VALUES($1,$2,$3,$4,$5);")
+	err := r.db.QueryRow("INSERT INTO challenge_requests (to_id, from_id, challenge_id, message, status, created_at) VALUES($1,$2,$3,$4,$5,$6) RETURNING id;",
+		c.ToID, c.FromID, c.ChallengeID, c.Message, c.Status, c.CreatedAt).Scan(&c.ID)
 	if err != nil {
-		log.Printf("create prepare statement error: %v", err)
-		return err
-	}
-
-	res, err := stmt.Exec(c.ToID, c.FromID, c.ChallengeID, c.Message, c.CreatedAt)
-	if err != nil {
-		log.Printf("exec statement error: %v", err)
-		return err
-	}
-
-	c.ID, err = res.LastInsertId()
-	if err != nil {
-		log.Printf("last insert id error: %v", err)
+		log.Printf("Create challenge request: insert error: %v", err)
 		return err
 	}
 
 	log.Printf("ChallengeRequest successfully created with id %v", c.ID)
 
-	affected, err := res.RowsAffected()
-	if err != nil {
-		log.Printf("rows effected error: %v", err)
-		return err
-	}
-	if affected == 0 {
-		log.Printf("rows effected -> %v", affected)
-		return errors.New("Server error")
-	}
-
 	return nil
 }
 
-//UpdateStatus func updates the status
-func (c *ChallengeRequest) UpdateStatus(status string) (int, error) {
-	count, err := c.Count("WHERE id=$1 AND to_id=$2 AND status='open'", c.ID, c.ToID)
-	if err != nil {
-		log.Printf("count challenge request err: %v", err)
-		return http.StatusInternalServerError, errors.New("Sever error")
-	}
-
-	if count != 1 {
-		log.Printf("challenge rewuest count: %v, must be 1", count)
-		return http.StatusNotFound, errors.New("challenge request not found")
-	}
-
-	stmt, err := db.Prepare("UPDATE challenge_requests SET status=$1 WHERE id=$2 AND to_id=$3 AND status='open';")
+//UpdateStatus func updates the status of an open challenge request sent to the user
+func (r *pgChallengeRequestRepository) UpdateStatus(c *ChallengeRequest, status string) error {
+	stmt, err := r.db.Prepare("UPDATE challenge_requests SET status=$1 WHERE id=$2 AND to_id=$3 AND status='open' RETURNING challenge_id;")
 	if err != nil {
 		log.Printf("create prepare statement error: %v", err)
-		return http.StatusInternalServerError, errors.New("Sever error")
+		return Internal(err)
 	}
+	defer stmt.Close()
 
-	res, err := stmt.Exec(status, c.ID, c.ToID)
-	if err != nil {
-		log.Printf("exec statement error: %v", err)
-		return http.StatusInternalServerError, errors.New("Sever error")
+	err = stmt.QueryRow(status, c.ID, c.ToID).Scan(&c.ChallengeID)
+	if err == sql.ErrNoRows {
+		log.Printf("challenge request not found -> id %v, to_id %v", c.ID, c.ToID)
+		return NotFound(CodeChallengeRequestNotFound, "Challenge request not found")
 	}
-
-	affected, err := res.RowsAffected()
 	if err != nil {
-		log.Printf("rows effected error: %v", err)
-		return http.StatusInternalServerError, errors.New("Sever error")
-	}
-	if affected == 0 {
-		log.Printf("rows effected -> %v", affected)
-		return http.StatusNotFound, errors.New("challenge request Not found")
+		log.Printf("exec statement error: %v", err)
+		return Internal(err)
 	}
 
-	err = db.QueryRow("SELECT challenge_id FROM challenge_requests WHERE id=$1;", c.ID).Scan(c.ChallengeID)
-	if err != nil {
-		log.Printf("seelct challenge_id form challenge_requests err -> %v", err)
-		return http.StatusInternalServerError, errors.New("Server error")
-	}
+	c.Status = status
 
-	return 0, nil
+	return nil
 }
 
-//Delete func updates the status
-func (c *ChallengeRequest) Delete() (int, error) {
-	count, err := c.Count("WHERE id=$1 AND from_id=$2 AND status='open'", c.ID, c.FromID)
-	if err != nil {
-		log.Printf("count challenge request err: %v", err)
-		return http.StatusInternalServerError, errors.New("Sever error")
-	}
-
-	if count != 1 {
-		log.Printf("challenge rewuest count: %v, must be 1", count)
-		return http.StatusNotFound, errors.New("challenge request not found")
-	}
-
-	stmt, err := db.Prepare("DELETE FROM challenge_requests WHERE id=$1 AND from_id=$2 AND status='open';")
+//Delete func deletes an open challenge request sent by the user
+func (r *pgChallengeRequestRepository) Delete(c *ChallengeRequest) error {
+	stmt, err := r.db.Prepare("DELETE FROM challenge_requests WHERE id=$1 AND from_id=$2 AND status='open';")
 	if err != nil {
 		log.Printf("create prepare statement error: %v", err)
-		return http.StatusInternalServerError, errors.New("Sever error")
+		return Internal(err)
 	}
+	defer stmt.Close()
 
 	res, err := stmt.Exec(c.ID, c.FromID)
 	if err != nil {
 		log.Printf("exec statement error: %v", err)
-		return http.StatusInternalServerError, errors.New("Sever error")
+		return Internal(err)
 	}
 
 	affected, err := res.RowsAffected()
 	if err != nil {
 		log.Printf("rows effected error: %v", err)
-		return http.StatusInternalServerError, errors.New("Sever error")
+		return Internal(err)
 	}
 	if affected == 0 {
 		log.Printf("rows effected -> %v", affected)
-		return http.StatusNotFound, errors.New("challenge request Not found")
+		return NotFound(CodeChallengeRequestNotFound, "Challenge request not found")
 	}
 
-	return 0, nil
+	return nil
 }
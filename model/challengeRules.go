@@ -0,0 +1,145 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//ChallengeRules struct holds the rules the posts of a challenge must follow. Zero values do not restrict: any media type,
+//size and duration, no location, any number of entries per user and entries during the whole challenge.
+type ChallengeRules struct {
+	MediaTypes      []string   `json:"media_types"`
+	MaxSize         int64      `json:"max_size"`
+	MaxDuration     float64    `json:"max_duration"`
+	Radius          int        `json:"radius"`
+	OneEntryPerUser bool       `json:"one_entry_per_user"`
+	EntryOpensAt    *time.Time `json:"entry_opens_at"`
+	EntryClosesAt   *time.Time `json:"entry_closes_at"`
+}
+
+//limits of the rules
+const (
+	maxRuleMediaTypes = 20
+	maxRuleRadius     = 1000000
+)
+
+//mediaTypeRegexp matches a media type like image/png or a wildcard like video/*
+var mediaTypeRegexp = regexp.MustCompile(`^[a-z]+/([a-z0-9][a-z0-9.+-]*|\*)$`)
+
+//Value func stores the rules in their jsonb column
+func (r ChallengeRules) Value() (driver.Value, error) {
+	if r.MediaTypes == nil {
+		r.MediaTypes = []string{}
+	}
+	return json.Marshal(r)
+}
+
+//Scan func reads the rules from their jsonb column
+func (r *ChallengeRules) Scan(src interface{}) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return errors.New("rules are not jsonb")
+	}
+	*r = ChallengeRules{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return err
+	}
+	if r.MediaTypes == nil {
+		r.MediaTypes = []string{}
+	}
+	return nil
+}
+
+//validate func returns the names of the invalid rules. The media types are lower cased.
+func (r *ChallengeRules) validate() []string {
+	errSlice := []string{}
+
+	if len(r.MediaTypes) > maxRuleMediaTypes {
+		errSlice = append(errSlice, "rules.media_types")
+	} else {
+		for i, mediaType := range r.MediaTypes {
+			r.MediaTypes[i] = strings.ToLower(strings.TrimSpace(mediaType))
+			if !mediaTypeRegexp.MatchString(r.MediaTypes[i]) {
+				errSlice = append(errSlice, "rules.media_types")
+				break
+			}
+		}
+	}
+
+	if r.MaxSize < 0 {
+		errSlice = append(errSlice, "rules.max_size")
+	}
+
+	if r.MaxDuration < 0 {
+		errSlice = append(errSlice, "rules.max_duration")
+	}
+
+	if r.Radius < 0 || r.Radius > maxRuleRadius {
+		errSlice = append(errSlice, "rules.radius")
+	}
+
+	if r.EntryOpensAt != nil && r.EntryClosesAt != nil && !r.EntryOpensAt.Before(*r.EntryClosesAt) {
+		errSlice = append(errSlice, "rules.entry_window")
+	}
+
+	return errSlice
+}
+
+//allowsMediaType func checks the content type against the media types, a type/* rule allows every subtype
+func (r *ChallengeRules) allowsMediaType(contentType string) bool {
+	if len(r.MediaTypes) == 0 {
+		return true
+	}
+
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	for _, mediaType := range r.MediaTypes {
+		if mediaType == contentType || (strings.HasSuffix(mediaType, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(mediaType, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+//violations func returns the names of the rules the post breaks at now. The store tells if the user already entered the
+//challenge and how far the location of the post is from the challenge, nil when the post has no location.
+func (r *ChallengeRules) violations(p *Post, now time.Time, entered bool, distance *float64) []string {
+	errSlice := []string{}
+
+	if !r.allowsMediaType(p.ContentType) {
+		errSlice = append(errSlice, "media_types")
+	}
+
+	if r.MaxSize > 0 && p.ContentSize > r.MaxSize {
+		errSlice = append(errSlice, "max_size")
+	}
+
+	if r.MaxDuration > 0 && strings.HasPrefix(strings.ToLower(p.ContentType), "video/") && (p.Duration == nil || *p.Duration > r.MaxDuration) {
+		errSlice = append(errSlice, "max_duration")
+	}
+
+	if r.Radius > 0 && (distance == nil || *distance > float64(r.Radius)) {
+		errSlice = append(errSlice, "radius")
+	}
+
+	if r.OneEntryPerUser && entered {
+		errSlice = append(errSlice, "one_entry_per_user")
+	}
+
+	if (r.EntryOpensAt != nil && now.Before(*r.EntryOpensAt)) || (r.EntryClosesAt != nil && !now.Before(*r.EntryClosesAt)) {
+		errSlice = append(errSlice, "entry_window")
+	}
+
+	return errSlice
+}
+
+//ruleViolation func reports the broken rules of a post as a validation error, nil when there are none
+func ruleViolation(rules []string) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	return Validation(CodeRuleViolation, "Post breaks the challenge rules", rules...)
+}
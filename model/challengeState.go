@@ -0,0 +1,148 @@
+package model
+
+import (
+	"time"
+)
+
+//states of a challenge. Only active challenges take posts, closed and archived ones never reopen.
+const (
+	ChallengeDraft     = "draft"
+	ChallengeScheduled = "scheduled"
+	ChallengeActive    = "active"
+	ChallengeClosed    = "closed"
+	ChallengeArchived  = "archived"
+)
+
+//challengeTransitions maps every state of a challenge to the states it may move to
+var challengeTransitions = map[string][]string{
+	ChallengeDraft:     {ChallengeScheduled, ChallengeActive, ChallengeArchived},
+	ChallengeScheduled: {ChallengeDraft, ChallengeActive, ChallengeClosed, ChallengeArchived},
+	ChallengeActive:    {ChallengeClosed, ChallengeArchived},
+	ChallengeClosed:    {ChallengeArchived},
+	ChallengeArchived:  {},
+}
+
+//IsChallengeState func checks if s is a state of a challenge
+func IsChallengeState(s string) bool {
+	_, ok := challengeTransitions[s]
+	return ok
+}
+
+//CanTransition func checks if a challenge may move from one state to the other
+func CanTransition(from, to string) bool {
+	for _, next := range challengeTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+//initState func checks the state of a new challenge at now. Without one it is scheduled when it starts in the future, active otherwise.
+func (c *Challenge) initState(now time.Time) error {
+	if c.Status == "" {
+		c.Status = ChallengeActive
+		if c.StartsAt != nil && c.StartsAt.After(now) {
+			c.Status = ChallengeScheduled
+		}
+	}
+
+	switch c.Status {
+	case ChallengeDraft:
+	case ChallengeScheduled:
+		if c.StartsAt == nil || !c.StartsAt.After(now) {
+			return Validation(CodeInvalidFields, "Scheduled challenges need a starts_at in the future", "starts_at")
+		}
+	case ChallengeActive:
+		if c.StartsAt != nil && c.StartsAt.After(now) {
+			return Validation(CodeInvalidFields, "Active challenges can not start in the future", "starts_at")
+		}
+	default:
+		return Conflict(CodeInvalidTransition, "Challenges start as draft, scheduled or active")
+	}
+
+	return checkDates(c.StartsAt, c.EndsAt)
+}
+
+//checkChange func validates the change next of the challenge c, which is the current row, at now
+func (c *Challenge) checkChange(next *Challenge, now time.Time) error {
+	if c.Status == ChallengeArchived {
+		return Conflict(CodeInvalidTransition, "Archived challenges can not be changed")
+	}
+
+	if next.StartsAt != nil && c.Status != ChallengeDraft && c.Status != ChallengeScheduled {
+		return Conflict(CodeInvalidTransition, "starts_at can not be changed once the challenge started")
+	}
+	if next.EndsAt != nil && c.Status == ChallengeClosed {
+		return Conflict(CodeInvalidTransition, "Closed challenges can not be reopened")
+	}
+
+	startsAt, endsAt := c.StartsAt, c.EndsAt
+	if next.StartsAt != nil {
+		startsAt = next.StartsAt
+	}
+	if next.EndsAt != nil {
+		endsAt = next.EndsAt
+	}
+
+	if next.Status != "" && next.Status != c.Status {
+		if !CanTransition(c.Status, next.Status) {
+			return Conflict(CodeInvalidTransition, "Challenge can not move from "+c.Status+" to "+next.Status)
+		}
+
+		switch next.Status {
+		case ChallengeScheduled:
+			if startsAt == nil || !startsAt.After(now) {
+				return Validation(CodeInvalidFields, "Scheduled challenges need a starts_at in the future", "starts_at")
+			}
+		case ChallengeActive:
+			if startsAt != nil && startsAt.After(now) {
+				return Validation(CodeInvalidFields, "Active challenges can not start in the future", "starts_at")
+			}
+			if endsAt != nil && !endsAt.After(now) {
+				return Validation(CodeInvalidFields, "Active challenges can not end in the past", "ends_at")
+			}
+		}
+	}
+
+	return checkDates(startsAt, endsAt)
+}
+
+//checkDates func checks that a challenge ends after it starts
+func checkDates(startsAt, endsAt *time.Time) error {
+	if startsAt != nil && endsAt != nil && !endsAt.After(*startsAt) {
+		return Validation(CodeInvalidFields, "ends_at must be after starts_at", "ends_at")
+	}
+	return nil
+}
+
+//acceptsPosts func checks if posts can be made to the challenge at now: it is active and now is within its dates
+func (c *Challenge) acceptsPosts(now time.Time) error {
+	if c.Status != ChallengeActive || (c.StartsAt != nil && now.Before(*c.StartsAt)) || (c.EndsAt != nil && !now.Before(*c.EndsAt)) {
+		return Conflict(CodeChallengeNotActive, "Challenge is not active")
+	}
+	return nil
+}
+
+//postLikesNeeded func returns the likes a post of the challenge needs to complete, at least one. The challenge sets it,
+//never the poster, since completing pays a reward.
+func (c *Challenge) postLikesNeeded() int {
+	if c.LikesNeededPerPost < 1 {
+		return 1
+	}
+	return c.LikesNeededPerPost
+}
+
+//acceptsInvites func checks if users can join the challenge at now through an invite: it is scheduled or active and has not ended
+func (c *Challenge) acceptsInvites(now time.Time) error {
+	if (c.Status != ChallengeScheduled && c.Status != ChallengeActive) || (c.EndsAt != nil && !now.Before(*c.EndsAt)) {
+		return Conflict(CodeChallengeNotActive, "Challenge is not open")
+	}
+	return nil
+}
+
+//challengeAdvances move the scheduled challenges whose start has come to active and then the active ones whose end has passed to closed. $1 is now.
+var challengeAdvances = []string{
+	"UPDATE challenges SET status='active', updated_at=$1 WHERE status='scheduled' AND starts_at<=$1 AND deleted_at IS NULL;",
+	"UPDATE challenges SET status='closed', updated_at=$1 WHERE status='active' AND ends_at<=$1 AND deleted_at IS NULL;",
+}
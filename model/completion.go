@@ -0,0 +1,76 @@
+package model
+
+import (
+	"database/sql"
+	"log"
+	"math"
+	"time"
+
+	"github.com/challengr/config"
+)
+
+//Completion struct is a model/schema for challenge_completions table. It marks the user as a participant who completed
+//the challenge and is the event the clients poll for: the post which got its likes_needed and the reward it earned.
+//PostID is zero once the post is purged.
+type Completion struct {
+	ID          int64     `json:"id" sql:"id"`
+	ChallengeID int64     `json:"challenge_id" sql:"challenge_id"`
+	UserID      int64     `json:"user_id" sql:"user_id"`
+	PostID      int64     `json:"post_id,omitempty" sql:"post_id"`
+	Exp         int       `json:"exp" sql:"exp"`
+	Coins       int64     `json:"coins" sql:"coins"`
+	CreatedAt   time.Time `json:"created_at" sql:"created_at"`
+}
+
+//completionReward func scales the configured reward by the weights of the challenge and of the user, negative weights
+//earn nothing
+func completionReward(rewards config.Rewards, challengeWeight, userWeight float64) Completion {
+	scale := math.Max(0, challengeWeight*userWeight)
+	return Completion{
+		Exp:   int(math.Round(float64(rewards.Exp) * scale)),
+		Coins: int64(math.Round(float64(rewards.Coins) * scale)),
+	}
+}
+
+//pgCompletionRepository struct is the postgres implementation of CompletionRepository
+type pgCompletionRepository struct {
+	db *sql.DB
+}
+
+//query func translates the filter into a typed query, oldest events first
+func (r *pgCompletionRepository) query(filter CompletionFilter) *Query {
+	q := NewQuery()
+
+	if filter.UserID > 0 {
+		q.Where(Eq("user_id", filter.UserID))
+	}
+
+	if filter.ChallengeID > 0 {
+		q.Where(Eq("challenge_id", filter.ChallengeID))
+	}
+
+	return q.Sort(SortKeys{"oldest": "id"}, "oldest", "oldest").Page(filter.AfterID, filter.Limit)
+}
+
+//Get func fetches the completion events passing the filter, oldest first
+func (r *pgCompletionRepository) Get(filter CompletionFilter) ([]*Completion, error) {
+	clause, args := r.query(filter).Build()
+
+	completionList := []*Completion{}
+	rows, err := r.db.Query("SELECT id, challenge_id, user_id, COALESCE(post_id, 0), exp, coins, created_at FROM challenge_completions "+clause+";", args...)
+	if err != nil {
+		log.Printf("Get completions: sql error %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		completion := Completion{}
+		if err = rows.Scan(&completion.ID, &completion.ChallengeID, &completion.UserID, &completion.PostID, &completion.Exp, &completion.Coins, &completion.CreatedAt); err != nil {
+			log.Printf("scanning row to struct error: %v", err)
+			return nil, err
+		}
+		completionList = append(completionList, &completion)
+	}
+	return completionList, nil
+}
@@ -1,7 +1,8 @@
 package model
 
-//ErrResp struct is used for send http error message
+//ErrResp struct is used for send http error message. Code is a stable machine readable error code.
 type ErrResp struct {
 	Error  interface{} `json:"error"`
+	Code   string      `json:"code,omitempty"`
 	Fields *[]string   `json:"fields,omitempty"`
 }
@@ -0,0 +1,174 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+//Kind type classifies the domain errors. Each kind maps onto one http status.
+type Kind string
+
+const (
+	//KindNotFound is used when the record does not exist or is hidden from the caller
+	KindNotFound Kind = "not_found"
+	//KindConflict is used when the record clashes with an existing one
+	KindConflict Kind = "conflict"
+	//KindForbidden is used when the caller is not allowed to do the action
+	KindForbidden Kind = "forbidden"
+	//KindValidation is used when the input is invalid. Fields lists the offending fields.
+	KindValidation Kind = "validation"
+	//KindInternal is used for everything else. Its cause is logged, never sent.
+	KindInternal Kind = "internal"
+)
+
+//Stable machine readable error codes which are sent to the clients. Never change the value of an existing code.
+const (
+	CodeNotFound                 = "not_found"
+	CodeUserNotFound             = "user_not_found"
+	CodeChallengeNotFound        = "challenge_not_found"
+	CodePostNotFound             = "post_not_found"
+	CodeScoreNotFound            = "score_not_found"
+	CodeChallengeRequestNotFound = "challenge_request_not_found"
+	CodeCategoryNotFound         = "category_not_found"
+	CodeInviteNotFound           = "invite_not_found"
+
+	CodeConflict           = "conflict"
+	CodeMultipleFound      = "multiple_records_found"
+	CodeIdentityInUse      = "identity_in_use"
+	CodeInvalidTransition  = "invalid_transition"
+	CodeChallengeNotActive = "challenge_not_active"
+	CodeJobRunning         = "job_running"
+	CodeInviteExpired      = "invite_expired"
+	CodeInviteUsedUp       = "invite_used_up"
+	CodeInviteRedeemed     = "invite_redeemed"
+
+	CodeForbidden         = "forbidden"
+	CodeInvalidToken      = "invalid_token"
+	CodeTokenExpired      = "token_expired"
+	CodeTokenRevoked      = "token_revoked"
+	CodeTokenReused       = "token_reused"
+	CodeInvalidCredential = "invalid_credential"
+	CodeLastIdentity      = "last_identity"
+	CodeNotAllowed        = "not_allowed"
+	CodeLevelTooLow       = "level_too_low"
+
+	CodeInvalidPayload     = "invalid_payload"
+	CodeFieldsNotAllowed   = "fields_not_allowed"
+	CodeInvalidFields      = "invalid_fields"
+	CodeInvalidPathParam   = "invalid_path_param"
+	CodeInvalidQueryString = "invalid_query_string"
+	CodeRuleViolation      = "rule_violation"
+
+	CodeRateLimited = "rate_limited"
+
+	CodeInternal = "internal"
+)
+
+//Error struct is the typed error of the domain. The model layer returns it and the error middleware renders it.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []string
+
+	//Err is the cause of an internal error
+	Err error
+}
+
+//Error func implements the error interface
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+//Unwrap func returns the cause of the error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+//Status func returns the http status of the error kind
+func (e *Error) Status() int {
+	switch e.Kind {
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindValidation:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+//Resp func returns the body which is sent to the client
+func (e *Error) Resp() *ErrResp {
+	resp := ErrResp{Error: e.Message, Code: e.Code}
+	if len(e.Fields) > 0 {
+		fields := e.Fields
+		resp.Fields = &fields
+	}
+	return &resp
+}
+
+//NotFound func creates a not found error
+func NotFound(code, message string) error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+//Conflict func creates a conflict error
+func Conflict(code, message string) error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+//Forbidden func creates a forbidden error
+func Forbidden(code, message string) error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+//Validation func creates a validation error for the given fields
+func Validation(code, message string, fields ...string) error {
+	return &Error{Kind: KindValidation, Code: code, Message: message, Fields: fields}
+}
+
+//Internal func wraps an unexpected error. The client only sees "Server error".
+func Internal(err error) error {
+	return &Error{Kind: KindInternal, Code: CodeInternal, Message: "Server error", Err: err}
+}
+
+//AsError func returns the domain error of err. Errors of other types become internal errors.
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Internal(err).(*Error)
+}
+
+//dbError func translates the errors of the sql driver into domain errors
+func dbError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if err == sql.ErrNoRows {
+		return NotFound(CodeNotFound, "Not found")
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return Conflict(CodeConflict, "Record already exists")
+		case "foreign_key_violation":
+			return NotFound(CodeNotFound, "Referenced record not found")
+		}
+	}
+
+	return Internal(err)
+}
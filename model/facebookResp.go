@@ -1,8 +0,0 @@
-package model
-
-//FacebookResp struct is used for login/signup
-type FacebookResp struct {
-	ID    string `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
-}
@@ -1,7 +1,362 @@
 package model
 
-//geometry is struct for parsing geojson coordinates for postgis
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/lib/pq"
+)
+
+//GeoJSON types of the geometries
+const (
+	GeoPoint        = "Point"
+	GeoPolygon      = "Polygon"
+	GeoMultiPolygon = "MultiPolygon"
+)
+
+//maxGeometryPositions caps the positions of an area, the validation is quadratic in them
+const maxGeometryPositions = 2000
+
+//geometry is struct for parsing geojson geometries for postgis. A Point keeps its long/lat in Coordinates, a Polygon is the
+//single polygon of Polygons and a MultiPolygon all of them. Every polygon is its exterior ring followed by its holes.
 type geometry struct {
-	Type        string    `json:"type"`
-	Coordinates []float64 `json:"coordinates"`
+	Type        string
+	Coordinates []float64
+	Polygons    [][][][]float64
+}
+
+//geoJSON struct is the wire format of a geometry, the coordinates are decoded once the type is known
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+//MarshalJSON func writes the geometry as a GeoJSON object
+func (g geometry) MarshalJSON() ([]byte, error) {
+	var coordinates interface{} = g.Coordinates
+	switch g.Type {
+	case GeoPolygon:
+		coordinates = [][][]float64{}
+		if len(g.Polygons) > 0 {
+			coordinates = g.Polygons[0]
+		}
+	case GeoMultiPolygon:
+		coordinates = g.Polygons
+	}
+
+	return json.Marshal(struct {
+		Type        string      `json:"type"`
+		Coordinates interface{} `json:"coordinates"`
+	}{g.Type, coordinates})
+}
+
+//UnmarshalJSON func reads a GeoJSON Point, Polygon or MultiPolygon. The shape of the coordinates must fit the type, their
+//values are checked by valid.
+func (g *geometry) UnmarshalJSON(b []byte) error {
+	raw := geoJSON{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if len(raw.Coordinates) == 0 || string(raw.Coordinates) == "null" {
+		return errors.New("geometry without coordinates")
+	}
+
+	parsed := geometry{Type: raw.Type}
+	switch raw.Type {
+	case GeoPoint:
+		if err := json.Unmarshal(raw.Coordinates, &parsed.Coordinates); err != nil {
+			return err
+		}
+	case GeoPolygon:
+		polygon := [][][]float64{}
+		if err := json.Unmarshal(raw.Coordinates, &polygon); err != nil {
+			return err
+		}
+		parsed.Polygons = [][][][]float64{polygon}
+	case GeoMultiPolygon:
+		if err := json.Unmarshal(raw.Coordinates, &parsed.Polygons); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported geometry type %q", raw.Type)
+	}
+
+	*g = parsed
+	return nil
+}
+
+//valid func checks the geometry against GeoJSON (RFC 7946) and the simple features rules postgis validates with: positions
+//in the long/lat ranges, closed rings of at least four positions which do not cross themselves nor each other, holes inside
+//their exterior ring and polygons of a MultiPolygon which do not overlap. Rings crossing the antimeridian are not supported.
+func (g *geometry) valid() bool {
+	switch g.Type {
+	case GeoPoint:
+		return validPosition(g.Coordinates)
+	case GeoPolygon, GeoMultiPolygon:
+		if len(g.Polygons) == 0 || (g.Type == GeoPolygon && len(g.Polygons) != 1) {
+			return false
+		}
+	default:
+		return false
+	}
+
+	positions := 0
+	for _, polygon := range g.Polygons {
+		for _, ring := range polygon {
+			positions = positions + len(ring)
+		}
+	}
+	if positions > maxGeometryPositions {
+		return false
+	}
+
+	for i, polygon := range g.Polygons {
+		if !validPolygon(polygon) {
+			return false
+		}
+		for _, other := range g.Polygons[:i] {
+			if ringsCross(polygon[0], other[0]) || pointInRing(polygon[0][0], other[0]) || pointInRing(other[0][0], polygon[0]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+//validPosition func checks a long/lat position with an optional altitude
+func validPosition(p []float64) bool {
+	if len(p) != 2 && len(p) != 3 {
+		return false
+	}
+	for _, v := range p {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return p[0] >= -180 && p[0] <= 180 && p[1] >= -90 && p[1] <= 90
+}
+
+//validRing func checks that the ring is closed, has an area and does not touch itself
+func validRing(ring [][]float64) bool {
+	if len(ring) < 4 {
+		return false
+	}
+	for _, p := range ring {
+		if !validPosition(p) {
+			return false
+		}
+	}
+	first, last := ring[0], ring[len(ring)-1]
+	if first[0] != last[0] || first[1] != last[1] {
+		return false
+	}
+
+	area := 0.0
+	for i := 0; i < len(ring)-1; i++ {
+		area = area + ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	if area == 0 {
+		return false
+	}
+
+	//segments i and j may only share the end of one and the start of the other when they follow each other
+	n := len(ring) - 1
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if j == i+1 || (i == 0 && j == n-1) {
+				continue
+			}
+			if segmentsIntersect(ring[i], ring[i+1], ring[j], ring[j+1], true) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+//validPolygon func checks the rings of a polygon and that its holes lie inside the exterior ring without crossing it or each other
+func validPolygon(polygon [][][]float64) bool {
+	if len(polygon) == 0 {
+		return false
+	}
+	for _, ring := range polygon {
+		if !validRing(ring) {
+			return false
+		}
+	}
+
+	exterior := polygon[0]
+	for i, hole := range polygon[1:] {
+		if ringsCross(hole, exterior) || !pointInRing(hole[0], exterior) {
+			return false
+		}
+		for _, other := range polygon[1 : i+1] {
+			if ringsCross(hole, other) || pointInRing(hole[0], other) || pointInRing(other[0], hole) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+//ringsCross func tells if an edge of a crosses an edge of b. Rings touching at a point do not cross.
+func ringsCross(a, b [][]float64) bool {
+	for i := 0; i < len(a)-1; i++ {
+		for j := 0; j < len(b)-1; j++ {
+			if segmentsIntersect(a[i], a[i+1], b[j], b[j+1], false) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//orientation func returns the sign of the turn p, q, r: 1 counterclockwise, -1 clockwise, 0 collinear
+func orientation(p, q, r []float64) int {
+	v := (q[0]-p[0])*(r[1]-p[1]) - (q[1]-p[1])*(r[0]-p[0])
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+//onSegment func tells if r, collinear with p and q, lies between them
+func onSegment(p, q, r []float64) bool {
+	return math.Min(p[0], q[0]) <= r[0] && r[0] <= math.Max(p[0], q[0]) && math.Min(p[1], q[1]) <= r[1] && r[1] <= math.Max(p[1], q[1])
+}
+
+//segmentsIntersect func tells if the segments p1-p2 and q1-q2 meet. With touching false only proper crossings count,
+//an end lying on the other segment does not.
+func segmentsIntersect(p1, p2, q1, q2 []float64, touching bool) bool {
+	o1, o2 := orientation(p1, p2, q1), orientation(p1, p2, q2)
+	o3, o4 := orientation(q1, q2, p1), orientation(q1, q2, p2)
+
+	if o1*o2 < 0 && o3*o4 < 0 {
+		return true
+	}
+	if !touching {
+		return false
+	}
+
+	return (o1 == 0 && onSegment(p1, p2, q1)) || (o2 == 0 && onSegment(p1, p2, q2)) ||
+		(o3 == 0 && onSegment(q1, q2, p1)) || (o4 == 0 && onSegment(q1, q2, p2))
+}
+
+//pointInRing func tells if the long/lat point lies inside the ring, by ray casting
+func pointInRing(p []float64, ring [][]float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > p[1]) != (b[1] > p[1]) && p[0] < (b[0]-a[0])*(p[1]-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+//pointInPolygon func tells if the point lies inside the exterior ring of the polygon and outside its holes
+func pointInPolygon(p []float64, polygon [][][]float64) bool {
+	if len(polygon) == 0 || !pointInRing(p, polygon[0]) {
+		return false
+	}
+	for _, hole := range polygon[1:] {
+		if pointInRing(p, hole) {
+			return false
+		}
+	}
+	return true
+}
+
+//distanceToSegment func returns the distance in meters from the point to the closest point of the segment a-b, found on a
+//plane around the point, which is close enough at the scale of a challenge area
+func distanceToSegment(p, a, b []float64) float64 {
+	scale := math.Cos(p[1] * math.Pi / 180)
+	ax, ay := (a[0]-p[0])*scale, a[1]-p[1]
+	bx, by := (b[0]-p[0])*scale, b[1]-p[1]
+
+	t := 0.0
+	if dx, dy := bx-ax, by-ay; dx != 0 || dy != 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/(dx*dx+dy*dy)))
+	}
+
+	return distanceSphere(p[0], p[1], a[0]+t*(b[0]-a[0]), a[1]+t*(b[1]-a[1]))
+}
+
+//distance func returns the distance in meters from the long/lat point to the geometry, 0 inside an area. It mirrors the
+//geography distance of the postgres store.
+func (g *geometry) distance(long, lat float64) float64 {
+	p := []float64{long, lat}
+	if g.Type == GeoPoint {
+		if len(g.Coordinates) < 2 {
+			return math.Inf(1)
+		}
+		return distanceSphere(g.Coordinates[0], g.Coordinates[1], long, lat)
+	}
+
+	min := math.Inf(1)
+	for _, polygon := range g.Polygons {
+		if pointInPolygon(p, polygon) {
+			return 0
+		}
+		for _, ring := range polygon {
+			for i := 0; i < len(ring)-1; i++ {
+				min = math.Min(min, distanceToSegment(p, ring[i], ring[i+1]))
+			}
+		}
+	}
+	return min
+}
+
+//intersects func tells if the geometry meets the box. A box crossing the antimeridian is checked as its two halves.
+func (g *geometry) intersects(box GeoBox) bool {
+	if box.MinLong > box.MaxLong {
+		east, west := box, box
+		east.MaxLong, west.MinLong = 180, -180
+		return g.intersects(east) || g.intersects(west)
+	}
+
+	inBox := func(p []float64) bool {
+		return len(p) >= 2 && p[0] >= box.MinLong && p[0] <= box.MaxLong && p[1] >= box.MinLat && p[1] <= box.MaxLat
+	}
+	if g.Type == GeoPoint {
+		return inBox(g.Coordinates)
+	}
+
+	corners := [][]float64{{box.MinLong, box.MinLat}, {box.MaxLong, box.MinLat}, {box.MaxLong, box.MaxLat}, {box.MinLong, box.MaxLat}, {box.MinLong, box.MinLat}}
+	for _, polygon := range g.Polygons {
+		if pointInPolygon(corners[0], polygon) {
+			return true
+		}
+		for _, ring := range polygon {
+			for i := 0; i < len(ring)-1; i++ {
+				if inBox(ring[i]) {
+					return true
+				}
+				for j := 0; j < 4; j++ {
+					if segmentsIntersect(ring[i], ring[i+1], corners[j], corners[j+1], true) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+//geometryError func turns the violation of the geometry check of the challenges, which postgis validity enforces, into a
+//validation error
+func geometryError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "check_violation" && pqErr.Constraint == "challenges_geometry_check" {
+		return Validation(CodeInvalidFields, "Invalid geo_coords", "geo_coords")
+	}
+	return err
 }
@@ -0,0 +1,245 @@
+package model
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+//Invite struct is a model/schema for invites table. The short code joins the users who redeem it to the challenge, on
+//behalf of the inviter. A nil ExpiresAt never expires, a nil MaxUses takes any number of redemptions. Link is the deep
+//link of the code, the service fills it in.
+type Invite struct {
+	ID             int64      `json:"id" sql:"id"`
+	Code           string     `json:"code" sql:"code"`
+	Link           string     `json:"link" sql:"-"`
+	ChallengeID    int64      `json:"challenge_id" sql:"challenge_id"`
+	InviterID      int64      `json:"inviter_id" sql:"inviter_id"`
+	ExpiresAt      *time.Time `json:"expires_at" sql:"expires_at" bind:"create"`
+	MaxUses        *int       `json:"max_uses" sql:"max_uses" bind:"create"`
+	Uses           int        `json:"uses" sql:"uses"`
+	Participants   int        `json:"participants" sql:"-"`
+	LastRedeemedAt *time.Time `json:"last_redeemed_at" sql:"-"`
+	CreatedAt      time.Time  `json:"created_at" sql:"created_at"`
+}
+
+//InviteRedemption struct is a model/schema for invite_redemptions table. ChallengeRequestID is the accepted challenge
+//request the redemption made, zero once it is purged.
+type InviteRedemption struct {
+	ID                 int64     `json:"id" sql:"id"`
+	InviteID           int64     `json:"invite_id" sql:"invite_id"`
+	UserID             int64     `json:"user_id" sql:"user_id"`
+	ChallengeRequestID int64     `json:"challenge_request_id,omitempty" sql:"challenge_request_id"`
+	CreatedAt          time.Time `json:"created_at" sql:"created_at"`
+}
+
+//inviteCodeAlphabet leaves out the characters which read alike, 0/O and 1/I/L
+const inviteCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+//limits of the invites
+const (
+	inviteCodeLength   = 8
+	inviteCodeAttempts = 5
+	maxInviteUses      = 1000000
+)
+
+//Validate func validates the incoming invite fields. The challenge and the inviter are taken from the path and the token.
+func (i *Invite) Validate(op string) []string {
+	errSlice := []string{}
+
+	if i.ExpiresAt != nil && !i.ExpiresAt.After(time.Now()) {
+		errSlice = append(errSlice, "expires_at")
+	}
+
+	if i.MaxUses != nil && (*i.MaxUses <= 0 || *i.MaxUses > maxInviteUses) {
+		errSlice = append(errSlice, "max_uses")
+	}
+
+	return errSlice
+}
+
+//redeemable func checks if the user can redeem the invite at now
+func (i *Invite) redeemable(userID int64, now time.Time) error {
+	if i.InviterID == userID {
+		return Conflict(CodeConflict, "Own invite can not be redeemed")
+	}
+	if i.ExpiresAt != nil && !now.Before(*i.ExpiresAt) {
+		return Conflict(CodeInviteExpired, "Invite expired")
+	}
+	if i.MaxUses != nil && i.Uses >= *i.MaxUses {
+		return Conflict(CodeInviteUsedUp, "Invite used up")
+	}
+	return nil
+}
+
+//newInviteCode func returns a random short code
+func newInviteCode() (string, error) {
+	b := make([]byte, inviteCodeLength)
+	max := big.NewInt(int64(len(inviteCodeAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = inviteCodeAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+//NormalizeInviteCode func reads a code the way it is stored, codes are case insensitive
+func NormalizeInviteCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+//pgInviteRepository struct is the postgres implementation of InviteRepository
+type pgInviteRepository struct {
+	db *sql.DB
+}
+
+//Create func inserts a new invite of a challenge with a fresh code. A code which is taken is drawn again.
+func (r *pgInviteRepository) Create(i *Invite) error {
+	i.CreatedAt = time.Now()
+
+	for attempt := 1; ; attempt++ {
+		code, err := newInviteCode()
+		if err != nil {
+			log.Printf("Create invite: code error: %v", err)
+			return Internal(err)
+		}
+
+		err = r.db.QueryRow(`INSERT INTO invites (code, challenge_id, inviter_id, expires_at, max_uses, created_at)
+		SELECT $1, id, $3, $4, $5, $6 FROM challenges WHERE id=$2 AND deleted_at IS NULL RETURNING id;`,
+			code, i.ChallengeID, i.InviterID, i.ExpiresAt, i.MaxUses, i.CreatedAt).Scan(&i.ID)
+		if err == sql.ErrNoRows {
+			return NotFound(CodeChallengeNotFound, "Challenge not found")
+		}
+
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" && attempt < inviteCodeAttempts {
+			continue
+		}
+		if err != nil {
+			log.Printf("Create invite: insert error: %v", err)
+			return dbError(err)
+		}
+
+		i.Code = code
+		break
+	}
+
+	log.Printf("invite successfully created with id %v", i.ID)
+
+	return nil
+}
+
+//Get func fetches the invites of the challenge with their redemption stats, newest first
+func (r *pgInviteRepository) Get(filter InviteFilter) ([]*Invite, error) {
+	q := NewQuery()
+
+	if filter.ChallengeID > 0 {
+		q.Where(Eq("challenge_id", filter.ChallengeID))
+	}
+
+	if filter.BeforeID > 0 {
+		q.Where(Lt("id", filter.BeforeID))
+	}
+
+	clause, args := q.Sort(SortKeys{"newest": "id DESC"}, "newest", "newest").Page(0, filter.Limit).Build()
+
+	inviteList := []*Invite{}
+	rows, err := r.db.Query(`SELECT id, code, challenge_id, inviter_id, expires_at, max_uses, uses,
+	(SELECT COUNT(ir.id) FROM invite_redemptions ir WHERE ir.invite_id=invites.id AND EXISTS (SELECT 1 FROM posts p WHERE p.challenge_id=invites.challenge_id AND p.user_id=ir.user_id AND p.deleted_at IS NULL)) AS participants,
+	(SELECT MAX(ir.created_at) FROM invite_redemptions ir WHERE ir.invite_id=invites.id) AS last_redeemed_at, created_at FROM invites `+clause+";", args...)
+	if err != nil {
+		log.Printf("Get invites: sql error %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		invite := Invite{}
+		if err = rows.Scan(&invite.ID, &invite.Code, &invite.ChallengeID, &invite.InviterID, &invite.ExpiresAt, &invite.MaxUses, &invite.Uses,
+			&invite.Participants, &invite.LastRedeemedAt, &invite.CreatedAt); err != nil {
+			log.Printf("scanning row to struct error: %v", err)
+			return nil, err
+		}
+		inviteList = append(inviteList, &invite)
+	}
+	return inviteList, nil
+}
+
+//Redeem func joins the user to the challenge of the invite: an accepted challenge request from the inviter to the user is
+//made and the use counted, once per user and invite
+func (r *pgInviteRepository) Redeem(code string, userID int64) (*ChallengeRequest, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Redeem invite: begin error: %v", err)
+		return nil, Internal(err)
+	}
+	defer tx.Rollback()
+
+	//the lock of the invite row queues the redemptions, the last use can not be taken twice
+	invite := Invite{}
+	challenge := Challenge{}
+	err = tx.QueryRow(`SELECT i.id, i.challenge_id, i.inviter_id, i.expires_at, i.max_uses, i.uses, c.status, c.ends_at FROM invites i
+	JOIN challenges c ON c.id=i.challenge_id WHERE i.code=$1 AND c.deleted_at IS NULL FOR UPDATE OF i;`, NormalizeInviteCode(code)).
+		Scan(&invite.ID, &invite.ChallengeID, &invite.InviterID, &invite.ExpiresAt, &invite.MaxUses, &invite.Uses, &challenge.Status, &challenge.EndsAt)
+	if err == sql.ErrNoRows {
+		return nil, NotFound(CodeInviteNotFound, "Invite not found")
+	}
+	if err != nil {
+		log.Printf("Redeem invite: invite error: %v", err)
+		return nil, Internal(err)
+	}
+
+	now := time.Now()
+	if err = invite.redeemable(userID, now); err != nil {
+		return nil, err
+	}
+	if err = challenge.acceptsInvites(now); err != nil {
+		return nil, err
+	}
+
+	var redeemed bool
+	if err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM invite_redemptions WHERE invite_id=$1 AND user_id=$2);", invite.ID, userID).Scan(&redeemed); err != nil {
+		log.Printf("Redeem invite: redemption error: %v", err)
+		return nil, Internal(err)
+	}
+	if redeemed {
+		return nil, Conflict(CodeInviteRedeemed, "Invite already redeemed")
+	}
+
+	challengeRequest := ChallengeRequest{FromID: invite.InviterID, ToID: userID, ChallengeID: invite.ChallengeID, Status: "accepted", CreatedAt: now}
+	err = tx.QueryRow("INSERT INTO challenge_requests (to_id, from_id, challenge_id, message, status, created_at) VALUES($1,$2,$3,$4,$5,$6) RETURNING id;",
+		challengeRequest.ToID, challengeRequest.FromID, challengeRequest.ChallengeID, challengeRequest.Message, challengeRequest.Status, challengeRequest.CreatedAt).Scan(&challengeRequest.ID)
+	if err != nil {
+		log.Printf("Redeem invite: challenge request error: %v", err)
+		return nil, dbError(err)
+	}
+
+	if _, err = tx.Exec("INSERT INTO invite_redemptions (invite_id, user_id, challenge_request_id, created_at) VALUES($1,$2,$3,$4);",
+		invite.ID, userID, challengeRequest.ID, now); err != nil {
+		log.Printf("Redeem invite: insert error: %v", err)
+		return nil, dbError(err)
+	}
+
+	if _, err = tx.Exec("UPDATE invites SET uses=uses+1 WHERE id=$1;", invite.ID); err != nil {
+		log.Printf("Redeem invite: uses error: %v", err)
+		return nil, Internal(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Redeem invite: commit error: %v", err)
+		return nil, Internal(err)
+	}
+
+	log.Printf("invite %v redeemed by user %v with challenge request id %v", invite.ID, userID, challengeRequest.ID)
+
+	return &challengeRequest, nil
+}
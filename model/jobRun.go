@@ -0,0 +1,122 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+//JobRun struct is a model/schema for job_runs table. A run without finished_at is still going or died with its process.
+type JobRun struct {
+	ID         int64      `json:"id" sql:"id"`
+	Name       string     `json:"name" sql:"name"`
+	Trigger    string     `json:"trigger" sql:"trigger"`
+	StartedAt  time.Time  `json:"started_at" sql:"started_at"`
+	FinishedAt *time.Time `json:"finished_at" sql:"finished_at"`
+	Error      string     `json:"error,omitempty" sql:"error"`
+}
+
+//jobLockClass is the first key of the advisory locks of the jobs, the second one is the hash of the job name
+const jobLockClass = 1017
+
+//pgJobRunRepository struct is the postgres implementation of JobRunRepository
+type pgJobRunRepository struct {
+	db *sql.DB
+}
+
+//Lock func takes the advisory lock of the job without waiting, ok is false when another process holds it.
+//The lock belongs to a connection of its own, so it is released with the connection if the process dies.
+func (r *pgJobRunRepository) Lock(name string) (func(), bool, error) {
+	ctx := context.Background()
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		log.Printf("Lock job: connection error: %v", err)
+		return nil, false, err
+	}
+
+	var ok bool
+	if err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1, hashtext($2));", jobLockClass, name).Scan(&ok); err != nil || !ok {
+		conn.Close()
+		if err != nil {
+			log.Printf("Lock job: sql error: %v", err)
+		}
+		return nil, false, err
+	}
+
+	unlock := func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1, hashtext($2));", jobLockClass, name); err != nil {
+			log.Printf("job %v unlock error: %v", name, err)
+		}
+		conn.Close()
+	}
+	return unlock, true, nil
+}
+
+//Start func inserts the run of a job which just started
+func (r *pgJobRunRepository) Start(run *JobRun) error {
+	run.StartedAt = time.Now()
+
+	err := r.db.QueryRow("INSERT INTO job_runs(name, trigger, started_at) VALUES($1,$2,$3) RETURNING id;", run.Name, run.Trigger, run.StartedAt).Scan(&run.ID)
+	if err != nil {
+		log.Printf("Start job run: insert error: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+//Finish func records the end of the run and its error, if any
+func (r *pgJobRunRepository) Finish(run *JobRun) error {
+	now := time.Now()
+	run.FinishedAt = &now
+
+	if _, err := r.db.Exec("UPDATE job_runs SET finished_at=$1, error=$2 WHERE id=$3;", run.FinishedAt, run.Error, run.ID); err != nil {
+		log.Printf("Finish job run: sql error %v", err)
+		return err
+	}
+
+	return nil
+}
+
+//Get func fetches the runs passing the filter, newest first
+func (r *pgJobRunRepository) Get(filter JobRunFilter) ([]*JobRun, error) {
+	q := NewQuery()
+	if filter.Name != "" {
+		q.Where(Eq("name", filter.Name))
+	}
+	if filter.Failed {
+		q.Where(Ne("error", ""))
+	}
+	clause, args := q.Sort(SortKeys{"newest": "id DESC"}, "newest", "newest").Page(0, filter.Limit).Build()
+
+	runList := []*JobRun{}
+	rows, err := r.db.Query("SELECT id, name, trigger, started_at, finished_at, error FROM job_runs "+clause+";", args...)
+	if err != nil {
+		log.Printf("Get job runs: sql error %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		run := JobRun{}
+		if err = rows.Scan(&run.ID, &run.Name, &run.Trigger, &run.StartedAt, &run.FinishedAt, &run.Error); err != nil {
+			log.Printf("scanning row to struct error: %v", err)
+			return nil, err
+		}
+		runList = append(runList, &run)
+	}
+	return runList, nil
+}
+
+//Prune func deletes the runs started before the given time
+func (r *pgJobRunRepository) Prune(before time.Time) (int64, error) {
+	res, err := r.db.Exec("DELETE FROM job_runs WHERE started_at<$1;", before)
+	if err != nil {
+		log.Printf("Prune job runs: sql error %v", err)
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
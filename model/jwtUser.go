@@ -1,13 +1,14 @@
-package middleware
+package model
 
 import jwt "github.com/dgrijalva/jwt-go"
 
-//JWTUser struct is a schema for
+//JWTUser struct is a schema for the claims of the access tokens
 type JWTUser struct {
 	ID             int64   `json:"id"`
 	FacebookUserID string  `json:"facebook_user_id"`
 	Weight         float32 `json:"weight"`
 	Role           string  `json:"role"`
+	SessionID      string  `json:"sid"`
 
 	jwt.StandardClaims
 }
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"database/sql"
+	"log"
+)
+
+//LeaderboardEntry struct is the standing of a participant of a challenge. Participants rank by the likes of their posts
+//in the challenge, ties go to the fastest to reach likes_needed: CompletedIn is the seconds from posting to completion of
+//their fastest completed post, nil when none completed. Participants tied on both share the rank.
+type LeaderboardEntry struct {
+	Rank        int      `json:"rank"`
+	UserID      int64    `json:"user_id"`
+	Name        string   `json:"name"`
+	Likes       int64    `json:"likes"`
+	CompletedIn *float64 `json:"completed_in"`
+}
+
+//Leaderboard struct is a page of the ranking of a challenge. Me is the entry of the caller wherever it ranks, nil when the
+//caller has not posted in the challenge.
+type Leaderboard struct {
+	ChallengeID int64               `json:"challenge_id"`
+	Total       int                 `json:"total"`
+	Entries     []*LeaderboardEntry `json:"entries"`
+	Me          *LeaderboardEntry   `json:"me"`
+}
+
+//leaderboardQuery ranks the participants of the challenge $1, the posts and users which are deleted do not count
+var leaderboardQuery = `SELECT RANK() OVER (ORDER BY t.likes DESC, t.completed_in ASC NULLS LAST) AS rank, t.user_id, u.name, t.likes, t.completed_in
+FROM (
+	SELECT p.user_id, COUNT(l.id) AS likes, MIN(EXTRACT(EPOCH FROM p.completed_at - p.created_at))::float8 AS completed_in
+	FROM posts p LEFT JOIN likes l ON l.post_id=p.id
+	WHERE p.challenge_id=$1 AND p.deleted_at IS NULL
+	GROUP BY p.user_id
+) t JOIN users u ON u.id=t.user_id
+WHERE u.deleted_at IS NULL
+ORDER BY rank, t.user_id;`
+
+//pgLeaderboardRepository struct is the postgres implementation of LeaderboardRepository
+type pgLeaderboardRepository struct {
+	db *sql.DB
+}
+
+//Get func ranks every participant of the challenge, best first
+func (r *pgLeaderboardRepository) Get(challengeID int64) ([]*LeaderboardEntry, error) {
+	var exists bool
+	if err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM challenges WHERE id=$1 AND deleted_at IS NULL);", challengeID).Scan(&exists); err != nil {
+		log.Printf("Get leaderboard: challenge error %v", err)
+		return nil, err
+	}
+	if !exists {
+		return nil, NotFound(CodeChallengeNotFound, "Challenge not found")
+	}
+
+	entryList := []*LeaderboardEntry{}
+	rows, err := r.db.Query(leaderboardQuery, challengeID)
+	if err != nil {
+		log.Printf("Get leaderboard: sql error %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry := LeaderboardEntry{}
+		if err = rows.Scan(&entry.Rank, &entry.UserID, &entry.Name, &entry.Likes, &entry.CompletedIn); err != nil {
+			log.Printf("scanning row to struct error: %v", err)
+			return nil, err
+		}
+		entryList = append(entryList, &entry)
+	}
+	return entryList, nil
+}
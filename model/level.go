@@ -1,6 +1,9 @@
 package model
 
-import "log"
+import (
+	"database/sql"
+	"log"
+)
 
 //Level struct is a model/schema for a level table
 type Level struct {
@@ -8,11 +11,26 @@ type Level struct {
 	Name string `json:"name" sql:"name"`
 }
 
-//Count func counts the users from db
-func (l *Level) Count(whereClause string, args ...interface{}) (int64, error) {
+//pgLevelRepository struct is the postgres implementation of LevelRepository
+type pgLevelRepository struct {
+	db *sql.DB
+}
+
+//Create func inserts a new level in the db
+func (r *pgLevelRepository) Create(l *Level) error {
+	if err := r.db.QueryRow("INSERT INTO levels (name) VALUES($1) RETURNING id;", l.Name).Scan(&l.ID); err != nil {
+		log.Printf("Create level: insert error: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+//Count func counts the levels with the given id
+func (r *pgLevelRepository) Count(id int64) (int64, error) {
 	var count int64
 
-	if err := db.QueryRow("SELECT COUNT(id) FROM levels "+whereClause+";", args...).Scan(&count); err != nil {
+	if err := r.db.QueryRow("SELECT COUNT(id) FROM levels WHERE id=$1;", id).Scan(&count); err != nil {
 		log.Printf("Count levels: sql error %v", err)
 		return count, err
 	}
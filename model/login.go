@@ -2,41 +2,51 @@ package model
 
 import "github.com/challengr/lib"
 
-//LogIn is a struct used for logging
+//LogIn is a struct used for logging. Facebook logins send the facebook fields, the other providers an id_token.
+//Provider defaults to facebook for the older clients.
 type LogIn struct {
-	Email          string `json:"email"`
-	FacebookUserID string `json:"facebook_user_id"`
-	FacebookToken  string `json:"facebook_token"`
-	Imei           string `json:"imei"`
-
-	Payload map[string]interface{} `json:"-"`
+	Provider       string `json:"provider" bind:"create"`
+	Email          string `json:"email" bind:"create"`
+	FacebookUserID string `json:"facebook_user_id" bind:"create"`
+	FacebookToken  string `json:"facebook_token" bind:"create"`
+	IDToken        string `json:"id_token" bind:"create"`
+	Imei           string `json:"imei" bind:"create"`
 }
 
-//ParseNotAllowedJSON unmarshalls JSON payload to struct payload and fields. Plus parses the JSON payload.
-func (l *LogIn) ParseNotAllowedJSON() []string {
-	errSlice := []string{}
-
-	delete(l.Payload, "email")
-	delete(l.Payload, "imei")
-	delete(l.Payload, "facebook_token")
-
-	for key := range l.Payload {
-		errSlice = append(errSlice, key)
+//ProviderName func returns the provider of the login
+func (l *LogIn) ProviderName() string {
+	if l.Provider == "" {
+		return providerFacebook
 	}
+	return l.Provider
+}
 
-	return errSlice
+//Credential func returns the token which the provider has to verify
+func (l *LogIn) Credential() string {
+	if l.ProviderName() == providerFacebook {
+		return l.FacebookToken
+	}
+	return l.IDToken
 }
 
-//PostValidate func validates a post payload data
-func (l *LogIn) PostValidate() []string {
+//Validate func validates a login payload data
+func (l *LogIn) Validate(op string) []string {
 	errSlice := []string{}
 
-	if !lib.ValidateEmail(l.Email) {
-		errSlice = append(errSlice, "email")
-	}
+	if l.ProviderName() == providerFacebook {
+		if !lib.ValidateEmail(l.Email) {
+			errSlice = append(errSlice, "email")
+		}
+
+		if l.FacebookUserID == "" {
+			errSlice = append(errSlice, "facebook_user_id")
+		}
 
-	if l.FacebookToken == "" {
-		errSlice = append(errSlice, "facebook_token")
+		if l.FacebookToken == "" {
+			errSlice = append(errSlice, "facebook_token")
+		}
+	} else if l.IDToken == "" {
+		errSlice = append(errSlice, "id_token")
 	}
 
 	if l.Imei == "" {
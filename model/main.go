@@ -2,54 +2,54 @@ package model
 
 import (
 	"database/sql"
-	"fmt"
 	"log"
-	"os"
 
-	"github.com/challengr/middleware"
+	"github.com/challengr/config"
 	_ "github.com/lib/pq"
 )
 
 //roleUser is used for assigning role to an user
 const roleUser = "user"
 
-//JWTSecret is user for encrypting and decrypting jwt
-const JWTSecret = middleware.JWTSecret
-
-var db *sql.DB
-
-func init() {
-	dbUser := os.Getenv("DB_USER")
-	dbName := os.Getenv("DB_NAME")
-	dbHost := os.Getenv("DB_HOST")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbSslMode := "disable"
-	if dbUser == "" {
-		dbUser = "challengr"
-	}
-	if dbName == "" {
-		dbName = "challengrdb"
-	}
-	if dbHost == "" {
-		dbHost = "challengr.cd61ijduodvj.us-east-1.rds.amazonaws.com:5432"
-	}
-	if dbPassword == "" {
-		dbPassword = "20Challengr17"
-	}
-
-	dataSource := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=%s",
-		dbUser, dbPassword, dbHost, dbName, dbSslMode)
-
-	log.Printf("dataSource: %v", dataSource)
-
-	db, err := sql.Open("postgres", dataSource)
+//Connect func opens the postgres connection pool and pings it
+func Connect(cfg config.DB) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.DataSource())
 	if err != nil {
 		log.Printf("%v", err)
+		return nil, err
 	}
 
 	log.Println("DB ping started...")
 	if err = db.Ping(); err != nil {
 		log.Printf("DB ping failed with error...%v", err)
+		return nil, err
 	}
 	log.Println("DB connected.")
+
+	return db, nil
+}
+
+//NewPostgresStore func creates a store whose repositories are backed by the given postgres connection
+func NewPostgresStore(db *sql.DB) *Store {
+	return &Store{
+		Users:             &pgUserRepository{db: db},
+		Challenges:        &pgChallengeRepository{db: db},
+		Posts:             &pgPostRepository{db: db},
+		Scores:            &pgScoreRepository{db: db},
+		BoughtItems:       &pgBoughtItemRepository{db: db},
+		OneSignals:        &pgOneSignalRepository{db: db},
+		ChallengeRequests: &pgChallengeRequestRepository{db: db},
+		Levels:            &pgLevelRepository{db: db},
+		VanityItems:       &pgVanityItemRepository{db: db},
+		RefreshTokens:     &pgRefreshTokenRepository{db: db},
+		UserIdentities:    &pgUserIdentityRepository{db: db},
+		PersonalData:      &pgPersonalDataRepository{db: db},
+		AuditEvents:       &pgAuditEventRepository{db: db},
+		JobRuns:           &pgJobRunRepository{db: db},
+		Categories:        &pgCategoryRepository{db: db},
+		Search:            &pgSearchRepository{db: db},
+		Completions:       &pgCompletionRepository{db: db},
+		Leaderboards:      &pgLeaderboardRepository{db: db},
+		Invites:           &pgInviteRepository{db: db},
+	}
 }
@@ -0,0 +1,394 @@
+package model
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+)
+
+//memoryStore struct holds the tables of the in-memory store. Every repository of the store shares it.
+type memoryStore struct {
+	mu sync.RWMutex
+
+	lastID            map[string]int64
+	users             []*User
+	challenges        []*Challenge
+	posts             []*Post
+	scores            []*Score
+	boughtItems       []*BoughtItem
+	oneSignals        []*OneSignal
+	challengeRequests []*ChallengeRequest
+	levels            []*Level
+	vanityItems       []*VanityItem
+	refreshTokens     []*RefreshToken
+	userIdentities    []*UserIdentity
+	auditEvents       []*AuditEvent
+	jobRuns           []*JobRun
+	categories        []*Category
+	completions       []*Completion
+	invites           []*Invite
+	inviteRedemptions []*InviteRedemption
+	jobLocks          map[string]bool
+}
+
+//NewMemoryStore func creates a store which keeps everything in memory. It is meant for tests and local development.
+func NewMemoryStore() *Store {
+	m := &memoryStore{lastID: make(map[string]int64), jobLocks: make(map[string]bool)}
+
+	return &Store{
+		Users:             &memoryUserRepository{m},
+		Challenges:        &memoryChallengeRepository{m},
+		Posts:             &memoryPostRepository{m},
+		Scores:            &memoryScoreRepository{m},
+		BoughtItems:       &memoryBoughtItemRepository{m},
+		OneSignals:        &memoryOneSignalRepository{m},
+		ChallengeRequests: &memoryChallengeRequestRepository{m},
+		Levels:            &memoryLevelRepository{m},
+		VanityItems:       &memoryVanityItemRepository{m},
+		RefreshTokens:     &memoryRefreshTokenRepository{m},
+		UserIdentities:    &memoryUserIdentityRepository{m},
+		PersonalData:      &memoryPersonalDataRepository{m},
+		AuditEvents:       &memoryAuditEventRepository{m},
+		JobRuns:           &memoryJobRunRepository{m},
+		Categories:        &memoryCategoryRepository{m},
+		Search:            &memorySearchRepository{m},
+		Completions:       &memoryCompletionRepository{m},
+		Leaderboards:      &memoryLeaderboardRepository{m},
+		Invites:           &memoryInviteRepository{m},
+	}
+}
+
+//nextID func hands out the next serial id of a table. Caller must hold the lock.
+func (m *memoryStore) nextID(table string) int64 {
+	m.lastID[table] = m.lastID[table] + 1
+	return m.lastID[table]
+}
+
+//challengeOf func returns the challenge row with the given id, nil when it is missing or deleted. Caller must hold the lock.
+func (m *memoryStore) challengeOf(id int64) *Challenge {
+	for _, row := range m.challenges {
+		if row.ID == id && row.DeletedAt == nil {
+			return row
+		}
+	}
+	return nil
+}
+
+//distanceSphere func returns the distance in meters between two long/lat points, like ST_Distance_Sphere
+func distanceSphere(long1, lat1, long2, lat2 float64) float64 {
+	const earthRadius = 6370986.0
+	rad := math.Pi / 180
+
+	dLat := (lat2 - lat1) * rad
+	dLong := (long2 - long1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLong/2)*math.Sin(dLong/2)
+
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+//memoryScoreRepository struct is the in-memory implementation of ScoreRepository
+type memoryScoreRepository struct {
+	*memoryStore
+}
+
+//Create func inserts a new score for a new user
+func (r *memoryScoreRepository) Create(s *Score) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s.ID = r.nextID("scores")
+	s.CreatedAt = time.Now()
+	row := *s
+	r.scores = append(r.scores, &row)
+
+	return nil
+}
+
+//add func applies the change to the score of the user
+func (r *memoryScoreRepository) add(s *Score, apply func(row *Score), e *AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, row := range r.scores {
+		if row.ID == s.ID && row.UserID == s.UserID {
+			before, _ := json.Marshal(row)
+			now := time.Now()
+			apply(row)
+			row.UpdatedAt = &now
+			s.UpdatedAt = &now
+			r.audit(e, auditScore, row.ID, before, row)
+			return nil
+		}
+	}
+
+	return NotFound(CodeScoreNotFound, "Score not found")
+}
+
+//AddExp func updates the experience points
+func (r *memoryScoreRepository) AddExp(s *Score, amount int, e *AuditEvent) error {
+	return r.add(s, func(row *Score) { row.Exp = row.Exp + amount }, e)
+}
+
+//AddCoins func updates the coins
+func (r *memoryScoreRepository) AddCoins(s *Score, amount int, e *AuditEvent) error {
+	return r.add(s, func(row *Score) { row.Coins = row.Coins + int64(amount) }, e)
+}
+
+//AddLikes func updates the remaining likes
+func (r *memoryScoreRepository) AddLikes(s *Score, amount int, e *AuditEvent) error {
+	return r.add(s, func(row *Score) { row.LikesRemaining = row.LikesRemaining + amount }, e)
+}
+
+//RefillLikes func gives the amount of likes back to the users who ran out of likes before emptySince
+func (r *memoryScoreRepository) RefillLikes(amount int, emptySince time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var refilled int64
+	now := time.Now()
+	for _, row := range r.scores {
+		emptyAt := row.CreatedAt
+		if row.LikesUpdatedAt != nil {
+			emptyAt = *row.LikesUpdatedAt
+		} else if row.UpdatedAt != nil {
+			emptyAt = *row.UpdatedAt
+		}
+
+		if row.LikesRemaining <= 0 && emptyAt.Before(emptySince) {
+			row.LikesRemaining = amount
+			row.LikesUpdatedAt = nil
+			row.UpdatedAt = &now
+			refilled = refilled + 1
+		}
+	}
+
+	return refilled, nil
+}
+
+//memoryBoughtItemRepository struct is the in-memory implementation of BoughtItemRepository
+type memoryBoughtItemRepository struct {
+	*memoryStore
+}
+
+//Create func adds an item to the table
+func (r *memoryBoughtItemRepository) Create(b *BoughtItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b.ID = r.nextID("bought_items")
+	b.CreatedAt = time.Now()
+	row := *b
+	r.boughtItems = append(r.boughtItems, &row)
+
+	return nil
+}
+
+//Get func fetches the bought items of an user, newest first
+func (r *memoryBoughtItemRepository) Get(filter BoughtItemFilter) ([]*BoughtItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.boughtItemsOf(filter.UserID), nil
+}
+
+//boughtItemsOf func collects the bought items of an user with their level and vanity item. Caller must hold the lock.
+func (m *memoryStore) boughtItemsOf(userID int64) []*BoughtItem {
+	boughtItemList := []*BoughtItem{}
+	for i := len(m.boughtItems) - 1; i >= 0; i-- {
+		if m.boughtItems[i].UserID != userID {
+			continue
+		}
+
+		boughtItem := *m.boughtItems[i]
+		for _, level := range m.levels {
+			if boughtItem.LevelID != nil && level.ID == *boughtItem.LevelID {
+				l := *level
+				boughtItem.Level = &l
+			}
+		}
+		for _, vanityItem := range m.vanityItems {
+			if boughtItem.VanityItemID != nil && vanityItem.ID == *boughtItem.VanityItemID {
+				v := *vanityItem
+				boughtItem.VanityItem = &v
+			}
+		}
+		boughtItemList = append(boughtItemList, &boughtItem)
+	}
+
+	return boughtItemList
+}
+
+//memoryOneSignalRepository struct is the in-memory implementation of OneSignalRepository
+type memoryOneSignalRepository struct {
+	*memoryStore
+}
+
+//Get func fetches the onesignal records of a user
+func (r *memoryOneSignalRepository) Get(filter OneSignalFilter) ([]*OneSignal, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	oneSignalList := []*OneSignal{}
+	for i := len(r.oneSignals) - 1; i >= 0; i-- {
+		row := r.oneSignals[i]
+		if row.UserID == filter.UserID && (filter.Imei == "" || row.Imei == filter.Imei) {
+			oneSignal := *row
+			oneSignalList = append(oneSignalList, &oneSignal)
+		}
+	}
+
+	return oneSignalList, nil
+}
+
+//Upsert func inserts or updates the onesignal info of the user
+func (r *memoryOneSignalRepository) Upsert(o *OneSignal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, row := range r.oneSignals {
+		if row.UserID == o.UserID && row.Imei == o.Imei {
+			row.PlayerID = o.PlayerID
+			row.UpdatedAt = &now
+			row.LastSeenAt = &now
+			o.ID = row.ID
+			o.UpdatedAt = &now
+			o.LastSeenAt = &now
+			return nil
+		}
+	}
+
+	o.ID = r.nextID("onesignal")
+	o.CreatedAt = &now
+	o.LastSeenAt = &now
+	row := *o
+	r.oneSignals = append(r.oneSignals, &row)
+
+	return nil
+}
+
+//Delete func deletes the onesignal record of the user
+func (r *memoryOneSignalRepository) Delete(o *OneSignal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, row := range r.oneSignals {
+		if row.UserID == o.UserID && row.Imei == o.Imei {
+			r.oneSignals = append(r.oneSignals[:i], r.oneSignals[i+1:]...)
+			return nil
+		}
+	}
+
+	return NotFound(CodeNotFound, "Onesignal account not found")
+}
+
+//Touch func sets the last seen time of the device of the user to now
+func (r *memoryOneSignalRepository) Touch(o *OneSignal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, row := range r.oneSignals {
+		if row.UserID == o.UserID && row.Imei == o.Imei {
+			row.LastSeenAt = &now
+		}
+	}
+
+	return nil
+}
+
+//DeleteAll func deletes every onesignal record of the user
+func (r *memoryOneSignalRepository) DeleteAll(userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oneSignals := []*OneSignal{}
+	for _, row := range r.oneSignals {
+		if row.UserID != userID {
+			oneSignals = append(oneSignals, row)
+		}
+	}
+	r.oneSignals = oneSignals
+
+	return nil
+}
+
+//memoryLevelRepository struct is the in-memory implementation of LevelRepository
+type memoryLevelRepository struct {
+	*memoryStore
+}
+
+//Create func inserts a new level
+func (r *memoryLevelRepository) Create(l *Level) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l.ID = r.nextID("levels")
+	row := *l
+	r.levels = append(r.levels, &row)
+
+	return nil
+}
+
+//Count func counts the levels with the given id
+func (r *memoryLevelRepository) Count(id int64) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, row := range r.levels {
+		if row.ID == id {
+			count = count + 1
+		}
+	}
+
+	return count, nil
+}
+
+//memoryVanityItemRepository struct is the in-memory implementation of VanityItemRepository
+type memoryVanityItemRepository struct {
+	*memoryStore
+}
+
+//Create func inserts a new vanity item
+func (r *memoryVanityItemRepository) Create(v *VanityItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v.ID = r.nextID("vanity_items")
+	v.CreatedAt = time.Now().Format(time.RFC3339)
+	row := *v
+	r.vanityItems = append(r.vanityItems, &row)
+
+	return nil
+}
+
+//Get func fetches the vanity items, newest first
+func (r *memoryVanityItemRepository) Get() ([]*VanityItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	vanityItemList := []*VanityItem{}
+	for i := len(r.vanityItems) - 1; i >= 0; i-- {
+		vanityItem := *r.vanityItems[i]
+		vanityItemList = append(vanityItemList, &vanityItem)
+	}
+
+	return vanityItemList, nil
+}
+
+//Count func counts the vanity items with the given id
+func (r *memoryVanityItemRepository) Count(id int64) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, row := range r.vanityItems {
+		if row.ID == id {
+			count = count + 1
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+//audit func appends the event of a change of the target row, before is its JSON from before the change.
+//A nil event is not audited. Caller must hold the lock.
+func (m *memoryStore) audit(e *AuditEvent, target string, targetID int64, before []byte, after interface{}) {
+	if e == nil {
+		return
+	}
+
+	e.ID = m.nextID("audit_events")
+	e.TargetType = target
+	e.TargetID = targetID
+	e.Before = before
+	e.After, _ = json.Marshal(after)
+	e.CreatedAt = time.Now()
+
+	row := *e
+	m.auditEvents = append(m.auditEvents, &row)
+}
+
+//memoryAuditEventRepository struct is the in-memory implementation of AuditEventRepository
+type memoryAuditEventRepository struct {
+	*memoryStore
+}
+
+//match func checks if the event passes the filter
+func (r *memoryAuditEventRepository) match(e *AuditEvent, filter AuditEventFilter) bool {
+	return (filter.ActorID == 0 || e.ActorID == filter.ActorID) &&
+		(filter.Action == "" || e.Action == filter.Action) &&
+		(filter.TargetType == "" || e.TargetType == filter.TargetType) &&
+		(filter.TargetID == 0 || e.TargetID == filter.TargetID) &&
+		(filter.From == nil || !e.CreatedAt.Before(*filter.From)) &&
+		(filter.To == nil || e.CreatedAt.Before(*filter.To)) &&
+		(filter.BeforeID == 0 || e.ID < filter.BeforeID)
+}
+
+//Get func fetches the audit events passing the filter, newest first
+func (r *memoryAuditEventRepository) Get(filter AuditEventFilter) ([]*AuditEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	eventList := []*AuditEvent{}
+	for i := len(r.auditEvents) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(eventList) == filter.Limit {
+			break
+		}
+		if r.match(r.auditEvents[i], filter) {
+			event := *r.auditEvents[i]
+			eventList = append(eventList, &event)
+		}
+	}
+
+	return eventList, nil
+}
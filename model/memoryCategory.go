@@ -0,0 +1,117 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+//memoryCategoryRepository struct is the in-memory implementation of CategoryRepository
+type memoryCategoryRepository struct {
+	*memoryStore
+}
+
+//slugTaken func checks if another category has the slug. Caller must hold the lock.
+func (r *memoryCategoryRepository) slugTaken(c *Category) bool {
+	for _, row := range r.categories {
+		if row.Slug == c.Slug && row.ID != c.ID {
+			return true
+		}
+	}
+	return false
+}
+
+//Create func inserts a new category
+func (r *memoryCategoryRepository) Create(c *Category) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.slugTaken(c) {
+		return Conflict(CodeConflict, "Record already exists")
+	}
+
+	c.ID = r.nextID("categories")
+	c.CreatedAt = time.Now()
+	row := *c
+	r.categories = append(r.categories, &row)
+
+	return nil
+}
+
+//Get func fetches the categories passing the filter, ordered by name
+func (r *memoryCategoryRepository) Get(filter CategoryFilter) ([]*Category, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	categoryList := []*Category{}
+	for _, row := range r.categories {
+		if (filter.ID == 0 || row.ID == filter.ID) && (filter.Slug == "" || row.Slug == filter.Slug) {
+			category := *row
+			categoryList = append(categoryList, &category)
+		}
+	}
+	sort.SliceStable(categoryList, func(i, j int) bool { return categoryList[i].Name < categoryList[j].Name })
+
+	return categoryList, nil
+}
+
+//Update func changes the slug and/or the name of the category
+func (r *memoryCategoryRepository) Update(c *Category) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, row := range r.categories {
+		if row.ID != c.ID {
+			continue
+		}
+		if c.Slug != "" && r.slugTaken(c) {
+			return Conflict(CodeConflict, "Record already exists")
+		}
+
+		now := time.Now()
+		if c.Slug != "" {
+			row.Slug = c.Slug
+		}
+		if c.Name != "" {
+			row.Name = c.Name
+		}
+		row.UpdatedAt = &now
+		c.UpdatedAt = &now
+		return nil
+	}
+
+	return NotFound(CodeCategoryNotFound, "Category not found")
+}
+
+//Delete func deletes the category, its challenges are left without a category
+func (r *memoryCategoryRepository) Delete(c *Category) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, row := range r.categories {
+		if row.ID == c.ID {
+			r.categories = append(r.categories[:i], r.categories[i+1:]...)
+			for _, challenge := range r.challenges {
+				if challenge.CategoryID != nil && *challenge.CategoryID == c.ID {
+					challenge.CategoryID = nil
+				}
+			}
+			return nil
+		}
+	}
+
+	return NotFound(CodeCategoryNotFound, "Category not found")
+}
+
+//categoryOf func returns a copy of the category with the given id, nil when there is none. Caller must hold the lock.
+func (m *memoryStore) categoryOf(id *int64) *Category {
+	if id == nil {
+		return nil
+	}
+	for _, row := range m.categories {
+		if row.ID == *id {
+			category := *row
+			return &category
+		}
+	}
+	return nil
+}
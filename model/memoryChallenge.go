@@ -0,0 +1,423 @@
+package model
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/challengr/config"
+)
+
+//memoryChallengeRepository struct is the in-memory implementation of ChallengeRepository
+type memoryChallengeRepository struct {
+	*memoryStore
+}
+
+//match func checks if the challenge row passes the filter
+func (r *memoryChallengeRepository) match(c *Challenge, filter ChallengeFilter) bool {
+	return c.DeletedAt == nil &&
+		(filter.ID == 0 || c.ID == filter.ID) &&
+		(filter.UserID == 0 || c.UserID == filter.UserID) &&
+		(filter.Status == "" || c.Status == filter.Status) &&
+		(filter.CategoryID == 0 || (c.CategoryID != nil && *c.CategoryID == filter.CategoryID)) &&
+		matchTags(c.Tags, filter.Tags, filter.AllTags) &&
+		(filter.Box == nil || (c.Location != nil && c.Location.intersects(*filter.Box))) &&
+		r.matchNear(c, filter)
+}
+
+//matchNear func checks the distance to the near point and pages on it, or on the sort without a near point
+func (r *memoryChallengeRepository) matchNear(c *Challenge, filter ChallengeFilter) bool {
+	if filter.Near == nil {
+		return filter.LastID == 0 || afterSortCursor(c, filter)
+	}
+	if c.Location == nil {
+		return false
+	}
+
+	distance := c.Location.distance(filter.Near.Long, filter.Near.Lat)
+	if distance > float64(filter.Near.Radius) {
+		return false
+	}
+	return filter.LastID == 0 || distance > filter.LastDistance || (distance == filter.LastDistance && c.ID > filter.LastID)
+}
+
+//afterSortCursor func checks if the challenge comes after the last one of the previous page in the sort of the filter, like
+//challengeCursor
+func afterSortCursor(c *Challenge, filter ChallengeFilter) bool {
+	switch filter.Sort {
+	case "trending", "hot":
+		return c.TrendingScore < filter.LastTrendingScore || (c.TrendingScore == filter.LastTrendingScore && c.ID > filter.LastID)
+	case "fresh":
+		return c.CreatedAt.Before(filter.LastCreatedAt) || (c.CreatedAt.Equal(filter.LastCreatedAt) && c.ID > filter.LastID)
+	}
+	return c.ID > filter.LastID
+}
+
+//matchTags func checks if the tags have any, or with all set every one, of the wanted tags. No wanted tags match anything.
+func matchTags(tags, wanted []string, all bool) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+
+	found := 0
+	for _, w := range wanted {
+		for _, tag := range tags {
+			if tag == w {
+				found = found + 1
+				break
+			}
+		}
+	}
+
+	if all {
+		return found == len(wanted)
+	}
+	return found > 0
+}
+
+//RefreshTrending func recounts the posts, liked posts and velocity of the active challenges into their trending score.
+//It returns the number of challenges whose score changed.
+func (r *memoryChallengeRepository) RefreshTrending(now time.Time, formula config.Trending) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var changed int64
+	for _, row := range r.challenges {
+		if row.DeletedAt != nil || row.Status != ChallengeActive {
+			continue
+		}
+
+		posts, likedPosts, velocity := 0, 0, 0.0
+		for _, post := range r.posts {
+			if post.ChallengeID != row.ID || post.DeletedAt != nil {
+				continue
+			}
+			posts = posts + 1
+			if len(post.Likes) > 0 {
+				likedPosts = likedPosts + 1
+			}
+			if post.CreatedAt != nil {
+				velocity = velocity + decayed(now, *post.CreatedAt, formula.Window)
+			}
+			for _, like := range post.Likes {
+				velocity = velocity + decayed(now, like.CreatedAt, formula.Window)
+			}
+		}
+
+		if score := trendScore(formula, posts, likedPosts, velocity, row.Weight); score != row.TrendingScore {
+			row.TrendingScore = score
+			changed = changed + 1
+		}
+	}
+
+	return changed, nil
+}
+
+//Create func inserts a new challenge
+func (r *memoryChallengeRepository) Create(c *Challenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := c.initState(time.Now()); err != nil {
+		return err
+	}
+	if err := r.setCategory(c, c.CategoryID); err != nil {
+		return err
+	}
+	if c.Tags == nil {
+		c.Tags = []string{}
+	}
+	if c.Rules == nil {
+		c.Rules = &ChallengeRules{MediaTypes: []string{}}
+	}
+
+	c.ID = r.nextID("challenges")
+	c.CreatedAt = time.Now()
+	row := *c
+	r.challenges = append(r.challenges, &row)
+
+	return nil
+}
+
+//setCategory func sets the category of the challenge, like a foreign key the category must exist. 0 removes the category.
+//Caller must hold the lock.
+func (r *memoryChallengeRepository) setCategory(c *Challenge, categoryID *int64) error {
+	if categoryID == nil || *categoryID == 0 {
+		c.CategoryID = nil
+		return nil
+	}
+	if r.categoryOf(categoryID) == nil {
+		return Validation(CodeInvalidFields, "Unknown category", "category_id")
+	}
+
+	id := *categoryID
+	c.CategoryID = &id
+	return nil
+}
+
+//Get func fetches the challenges passing the filter
+func (r *memoryChallengeRepository) Get(filter ChallengeFilter) ([]*Challenge, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	challengeList := []*Challenge{}
+	for _, row := range r.challenges {
+		if !r.match(row, filter) {
+			continue
+		}
+
+		challenge := *row
+		for _, post := range r.posts {
+			if post.ChallengeID == challenge.ID && post.DeletedAt == nil {
+				challenge.TotalPost = challenge.TotalPost + 1
+			}
+		}
+		challenge.Tags = append([]string{}, row.Tags...)
+		challenge.Category = r.categoryOf(row.CategoryID)
+		if filter.Near != nil {
+			distance := row.Location.distance(filter.Near.Long, filter.Near.Lat)
+			challenge.Distance = &distance
+		}
+		challengeList = append(challengeList, &challenge)
+	}
+
+	switch {
+	case filter.Near != nil:
+		sort.SliceStable(challengeList, func(i, j int) bool {
+			if *challengeList[i].Distance != *challengeList[j].Distance {
+				return *challengeList[i].Distance < *challengeList[j].Distance
+			}
+			return challengeList[i].ID < challengeList[j].ID
+		})
+	case filter.Sort == "trending" || filter.Sort == "hot":
+		sort.SliceStable(challengeList, func(i, j int) bool { return challengeList[i].TrendingScore > challengeList[j].TrendingScore })
+	case filter.Sort == "fresh":
+		sort.SliceStable(challengeList, func(i, j int) bool { return challengeList[i].CreatedAt.After(challengeList[j].CreatedAt) })
+	}
+	if filter.Limit > 0 && len(challengeList) > filter.Limit {
+		challengeList = challengeList[:filter.Limit]
+	}
+
+	return challengeList, nil
+}
+
+//Count func counts the challenges passing the filter
+func (r *memoryChallengeRepository) Count(filter ChallengeFilter) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, row := range r.challenges {
+		if r.match(row, filter) {
+			count = count + 1
+		}
+	}
+
+	return count, nil
+}
+
+//update func applies the given fields. The ownerID is checked only when it is bigger than zero.
+//The change is checked against the current state of the challenge.
+func (r *memoryChallengeRepository) update(c *Challenge, ownerID int64, withWeight bool, e *AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, row := range r.challenges {
+		if !r.match(row, ChallengeFilter{ID: c.ID, UserID: ownerID}) {
+			continue
+		}
+		if err := row.checkChange(c, time.Now()); err != nil {
+			return err
+		}
+		before, _ := json.Marshal(row)
+
+		if c.CategoryID != nil {
+			if err := r.setCategory(row, c.CategoryID); err != nil {
+				return err
+			}
+		}
+
+		if c.Description != nil {
+			description := *c.Description
+			row.Description = &description
+		}
+		if c.Status != "" {
+			row.Status = c.Status
+		}
+		if c.StartsAt != nil {
+			startsAt := *c.StartsAt
+			row.StartsAt = &startsAt
+		}
+		if c.EndsAt != nil {
+			endsAt := *c.EndsAt
+			row.EndsAt = &endsAt
+		}
+		if c.Tags != nil {
+			row.Tags = append([]string{}, c.Tags...)
+		}
+		if c.Rules != nil {
+			rules := *c.Rules
+			rules.MediaTypes = append([]string{}, c.Rules.MediaTypes...)
+			row.Rules = &rules
+		}
+		if withWeight && c.Weight != nil {
+			weight := *c.Weight
+			row.Weight = &weight
+		}
+		if c.UpdatedAt != nil {
+			row.UpdatedAt = c.UpdatedAt
+		}
+		if c.Location != nil {
+			row.Location = c.Location
+		}
+
+		r.audit(e, auditChallenge, row.ID, before, row)
+		return nil
+	}
+
+	return NotFound(CodeChallengeNotFound, "Challenge not found")
+}
+
+//Update func updates a challenge of its owner
+func (r *memoryChallengeRepository) Update(c *Challenge) error {
+	return r.update(c, c.UserID, false, nil)
+}
+
+//AdminUpdate func updates any challenge, recording the audit event
+func (r *memoryChallengeRepository) AdminUpdate(c *Challenge, e *AuditEvent) error {
+	return r.update(c, 0, true, e)
+}
+
+//delete func hides the challenge. The ownerID is checked only when it is bigger than zero.
+func (r *memoryChallengeRepository) delete(c *Challenge, ownerID int64, e *AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, row := range r.challenges {
+		if r.match(row, ChallengeFilter{ID: c.ID, UserID: ownerID}) {
+			before, _ := json.Marshal(row)
+			now := time.Now()
+			row.DeletedAt = &now
+			r.audit(e, auditChallenge, row.ID, before, row)
+			return nil
+		}
+	}
+
+	return NotFound(CodeChallengeNotFound, "Challenge not found")
+}
+
+//Delete func hides the challenge of its owner
+func (r *memoryChallengeRepository) Delete(c *Challenge) error {
+	return r.delete(c, c.UserID, nil)
+}
+
+//AdminDelete func hides any challenge, recording the audit event
+func (r *memoryChallengeRepository) AdminDelete(c *Challenge, e *AuditEvent) error {
+	return r.delete(c, 0, e)
+}
+
+//Advance func moves the scheduled challenges whose start has come to active and the active ones whose end has passed to closed
+func (r *memoryChallengeRepository) Advance(now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var moved int64
+	for _, row := range r.challenges {
+		if row.DeletedAt != nil {
+			continue
+		}
+
+		status := row.Status
+		if status == ChallengeScheduled && row.StartsAt != nil && !row.StartsAt.After(now) {
+			status = ChallengeActive
+		}
+		if status == ChallengeActive && row.EndsAt != nil && !row.EndsAt.After(now) {
+			status = ChallengeClosed
+		}
+
+		if status != row.Status {
+			updatedAt := now
+			row.Status = status
+			row.UpdatedAt = &updatedAt
+			moved = moved + 1
+		}
+	}
+
+	return moved, nil
+}
+
+//CloseIdle func closes the active challenges which got no post since the given time, unless they were created after it
+func (r *memoryChallengeRepository) CloseIdle(since time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var closed int64
+	now := time.Now()
+	for _, row := range r.challenges {
+		if row.Status != ChallengeActive || row.DeletedAt != nil || !row.CreatedAt.Before(since) {
+			continue
+		}
+
+		idle := true
+		for _, post := range r.posts {
+			if post.ChallengeID == row.ID && post.DeletedAt == nil && post.CreatedAt != nil && !post.CreatedAt.Before(since) {
+				idle = false
+			}
+		}
+		if idle {
+			row.Status = ChallengeClosed
+			row.UpdatedAt = &now
+			closed = closed + 1
+		}
+	}
+
+	return closed, nil
+}
+
+//Purge func removes the challenges deleted before the given time for good, with their challenge requests.
+//Challenges which still hold posts are kept.
+func (r *memoryChallengeRepository) Purge(before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := make(map[int64]bool)
+	challenges := []*Challenge{}
+	for _, row := range r.challenges {
+		if row.DeletedAt != nil && row.DeletedAt.Before(before) && !r.hasPosts(row.ID) {
+			purged[row.ID] = true
+			continue
+		}
+		challenges = append(challenges, row)
+	}
+	r.challenges = challenges
+
+	challengeRequests := []*ChallengeRequest{}
+	for _, row := range r.challengeRequests {
+		if !purged[row.ChallengeID] {
+			challengeRequests = append(challengeRequests, row)
+		}
+	}
+	r.challengeRequests = challengeRequests
+
+	completions := []*Completion{}
+	for _, row := range r.completions {
+		if !purged[row.ChallengeID] {
+			completions = append(completions, row)
+		}
+	}
+	r.completions = completions
+
+	r.dropInvites(func(i *Invite) bool { return purged[i.ChallengeID] })
+
+	return int64(len(purged)), nil
+}
+
+//hasPosts func checks if any post, deleted ones included, belongs to the challenge. Caller must hold the lock.
+func (r *memoryChallengeRepository) hasPosts(challengeID int64) bool {
+	for _, post := range r.posts {
+		if post.ChallengeID == challengeID {
+			return true
+		}
+	}
+	return false
+}
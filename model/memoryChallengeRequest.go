@@ -0,0 +1,95 @@
+package model
+
+import (
+	"time"
+)
+
+//memoryChallengeRequestRepository struct is the in-memory implementation of ChallengeRequestRepository
+type memoryChallengeRequestRepository struct {
+	*memoryStore
+}
+
+//Create func adds a challenge request
+func (r *memoryChallengeRequestRepository) Create(c *ChallengeRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c.ID = r.nextID("challenge_requests")
+	c.CreatedAt = time.Now()
+	row := *c
+	r.challengeRequests = append(r.challengeRequests, &row)
+
+	return nil
+}
+
+//Get func fetches the challenge requests with their users and challenge, newest first
+func (r *memoryChallengeRequestRepository) Get(filter ChallengeRequestFilter) ([]*ChallengeRequest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	challengeRequestList := []*ChallengeRequest{}
+	for i := len(r.challengeRequests) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(challengeRequestList) == filter.Limit {
+			break
+		}
+
+		row := r.challengeRequests[i]
+		if (filter.FromID > 0 && row.FromID != filter.FromID) || (filter.ToID > 0 && row.ToID != filter.ToID) || row.ID <= filter.LastID {
+			continue
+		}
+
+		challengeRequest := *row
+		for _, user := range r.users {
+			if user.ID == row.ToID {
+				to := *user
+				challengeRequest.To = &to
+			}
+			if user.ID == row.FromID {
+				from := *user
+				challengeRequest.From = &from
+			}
+		}
+		for _, challenge := range r.challenges {
+			if challenge.ID == row.ChallengeID {
+				ch := *challenge
+				challengeRequest.Challenge = &ch
+			}
+		}
+
+		challengeRequestList = append(challengeRequestList, &challengeRequest)
+	}
+
+	return challengeRequestList, nil
+}
+
+//UpdateStatus func updates the status of an open challenge request sent to the user
+func (r *memoryChallengeRequestRepository) UpdateStatus(c *ChallengeRequest, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, row := range r.challengeRequests {
+		if row.ID == c.ID && row.ToID == c.ToID && row.Status == "open" {
+			row.Status = status
+			c.Status = status
+			c.ChallengeID = row.ChallengeID
+			return nil
+		}
+	}
+
+	return NotFound(CodeChallengeRequestNotFound, "Challenge request not found")
+}
+
+//Delete func deletes an open challenge request sent by the user
+func (r *memoryChallengeRequestRepository) Delete(c *ChallengeRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, row := range r.challengeRequests {
+		if row.ID == c.ID && row.FromID == c.FromID && row.Status == "open" {
+			r.challengeRequests = append(r.challengeRequests[:i], r.challengeRequests[i+1:]...)
+			return nil
+		}
+	}
+
+	return NotFound(CodeChallengeRequestNotFound, "Challenge request not found")
+}
@@ -0,0 +1,32 @@
+package model
+
+//memoryCompletionRepository struct is the in-memory implementation of CompletionRepository
+type memoryCompletionRepository struct {
+	*memoryStore
+}
+
+//match func checks if the completion event passes the filter
+func (r *memoryCompletionRepository) match(c *Completion, filter CompletionFilter) bool {
+	return (filter.UserID == 0 || c.UserID == filter.UserID) &&
+		(filter.ChallengeID == 0 || c.ChallengeID == filter.ChallengeID) &&
+		c.ID > filter.AfterID
+}
+
+//Get func fetches the completion events passing the filter, oldest first
+func (r *memoryCompletionRepository) Get(filter CompletionFilter) ([]*Completion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	completionList := []*Completion{}
+	for _, row := range r.completions {
+		if filter.Limit > 0 && len(completionList) == filter.Limit {
+			break
+		}
+		if r.match(row, filter) {
+			completion := *row
+			completionList = append(completionList, &completion)
+		}
+	}
+
+	return completionList, nil
+}
@@ -0,0 +1,148 @@
+package model
+
+import "time"
+
+//memoryInviteRepository struct is the in-memory implementation of InviteRepository
+type memoryInviteRepository struct {
+	*memoryStore
+}
+
+//Create func inserts a new invite of a challenge with a fresh code. A code which is taken is drawn again.
+func (r *memoryInviteRepository) Create(i *Invite) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.challengeOf(i.ChallengeID) == nil {
+		return NotFound(CodeChallengeNotFound, "Challenge not found")
+	}
+
+	for attempt := 1; ; attempt++ {
+		code, err := newInviteCode()
+		if err != nil {
+			return Internal(err)
+		}
+		if r.inviteOf(code) == nil {
+			i.Code = code
+			break
+		}
+		if attempt == inviteCodeAttempts {
+			return Conflict(CodeConflict, "Record already exists")
+		}
+	}
+
+	i.ID = r.nextID("invites")
+	i.CreatedAt = time.Now()
+	row := *i
+	r.invites = append(r.invites, &row)
+
+	return nil
+}
+
+//inviteOf func returns the invite with the code, nil when there is none. Caller must hold the lock.
+func (r *memoryInviteRepository) inviteOf(code string) *Invite {
+	for _, row := range r.invites {
+		if row.Code == code {
+			return row
+		}
+	}
+	return nil
+}
+
+//dropInvites func removes the invites which drop matches with their redemptions, like the cascade of the foreign key.
+//Caller must hold the lock.
+func (m *memoryStore) dropInvites(drop func(i *Invite) bool) {
+	dropped := make(map[int64]bool)
+	invites := []*Invite{}
+	for _, row := range m.invites {
+		if drop(row) {
+			dropped[row.ID] = true
+			continue
+		}
+		invites = append(invites, row)
+	}
+	m.invites = invites
+
+	redemptions := []*InviteRedemption{}
+	for _, row := range m.inviteRedemptions {
+		if !dropped[row.InviteID] {
+			redemptions = append(redemptions, row)
+		}
+	}
+	m.inviteRedemptions = redemptions
+}
+
+//Get func fetches the invites of the challenge with their redemption stats, newest first
+func (r *memoryInviteRepository) Get(filter InviteFilter) ([]*Invite, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	inviteList := []*Invite{}
+	for i := len(r.invites) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(inviteList) == filter.Limit {
+			break
+		}
+		row := r.invites[i]
+		if (filter.ChallengeID != 0 && row.ChallengeID != filter.ChallengeID) || (filter.BeforeID != 0 && row.ID >= filter.BeforeID) {
+			continue
+		}
+
+		invite := *row
+		for _, redemption := range r.inviteRedemptions {
+			if redemption.InviteID != row.ID {
+				continue
+			}
+			if invite.LastRedeemedAt == nil || redemption.CreatedAt.After(*invite.LastRedeemedAt) {
+				redeemedAt := redemption.CreatedAt
+				invite.LastRedeemedAt = &redeemedAt
+			}
+			for _, post := range r.posts {
+				if post.ChallengeID == row.ChallengeID && post.UserID == redemption.UserID && post.DeletedAt == nil {
+					invite.Participants = invite.Participants + 1
+					break
+				}
+			}
+		}
+		inviteList = append(inviteList, &invite)
+	}
+
+	return inviteList, nil
+}
+
+//Redeem func joins the user to the challenge of the invite: an accepted challenge request from the inviter to the user is
+//made and the use counted, once per user and invite
+func (r *memoryInviteRepository) Redeem(code string, userID int64) (*ChallengeRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invite := r.inviteOf(NormalizeInviteCode(code))
+	if invite == nil {
+		return nil, NotFound(CodeInviteNotFound, "Invite not found")
+	}
+	challenge := r.challengeOf(invite.ChallengeID)
+	if challenge == nil {
+		return nil, NotFound(CodeInviteNotFound, "Invite not found")
+	}
+
+	now := time.Now()
+	if err := invite.redeemable(userID, now); err != nil {
+		return nil, err
+	}
+	if err := challenge.acceptsInvites(now); err != nil {
+		return nil, err
+	}
+
+	for _, row := range r.inviteRedemptions {
+		if row.InviteID == invite.ID && row.UserID == userID {
+			return nil, Conflict(CodeInviteRedeemed, "Invite already redeemed")
+		}
+	}
+
+	challengeRequest := ChallengeRequest{ID: r.nextID("challenge_requests"), FromID: invite.InviterID, ToID: userID, ChallengeID: invite.ChallengeID, Status: "accepted", CreatedAt: now}
+	row := challengeRequest
+	r.challengeRequests = append(r.challengeRequests, &row)
+
+	r.inviteRedemptions = append(r.inviteRedemptions, &InviteRedemption{ID: r.nextID("invite_redemptions"), InviteID: invite.ID, UserID: userID, ChallengeRequestID: challengeRequest.ID, CreatedAt: now})
+	invite.Uses = invite.Uses + 1
+
+	return &challengeRequest, nil
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"time"
+)
+
+//memoryJobRunRepository struct is the in-memory implementation of JobRunRepository. Its locks only guard one process.
+type memoryJobRunRepository struct {
+	*memoryStore
+}
+
+//Lock func takes the lock of the job without waiting, ok is false when it is held
+func (r *memoryJobRunRepository) Lock(name string) (func(), bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.jobLocks[name] {
+		return nil, false, nil
+	}
+	r.jobLocks[name] = true
+
+	unlock := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.jobLocks, name)
+	}
+	return unlock, true, nil
+}
+
+//Start func inserts the run of a job which just started
+func (r *memoryJobRunRepository) Start(run *JobRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run.ID = r.nextID("job_runs")
+	run.StartedAt = time.Now()
+	row := *run
+	r.jobRuns = append(r.jobRuns, &row)
+
+	return nil
+}
+
+//Finish func records the end of the run and its error, if any
+func (r *memoryJobRunRepository) Finish(run *JobRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	run.FinishedAt = &now
+	for _, row := range r.jobRuns {
+		if row.ID == run.ID {
+			row.FinishedAt = &now
+			row.Error = run.Error
+		}
+	}
+
+	return nil
+}
+
+//Get func fetches the runs passing the filter, newest first
+func (r *memoryJobRunRepository) Get(filter JobRunFilter) ([]*JobRun, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	runList := []*JobRun{}
+	for i := len(r.jobRuns) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(runList) == filter.Limit {
+			break
+		}
+		row := r.jobRuns[i]
+		if (filter.Name == "" || row.Name == filter.Name) && (!filter.Failed || row.Error != "") {
+			run := *row
+			runList = append(runList, &run)
+		}
+	}
+
+	return runList, nil
+}
+
+//Prune func deletes the runs started before the given time
+func (r *memoryJobRunRepository) Prune(before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pruned int64
+	jobRuns := []*JobRun{}
+	for _, row := range r.jobRuns {
+		if row.StartedAt.Before(before) {
+			pruned = pruned + 1
+			continue
+		}
+		jobRuns = append(jobRuns, row)
+	}
+	r.jobRuns = jobRuns
+
+	return pruned, nil
+}
@@ -0,0 +1,83 @@
+package model
+
+import "sort"
+
+//memoryLeaderboardRepository struct is the in-memory implementation of LeaderboardRepository
+type memoryLeaderboardRepository struct {
+	*memoryStore
+}
+
+//Get func ranks every participant of the challenge, best first
+func (r *memoryLeaderboardRepository) Get(challengeID int64) ([]*LeaderboardEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.challengeOf(challengeID) == nil {
+		return nil, NotFound(CodeChallengeNotFound, "Challenge not found")
+	}
+
+	entries := make(map[int64]*LeaderboardEntry)
+	for _, row := range r.posts {
+		if row.ChallengeID != challengeID || row.DeletedAt != nil {
+			continue
+		}
+
+		entry, ok := entries[row.UserID]
+		if !ok {
+			entry = &LeaderboardEntry{UserID: row.UserID}
+			entries[row.UserID] = entry
+		}
+		entry.Likes = entry.Likes + int64(len(row.Likes))
+		if row.CompletedAt != nil && row.CreatedAt != nil {
+			completedIn := row.CompletedAt.Sub(*row.CreatedAt).Seconds()
+			if entry.CompletedIn == nil || completedIn < *entry.CompletedIn {
+				entry.CompletedIn = &completedIn
+			}
+		}
+	}
+
+	entryList := []*LeaderboardEntry{}
+	for _, user := range r.users {
+		if entry, ok := entries[user.ID]; ok && user.DeletedAt == nil {
+			entry.Name = user.Name
+			entryList = append(entryList, entry)
+		}
+	}
+
+	sort.Slice(entryList, func(i, j int) bool {
+		if c := compareEntries(entryList[i], entryList[j]); c != 0 {
+			return c < 0
+		}
+		return entryList[i].UserID < entryList[j].UserID
+	})
+	for i, entry := range entryList {
+		entry.Rank = i + 1
+		if i > 0 && compareEntries(entryList[i-1], entry) == 0 {
+			entry.Rank = entryList[i-1].Rank
+		}
+	}
+
+	return entryList, nil
+}
+
+//compareEntries func orders two entries by likes desc then time to complete asc, the ones which never completed last
+func compareEntries(a, b *LeaderboardEntry) int {
+	switch {
+	case a.Likes != b.Likes:
+		if a.Likes > b.Likes {
+			return -1
+		}
+		return 1
+	case a.CompletedIn == nil && b.CompletedIn == nil:
+		return 0
+	case a.CompletedIn == nil:
+		return 1
+	case b.CompletedIn == nil:
+		return -1
+	case *a.CompletedIn < *b.CompletedIn:
+		return -1
+	case *a.CompletedIn > *b.CompletedIn:
+		return 1
+	}
+	return 0
+}
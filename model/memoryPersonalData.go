@@ -0,0 +1,329 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+//memoryPersonalDataRepository struct is the in-memory implementation of PersonalDataRepository
+type memoryPersonalDataRepository struct {
+	*memoryStore
+}
+
+//Export func collects the rows of the user from every table
+func (r *memoryPersonalDataRepository) Export(userID int64) (*PersonalData, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := []*User{}
+	for _, row := range r.users {
+		if row.ID == userID && row.DeletedAt == nil {
+			users = append(users, row)
+		}
+	}
+	if len(users) == 0 {
+		return nil, NotFound(CodeUserNotFound, "User not found")
+	}
+
+	identities := []*UserIdentity{}
+	for _, row := range r.userIdentities {
+		if row.UserID == userID {
+			identities = append(identities, row)
+		}
+	}
+
+	scores := []*Score{}
+	for _, row := range r.scores {
+		if row.UserID == userID {
+			scores = append(scores, row)
+		}
+	}
+
+	challenges := []*Challenge{}
+	for _, row := range r.challenges {
+		if row.UserID == userID {
+			challenges = append(challenges, row)
+		}
+	}
+
+	posts := []*Post{}
+	likes := []map[string]interface{}{}
+	flags := []map[string]interface{}{}
+	for _, row := range r.posts {
+		if row.UserID == userID {
+			posts = append(posts, row)
+		}
+		for _, like := range row.Likes {
+			if like.UserID == userID {
+				likes = append(likes, map[string]interface{}{"id": like.ID, "post_id": row.ID, "created_at": like.CreatedAt})
+			}
+		}
+		for _, flag := range row.Flags {
+			if flag.UserID == userID {
+				flags = append(flags, map[string]interface{}{"id": flag.ID, "post_id": row.ID, "created_at": flag.CreatedAt})
+			}
+		}
+	}
+
+	completions := []*Completion{}
+	for _, row := range r.completions {
+		if row.UserID == userID {
+			completions = append(completions, row)
+		}
+	}
+
+	invites := []*Invite{}
+	for _, row := range r.invites {
+		if row.InviterID == userID {
+			invites = append(invites, row)
+		}
+	}
+
+	redemptions := []*InviteRedemption{}
+	for _, row := range r.inviteRedemptions {
+		if row.UserID == userID {
+			redemptions = append(redemptions, row)
+		}
+	}
+
+	boughtItems := []*BoughtItem{}
+	for _, row := range r.boughtItems {
+		if row.UserID == userID {
+			boughtItems = append(boughtItems, row)
+		}
+	}
+
+	challengeRequests := []*ChallengeRequest{}
+	for _, row := range r.challengeRequests {
+		if row.FromID == userID || row.ToID == userID {
+			challengeRequests = append(challengeRequests, row)
+		}
+	}
+
+	oneSignals := []*OneSignal{}
+	for _, row := range r.oneSignals {
+		if row.UserID == userID {
+			oneSignals = append(oneSignals, row)
+		}
+	}
+
+	sessions := []*RefreshToken{}
+	for _, row := range r.refreshTokens {
+		if row.UserID == userID {
+			sessions = append(sessions, row)
+		}
+	}
+
+	data := PersonalData{UserID: userID, Media: r.media(userID)}
+	for _, table := range []struct {
+		name string
+		rows interface{}
+	}{
+		{"users", users},
+		{"user_identities", identities},
+		{"scores", scores},
+		{"challenges", challenges},
+		{"posts", posts},
+		{"likes", likes},
+		{"flags", flags},
+		{"challenge_completions", completions},
+		{"bought_items", boughtItems},
+		{"challenge_requests", challengeRequests},
+		{"invites", invites},
+		{"invite_redemptions", redemptions},
+		{"group_challenge_requests", []interface{}{}},
+		{"onesignal", oneSignals},
+		{"sessions", sessions},
+	} {
+		rows, err := json.Marshal(table.rows)
+		if err != nil {
+			return nil, err
+		}
+		data.Tables = append(data.Tables, PersonalDataTable{Name: table.name, Rows: rows})
+	}
+
+	return &data, nil
+}
+
+//Media func returns the urls of the files the user uploaded, deleted posts included
+func (r *memoryPersonalDataRepository) Media(userID int64) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.media(userID), nil
+}
+
+//media func returns the file urls of the posts of the user. Caller must hold the lock.
+func (r *memoryPersonalDataRepository) media(userID int64) []string {
+	urls := []string{}
+	for _, row := range r.posts {
+		if row.UserID == userID && row.FileURL != "" {
+			urls = append(urls, row.FileURL)
+		}
+	}
+	return urls
+}
+
+//findUser func returns the not deleted user row. Caller must hold the lock.
+func (r *memoryPersonalDataRepository) findUser(userID int64) (*User, error) {
+	for _, row := range r.users {
+		if row.ID == userID && row.DeletedAt == nil {
+			return row, nil
+		}
+	}
+	return nil, NotFound(CodeUserNotFound, "User not found")
+}
+
+//ScheduleDeletion func marks the user for erasure at due. Asking again keeps the first schedule.
+func (r *memoryPersonalDataRepository) ScheduleDeletion(u *User, due time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row, err := r.findUser(u.ID)
+	if err != nil {
+		return err
+	}
+
+	if row.DeletionDueAt == nil {
+		row.DeletionDueAt = &due
+	}
+	u.DeletionDueAt = row.DeletionDueAt
+
+	return nil
+}
+
+//CancelDeletion func removes the pending deletion of the user
+func (r *memoryPersonalDataRepository) CancelDeletion(u *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row, err := r.findUser(u.ID)
+	if err != nil {
+		return err
+	}
+
+	if row.DeletionDueAt == nil {
+		return NotFound(CodeNotFound, "No pending deletion")
+	}
+	row.DeletionDueAt = nil
+	u.DeletionDueAt = nil
+
+	return nil
+}
+
+//DueDeletions func returns the ids of the users whose grace period is over
+func (r *memoryPersonalDataRepository) DueDeletions(now time.Time) ([]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := []int64{}
+	for _, row := range r.users {
+		if row.DeletionDueAt != nil && !row.DeletionDueAt.After(now) {
+			ids = append(ids, row.ID)
+		}
+	}
+	return ids, nil
+}
+
+//Erase func deletes the rows of the user from every table and anonymizes the user row
+func (r *memoryPersonalDataRepository) Erase(userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	posts := []*Post{}
+	for _, row := range r.posts {
+		if row.UserID == userID {
+			continue
+		}
+
+		likes := []*Like{}
+		for _, like := range row.Likes {
+			if like.UserID != userID {
+				likes = append(likes, like)
+			}
+		}
+		row.Likes = likes
+
+		flags := []*Flag{}
+		for _, flag := range row.Flags {
+			if flag.UserID != userID {
+				flags = append(flags, flag)
+			}
+		}
+		row.Flags = flags
+
+		posts = append(posts, row)
+	}
+	r.posts = posts
+
+	completions := []*Completion{}
+	for _, row := range r.completions {
+		if row.UserID != userID {
+			completions = append(completions, row)
+		}
+	}
+	r.completions = completions
+
+	r.dropInvites(func(i *Invite) bool { return i.InviterID == userID })
+	redemptions := []*InviteRedemption{}
+	for _, row := range r.inviteRedemptions {
+		if row.UserID != userID {
+			redemptions = append(redemptions, row)
+		}
+	}
+	r.inviteRedemptions = redemptions
+
+	scores := []*Score{}
+	for _, row := range r.scores {
+		if row.UserID != userID {
+			scores = append(scores, row)
+		}
+	}
+	r.scores = scores
+
+	challengeRequests := []*ChallengeRequest{}
+	for _, row := range r.challengeRequests {
+		if row.FromID != userID && row.ToID != userID {
+			challengeRequests = append(challengeRequests, row)
+		}
+	}
+	r.challengeRequests = challengeRequests
+
+	oneSignals := []*OneSignal{}
+	for _, row := range r.oneSignals {
+		if row.UserID != userID {
+			oneSignals = append(oneSignals, row)
+		}
+	}
+	r.oneSignals = oneSignals
+
+	refreshTokens := []*RefreshToken{}
+	for _, row := range r.refreshTokens {
+		if row.UserID != userID {
+			refreshTokens = append(refreshTokens, row)
+		}
+	}
+	r.refreshTokens = refreshTokens
+
+	identities := []*UserIdentity{}
+	for _, row := range r.userIdentities {
+		if row.UserID != userID {
+			identities = append(identities, row)
+		}
+	}
+	r.userIdentities = identities
+
+	now := time.Now()
+	for _, row := range r.users {
+		if row.ID == userID {
+			row.Name, row.Email, row.FacebookUserID, row.Gender, row.DOB = "", "", "", "", ""
+			row.Location = nil
+			row.DeletionDueAt = nil
+			if row.DeletedAt == nil {
+				row.DeletedAt = &now
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,289 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/challengr/config"
+)
+
+//memoryPostRepository struct is the in-memory implementation of PostRepository
+type memoryPostRepository struct {
+	*memoryStore
+}
+
+//match func checks if the post row passes the filter
+func (r *memoryPostRepository) match(p *Post, filter PostFilter) bool {
+	return p.DeletedAt == nil &&
+		(filter.ID == 0 || p.ID == filter.ID) &&
+		(filter.ChallengeID == 0 || p.ChallengeID == filter.ChallengeID) &&
+		(filter.UserID == 0 || p.UserID == filter.UserID) &&
+		p.ID > filter.LastID
+}
+
+//find func returns the single post passing the filter. Caller must hold the lock.
+func (r *memoryPostRepository) find(filter PostFilter) (*Post, error) {
+	for _, row := range r.posts {
+		if r.match(row, filter) {
+			return row, nil
+		}
+	}
+
+	return nil, NotFound(CodePostNotFound, "Post not found")
+}
+
+//Create func inserts a new post
+func (r *memoryPostRepository) Create(p *Post) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	challenge := r.challengeOf(p.ChallengeID)
+	if challenge == nil {
+		return NotFound(CodeChallengeNotFound, "Challenge not found")
+	}
+	if err := challenge.acceptsPosts(now); err != nil {
+		return err
+	}
+
+	if challenge.Rules != nil {
+		entered := false
+		for _, row := range r.posts {
+			if row.ChallengeID == p.ChallengeID && row.UserID == p.UserID && row.DeletedAt == nil {
+				entered = true
+			}
+		}
+
+		var distance *float64
+		if p.Location != nil && challenge.Location != nil && len(p.Location.Coordinates) >= 2 {
+			d := challenge.Location.distance(p.Location.Coordinates[0], p.Location.Coordinates[1])
+			distance = &d
+		}
+
+		if err := ruleViolation(challenge.Rules.violations(p, now, entered, distance)); err != nil {
+			return err
+		}
+	}
+
+	p.LikesNeeded = challenge.postLikesNeeded()
+	p.ID = r.nextID("posts")
+	p.CreatedAt = &now
+	row := *p
+	row.Likes = []*Like{}
+	row.Flags = []*Flag{}
+	r.posts = append(r.posts, &row)
+
+	return nil
+}
+
+//Get func fetches the posts passing the filter, newest first
+func (r *memoryPostRepository) Get(filter PostFilter) ([]*Post, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	postList := []*Post{}
+	for i := len(r.posts) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(postList) == filter.Limit {
+			break
+		}
+		if r.match(r.posts[i], filter) {
+			post := *r.posts[i]
+			post.Likes = append([]*Like{}, post.Likes...)
+			post.Flags = append([]*Flag{}, post.Flags...)
+			postList = append(postList, &post)
+		}
+	}
+
+	return postList, nil
+}
+
+//Count func counts the posts passing the filter
+func (r *memoryPostRepository) Count(filter PostFilter) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, row := range r.posts {
+		if r.match(row, filter) {
+			count = count + 1
+		}
+	}
+
+	return count, nil
+}
+
+//Flag func flags the post once per user
+func (r *memoryPostRepository) Flag(p *Post, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row, err := r.find(PostFilter{ID: p.ID, ChallengeID: p.ChallengeID})
+	if err != nil {
+		return err
+	}
+
+	for _, flag := range row.Flags {
+		if flag.UserID == userID {
+			return nil
+		}
+	}
+	row.Flags = append(row.Flags, &Flag{UserID: userID, PostID: row.ID, CreatedAt: time.Now()})
+
+	return nil
+}
+
+//UnFlag func removes the flag of the user from the post
+func (r *memoryPostRepository) UnFlag(p *Post, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row, err := r.find(PostFilter{ID: p.ID, ChallengeID: p.ChallengeID})
+	if err != nil {
+		return err
+	}
+
+	flags := []*Flag{}
+	for _, flag := range row.Flags {
+		if flag.UserID != userID {
+			flags = append(flags, flag)
+		}
+	}
+	row.Flags = flags
+
+	return nil
+}
+
+//Like func likes the post of another user once per user. The like which brings the post to the likes_needed_per_post of
+//its challenge completes it.
+func (r *memoryPostRepository) Like(p *Post, userID int64, rewards config.Rewards) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row, err := r.find(PostFilter{ID: p.ID, ChallengeID: p.ChallengeID})
+	if err != nil {
+		return err
+	}
+
+	if row.UserID == userID {
+		return Forbidden(CodeNotAllowed, "Own posts can not be liked")
+	}
+
+	for _, like := range row.Likes {
+		if like.UserID == userID {
+			return nil
+		}
+	}
+	now := time.Now()
+	row.Likes = append(row.Likes, &Like{ID: r.nextID("likes"), UserID: userID, CreatedAt: now})
+
+	likesNeeded := 1
+	for _, challenge := range r.challenges {
+		if challenge.ID == row.ChallengeID {
+			likesNeeded = challenge.postLikesNeeded()
+		}
+	}
+	if row.CompletedAt == nil && len(row.Likes) >= likesNeeded {
+		r.complete(row, rewards, now)
+	}
+
+	return nil
+}
+
+//complete func marks the post completed. On the first completion of its owner in the challenge the completion event is
+//recorded and the reward credited to the score. Caller must hold the lock.
+func (r *memoryPostRepository) complete(post *Post, rewards config.Rewards, now time.Time) {
+	completedAt := now
+	post.CompletedAt = &completedAt
+
+	for _, row := range r.completions {
+		if row.ChallengeID == post.ChallengeID && row.UserID == post.UserID {
+			return
+		}
+	}
+
+	challengeWeight, userWeight := 1.0, 1.0
+	for _, row := range r.challenges {
+		if row.ID == post.ChallengeID && row.Weight != nil {
+			challengeWeight = float64(*row.Weight)
+		}
+	}
+	for _, row := range r.users {
+		if row.ID == post.UserID && row.Weight != nil {
+			userWeight = float64(*row.Weight)
+		}
+	}
+
+	completion := completionReward(rewards, challengeWeight, userWeight)
+	completion.ID = r.nextID("challenge_completions")
+	completion.ChallengeID = post.ChallengeID
+	completion.UserID = post.UserID
+	completion.PostID = post.ID
+	completion.CreatedAt = now
+	r.completions = append(r.completions, &completion)
+
+	for _, row := range r.scores {
+		if row.UserID == post.UserID {
+			row.Exp = row.Exp + completion.Exp
+			row.Coins = row.Coins + completion.Coins
+			row.UpdatedAt = &completedAt
+		}
+	}
+}
+
+//delete func hides the post. The ownerID is checked only when it is bigger than zero.
+func (r *memoryPostRepository) delete(p *Post, ownerID int64, e *AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row, err := r.find(PostFilter{ID: p.ID, ChallengeID: p.ChallengeID, UserID: ownerID})
+	if err != nil {
+		return err
+	}
+
+	before, _ := json.Marshal(row)
+	now := time.Now()
+	row.DeletedAt = &now
+	r.audit(e, auditPost, row.ID, before, row)
+
+	return nil
+}
+
+//Delete func hides the post of its owner
+func (r *memoryPostRepository) Delete(p *Post) error {
+	return r.delete(p, p.UserID, nil)
+}
+
+//AdminDelete func hides any post, recording the audit event
+func (r *memoryPostRepository) AdminDelete(p *Post, e *AuditEvent) error {
+	return r.delete(p, 0, e)
+}
+
+//Purge func removes the posts deleted before the given time for good and returns their file urls
+func (r *memoryPostRepository) Purge(before time.Time) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	urls := []string{}
+	purged := make(map[int64]bool)
+	posts := []*Post{}
+	for _, row := range r.posts {
+		if row.DeletedAt != nil && row.DeletedAt.Before(before) {
+			purged[row.ID] = true
+			if row.FileURL != "" {
+				urls = append(urls, row.FileURL)
+			}
+			continue
+		}
+		posts = append(posts, row)
+	}
+	r.posts = posts
+
+	//like the foreign key, the completions outlive their purged posts
+	for _, row := range r.completions {
+		if purged[row.PostID] {
+			row.PostID = 0
+		}
+	}
+
+	return urls, nil
+}
@@ -0,0 +1,162 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+//memoryRefreshTokenRepository struct is the in-memory implementation of RefreshTokenRepository
+type memoryRefreshTokenRepository struct {
+	*memoryStore
+}
+
+//Create func inserts a new refresh token
+func (r *memoryRefreshTokenRepository) Create(t *RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, row := range r.refreshTokens {
+		if row.TokenHash == t.TokenHash {
+			return Conflict(CodeConflict, "Refresh token already exists")
+		}
+	}
+
+	t.ID = r.nextID("refresh_tokens")
+	t.CreatedAt = time.Now()
+	row := *t
+	r.refreshTokens = append(r.refreshTokens, &row)
+
+	return nil
+}
+
+//GetByHash func fetches the refresh token stored under the hash
+func (r *memoryRefreshTokenRepository) GetByHash(hash string) (*RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, row := range r.refreshTokens {
+		if row.TokenHash == hash {
+			t := *row
+			return &t, nil
+		}
+	}
+
+	return nil, NotFound(CodeInvalidToken, "Refresh token not found")
+}
+
+//Rotate func marks old as rotated and inserts next in the same family
+func (r *memoryRefreshTokenRepository) Rotate(old, next *RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var current *RefreshToken
+	for _, row := range r.refreshTokens {
+		if row.ID == old.ID {
+			current = row
+		}
+	}
+	if current == nil || current.RotatedAt != nil || current.RevokedAt != nil {
+		return Forbidden(CodeTokenReused, "Refresh token already used")
+	}
+
+	now := time.Now()
+	current.RotatedAt = &now
+	old.RotatedAt = &now
+
+	next.ID = r.nextID("refresh_tokens")
+	next.UserID = old.UserID
+	next.Imei = old.Imei
+	next.FamilyID = old.FamilyID
+	next.CreatedAt = now
+	row := *next
+	r.refreshTokens = append(r.refreshTokens, &row)
+
+	return nil
+}
+
+//revoke func revokes the tokens matching the condition. Caller must hold the lock.
+func (r *memoryRefreshTokenRepository) revoke(match func(row *RefreshToken) bool) {
+	now := time.Now()
+	for _, row := range r.refreshTokens {
+		if row.RevokedAt == nil && match(row) {
+			revokedAt := now
+			row.RevokedAt = &revokedAt
+		}
+	}
+}
+
+//RevokeFamily func revokes every token of the session
+func (r *memoryRefreshTokenRepository) RevokeFamily(familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revoke(func(row *RefreshToken) bool { return row.FamilyID == familyID })
+	return nil
+}
+
+//RevokeDevice func revokes every session of the device of the user
+func (r *memoryRefreshTokenRepository) RevokeDevice(userID int64, imei string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revoke(func(row *RefreshToken) bool { return row.UserID == userID && row.Imei == imei })
+	return nil
+}
+
+//RevokeUser func revokes every session of the user
+func (r *memoryRefreshTokenRepository) RevokeUser(userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revoke(func(row *RefreshToken) bool { return row.UserID == userID })
+	return nil
+}
+
+//Revoked func tells whether the session is revoked. An unknown session counts as revoked.
+func (r *memoryRefreshTokenRepository) Revoked(familyID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, row := range r.refreshTokens {
+		if row.FamilyID == familyID && row.RevokedAt == nil {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+//Sessions func lists the devices of the user with an active session, the ones signed in most recently first
+func (r *memoryRefreshTokenRepository) Sessions(userID int64) ([]*Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	sessionList := []*Session{}
+	for _, row := range r.refreshTokens {
+		if row.UserID != userID || row.RevokedAt != nil || row.RotatedAt != nil || !now.Before(row.ExpiresAt) {
+			continue
+		}
+
+		lastSeenAt := row.CreatedAt
+		session := Session{UserID: row.UserID, Imei: row.Imei, SignedInAt: row.CreatedAt, ExpiresAt: row.ExpiresAt, LastSeenAt: &lastSeenAt}
+		for _, token := range r.refreshTokens {
+			if token.FamilyID == row.FamilyID && token.CreatedAt.Before(session.SignedInAt) {
+				session.SignedInAt = token.CreatedAt
+			}
+		}
+		for _, oneSignal := range r.oneSignals {
+			if oneSignal.UserID == row.UserID && oneSignal.Imei == row.Imei {
+				session.PlayerID = oneSignal.PlayerID
+				if oneSignal.LastSeenAt != nil {
+					session.LastSeenAt = oneSignal.LastSeenAt
+				}
+			}
+		}
+		sessionList = append(sessionList, &session)
+	}
+
+	sort.SliceStable(sessionList, func(i, j int) bool { return sessionList[i].SignedInAt.After(sessionList[j].SignedInAt) })
+
+	return sessionList, nil
+}
@@ -0,0 +1,113 @@
+package model
+
+import (
+	"html"
+	"sort"
+	"strings"
+)
+
+//memorySearchRepository struct is the in-memory implementation of SearchRepository. It matches the words of the query as
+//case insensitive substrings and ranks by the number of matches, names counting twice.
+type memorySearchRepository struct {
+	*memoryStore
+}
+
+//countWords func returns how many of the words are found in the text
+func countWords(text string, words []string) int {
+	text = strings.ToLower(text)
+	count := 0
+	for _, word := range words {
+		if strings.Contains(text, word) {
+			count++
+		}
+	}
+	return count
+}
+
+//highlight func escapes the text and wraps the occurrences of the words in <mark> tags
+func highlight(text string, words []string) string {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		return html.EscapeString(text)
+	}
+	marked := make([]bool, len(text))
+	for _, word := range words {
+		for i := 0; ; {
+			j := strings.Index(lower[i:], word)
+			if j < 0 {
+				break
+			}
+			for k := i + j; k < i+j+len(word); k++ {
+				marked[k] = true
+			}
+			i = i + j + len(word)
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		j := i
+		for j < len(text) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString("<mark>" + html.EscapeString(text[i:j]) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(text[i:j]))
+		}
+		i = j
+	}
+	return b.String()
+}
+
+//Search func fetches the results of the query, best match first
+func (r *memorySearchRepository) Search(filter SearchFilter) ([]*SearchResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	words := strings.Fields(strings.ToLower(filter.Query))
+	resultList := []*SearchResult{}
+
+	if filter.Type == "" || filter.Type == SearchChallenge {
+		for _, row := range r.challenges {
+			if row.DeletedAt != nil || (row.Status != ChallengeScheduled && row.Status != ChallengeActive && row.Status != ChallengeClosed) {
+				continue
+			}
+			text := row.Name
+			if row.Description != nil {
+				text = text + " — " + *row.Description
+			}
+			rank := countWords(row.Name, words) + countWords(text, words)
+			if rank > 0 {
+				resultList = append(resultList, &SearchResult{Type: SearchChallenge, ID: row.ID, Title: row.Name, Snippet: highlight(text, words), Rank: float64(rank)})
+			}
+		}
+	}
+
+	if filter.Type == "" || filter.Type == SearchUser {
+		for _, row := range r.users {
+			if row.DeletedAt != nil {
+				continue
+			}
+			if rank := 2 * countWords(row.Name, words); rank > 0 {
+				resultList = append(resultList, &SearchResult{Type: SearchUser, ID: row.ID, Title: row.Name, Snippet: highlight(row.Name, words), Rank: float64(rank)})
+			}
+		}
+	}
+
+	sort.Slice(resultList, func(i, j int) bool {
+		c := SearchCursor{Rank: resultList[i].Rank, Type: resultList[i].Type, ID: resultList[i].ID}
+		return c.after(resultList[j])
+	})
+
+	if filter.Cursor != nil {
+		for len(resultList) > 0 && !filter.Cursor.after(resultList[0]) {
+			resultList = resultList[1:]
+		}
+	}
+	if filter.Limit > 0 && len(resultList) > filter.Limit {
+		resultList = resultList[:filter.Limit]
+	}
+
+	return resultList, nil
+}
@@ -0,0 +1,227 @@
+package model
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+//memoryUserRepository struct is the in-memory implementation of UserRepository
+type memoryUserRepository struct {
+	*memoryStore
+}
+
+//match func checks if the user row passes the filter
+func (r *memoryUserRepository) match(u *User, filter UserFilter) bool {
+	if u.DeletedAt != nil {
+		return false
+	}
+
+	if len(filter.IDs) > 0 {
+		found := false
+		for _, id := range filter.IDs {
+			found = found || u.ID == id
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(filter.FacebookUserIDs) > 0 {
+		found := false
+		for _, fbID := range filter.FacebookUserIDs {
+			found = found || u.FacebookUserID == fbID
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if u.ID <= filter.LastID {
+		return false
+	}
+
+	if filter.Near != nil {
+		if u.Location == nil || len(u.Location.Coordinates) < 2 {
+			return false
+		}
+		if distanceSphere(u.Location.Coordinates[0], u.Location.Coordinates[1], filter.Near.Long, filter.Near.Lat) > float64(filter.Near.Radius) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//Create func inserts a new user
+func (r *memoryUserRepository) Create(u *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.insert(u, time.Now())
+	return nil
+}
+
+//insert func inserts the user with the user role and the default weight. Caller must hold the lock.
+func (r *memoryUserRepository) insert(u *User, now time.Time) {
+	u.ID = r.nextID("users")
+	u.CreatedAt = &now
+	u.Role = roleUser
+	if u.Weight == nil {
+		weight := float32(1)
+		u.Weight = &weight
+	}
+
+	row := *u
+	r.users = append(r.users, &row)
+}
+
+//SignUp func inserts the user of a first login with its sign in identity and its score at once. When the identity is
+//linked already nothing is inserted and the error has the CodeIdentityInUse code.
+func (r *memoryUserRepository) SignUp(u *User, i *UserIdentity, s *Score) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, row := range r.userIdentities {
+		if row.Provider == i.Provider && row.Subject == i.Subject {
+			return Conflict(CodeIdentityInUse, "Identity already linked to a user")
+		}
+	}
+
+	now := time.Now()
+	r.insert(u, now)
+
+	i.ID = r.nextID("user_identities")
+	i.UserID = u.ID
+	i.CreatedAt = now
+	identity := *i
+	r.userIdentities = append(r.userIdentities, &identity)
+
+	s.ID = r.nextID("scores")
+	s.UserID = u.ID
+	s.CreatedAt = now
+	score := *s
+	r.scores = append(r.scores, &score)
+
+	return nil
+}
+
+//Get func fetches the users with their level, score, bought items and total posts
+func (r *memoryUserRepository) Get(filter UserFilter) ([]*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	userList := []*User{}
+	for _, row := range r.users {
+		if !r.match(row, filter) {
+			continue
+		}
+
+		user := *row
+		for _, post := range r.posts {
+			if post.UserID == user.ID && post.DeletedAt == nil {
+				user.TotalPost = user.TotalPost + 1
+			}
+		}
+		for _, level := range r.levels {
+			if level.ID == user.LevelID {
+				l := *level
+				user.Level = &l
+			}
+		}
+		for _, score := range r.scores {
+			if score.UserID == user.ID {
+				s := *score
+				user.Score = &s
+			}
+		}
+		user.BoughtItems = r.boughtItemsOf(user.ID)
+
+		userList = append(userList, &user)
+	}
+
+	sort.SliceStable(userList, func(i, j int) bool { return userList[i].LevelID > userList[j].LevelID })
+	if filter.Limit > 0 && len(userList) > filter.Limit {
+		userList = userList[:filter.Limit]
+	}
+
+	return userList, nil
+}
+
+//Count func counts the users passing the filter
+func (r *memoryUserRepository) Count(filter UserFilter) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, row := range r.users {
+		if r.match(row, filter) {
+			count = count + 1
+		}
+	}
+
+	return count, nil
+}
+
+//Update func updates the non empty fields of the user. With an audit event the change is recorded.
+func (r *memoryUserRepository) Update(u *User, e *AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, row := range r.users {
+		if row.ID != u.ID || row.DeletedAt != nil {
+			continue
+		}
+		before, _ := json.Marshal(row)
+
+		if u.Name != "" {
+			row.Name = u.Name
+		}
+		if u.Email != "" {
+			row.Email = u.Email
+		}
+		if u.FacebookUserID != "" {
+			row.FacebookUserID = u.FacebookUserID
+		}
+		if u.LevelID != 0 {
+			row.LevelID = u.LevelID
+		}
+		if u.Role != "" {
+			row.Role = u.Role
+		}
+		if u.Gender != "" {
+			row.Gender = u.Gender
+		}
+		if u.DOB != "" {
+			row.DOB = u.DOB
+		}
+		if u.Weight != nil {
+			weight := *u.Weight
+			row.Weight = &weight
+		}
+		if u.UpdatedAt != nil {
+			row.UpdatedAt = u.UpdatedAt
+		}
+
+		r.audit(e, auditUser, row.ID, before, row)
+		return nil
+	}
+
+	return NotFound(CodeUserNotFound, "User not found")
+}
+
+//Delete func hides the user
+func (r *memoryUserRepository) Delete(u *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, row := range r.users {
+		if row.ID == u.ID && row.DeletedAt == nil {
+			now := time.Now()
+			row.DeletedAt = &now
+			return nil
+		}
+	}
+
+	return NotFound(CodeUserNotFound, "User account not found")
+}
@@ -0,0 +1,88 @@
+package model
+
+import "time"
+
+//memoryUserIdentityRepository struct is the in-memory implementation of UserIdentityRepository
+type memoryUserIdentityRepository struct {
+	*memoryStore
+}
+
+//setFacebookUserID func keeps the facebook_user_id of the user in sync. Caller must hold the lock.
+func (r *memoryUserIdentityRepository) setFacebookUserID(userID int64, facebookUserID string) {
+	for _, row := range r.users {
+		if row.ID == userID {
+			row.FacebookUserID = facebookUserID
+		}
+	}
+}
+
+//Link func links the identity to its user
+func (r *memoryUserIdentityRepository) Link(i *UserIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, row := range r.userIdentities {
+		if row.Provider == i.Provider && row.Subject == i.Subject {
+			return Conflict(CodeIdentityInUse, "Identity already linked to a user")
+		}
+	}
+
+	i.ID = r.nextID("user_identities")
+	i.CreatedAt = time.Now()
+	row := *i
+	r.userIdentities = append(r.userIdentities, &row)
+
+	if i.Provider == providerFacebook {
+		r.setFacebookUserID(i.UserID, i.Subject)
+	}
+
+	return nil
+}
+
+//Get func fetches the identities passing the filter
+func (r *memoryUserIdentityRepository) Get(filter UserIdentityFilter) ([]*UserIdentity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	identityList := []*UserIdentity{}
+	for _, row := range r.userIdentities {
+		if (filter.UserID == 0 || row.UserID == filter.UserID) && (filter.Provider == "" || row.Provider == filter.Provider) && (filter.Subject == "" || row.Subject == filter.Subject) {
+			identity := *row
+			identityList = append(identityList, &identity)
+		}
+	}
+
+	return identityList, nil
+}
+
+//Unlink func removes the identity of the provider from the user, unless it is the last one
+func (r *memoryUserIdentityRepository) Unlink(i *UserIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index, others := -1, 0
+	for j, row := range r.userIdentities {
+		if row.UserID != i.UserID {
+			continue
+		}
+		if row.Provider == i.Provider {
+			index = j
+		} else {
+			others++
+		}
+	}
+
+	if others == 0 {
+		return Forbidden(CodeLastIdentity, "The last sign in method can not be unlinked")
+	}
+	if index < 0 {
+		return NotFound(CodeNotFound, "Identity not found")
+	}
+
+	r.userIdentities = append(r.userIdentities[:index], r.userIdentities[index+1:]...)
+	if i.Provider == providerFacebook {
+		r.setFacebookUserID(i.UserID, "")
+	}
+
+	return nil
+}
@@ -1,47 +1,27 @@
 package model
 
 import (
-	"errors"
-	"fmt"
+	"database/sql"
 	"log"
 	"time"
 )
 
-//OneSignal struct is a model/schema for one_signal table
+//OneSignal struct is a model/schema for one_signal table. A row is the registration of a device of the user,
+//so the rows double as the device sessions of the user.
 type OneSignal struct {
-	ID        int64      `json:"id" sql:"id"`
-	UserID    int64      `json:"user_id" sql:"user_id"`
-	Imei      string     `json:"imei" sql:"imei"`
-	PlayerID  string     `json:"player_id" sql:"player_id"`
-	CreatedAt *time.Time `json:"created_at" sql:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at" sql:"updated_at"`
-
-	Payload map[string]interface{} `json:"-"`
+	ID         int64      `json:"id" sql:"id"`
+	UserID     int64      `json:"user_id" sql:"user_id"`
+	Imei       string     `json:"imei" sql:"imei" bind:"update"`
+	PlayerID   string     `json:"player_id" sql:"player_id" bind:"update"`
+	CreatedAt  *time.Time `json:"created_at" sql:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at" sql:"updated_at"`
+	LastSeenAt *time.Time `json:"last_seen_at" sql:"last_seen_at"`
 }
 
-//ParseNotAllowedJSON unmarshalls JSON payload to struct payload and fields. Plus parses the JSON payload.
-func (o *OneSignal) ParseNotAllowedJSON() []string {
+//Validate func validates a onesignal payload data. The user is taken from the token.
+func (o *OneSignal) Validate(op string) []string {
 	errSlice := []string{}
 
-	delete(o.Payload, "user_id")
-	delete(o.Payload, "imei")
-	delete(o.Payload, "player_id")
-
-	for key := range o.Payload {
-		errSlice = append(errSlice, key)
-	}
-
-	return errSlice
-}
-
-//Validate func validates a post payload data
-func (o *OneSignal) Validate() []string {
-	errSlice := []string{}
-
-	if o.UserID < 1 {
-		errSlice = append(errSlice, "user_id")
-	}
-
 	if o.Imei == "" {
 		errSlice = append(errSlice, "imei")
 	}
@@ -53,17 +33,24 @@ func (o *OneSignal) Validate() []string {
 	return errSlice
 }
 
+//pgOneSignalRepository struct is the postgres implementation of OneSignalRepository
+type pgOneSignalRepository struct {
+	db *sql.DB
+}
+
 //Get func fetches the onesignal records of a user from db
-func (o *OneSignal) Get(whereClause string, args ...interface{}) ([]*OneSignal, error) {
+func (r *pgOneSignalRepository) Get(filter OneSignalFilter) ([]*OneSignal, error) {
 	oneSignalList := []*OneSignal{}
-	rows, err := db.Query("SELECT id, user_id, imei, player_id, created_at, updated_at FROM users "+whereClause+" ORDER BY created_at DESC;", args...)
+	rows, err := r.db.Query("SELECT id, user_id, imei, player_id, created_at, updated_at, last_seen_at FROM onesignal WHERE user_id=$1 AND ($2='' OR imei=$2) ORDER BY created_at DESC;", filter.UserID, filter.Imei)
 	if err != nil {
-		log.Printf("Get users: sql error %v", err)
+		log.Printf("Get onesignal: sql error %v", err)
 		return nil, err
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		oneSignal := OneSignal{}
-		if err = rows.Scan(&oneSignal.ID, &oneSignal.UserID, &oneSignal.Imei, &oneSignal.PlayerID, &oneSignal.CreatedAt, &oneSignal.UpdatedAt); err != nil {
+		if err = rows.Scan(&oneSignal.ID, &oneSignal.UserID, &oneSignal.Imei, &oneSignal.PlayerID, &oneSignal.CreatedAt, &oneSignal.UpdatedAt, &oneSignal.LastSeenAt); err != nil {
 			log.Printf("scanning row to struct error: %v", err)
 			return nil, err
 		}
@@ -73,11 +60,11 @@ func (o *OneSignal) Get(whereClause string, args ...interface{}) ([]*OneSignal,
 	return oneSignalList, nil
 }
 
-//Count func counts the users from db
-func (o *OneSignal) Count(whereClause string, args ...interface{}) (int64, error) {
+//count func counts the onesignal records of the device of the user
+func (r *pgOneSignalRepository) count(o *OneSignal) (int64, error) {
 	var count int64
 
-	if err := db.QueryRow("SELECT COUNT(id) FROM onesignal "+whereClause+";", args...).Scan(&count); err != nil {
+	if err := r.db.QueryRow("SELECT COUNT(id) FROM onesignal WHERE user_id=$1 AND imei=$2;", o.UserID, o.Imei).Scan(&count); err != nil {
 		log.Printf("Count onesignal: sql error %v", err)
 		return count, err
 	}
@@ -86,8 +73,8 @@ func (o *OneSignal) Count(whereClause string, args ...interface{}) (int64, error
 }
 
 //Upsert func inserts or updates the onesignal info in the db of the user
-func (o *OneSignal) Upsert() error {
-	count, err := o.Count("WHERE user_id=$1 AND imei=$2", o.UserID, o.Imei)
+func (r *pgOneSignalRepository) Upsert(o *OneSignal) error {
+	count, err := r.count(o)
 	if err != nil {
 		log.Printf("Onesignal upsert: error on fetching onesignal record counts: %v", err)
 		return err
@@ -97,43 +84,24 @@ func (o *OneSignal) Upsert() error {
 
 	if count == 0 {
 		o.CreatedAt = &now
-		stmt, err := db.Prepare("INSERT INTO onesignal(user_id, imei, player_id, created_at) VALUES($1,$2,$3,$4);")
-		if err != nil {
-			log.Printf("create prepare statement error: %v", err)
-			return err
-		}
-
-		res, err := stmt.Exec(o.UserID, o.Imei, o.PlayerID, o.CreatedAt)
-		if err != nil {
-			log.Printf("exec statement error: %v", err)
-			return err
-		}
-
-		o.ID, err = res.LastInsertId()
+		o.LastSeenAt = &now
+		err = r.db.QueryRow("INSERT INTO onesignal(user_id, imei, player_id, created_at, last_seen_at) VALUES($1,$2,$3,$4,$5) RETURNING id;", o.UserID, o.Imei, o.PlayerID, o.CreatedAt, o.LastSeenAt).Scan(&o.ID)
 		if err != nil {
-			log.Printf("last insert id error: %v", err)
+			log.Printf("Create onesignal: insert error: %v", err)
 			return err
 		}
 
 		log.Printf("onesignal successfully created with id %v", o.ID)
-
-		affected, err := res.RowsAffected()
-		if err != nil {
-			log.Printf("rows effected error: %v", err)
-			return err
-		}
-		if affected == 0 {
-			log.Printf("rows effected -> %v", affected)
-			return errors.New("Server error")
-		}
 	} else if count == 1 {
-		stmt, err := db.Prepare("UPDATE onesignal SET player_id=$1, updated_at=$2 WHERE user_id=$3 AND imei=$4;")
+		stmt, err := r.db.Prepare("UPDATE onesignal SET player_id=$1, updated_at=$2, last_seen_at=$2 WHERE user_id=$3 AND imei=$4;")
 		if err != nil {
 			log.Printf("create prepare statement error: %v", err)
 			return err
 		}
+		defer stmt.Close()
 
 		o.UpdatedAt = &now
+		o.LastSeenAt = &now
 
 		res, err := stmt.Exec(o.PlayerID, o.UpdatedAt, o.UserID, o.Imei)
 		if err != nil {
@@ -148,35 +116,34 @@ func (o *OneSignal) Upsert() error {
 		}
 		if affected == 0 {
 			log.Printf("rows effected -> %v", affected)
-			return errors.New("Server error")
+			return NotFound(CodeNotFound, "Onesignal account not found")
 		}
 	} else {
-		err = fmt.Errorf("Multiple oneSignal record found for -> user_id %v, imei %v, total found %v", o.UserID, o.Imei, count)
-		log.Printf("%v", err)
-		return err
+		log.Printf("Multiple oneSignal record found for -> user_id %v, imei %v, total found %v", o.UserID, o.Imei, count)
+		return Conflict(CodeMultipleFound, "Multiple onesignal records detected")
 	}
 
 	return nil
 }
 
 //Delete func deletes the onesignal record of the user
-func (o *OneSignal) Delete() error {
-	count, err := o.Count("WHERE user_id=$1 AND imei=$2", o.UserID, o.Imei)
+func (r *pgOneSignalRepository) Delete(o *OneSignal) error {
+	count, err := r.count(o)
 	if err != nil {
 		log.Printf("Onesignal delete: error on fetching onesignal record count: %v", err)
 		return err
 	}
 
 	if count == 0 {
-		err = fmt.Errorf("Onesignal account not found-> user_id %v, imei %v, total found %v", o.UserID, o.Imei, count)
-		log.Printf("%v", err)
-		return err
+		log.Printf("Onesignal account not found-> user_id %v, imei %v, total found %v", o.UserID, o.Imei, count)
+		return NotFound(CodeNotFound, "Onesignal account not found")
 	} else if count == 1 {
-		stmt, err := db.Prepare("DELETE FROM onesignal WHERE user_id=$1 AND imei=$2;")
+		stmt, err := r.db.Prepare("DELETE FROM onesignal WHERE user_id=$1 AND imei=$2;")
 		if err != nil {
 			log.Printf("create prepare statement error: %v", err)
 			return err
 		}
+		defer stmt.Close()
 
 		res, err := stmt.Exec(o.UserID, o.Imei)
 		if err != nil {
@@ -191,11 +158,30 @@ func (o *OneSignal) Delete() error {
 		}
 		if affected == 0 {
 			log.Printf("rows effected -> %v", affected)
-			return errors.New("Server error")
+			return NotFound(CodeNotFound, "Onesignal account not found")
 		}
 	} else {
-		err = fmt.Errorf("Onesignal account multiple record found-> user_id %v, imei %v, total found %v", o.UserID, o.Imei, count)
-		log.Printf("%v", err)
+		log.Printf("Onesignal account multiple record found-> user_id %v, imei %v, total found %v", o.UserID, o.Imei, count)
+		return Conflict(CodeMultipleFound, "Multiple onesignal records detected")
+	}
+
+	return nil
+}
+
+//Touch func sets the last seen time of the device of the user to now. Devices without a record are skipped.
+func (r *pgOneSignalRepository) Touch(o *OneSignal) error {
+	if _, err := r.db.Exec("UPDATE onesignal SET last_seen_at=NOW() WHERE user_id=$1 AND imei=$2;", o.UserID, o.Imei); err != nil {
+		log.Printf("Touch onesignal: sql error %v", err)
+		return err
+	}
+
+	return nil
+}
+
+//DeleteAll func deletes every onesignal record of the user
+func (r *pgOneSignalRepository) DeleteAll(userID int64) error {
+	if _, err := r.db.Exec("DELETE FROM onesignal WHERE user_id=$1;", userID); err != nil {
+		log.Printf("Delete all onesignal: sql error %v", err)
 		return err
 	}
 
@@ -0,0 +1,213 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+//PersonalData struct is everything stored about a user, one JSON array of rows per table. Media holds the urls of the uploaded files.
+type PersonalData struct {
+	UserID int64
+	Tables []PersonalDataTable
+	Media  []string
+}
+
+//PersonalDataTable struct holds the rows of one table as a JSON array
+type PersonalDataTable struct {
+	Name string
+	Rows json.RawMessage
+}
+
+//personalDataQueries are the selects of the export, in the order of the archive. $1 is the user id.
+//Geometries are exported as GeoJSON, secrets like the refresh token hashes are left out.
+var personalDataQueries = []struct {
+	table string
+	query string
+}{
+	{"users", "SELECT id, name, email, facebook_user_id, role, gender, date_of_birth, weight, level_id, ST_AsGeoJSON(geometry)::json AS geometry, created_at, updated_at, deletion_requested_at, deletion_due_at FROM users WHERE id=$1"},
+	{"user_identities", "SELECT id, provider, subject, email, created_at FROM user_identities WHERE user_id=$1"},
+	{"scores", "SELECT id, exp, coins, likes_remaining, likes_updated_at, created_at, updated_at FROM scores WHERE user_id=$1"},
+	{"challenges", "SELECT id, name, description, likes_needed_per_post, status, starts_at, ends_at, category_id, tags, rules, weight, ST_AsGeoJSON(geometry)::json AS geometry, created_at, updated_at, deleted_at FROM challenges WHERE user_id=$1"},
+	{"posts", "SELECT id, challenge_id, likes_needed, file_url, content_type, content_size, duration, ST_AsGeoJSON(geometry)::json AS geometry, completed_at, created_at, updated_at, deleted_at FROM posts WHERE user_id=$1"},
+	{"likes", "SELECT id, post_id, created_at FROM likes WHERE user_id=$1"},
+	{"flags", "SELECT id, post_id, created_at FROM flags WHERE user_id=$1"},
+	{"challenge_completions", "SELECT id, challenge_id, post_id, exp, coins, created_at FROM challenge_completions WHERE user_id=$1"},
+	{"bought_items", "SELECT id, vanity_item_id, level_id, amount, currency, created_at FROM bought_items WHERE user_id=$1"},
+	{"challenge_requests", "SELECT id, from_id, to_id, challenge_id, message, status, created_at FROM challenge_requests WHERE from_id=$1 OR to_id=$1"},
+	{"invites", "SELECT id, code, challenge_id, expires_at, max_uses, uses, created_at FROM invites WHERE inviter_id=$1"},
+	{"invite_redemptions", "SELECT id, invite_id, challenge_request_id, created_at FROM invite_redemptions WHERE user_id=$1"},
+	{"group_challenge_requests", "SELECT id, from_id, to_ids, accepted_ids, message, created_at FROM group_challenge_requests WHERE from_id=$1 OR $1=ANY(to_ids)"},
+	{"onesignal", "SELECT id, imei, player_id, created_at, updated_at, last_seen_at FROM onesignal WHERE user_id=$1"},
+	{"sessions", "SELECT id, imei, family_id, expires_at, created_at, rotated_at, revoked_at FROM refresh_tokens WHERE user_id=$1"},
+}
+
+//eraseStatements wipe the rows of a user, in foreign key order. $1 is the user id. The challenges stay since the posts of
+//other users belong to them, they keep pointing to the anonymized user row. The bought items stay as anonymous purchase records.
+var eraseStatements = []string{
+	"DELETE FROM likes WHERE user_id=$1 OR post_id IN (SELECT id FROM posts WHERE user_id=$1);",
+	"DELETE FROM flags WHERE user_id=$1 OR post_id IN (SELECT id FROM posts WHERE user_id=$1);",
+	"DELETE FROM challenge_completions WHERE user_id=$1;",
+	"DELETE FROM posts WHERE user_id=$1;",
+	"DELETE FROM scores WHERE user_id=$1;",
+	"DELETE FROM invite_redemptions WHERE user_id=$1;",
+	"DELETE FROM invites WHERE inviter_id=$1;",
+	"DELETE FROM challenge_requests WHERE from_id=$1 OR to_id=$1;",
+	"DELETE FROM group_challenge_requests WHERE from_id=$1;",
+	"UPDATE group_challenge_requests SET to_ids=array_remove(to_ids, $1), accepted_ids=array_remove(accepted_ids, $1) WHERE $1=ANY(to_ids);",
+	"DELETE FROM onesignal WHERE user_id=$1;",
+	"DELETE FROM refresh_tokens WHERE user_id=$1;",
+	"DELETE FROM user_identities WHERE user_id=$1;",
+	`UPDATE users SET name='', email='', facebook_user_id='', gender='', date_of_birth='', geometry=NULL, deleted_at=COALESCE(deleted_at, NOW()),
+	deletion_requested_at=NULL, deletion_due_at=NULL, erased_at=NOW() WHERE id=$1;`,
+}
+
+//pgPersonalDataRepository struct is the postgres implementation of PersonalDataRepository
+type pgPersonalDataRepository struct {
+	db *sql.DB
+}
+
+//Export func collects the rows of the user from every table. It reads from one snapshot so the tables are consistent.
+func (r *pgPersonalDataRepository) Export(userID int64) (*PersonalData, error) {
+	tx, err := r.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		log.Printf("Export personal data: begin error: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id=$1 AND deleted_at IS NULL);", userID).Scan(&exists); err != nil {
+		log.Printf("Export personal data: user error: %v", err)
+		return nil, err
+	}
+	if !exists {
+		return nil, NotFound(CodeUserNotFound, "User not found")
+	}
+
+	data := PersonalData{UserID: userID}
+	for _, q := range personalDataQueries {
+		var rows []byte
+		if err = tx.QueryRow("SELECT COALESCE(json_agg(t ORDER BY t.id), '[]') FROM ("+q.query+") t;", userID).Scan(&rows); err != nil {
+			log.Printf("Export personal data: %v error: %v", q.table, err)
+			return nil, err
+		}
+		data.Tables = append(data.Tables, PersonalDataTable{Name: q.table, Rows: rows})
+	}
+
+	if data.Media, err = mediaURLs(tx, userID); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+//Media func returns the urls of the files the user uploaded, deleted posts included
+func (r *pgPersonalDataRepository) Media(userID int64) ([]string, error) {
+	return mediaURLs(r.db, userID)
+}
+
+//mediaURLs func selects the file urls of the posts of the user
+func mediaURLs(q dbtx, userID int64) ([]string, error) {
+	rows, err := q.Query("SELECT file_url FROM posts WHERE user_id=$1 AND file_url<>'' ORDER BY id;", userID)
+	if err != nil {
+		log.Printf("Get media urls: sql error %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	urls := []string{}
+	for rows.Next() {
+		var url string
+		if err = rows.Scan(&url); err != nil {
+			log.Printf("scanning row to struct error: %v", err)
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+	return urls, nil
+}
+
+//ScheduleDeletion func marks the user for erasure at due. Asking again keeps the first schedule.
+func (r *pgPersonalDataRepository) ScheduleDeletion(u *User, due time.Time) error {
+	err := r.db.QueryRow(`UPDATE users SET deletion_requested_at=COALESCE(deletion_requested_at, NOW()), deletion_due_at=COALESCE(deletion_due_at, $1)
+	WHERE id=$2 AND deleted_at IS NULL RETURNING deletion_due_at;`, due, u.ID).Scan(&u.DeletionDueAt)
+	if err == sql.ErrNoRows {
+		return NotFound(CodeUserNotFound, "User not found")
+	}
+	if err != nil {
+		log.Printf("Schedule deletion: sql error %v", err)
+		return err
+	}
+
+	return nil
+}
+
+//CancelDeletion func removes the pending deletion of the user
+func (r *pgPersonalDataRepository) CancelDeletion(u *User) error {
+	res, err := r.db.Exec("UPDATE users SET deletion_requested_at=NULL, deletion_due_at=NULL WHERE id=$1 AND deleted_at IS NULL AND deletion_due_at IS NOT NULL;", u.ID)
+	if err != nil {
+		log.Printf("Cancel deletion: sql error %v", err)
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Printf("rows effected error: %v", err)
+		return err
+	}
+	if affected == 0 {
+		return NotFound(CodeNotFound, "No pending deletion")
+	}
+
+	u.DeletionDueAt = nil
+	return nil
+}
+
+//DueDeletions func returns the ids of the users whose grace period is over
+func (r *pgPersonalDataRepository) DueDeletions(now time.Time) ([]int64, error) {
+	rows, err := r.db.Query("SELECT id FROM users WHERE deletion_due_at<=$1 AND erased_at IS NULL ORDER BY deletion_due_at;", now)
+	if err != nil {
+		log.Printf("Get due deletions: sql error %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			log.Printf("scanning row to struct error: %v", err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+//Erase func deletes the rows of the user from every table and anonymizes the user row, all or nothing
+func (r *pgPersonalDataRepository) Erase(userID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Erase user: begin error: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range eraseStatements {
+		if _, err = tx.Exec(stmt, userID); err != nil {
+			log.Printf("Erase user: %v error: %v", stmt, err)
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Erase user: commit error: %v", err)
+		return err
+	}
+
+	log.Printf("user %v erased", userID)
+	return nil
+}
@@ -1,11 +1,12 @@
 package model
 
 import (
+	"database/sql"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"log"
 	"time"
+
+	"github.com/challengr/config"
 )
 
 //Post struct is a model/schema for post table
@@ -14,37 +15,22 @@ type Post struct {
 	UserID      int64      `json:"user_id" sql:"user_id"`
 	ChallengeID int64      `json:"challenge_id" sql:"challenge_id"`
 	LikesNeeded int        `json:"likes_needed" sql:"likes_needed"`
-	FileURL     string     `json:"file_url" sql:"file_url"`
-	ContentType string     `json:"content_type" sql:"content_type"`
-	ContentSize int64      `json:"content_size" sql:"content_size"`
+	FileURL     string     `json:"file_url" sql:"file_url" bind:"create"`
+	ContentType string     `json:"content_type" sql:"content_type" bind:"create"`
+	ContentSize int64      `json:"content_size" sql:"content_size" bind:"create"`
+	Duration    *float64   `json:"duration,omitempty" sql:"duration" bind:"create"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" sql:"completed_at"`
 	CreatedAt   *time.Time `json:"created_at" sql:"created_at"`
 	UpdatedAt   *time.Time `json:"updated_at,omitempty" sql:"updated_at"`
+	DeletedAt   *time.Time `json:"-" sql:"deleted_at"`
 
-	Location *geometry `json:"geo_coords" sql:"-"`
+	Location *geometry `json:"geo_coords" sql:"-" bind:"create"`
 	Flags    []*Flag   `json:"flags" sql:"-"`
 	Likes    []*Like   `json:"likes" sql:"-"`
-
-	Payload map[string]interface{} `json:"-"`
 }
 
-//ParseNotAllowedJSON unmarshalls JSON payload to struct payload and fields. Plus parses the JSON payload.
-func (p *Post) ParseNotAllowedJSON() []string {
-	errSlice := []string{}
-
-	delete(p.Payload, "file_url")
-	delete(p.Payload, "content_type")
-	delete(p.Payload, "content_size")
-	delete(p.Payload, "likes_needed")
-
-	for key := range p.Payload {
-		errSlice = append(errSlice, key)
-	}
-
-	return errSlice
-}
-
-//PostValidate func validates the incoming allowed post fields
-func (p *Post) PostValidate() []string {
+//Validate func validates the incoming allowed post fields. The location of a post is a point, the duration of a video is in seconds.
+func (p *Post) Validate(op string) []string {
 	errSlice := []string{}
 
 	if p.FileURL == "" {
@@ -59,80 +45,172 @@ func (p *Post) PostValidate() []string {
 		errSlice = append(errSlice, "content_type")
 	}
 
-	if p.LikesNeeded == 0 {
-		errSlice = append(errSlice, "likes_needed")
+	if p.Duration != nil && *p.Duration < 0 {
+		errSlice = append(errSlice, "duration")
+	}
+
+	if p.Location != nil && (p.Location.Type != GeoPoint || !p.Location.valid()) {
+		errSlice = append(errSlice, "geo_coords")
 	}
 
 	return errSlice
 }
 
+//pgPostRepository struct is the postgres implementation of PostRepository
+type pgPostRepository struct {
+	db *sql.DB
+}
+
+//postSorts is the whitelist of the orderings of the posts
+var postSorts = SortKeys{
+	"newest": "created_at DESC",
+}
+
+//query func translates the filter into a typed query
+func (r *pgPostRepository) query(filter PostFilter) *Query {
+	q := NewQuery().Where(IsNull("deleted_at"))
+
+	if filter.ID > 0 {
+		q.Where(Eq("id", filter.ID))
+	}
+
+	if filter.ChallengeID > 0 {
+		q.Where(Eq("challenge_id", filter.ChallengeID))
+	}
+
+	if filter.UserID > 0 {
+		q.Where(Eq("user_id", filter.UserID))
+	}
+
+	return q.Sort(postSorts, "newest", "newest").Page(filter.LastID, filter.Limit)
+}
+
 //Create func inserts new post in db
-func (p *Post) Create() error {
+func (r *pgPostRepository) Create(p *Post) error {
 	now := time.Now()
 	p.CreatedAt = &now
 
-	stmt, err := db.Prepare("INSERT INTO posts(user_id, likes_needed, challenge_id, file_url, content_type, content_size, created_at) VALUES($1,$2,$3,$4,$5,$6);")
+	tx, err := r.db.Begin()
 	if err != nil {
-		log.Printf("create prepare statement error: %v", err)
+		log.Printf("Create post: begin error: %v", err)
 		return err
 	}
+	defer tx.Rollback()
 
-	res, err := stmt.Exec(p.UserID, p.LikesNeeded, p.ChallengeID, p.FileURL, p.ContentType, p.ContentSize, p.CreatedAt)
+	//the share lock keeps the challenge from closing, or its rules from changing, until the post is in
+	challenge := Challenge{Rules: &ChallengeRules{}}
+	err = tx.QueryRow("SELECT likes_needed_per_post, status, starts_at, ends_at, rules FROM challenges WHERE id=$1 AND deleted_at IS NULL FOR SHARE;", p.ChallengeID).
+		Scan(&challenge.LikesNeededPerPost, &challenge.Status, &challenge.StartsAt, &challenge.EndsAt, challenge.Rules)
+	if err == sql.ErrNoRows {
+		return NotFound(CodeChallengeNotFound, "Challenge not found")
+	}
 	if err != nil {
-		log.Printf("exec statement error: %v", err)
+		log.Printf("Create post: challenge error: %v", err)
 		return err
 	}
-
-	p.ID, err = res.LastInsertId()
-	if err != nil {
-		log.Printf("last insert id error: %v", err)
+	if err = challenge.acceptsPosts(now); err != nil {
 		return err
 	}
 
-	log.Printf("onesignal successfully created with id %v", p.ID)
+	var geomStr *string
+	if p.Location != nil {
+		b, err := json.Marshal(p.Location)
+		if err != nil {
+			log.Printf("Bad location value err: %v\n", err)
+			return err
+		}
+		s := string(b)
+		geomStr = &s
+	}
+
+	entered := false
+	if challenge.Rules.OneEntryPerUser {
+		//the lock of the user row queues the posts of the user, two entries can not slip in side by side
+		if _, err = tx.Exec("SELECT id FROM users WHERE id=$1 FOR UPDATE;", p.UserID); err != nil {
+			log.Printf("Create post: user lock error: %v", err)
+			return err
+		}
+		err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM posts WHERE challenge_id=$1 AND user_id=$2 AND deleted_at IS NULL);", p.ChallengeID, p.UserID).Scan(&entered)
+		if err != nil {
+			log.Printf("Create post: entries error: %v", err)
+			return err
+		}
+	}
+
+	var distance *float64
+	if challenge.Rules.Radius > 0 && geomStr != nil {
+		err = tx.QueryRow("SELECT ST_Distance(geometry::geography, ST_GeomFromGeoJSON($2)::geography) FROM challenges WHERE id=$1;", p.ChallengeID, *geomStr).Scan(&distance)
+		if err != nil {
+			log.Printf("Create post: distance error: %v", err)
+			return err
+		}
+	}
 
-	affected, err := res.RowsAffected()
+	if err = ruleViolation(challenge.Rules.violations(p, now, entered, distance)); err != nil {
+		return err
+	}
+	p.LikesNeeded = challenge.postLikesNeeded()
+
+	err = tx.QueryRow("INSERT INTO posts(user_id, likes_needed, challenge_id, file_url, content_type, content_size, duration, geometry, created_at) VALUES($1,$2,$3,$4,$5,$6,$7,ST_Force2D(ST_GeomFromGeoJSON($8)),$9) RETURNING id;",
+		p.UserID, p.LikesNeeded, p.ChallengeID, p.FileURL, p.ContentType, p.ContentSize, p.Duration, geomStr, p.CreatedAt).Scan(&p.ID)
 	if err != nil {
-		log.Printf("rows effected error: %v", err)
+		log.Printf("Create post: insert error: %v", err)
 		return err
 	}
-	if affected == 0 {
-		log.Printf("rows effected -> %v", affected)
-		return errors.New("Server error")
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Create post: commit error: %v", err)
+		return err
 	}
 
+	log.Printf("post successfully created with id %v", p.ID)
+
 	return nil
 }
 
 //Count func counts the total posts in db
-func (p *Post) Count(whereClause string, args ...interface{}) (int64, error) {
+func (r *pgPostRepository) Count(filter PostFilter) (int64, error) {
 	var count int64
 
-	if err := db.QueryRow("SELECT COUNT(id) FROM posts "+whereClause+";", args...).Scan(&count); err != nil {
-		log.Printf("Count onesignal: sql error %v", err)
+	whereClause, args := r.query(filter).WhereClause()
+	if err := r.db.QueryRow("SELECT COUNT(id) FROM posts "+whereClause+";", args...).Scan(&count); err != nil {
+		log.Printf("Count posts: sql error %v", err)
 		return count, err
 	}
 
 	return count, nil
 }
 
-//Get func counts the total posts in db
-func (p *Post) Get(whereClause string, args ...interface{}) ([]*Post, error) {
+//Get func fetches the posts from db
+func (r *pgPostRepository) Get(filter PostFilter) ([]*Post, error) {
+	whereClause, args := r.query(filter).Build()
+
 	postList := []*Post{}
-	rows, err := db.Query("SELECT id, likes_needed, file_url, content_type, content_size, created_at, updated_at, (SELECT COALESCE(array_to_json(array_agg(likes)), '[]') FROM likes WHERE post_id=posts.id) as likes, (SELECT COALESCE(array_to_json(array_agg(flags)), '[]') FROM flags WHERE post_id=posts.id) as flags FROM posts "+whereClause+" ORDER BY created_at DESC;", args...)
+	rows, err := r.db.Query("SELECT id, user_id, challenge_id, likes_needed, file_url, content_type, content_size, duration, ST_AsGeoJSON(geometry) AS location, completed_at, created_at, updated_at, (SELECT COALESCE(array_to_json(array_agg(likes)), '[]') FROM likes WHERE post_id=posts.id) as likes, (SELECT COALESCE(array_to_json(array_agg(flags)), '[]') FROM flags WHERE post_id=posts.id) as flags FROM posts "+whereClause+";", args...)
 	if err != nil {
-		log.Printf("Get users: sql error %v", err)
+		log.Printf("Get posts: sql error %v", err)
 		return nil, err
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		post := Post{}
 		likesStr := ""
 		flagsStr := ""
-		if err = rows.Scan(&post.ID, &post.LikesNeeded, &post.FileURL, &post.ContentType, &post.ContentSize, &post.CreatedAt, &post.UpdatedAt, &likesStr, &flagsStr); err != nil {
+		var geomStr *string
+		if err = rows.Scan(&post.ID, &post.UserID, &post.ChallengeID, &post.LikesNeeded, &post.FileURL, &post.ContentType, &post.ContentSize, &post.Duration, &geomStr,
+			&post.CompletedAt, &post.CreatedAt, &post.UpdatedAt, &likesStr, &flagsStr); err != nil {
 			log.Printf("scanning row to struct error: %v", err)
 			return nil, err
 		}
 
+		if geomStr != nil {
+			if err = json.Unmarshal([]byte(*geomStr), &post.Location); err != nil {
+				log.Printf("Unmarshaling of location subquery error: %v", err)
+				return nil, err
+			}
+		}
+
 		if err = json.Unmarshal([]byte(likesStr), &post.Likes); err != nil {
 			log.Printf("Unmarshaling of likes subquery error: %v", err)
 			return nil, err
@@ -148,205 +226,243 @@ func (p *Post) Get(whereClause string, args ...interface{}) ([]*Post, error) {
 	return postList, nil
 }
 
-//Flag func flags the post
-func (p *Post) Flag(userID int64) (int, error) {
-	count, err := p.Count("WHERE id=$1 AND challenge_id=$2", p.ID, p.ChallengeID)
+//exists func checks that exactly one post matches the filter
+func (r *pgPostRepository) exists(filter PostFilter) error {
+	count, err := r.Count(filter)
 	if err != nil {
-		log.Printf("Post flag: error on fetching Post record count: %v", err)
-		return 500, err
+		log.Printf("Post: error on fetching Post record count: %v", err)
+		return Internal(err)
 	}
 
 	if count == 0 {
-		err = fmt.Errorf("Post not found-> id %v, total found %v", p.ID, count)
-		log.Printf("%v", err)
-		return 404, err
+		log.Printf("Post not found-> id %v, challenge_id %v, total found %v", filter.ID, filter.ChallengeID, count)
+		return NotFound(CodePostNotFound, "Post not found")
 	} else if count != 1 {
-		err = fmt.Errorf("Post multiple found-> id %v, total found %v", p.ID, count)
-		log.Printf("%v", err)
-		return 409, err
+		log.Printf("Post multiple found-> id %v, total found %v", filter.ID, count)
+		return Conflict(CodeMultipleFound, "Multiple posts detected")
 	}
 
-	stmt, err := db.Prepare("INSERT INTO flags (user_id, post_id, created_at) VALUES ($1, $2, $3) WHERE NOT EXISTS (SELECT id FROM flags WHERE user_id=$4 AND post_id=$5)")
+	return nil
+}
+
+//exec func runs a write statement which does not need to affect any row
+func (r *pgPostRepository) exec(query string, args ...interface{}) error {
+	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		log.Printf("create prepare statement error: %v", err)
-		return 500, err
+		return Internal(err)
 	}
+	defer stmt.Close()
 
-	res, err := stmt.Exec(userID, p.ID, time.Now(), userID, p.ID)
-	if err != nil {
+	if _, err = stmt.Exec(args...); err != nil {
 		log.Printf("exec statement error: %v", err)
-		return 500, err
+		return Internal(err)
 	}
 
-	_, err = res.RowsAffected()
-	if err != nil {
-		log.Printf("rows effected error: %v", err)
-		return 500, err
+	return nil
+}
+
+//Flag func flags the post
+func (r *pgPostRepository) Flag(p *Post, userID int64) error {
+	if err := r.exists(PostFilter{ID: p.ID, ChallengeID: p.ChallengeID}); err != nil {
+		return err
 	}
 
-	return 0, nil
+	return r.exec("INSERT INTO flags (user_id, post_id, created_at) SELECT $1, $2, $3 WHERE NOT EXISTS (SELECT id FROM flags WHERE user_id=$1 AND post_id=$2);", userID, p.ID, time.Now())
 }
 
 //UnFlag func unflaggs the post
-func (p *Post) UnFlag(userID int64) (int, error) {
-	count, err := p.Count("WHERE id=$1 AND challenge_id=$2", p.ID, p.ChallengeID)
-	if err != nil {
-		log.Printf("Post unflag: error on fetching Post record count: %v", err)
-		return 0, err
+func (r *pgPostRepository) UnFlag(p *Post, userID int64) error {
+	if err := r.exists(PostFilter{ID: p.ID, ChallengeID: p.ChallengeID}); err != nil {
+		return err
 	}
 
-	if count == 0 {
-		err = fmt.Errorf("Post not found-> id %v, total found %v", p.ID, count)
-		log.Printf("%v", err)
-		return 404, err
-	} else if count != 1 {
-		err = fmt.Errorf("Post multiple found-> id %v, total found %v", p.ID, count)
-		log.Printf("%v", err)
-		return 409, err
-	}
+	return r.exec("DELETE FROM flags WHERE user_id=$1 AND post_id=$2;", userID, p.ID)
+}
 
-	stmt, err := db.Prepare("DELETE FROM flags WHERE user_id=$1 AND post_id=$2;")
+//Like func likes a post of another user. The like which brings the post to the likes_needed_per_post of its challenge
+//completes it, in the same transaction.
+func (r *pgPostRepository) Like(p *Post, userID int64, rewards config.Rewards) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		log.Printf("create prepare statement error: %v", err)
-		return 500, err
+		log.Printf("Like post: begin error: %v", err)
+		return Internal(err)
+	}
+	defer tx.Rollback()
+
+	//the lock of the post row queues its likes, only one of them can complete it
+	post := Post{}
+	var challengeWeight, userWeight float64
+	err = tx.QueryRow(`SELECT p.id, p.user_id, p.challenge_id, GREATEST(c.likes_needed_per_post, 1), p.completed_at, c.weight, u.weight FROM posts p
+	JOIN challenges c ON c.id=p.challenge_id JOIN users u ON u.id=p.user_id
+	WHERE p.id=$1 AND p.challenge_id=$2 AND p.deleted_at IS NULL FOR UPDATE OF p;`, p.ID, p.ChallengeID).
+		Scan(&post.ID, &post.UserID, &post.ChallengeID, &post.LikesNeeded, &post.CompletedAt, &challengeWeight, &userWeight)
+	if err == sql.ErrNoRows {
+		log.Printf("Post not found-> id %v, challenge_id %v", p.ID, p.ChallengeID)
+		return NotFound(CodePostNotFound, "Post not found")
 	}
-
-	res, err := stmt.Exec(userID, p.ID)
 	if err != nil {
-		log.Printf("exec statement error: %v", err)
-		return 500, err
+		log.Printf("Like post: post error: %v", err)
+		return Internal(err)
+	}
+	if post.UserID == userID {
+		return Forbidden(CodeNotAllowed, "Own posts can not be liked")
 	}
 
-	_, err = res.RowsAffected()
+	now := time.Now()
+	res, err := tx.Exec("INSERT INTO likes (user_id, post_id, created_at) SELECT $1, $2, $3 WHERE NOT EXISTS (SELECT id FROM likes WHERE user_id=$1 AND post_id=$2);", userID, p.ID, now)
+	if err != nil {
+		log.Printf("Like post: insert error: %v", err)
+		return Internal(err)
+	}
+	liked, err := res.RowsAffected()
 	if err != nil {
 		log.Printf("rows effected error: %v", err)
-		return 500, err
+		return Internal(err)
 	}
 
-	return 0, nil
-}
-
-//Like func likes a post
-func (p *Post) Like(userID int64) (int, error) {
-	count, err := p.Count("WHERE id=$1 AND challenge_id=$2 AND deleted_at IS NULL", p.ID, p.ChallengeID)
-	if err != nil {
-		log.Printf("Post flag: error on fetching Post record count: %v", err)
-		return 500, err
+	if liked > 0 && post.CompletedAt == nil {
+		var likes int
+		if err = tx.QueryRow("SELECT COUNT(id) FROM likes WHERE post_id=$1;", p.ID).Scan(&likes); err != nil {
+			log.Printf("Like post: count error: %v", err)
+			return Internal(err)
+		}
+		if likes >= post.LikesNeeded {
+			if err = r.complete(tx, &post, completionReward(rewards, challengeWeight, userWeight), now); err != nil {
+				return err
+			}
+		}
 	}
 
-	if count == 0 {
-		err = fmt.Errorf("Post not found-> id %v, total found %v", p.ID, count)
-		log.Printf("%v", err)
-		return 404, err
-	} else if count != 1 {
-		err = fmt.Errorf("Post multiple found-> id %v, total found %v", p.ID, count)
-		log.Printf("%v", err)
-		return 409, err
+	if err = tx.Commit(); err != nil {
+		log.Printf("Like post: commit error: %v", err)
+		return Internal(err)
 	}
 
-	stmt, err := db.Prepare("INSERT INTO likes (user_id, post_id, created_at) SELECT $1, $2, $3 WHERE NOT EXISTS (SELECT id FROM likes WHERE user_id=$4 AND post_id=$5)")
-	if err != nil {
-		log.Printf("create prepare statement error: %v", err)
-		return 500, err
+	return nil
+}
+
+//complete func marks the post completed. On the first completion of its owner in the challenge the completion event is
+//recorded and the reward credited to the score, later posts of the owner complete without a reward.
+func (r *pgPostRepository) complete(tx *sql.Tx, post *Post, completion Completion, now time.Time) error {
+	if _, err := tx.Exec("UPDATE posts SET completed_at=$1 WHERE id=$2;", now, post.ID); err != nil {
+		log.Printf("Complete post: update error: %v", err)
+		return Internal(err)
 	}
 
-	res, err := stmt.Exec(userID, p.ID, time.Now(), userID, p.ID)
+	//the unique participant of the challenge makes a concurrent completion of another post of the owner wait, then skip
+	err := tx.QueryRow(`INSERT INTO challenge_completions (challenge_id, user_id, post_id, exp, coins, created_at) VALUES($1,$2,$3,$4,$5,$6)
+	ON CONFLICT (challenge_id, user_id) DO NOTHING RETURNING id;`, post.ChallengeID, post.UserID, post.ID, completion.Exp, completion.Coins, now).Scan(&completion.ID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
 	if err != nil {
-		log.Printf("exec statement error: %v", err)
-		return 500, err
+		log.Printf("Complete post: completion error: %v", err)
+		return Internal(err)
 	}
 
-	_, err = res.RowsAffected()
-	if err != nil {
-		log.Printf("rows effected error: %v", err)
-		return 500, err
+	if _, err = tx.Exec("UPDATE scores SET exp=exp+$1, coins=coins+$2, updated_at=$3 WHERE user_id=$4 AND deleted_at IS NULL;",
+		completion.Exp, completion.Coins, now, post.UserID); err != nil {
+		log.Printf("Complete post: score error: %v", err)
+		return Internal(err)
 	}
 
-	return 0, nil
+	log.Printf("post %v completed challenge %v with completion id %v", post.ID, post.ChallengeID, completion.ID)
+
+	return nil
 }
 
-//Delete func deletes the post record of the user. Delete meaning it doesnt purge it. Just hides it.
-func (p *Post) Delete() (int, error) {
-	count, err := p.Count("WHERE id=$1 AND challenge_id=$2 AND user_id=$3 AND deleted_at IS NULL", p.ID, p.ChallengeID, p.UserID)
-	if err != nil {
-		log.Printf("Post delete: error on fetching Post record count: %v", err)
-		return 500, err
+//delete func hides the post. The ownerID is checked only when it is bigger than zero.
+func (r *pgPostRepository) delete(p *Post, ownerID int64, e *AuditEvent) error {
+	if err := r.exists(PostFilter{ID: p.ID, ChallengeID: p.ChallengeID, UserID: ownerID}); err != nil {
+		return err
 	}
 
-	if count == 0 {
-		err = fmt.Errorf("Post not found-> id %v, challenge_id %v, user_id %v total found %v", p.ID, p.ChallengeID, p.UserID, count)
-		log.Printf("%v", err)
-		return 404, err
-	} else if count == 1 {
-		stmt, err := db.Prepare("UPDATE posts SET deleted_at=$1 WHERE id=$2 AND user_id=$3;")
+	return withAudit(r.db, e, auditPost, p.ID, func(q dbtx) error {
+		stmt, err := q.Prepare("UPDATE posts SET deleted_at=$1 WHERE id=$2 AND ($3=0 OR user_id=$3);")
 		if err != nil {
 			log.Printf("create prepare statement error: %v", err)
-			return 500, err
+			return Internal(err)
 		}
+		defer stmt.Close()
 
-		res, err := stmt.Exec(time.Now(), p.ID, p.UserID)
+		res, err := stmt.Exec(time.Now(), p.ID, ownerID)
 		if err != nil {
 			log.Printf("exec statement error: %v", err)
-			return 500, err
+			return Internal(err)
 		}
 
 		affected, err := res.RowsAffected()
 		if err != nil {
 			log.Printf("rows effected error: %v", err)
-			return 500, err
+			return Internal(err)
 		}
 		if affected == 0 {
 			log.Printf("rows effected -> %v", affected)
-			return 404, errors.New("Post not found")
+			return NotFound(CodePostNotFound, "Post not found")
 		}
-	} else {
-		err = fmt.Errorf("multiple posts found-> id %v, total found %v", p.ID, count)
-		log.Printf("%v", err)
-		return 409, err
-	}
 
-	return 0, nil
+		return nil
+	})
+}
+
+//Delete func deletes the post record of the user. Delete meaning it doesnt purge it. Just hides it.
+func (r *pgPostRepository) Delete(p *Post) error {
+	return r.delete(p, p.UserID, nil)
+}
+
+//AdminDelete func deletes any post record, recording the audit event. Delete meaning it doesnt purge it. Just hides it.
+func (r *pgPostRepository) AdminDelete(p *Post, e *AuditEvent) error {
+	return r.delete(p, 0, e)
 }
 
-//AdminDelete func deletes the post record of the user. Delete meaning it doesnt purge it. Just hides it.
-func (p *Post) AdminDelete() (int, error) {
-	count, err := p.Count("WHERE id=$1 AND challenge_id=$2 AND deleted_at IS NULL", p.ID, p.ChallengeID)
+//Purge func removes the posts deleted before the given time for good, with their likes and flags.
+//It returns the file urls of the purged posts, so their uploads can be removed too.
+func (r *pgPostRepository) Purge(before time.Time) ([]string, error) {
+	tx, err := r.db.Begin()
 	if err != nil {
-		log.Printf("Post delete: error on fetching Post record count: %v", err)
-		return 500, err
+		log.Printf("Purge posts: begin error: %v", err)
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	if count == 0 {
-		err = fmt.Errorf("Post not found-> id %v, challenge_id %v total found %v", p.ID, p.ChallengeID, count)
-		log.Printf("%v", err)
-		return 404, err
-	} else if count == 1 {
-		stmt, err := db.Prepare("UPDATE posts SET deleted_at=$1 WHERE id=$2;")
-		if err != nil {
-			log.Printf("create prepare statement error: %v", err)
-			return 500, err
+	for _, stmt := range []string{
+		"DELETE FROM likes WHERE post_id IN (SELECT id FROM posts WHERE deleted_at<$1);",
+		"DELETE FROM flags WHERE post_id IN (SELECT id FROM posts WHERE deleted_at<$1);",
+	} {
+		if _, err = tx.Exec(stmt, before); err != nil {
+			log.Printf("Purge posts: %v error: %v", stmt, err)
+			return nil, err
 		}
+	}
 
-		res, err := stmt.Exec(time.Now(), p.ID)
-		if err != nil {
-			log.Printf("exec statement error: %v", err)
-			return 500, err
-		}
+	rows, err := tx.Query("DELETE FROM posts WHERE deleted_at<$1 RETURNING file_url;", before)
+	if err != nil {
+		log.Printf("Purge posts: sql error %v", err)
+		return nil, err
+	}
+	defer rows.Close()
 
-		affected, err := res.RowsAffected()
-		if err != nil {
-			log.Printf("rows effected error: %v", err)
-			return 500, err
+	urls := []string{}
+	for rows.Next() {
+		var url string
+		if err = rows.Scan(&url); err != nil {
+			log.Printf("scanning row to struct error: %v", err)
+			return nil, err
 		}
-		if affected == 0 {
-			log.Printf("rows effected -> %v", affected)
-			return 404, errors.New("Post not found")
+		if url != "" {
+			urls = append(urls, url)
 		}
-	} else {
-		err = fmt.Errorf("multiple posts found-> id %v, total found %v", p.ID, count)
-		log.Printf("%v", err)
-		return 409, err
+	}
+	if err = rows.Err(); err != nil {
+		log.Printf("Purge posts: rows error %v", err)
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Purge posts: commit error: %v", err)
+		return nil, err
 	}
 
-	return 0, nil
+	return urls, nil
 }
@@ -0,0 +1,231 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+//Predicate interface is a typed condition of a where clause. Values are always passed as numbered args.
+type Predicate interface {
+	build(q *Query) string
+}
+
+//predicateFunc type adapts a func to the Predicate interface
+type predicateFunc func(q *Query) string
+
+func (f predicateFunc) build(q *Query) string {
+	return f(q)
+}
+
+//Eq func matches rows whose column equals the value
+func Eq(column string, value interface{}) Predicate {
+	return predicateFunc(func(q *Query) string {
+		return column + "=" + q.arg(value)
+	})
+}
+
+//Ne func matches rows whose column differs from the value
+func Ne(column string, value interface{}) Predicate {
+	return predicateFunc(func(q *Query) string {
+		return column + "<>" + q.arg(value)
+	})
+}
+
+//Gt func matches rows whose column is greater than the value
+func Gt(column string, value interface{}) Predicate {
+	return predicateFunc(func(q *Query) string {
+		return column + ">" + q.arg(value)
+	})
+}
+
+//Gte func matches rows whose column is greater than or equal to the value
+func Gte(column string, value interface{}) Predicate {
+	return predicateFunc(func(q *Query) string {
+		return column + ">=" + q.arg(value)
+	})
+}
+
+//Lt func matches rows whose column is less than the value
+func Lt(column string, value interface{}) Predicate {
+	return predicateFunc(func(q *Query) string {
+		return column + "<" + q.arg(value)
+	})
+}
+
+//In func matches rows whose column is one of the values
+func In(column string, values ...interface{}) Predicate {
+	return predicateFunc(func(q *Query) string {
+		if len(values) == 0 {
+			return "FALSE"
+		}
+
+		placeholders := make([]string, len(values))
+		for i, value := range values {
+			placeholders[i] = q.arg(value)
+		}
+		return column + " IN (" + strings.Join(placeholders, ", ") + ")"
+	})
+}
+
+//ArrayOverlaps func matches rows whose array column has any of the values
+func ArrayOverlaps(column string, values []string) Predicate {
+	return predicateFunc(func(q *Query) string {
+		return column + " && " + q.arg(pq.Array(values))
+	})
+}
+
+//ArrayContains func matches rows whose array column has all of the values
+func ArrayContains(column string, values []string) Predicate {
+	return predicateFunc(func(q *Query) string {
+		return column + " @> " + q.arg(pq.Array(values))
+	})
+}
+
+//IsNull func matches rows whose column is null
+func IsNull(column string) Predicate {
+	return predicateFunc(func(q *Query) string {
+		return column + " IS NULL"
+	})
+}
+
+//Or func matches rows passing any of the predicates
+func Or(predicates ...Predicate) Predicate {
+	return predicateFunc(func(q *Query) string {
+		parts := make([]string, len(predicates))
+		for i, p := range predicates {
+			parts[i] = p.build(q)
+		}
+		return "(" + strings.Join(parts, " OR ") + ")"
+	})
+}
+
+//WithinRadius func matches rows whose geometry column lies inside the circle
+func WithinRadius(column string, near GeoRadius) Predicate {
+	return predicateFunc(func(q *Query) string {
+		return fmt.Sprintf("ST_Distance_Sphere(%s, ST_SetSRID(ST_MakePoint(%s, %s), 4326)) <= %s", column, q.arg(near.Long), q.arg(near.Lat), q.arg(near.Radius))
+	})
+}
+
+//GeographyPoint func registers the long/lat and returns the expression of the point as a geography, measured in meters
+func (q *Query) GeographyPoint(long, lat float64) string {
+	return fmt.Sprintf("ST_SetSRID(ST_MakePoint(%s, %s), 4326)::geography", q.arg(long), q.arg(lat))
+}
+
+//WithinDistance func matches rows whose geometry column, a point or an area, comes within the radius of the geography point.
+//The geography cast of the column should be indexed.
+func WithinDistance(column, point string, radius int) Predicate {
+	return predicateFunc(func(q *Query) string {
+		return fmt.Sprintf("ST_DWithin(%s::geography, %s, %s)", column, point, q.arg(radius))
+	})
+}
+
+//AfterDistance func applies keyset pagination on the distance expression and the id column
+func AfterDistance(distance string, lastDistance float64, lastID int64) Predicate {
+	return predicateFunc(func(q *Query) string {
+		d := q.arg(lastDistance)
+		return fmt.Sprintf("(%s>%s OR (%s=%s AND id>%s))", distance, d, distance, d, q.arg(lastID))
+	})
+}
+
+//AfterDesc func applies keyset pagination on a column sorted descending, whose ties are sorted by the id column
+func AfterDesc(column string, lastValue interface{}, lastID int64) Predicate {
+	return predicateFunc(func(q *Query) string {
+		v := q.arg(lastValue)
+		return fmt.Sprintf("(%s<%s OR (%s=%s AND id>%s))", column, v, column, v, q.arg(lastID))
+	})
+}
+
+//WithinBox func matches rows whose geometry column intersects the long/lat box, a box crossing the antimeridian is
+//matched as its two halves
+func WithinBox(column string, box GeoBox) Predicate {
+	return predicateFunc(func(q *Query) string {
+		envelope := func(minLong, maxLong float64) string {
+			return fmt.Sprintf("ST_Intersects(%s, ST_MakeEnvelope(%s, %s, %s, %s, 4326))", column, q.arg(minLong), q.arg(box.MinLat), q.arg(maxLong), q.arg(box.MaxLat))
+		}
+		if box.MinLong > box.MaxLong {
+			return "(" + envelope(box.MinLong, 180) + " OR " + envelope(-180, box.MaxLong) + ")"
+		}
+		return envelope(box.MinLong, box.MaxLong)
+	})
+}
+
+//SortKeys type maps the sort keys clients may ask for onto their ORDER BY expressions
+type SortKeys map[string]string
+
+//Has func tells if the sort key is whitelisted
+func (s SortKeys) Has(key string) bool {
+	_, ok := s[key]
+	return ok
+}
+
+//Query struct builds a where/order by/limit tail for a select. It numbers the args for us.
+type Query struct {
+	conds   []string
+	args    []interface{}
+	orderBy string
+	limit   int
+}
+
+//NewQuery func creates an empty query
+func NewQuery() *Query {
+	return &Query{}
+}
+
+//arg func registers a value and returns its placeholder
+func (q *Query) arg(value interface{}) string {
+	q.args = append(q.args, value)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+//Where func adds the predicates, joined with AND
+func (q *Query) Where(predicates ...Predicate) *Query {
+	for _, p := range predicates {
+		q.conds = append(q.conds, p.build(q))
+	}
+	return q
+}
+
+//Sort func orders by a whitelisted key. Unknown keys fall back to the fallback key.
+func (q *Query) Sort(sorts SortKeys, key, fallback string) *Query {
+	orderBy, ok := sorts[key]
+	if !ok {
+		orderBy = sorts[fallback]
+	}
+	q.orderBy = orderBy
+	return q
+}
+
+//Page func applies keyset pagination on the id column and limits the rows
+func (q *Query) Page(lastID int64, limit int) *Query {
+	if lastID > 0 {
+		q.Where(Gt("id", lastID))
+	}
+	q.limit = limit
+	return q
+}
+
+//WhereClause func returns only the where part with its args, which is what counts need
+func (q *Query) WhereClause() (string, []interface{}) {
+	if len(q.conds) == 0 {
+		return "", q.args
+	}
+	return "WHERE " + strings.Join(q.conds, " AND "), q.args
+}
+
+//Build func returns the where, order by and limit parts with their args
+func (q *Query) Build() (string, []interface{}) {
+	clause, args := q.WhereClause()
+
+	if q.orderBy != "" {
+		clause = clause + " ORDER BY " + q.orderBy
+	}
+
+	if q.limit > 0 {
+		clause = clause + " LIMIT " + strconv.Itoa(q.limit)
+	}
+
+	return clause, args
+}
@@ -0,0 +1,180 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+//queryCase struct is a built query with the sql and args it must produce
+type queryCase struct {
+	name  string
+	query *Query
+	sql   string
+	args  []interface{}
+}
+
+//checkQueries func builds every query and compares it with the expected sql and args
+func checkQueries(t *testing.T, cases []queryCase) {
+	t.Helper()
+	for _, tc := range cases {
+		sql, args := tc.query.Build()
+		if sql != tc.sql {
+			t.Errorf("%s sql = %q, want %q", tc.name, sql, tc.sql)
+		}
+		if !reflect.DeepEqual(args, tc.args) {
+			t.Errorf("%s args = %#v, want %#v", tc.name, args, tc.args)
+		}
+	}
+}
+
+func TestQueryPredicates(t *testing.T) {
+	checkQueries(t, []queryCase{
+		{"empty", NewQuery(), "", nil},
+		{"eq", NewQuery().Where(Eq("id", 1)), "WHERE id=$1", []interface{}{1}},
+		{"ne", NewQuery().Where(Ne("status", "closed")), "WHERE status<>$1", []interface{}{"closed"}},
+		{"gt gte lt", NewQuery().Where(Gt("a", 1), Gte("b", 2), Lt("c", 3)), "WHERE a>$1 AND b>=$2 AND c<$3", []interface{}{1, 2, 3}},
+		{"in", NewQuery().Where(In("id", 4, 5, 6)), "WHERE id IN ($1, $2, $3)", []interface{}{4, 5, 6}},
+		{"empty in", NewQuery().Where(In("id")), "WHERE FALSE", nil},
+		{"is null", NewQuery().Where(IsNull("deleted_at")), "WHERE deleted_at IS NULL", nil},
+		{"array overlaps", NewQuery().Where(ArrayOverlaps("tags", []string{"art"})), "WHERE tags && $1", []interface{}{pq.Array([]string{"art"})}},
+		{"array contains", NewQuery().Where(ArrayContains("tags", []string{"art", "city"})), "WHERE tags @> $1", []interface{}{pq.Array([]string{"art", "city"})}},
+		{"or", NewQuery().Where(Or(Eq("a", 1), IsNull("b"), Eq("c", 2))), "WHERE (a=$1 OR b IS NULL OR c=$2)", []interface{}{1, 2}},
+		{
+			"within radius",
+			NewQuery().Where(WithinRadius("geometry", GeoRadius{Long: 13.4, Lat: 52.5, Radius: 1000})),
+			"WHERE ST_Distance_Sphere(geometry, ST_SetSRID(ST_MakePoint($1, $2), 4326)) <= $3",
+			[]interface{}{13.4, 52.5, 1000},
+		},
+	})
+}
+
+//TestQueryNumbering checks that the placeholders keep counting across chained calls, nested predicates and the page
+func TestQueryNumbering(t *testing.T) {
+	q := NewQuery().Where(Eq("user_id", int64(7)), Or(Eq("status", "active"), In("category_id", int64(1), int64(2))))
+	q.Where(ArrayOverlaps("tags", []string{"art"}))
+	q.Page(30, 10)
+
+	checkQueries(t, []queryCase{{
+		"chained",
+		q,
+		"WHERE user_id=$1 AND (status=$2 OR category_id IN ($3, $4)) AND tags && $5 AND id>$6 LIMIT 10",
+		[]interface{}{int64(7), "active", int64(1), int64(2), pq.Array([]string{"art"}), int64(30)},
+	}})
+
+	clause, args := NewQuery().Where(Eq("a", 1)).Sort(SortKeys{"id": "id"}, "id", "id").Page(0, 5).WhereClause()
+	if clause != "WHERE a=$1" || !reflect.DeepEqual(args, []interface{}{1}) {
+		t.Errorf("where clause = %q %v, want the conditions without order and limit", clause, args)
+	}
+}
+
+//TestQuerySort checks that only the whitelisted sort keys reach the order by, the others fall back
+func TestQuerySort(t *testing.T) {
+	sorts := SortKeys{"newest": "id DESC", "name": "name, id"}
+
+	checkQueries(t, []queryCase{
+		{"whitelisted", NewQuery().Sort(sorts, "name", "newest"), " ORDER BY name, id", nil},
+		{"unknown key", NewQuery().Sort(sorts, "password", "newest"), " ORDER BY id DESC", nil},
+		{"injected key", NewQuery().Sort(sorts, "id; DROP TABLE users", "newest"), " ORDER BY id DESC", nil},
+		{"empty key", NewQuery().Sort(sorts, "", "newest"), " ORDER BY id DESC", nil},
+		{"column name is not a key", NewQuery().Sort(sorts, "id DESC", "name"), " ORDER BY name, id", nil},
+		{"page without cursor", NewQuery().Sort(sorts, "newest", "newest").Page(0, 20), " ORDER BY id DESC LIMIT 20", nil},
+		{"page after id", NewQuery().Where(IsNull("deleted_at")).Sort(sorts, "name", "name").Page(9, 20), "WHERE deleted_at IS NULL AND id>$1 ORDER BY name, id LIMIT 20", []interface{}{int64(9)}},
+	})
+
+	if !sorts.Has("newest") || sorts.Has("id DESC") || sorts.Has("") {
+		t.Error("Has does not follow the whitelist")
+	}
+}
+
+func TestQueryKeyset(t *testing.T) {
+	createdAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	q := NewQuery()
+	distance := "ST_Distance(geometry::geography, " + q.GeographyPoint(13.4, 52.5) + ")"
+	q.Where(AfterDistance(distance, 250.5, 12))
+
+	checkQueries(t, []queryCase{
+		{
+			"after desc",
+			NewQuery().Where(Eq("status", "active"), AfterDesc("trending_score", 4.5, 12)),
+			"WHERE status=$1 AND (trending_score<$2 OR (trending_score=$2 AND id>$3))",
+			[]interface{}{"active", 4.5, int64(12)},
+		},
+		{
+			"after desc of a time",
+			NewQuery().Where(AfterDesc("created_at", createdAt, 3)),
+			"WHERE (created_at<$1 OR (created_at=$1 AND id>$2))",
+			[]interface{}{createdAt, int64(3)},
+		},
+		{
+			"after distance",
+			q,
+			"WHERE (ST_Distance(geometry::geography, ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography)>$3 OR " +
+				"(ST_Distance(geometry::geography, ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography)=$3 AND id>$4))",
+			[]interface{}{13.4, 52.5, 250.5, int64(12)},
+		},
+		{
+			"within distance",
+			func() *Query {
+				q := NewQuery().Where(Eq("status", "active"))
+				return q.Where(WithinDistance("geometry", q.GeographyPoint(13.4, 52.5), 5000))
+			}(),
+			"WHERE status=$1 AND ST_DWithin(geometry::geography, ST_SetSRID(ST_MakePoint($2, $3), 4326)::geography, $4)",
+			[]interface{}{"active", 13.4, 52.5, 5000},
+		},
+		{
+			"within box",
+			NewQuery().Where(WithinBox("geometry", GeoBox{MinLong: 13, MinLat: 52, MaxLong: 14, MaxLat: 53})),
+			"WHERE ST_Intersects(geometry, ST_MakeEnvelope($1, $2, $3, $4, 4326))",
+			[]interface{}{13.0, 52.0, 14.0, 53.0},
+		},
+		{
+			"within box across the antimeridian",
+			NewQuery().Where(Eq("id", 1), WithinBox("geometry", GeoBox{MinLong: 170, MinLat: -20, MaxLong: -170, MaxLat: -10})),
+			"WHERE id=$1 AND (ST_Intersects(geometry, ST_MakeEnvelope($2, $3, $4, $5, 4326)) OR " +
+				"ST_Intersects(geometry, ST_MakeEnvelope($6, $7, $8, $9, 4326)))",
+			[]interface{}{1, 170.0, -20.0, 180.0, -10.0, -180.0, -20.0, -170.0, -10.0},
+		},
+	})
+}
+
+//TestChallengeQuery checks that every challenge sort pages on its own keyset
+func TestChallengeQuery(t *testing.T) {
+	r := &pgChallengeRepository{}
+	createdAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	build := func(filter ChallengeFilter) *Query {
+		q, _ := r.query(filter)
+		return q
+	}
+
+	checkQueries(t, []queryCase{
+		{
+			"first page",
+			build(ChallengeFilter{Sort: "fresh", Limit: 20}),
+			"WHERE deleted_at IS NULL ORDER BY challenges.created_at DESC, challenges.id LIMIT 20",
+			nil,
+		},
+		{
+			"fresh",
+			build(ChallengeFilter{Sort: "fresh", LastID: 8, LastCreatedAt: createdAt, Limit: 20}),
+			"WHERE deleted_at IS NULL AND (created_at<$1 OR (created_at=$1 AND id>$2)) ORDER BY challenges.created_at DESC, challenges.id LIMIT 20",
+			[]interface{}{createdAt, int64(8)},
+		},
+		{
+			"trending",
+			build(ChallengeFilter{Sort: "hot", LastID: 8, LastTrendingScore: 1.5, Limit: 20}),
+			"WHERE deleted_at IS NULL AND (trending_score<$1 OR (trending_score=$1 AND id>$2)) ORDER BY challenges.trending_score DESC, challenges.id LIMIT 20",
+			[]interface{}{1.5, int64(8)},
+		},
+		{
+			"unknown sort",
+			build(ChallengeFilter{Sort: "name", LastID: 8, Limit: 20}),
+			"WHERE deleted_at IS NULL AND id>$1 ORDER BY challenges.id LIMIT 20",
+			[]interface{}{int64(8)},
+		},
+	})
+}
@@ -0,0 +1,224 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"time"
+)
+
+//RefreshToken struct is a model/schema for refresh_tokens table. Only the sha256 hash of the token is stored.
+//Every token issued for one login of a device shares the family id, which is also the session id of the access tokens.
+type RefreshToken struct {
+	ID        int64      `json:"id" sql:"id"`
+	UserID    int64      `json:"user_id" sql:"user_id"`
+	Imei      string     `json:"imei" sql:"imei"`
+	FamilyID  string     `json:"family_id" sql:"family_id"`
+	TokenHash string     `json:"-" sql:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" sql:"expires_at"`
+	CreatedAt time.Time  `json:"created_at" sql:"created_at"`
+	RotatedAt *time.Time `json:"rotated_at" sql:"rotated_at"`
+	RevokedAt *time.Time `json:"revoked_at" sql:"revoked_at"`
+}
+
+//Session struct is a device of the user with an active session, listed from its live refresh token with the push
+//registration of the device. PlayerID is empty for the devices which never registered for push, their LastSeenAt is the
+//last login or token refresh.
+type Session struct {
+	UserID     int64      `json:"user_id"`
+	Imei       string     `json:"imei"`
+	PlayerID   string     `json:"player_id"`
+	SignedInAt time.Time  `json:"signed_in_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+}
+
+//TokenRefresh struct is the payload of the refresh endpoint
+type TokenRefresh struct {
+	RefreshToken string `json:"refresh_token" bind:"create"`
+	Imei         string `json:"imei" bind:"create"`
+}
+
+//Validate func validates a refresh payload data
+func (t *TokenRefresh) Validate(op string) []string {
+	errSlice := []string{}
+
+	if t.RefreshToken == "" {
+		errSlice = append(errSlice, "refresh_token")
+	}
+
+	if t.Imei == "" {
+		errSlice = append(errSlice, "imei")
+	}
+
+	return errSlice
+}
+
+//TokenPair struct is the response of the refresh endpoint
+type TokenPair struct {
+	Token          string    `json:"token"`
+	TokenExpiresAt time.Time `json:"token_expires_at"`
+	RefreshToken   string    `json:"refresh_token"`
+}
+
+//NewRefreshTokenString func returns a new random refresh token and the hash to store
+func NewRefreshTokenString() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token), nil
+}
+
+//HashRefreshToken func returns the hash under which a refresh token is stored
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+//pgRefreshTokenRepository struct is the postgres implementation of RefreshTokenRepository
+type pgRefreshTokenRepository struct {
+	db *sql.DB
+}
+
+//Create func inserts a new refresh token
+func (r *pgRefreshTokenRepository) Create(t *RefreshToken) error {
+	t.CreatedAt = time.Now()
+	err := r.db.QueryRow("INSERT INTO refresh_tokens(user_id, imei, family_id, token_hash, expires_at, created_at) VALUES($1,$2,$3,$4,$5,$6) RETURNING id;", t.UserID, t.Imei, t.FamilyID, t.TokenHash, t.ExpiresAt, t.CreatedAt).Scan(&t.ID)
+	if err != nil {
+		log.Printf("Create refresh token: insert error: %v", err)
+		return dbError(err)
+	}
+
+	return nil
+}
+
+//GetByHash func fetches the refresh token stored under the hash
+func (r *pgRefreshTokenRepository) GetByHash(hash string) (*RefreshToken, error) {
+	t := RefreshToken{}
+	err := r.db.QueryRow("SELECT id, user_id, imei, family_id, token_hash, expires_at, created_at, rotated_at, revoked_at FROM refresh_tokens WHERE token_hash=$1;", hash).Scan(&t.ID, &t.UserID, &t.Imei, &t.FamilyID, &t.TokenHash, &t.ExpiresAt, &t.CreatedAt, &t.RotatedAt, &t.RevokedAt)
+	if err == sql.ErrNoRows {
+		return nil, NotFound(CodeInvalidToken, "Refresh token not found")
+	}
+	if err != nil {
+		log.Printf("Get refresh token: sql error %v", err)
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+//Rotate func marks old as rotated and inserts next in the same family. Only one caller can rotate a token,
+//the others get a token_reused error.
+func (r *pgRefreshTokenRepository) Rotate(old, next *RefreshToken) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Rotate refresh token: begin error: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	res, err := tx.Exec("UPDATE refresh_tokens SET rotated_at=$1 WHERE id=$2 AND rotated_at IS NULL AND revoked_at IS NULL;", now, old.ID)
+	if err != nil {
+		log.Printf("Rotate refresh token: update error: %v", err)
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Printf("rows effected error: %v", err)
+		return err
+	}
+	if affected == 0 {
+		return Forbidden(CodeTokenReused, "Refresh token already used")
+	}
+
+	next.UserID = old.UserID
+	next.Imei = old.Imei
+	next.FamilyID = old.FamilyID
+	next.CreatedAt = now
+	err = tx.QueryRow("INSERT INTO refresh_tokens(user_id, imei, family_id, token_hash, expires_at, created_at) VALUES($1,$2,$3,$4,$5,$6) RETURNING id;", next.UserID, next.Imei, next.FamilyID, next.TokenHash, next.ExpiresAt, next.CreatedAt).Scan(&next.ID)
+	if err != nil {
+		log.Printf("Rotate refresh token: insert error: %v", err)
+		return dbError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Rotate refresh token: commit error: %v", err)
+		return err
+	}
+
+	old.RotatedAt = &now
+	return nil
+}
+
+//RevokeFamily func revokes every token of the session
+func (r *pgRefreshTokenRepository) RevokeFamily(familyID string) error {
+	if _, err := r.db.Exec("UPDATE refresh_tokens SET revoked_at=NOW() WHERE family_id=$1 AND revoked_at IS NULL;", familyID); err != nil {
+		log.Printf("Revoke refresh token family: sql error %v", err)
+		return err
+	}
+
+	return nil
+}
+
+//RevokeDevice func revokes every session of the device of the user
+func (r *pgRefreshTokenRepository) RevokeDevice(userID int64, imei string) error {
+	if _, err := r.db.Exec("UPDATE refresh_tokens SET revoked_at=NOW() WHERE user_id=$1 AND imei=$2 AND revoked_at IS NULL;", userID, imei); err != nil {
+		log.Printf("Revoke refresh tokens of device: sql error %v", err)
+		return err
+	}
+
+	return nil
+}
+
+//RevokeUser func revokes every session of the user
+func (r *pgRefreshTokenRepository) RevokeUser(userID int64) error {
+	if _, err := r.db.Exec("UPDATE refresh_tokens SET revoked_at=NOW() WHERE user_id=$1 AND revoked_at IS NULL;", userID); err != nil {
+		log.Printf("Revoke refresh tokens of user: sql error %v", err)
+		return err
+	}
+
+	return nil
+}
+
+//Revoked func tells whether the session is revoked. An unknown session counts as revoked.
+func (r *pgRefreshTokenRepository) Revoked(familyID string) (bool, error) {
+	var revoked bool
+	if err := r.db.QueryRow("SELECT NOT EXISTS(SELECT 1 FROM refresh_tokens WHERE family_id=$1 AND revoked_at IS NULL);", familyID).Scan(&revoked); err != nil {
+		log.Printf("Refresh token family revoked: sql error %v", err)
+		return true, err
+	}
+
+	return revoked, nil
+}
+
+//Sessions func lists the devices of the user with an active session, the ones signed in most recently first
+func (r *pgRefreshTokenRepository) Sessions(userID int64) ([]*Session, error) {
+	sessionList := []*Session{}
+	rows, err := r.db.Query(`SELECT t.user_id, t.imei, COALESCE(o.player_id, ''),
+	(SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id=t.family_id) AS signed_in_at, t.expires_at,
+	COALESCE(o.last_seen_at, t.created_at) AS last_seen_at FROM refresh_tokens t LEFT JOIN onesignal o ON o.user_id=t.user_id AND o.imei=t.imei
+	WHERE t.user_id=$1 AND t.revoked_at IS NULL AND t.rotated_at IS NULL AND t.expires_at>NOW() ORDER BY signed_in_at DESC;`, userID)
+	if err != nil {
+		log.Printf("Get sessions: sql error %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		session := Session{}
+		if err = rows.Scan(&session.UserID, &session.Imei, &session.PlayerID, &session.SignedInAt, &session.ExpiresAt, &session.LastSeenAt); err != nil {
+			log.Printf("scanning row to struct error: %v", err)
+			return nil, err
+		}
+		sessionList = append(sessionList, &session)
+	}
+	return sessionList, nil
+}
@@ -0,0 +1,318 @@
+package model
+
+import (
+	"time"
+
+	"github.com/challengr/config"
+)
+
+//Store struct bundles the repositories of every aggregate. Handlers only talk to the database through it.
+type Store struct {
+	Users             UserRepository
+	Challenges        ChallengeRepository
+	Posts             PostRepository
+	Scores            ScoreRepository
+	BoughtItems       BoughtItemRepository
+	OneSignals        OneSignalRepository
+	ChallengeRequests ChallengeRequestRepository
+	Levels            LevelRepository
+	VanityItems       VanityItemRepository
+	RefreshTokens     RefreshTokenRepository
+	UserIdentities    UserIdentityRepository
+	PersonalData      PersonalDataRepository
+	AuditEvents       AuditEventRepository
+	JobRuns           JobRunRepository
+	Categories        CategoryRepository
+	Search            SearchRepository
+	Completions       CompletionRepository
+	Leaderboards      LeaderboardRepository
+	Invites           InviteRepository
+}
+
+//UserFilter struct is used for narrowing down the users while fetching or counting
+type UserFilter struct {
+	IDs             []int64
+	FacebookUserIDs []string
+	Near            *GeoRadius
+	LastID          int64
+	Limit           int
+}
+
+//GeoRadius struct describes a circle around a point. Radius is in meters.
+type GeoRadius struct {
+	Long   float64
+	Lat    float64
+	Radius int
+}
+
+//GeoBox struct describes a long/lat box. A box whose MinLong is bigger than its MaxLong crosses the antimeridian.
+type GeoBox struct {
+	MinLong float64
+	MinLat  float64
+	MaxLong float64
+	MaxLat  float64
+}
+
+//ChallengeFilter struct is used for narrowing down the challenges while fetching or counting. With Near the challenges
+//are sorted by their distance and paged past the LastDistance and LastID of the previous page, otherwise past the
+//LastTrendingScore or LastCreatedAt of the trending and fresh sorts and the LastID.
+type ChallengeFilter struct {
+	ID                int64
+	UserID            int64
+	Status            string
+	CategoryID        int64
+	Tags              []string
+	AllTags           bool
+	Near              *GeoRadius
+	Box               *GeoBox
+	LastDistance      float64
+	LastTrendingScore float64
+	LastCreatedAt     time.Time
+	LastID            int64
+	Sort              string
+	Limit             int
+}
+
+//PostFilter struct is used for narrowing down the posts while fetching or counting
+type PostFilter struct {
+	ID          int64
+	ChallengeID int64
+	UserID      int64
+	LastID      int64
+	Limit       int
+}
+
+//BoughtItemFilter struct is used for narrowing down the bought items while fetching
+type BoughtItemFilter struct {
+	UserID int64
+}
+
+//OneSignalFilter struct is used for narrowing down the onesignal records while fetching
+type OneSignalFilter struct {
+	UserID int64
+	Imei   string
+}
+
+//ChallengeRequestFilter struct is used for narrowing down the challenge requests while fetching
+type ChallengeRequestFilter struct {
+	FromID int64
+	ToID   int64
+	LastID int64
+	Limit  int
+}
+
+//AuditEventFilter struct is used for narrowing down the audit events while fetching. From is inclusive, To exclusive,
+//BeforeID pages to the events older than it.
+type AuditEventFilter struct {
+	ActorID    int64
+	Action     string
+	TargetType string
+	TargetID   int64
+	From       *time.Time
+	To         *time.Time
+	BeforeID   int64
+	Limit      int
+}
+
+//CategoryFilter struct is used for narrowing down the categories while fetching
+type CategoryFilter struct {
+	ID   int64
+	Slug string
+}
+
+//SearchFilter struct is used for narrowing down the search results. Type keeps one type of results, Cursor pages past
+//the last result of the previous page.
+type SearchFilter struct {
+	Query  string
+	Type   string
+	Cursor *SearchCursor
+	Limit  int
+}
+
+//CompletionFilter struct is used for narrowing down the completion events while fetching. AfterID pages to the events
+//newer than it, which is how clients poll for new completions.
+type CompletionFilter struct {
+	UserID      int64
+	ChallengeID int64
+	AfterID     int64
+	Limit       int
+}
+
+//InviteFilter struct is used for narrowing down the invites while fetching. BeforeID pages to the invites older than it.
+type InviteFilter struct {
+	ChallengeID int64
+	BeforeID    int64
+	Limit       int
+}
+
+//JobRunFilter struct is used for narrowing down the job runs while fetching. Failed keeps the runs which ended with an error.
+type JobRunFilter struct {
+	Name   string
+	Failed bool
+	Limit  int
+}
+
+//UserIdentityFilter struct is used for narrowing down the linked identities while fetching
+type UserIdentityFilter struct {
+	UserID   int64
+	Provider string
+	Subject  string
+}
+
+//UserRepository interface is implemented by the data stores of the users table
+type UserRepository interface {
+	Create(u *User) error
+	SignUp(u *User, i *UserIdentity, s *Score) error
+	Get(filter UserFilter) ([]*User, error)
+	Count(filter UserFilter) (int64, error)
+	Update(u *User, e *AuditEvent) error
+	Delete(u *User) error
+}
+
+//ChallengeRepository interface is implemented by the data stores of the challenges table
+type ChallengeRepository interface {
+	Create(c *Challenge) error
+	Get(filter ChallengeFilter) ([]*Challenge, error)
+	Count(filter ChallengeFilter) (int64, error)
+	Update(c *Challenge) error
+	AdminUpdate(c *Challenge, e *AuditEvent) error
+	Delete(c *Challenge) error
+	AdminDelete(c *Challenge, e *AuditEvent) error
+	Advance(now time.Time) (int64, error)
+	CloseIdle(since time.Time) (int64, error)
+	Purge(before time.Time) (int64, error)
+	RefreshTrending(now time.Time, formula config.Trending) (int64, error)
+}
+
+//CategoryRepository interface is implemented by the data stores of the categories table
+type CategoryRepository interface {
+	Create(c *Category) error
+	Get(filter CategoryFilter) ([]*Category, error)
+	Update(c *Category) error
+	Delete(c *Category) error
+}
+
+//PostRepository interface is implemented by the data stores of the posts, likes and flags tables
+type PostRepository interface {
+	Create(p *Post) error
+	Get(filter PostFilter) ([]*Post, error)
+	Count(filter PostFilter) (int64, error)
+	Flag(p *Post, userID int64) error
+	UnFlag(p *Post, userID int64) error
+	Like(p *Post, userID int64, rewards config.Rewards) error
+	Delete(p *Post) error
+	AdminDelete(p *Post, e *AuditEvent) error
+	Purge(before time.Time) ([]string, error)
+}
+
+//ScoreRepository interface is implemented by the data stores of the scores table
+type ScoreRepository interface {
+	Create(s *Score) error
+	AddExp(s *Score, amount int, e *AuditEvent) error
+	AddCoins(s *Score, amount int, e *AuditEvent) error
+	AddLikes(s *Score, amount int, e *AuditEvent) error
+	RefillLikes(amount int, emptySince time.Time) (int64, error)
+}
+
+//BoughtItemRepository interface is implemented by the data stores of the bought_items table
+type BoughtItemRepository interface {
+	Create(b *BoughtItem) error
+	Get(filter BoughtItemFilter) ([]*BoughtItem, error)
+}
+
+//OneSignalRepository interface is implemented by the data stores of the onesignal table
+type OneSignalRepository interface {
+	Get(filter OneSignalFilter) ([]*OneSignal, error)
+	Upsert(o *OneSignal) error
+	Touch(o *OneSignal) error
+	Delete(o *OneSignal) error
+	DeleteAll(userID int64) error
+}
+
+//ChallengeRequestRepository interface is implemented by the data stores of the challenge_requests table
+type ChallengeRequestRepository interface {
+	Create(c *ChallengeRequest) error
+	Get(filter ChallengeRequestFilter) ([]*ChallengeRequest, error)
+	UpdateStatus(c *ChallengeRequest, status string) error
+	Delete(c *ChallengeRequest) error
+}
+
+//LevelRepository interface is implemented by the data stores of the levels table
+type LevelRepository interface {
+	Create(l *Level) error
+	Count(id int64) (int64, error)
+}
+
+//VanityItemRepository interface is implemented by the data stores of the vanity_items table
+type VanityItemRepository interface {
+	Create(v *VanityItem) error
+	Get() ([]*VanityItem, error)
+	Count(id int64) (int64, error)
+}
+
+//RefreshTokenRepository interface is implemented by the data stores of the refresh_tokens table
+type RefreshTokenRepository interface {
+	Create(t *RefreshToken) error
+	GetByHash(hash string) (*RefreshToken, error)
+	Rotate(old, next *RefreshToken) error
+	RevokeFamily(familyID string) error
+	RevokeDevice(userID int64, imei string) error
+	RevokeUser(userID int64) error
+	Revoked(familyID string) (bool, error)
+	Sessions(userID int64) ([]*Session, error)
+}
+
+//UserIdentityRepository interface is implemented by the data stores of the user_identities table
+type UserIdentityRepository interface {
+	Link(i *UserIdentity) error
+	Get(filter UserIdentityFilter) ([]*UserIdentity, error)
+	Unlink(i *UserIdentity) error
+}
+
+//PersonalDataRepository interface is implemented by the data stores which export and erase everything stored about a user
+type PersonalDataRepository interface {
+	Export(userID int64) (*PersonalData, error)
+	Media(userID int64) ([]string, error)
+	ScheduleDeletion(u *User, due time.Time) error
+	CancelDeletion(u *User) error
+	DueDeletions(now time.Time) ([]int64, error)
+	Erase(userID int64) error
+}
+
+//AuditEventRepository interface is implemented by the data stores of the audit_events table. The events are appended by the
+//audited changes of the other repositories, which take the event as their last argument; a nil event is not audited.
+type AuditEventRepository interface {
+	Get(filter AuditEventFilter) ([]*AuditEvent, error)
+}
+
+//JobRunRepository interface is implemented by the data stores of the job_runs table and of the locks of the jobs
+type JobRunRepository interface {
+	Lock(name string) (unlock func(), ok bool, err error)
+	Start(run *JobRun) error
+	Finish(run *JobRun) error
+	Get(filter JobRunFilter) ([]*JobRun, error)
+	Prune(before time.Time) (int64, error)
+}
+
+//SearchRepository interface is implemented by the data stores which search the challenges and the users
+type SearchRepository interface {
+	Search(filter SearchFilter) ([]*SearchResult, error)
+}
+
+//CompletionRepository interface is implemented by the data stores of the challenge_completions table. The completions
+//are written by the likes of the posts.
+type CompletionRepository interface {
+	Get(filter CompletionFilter) ([]*Completion, error)
+}
+
+//LeaderboardRepository interface is implemented by the data stores which rank the participants of the challenges
+type LeaderboardRepository interface {
+	Get(challengeID int64) ([]*LeaderboardEntry, error)
+}
+
+//InviteRepository interface is implemented by the data stores of the invites and invite_redemptions tables
+type InviteRepository interface {
+	Create(i *Invite) error
+	Get(filter InviteFilter) ([]*Invite, error)
+	Redeem(code string, userID int64) (*ChallengeRequest, error)
+}
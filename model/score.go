@@ -1,7 +1,7 @@
 package model
 
 import (
-	"errors"
+	"database/sql"
 	"log"
 	"time"
 )
@@ -18,208 +18,100 @@ type Score struct {
 	UpdatedAt      *time.Time `json:"updated_at,omitempty" sql:"updated_at"`
 }
 
-//Create func inserts a new score for a new user
-func (s *Score) Create() (int, error) {
-
-	stmt, err := db.Prepare("INSERT INTO scores(user_id, exp, coins, likes_remaining, created_at) VALUES($1,$2,$3,$4,$5);")
-	if err != nil {
-		log.Printf("Create score: create prepare statement error: %v", err)
-		return 0, err
-	}
+//pgScoreRepository struct is the postgres implementation of ScoreRepository
+type pgScoreRepository struct {
+	db *sql.DB
+}
 
-	res, err := stmt.Exec(s.UserID, s.Exp, s.Coins, s.LikesRemaining, time.Now())
-	if err != nil {
-		log.Printf("Create score: exec statement error: %v", err)
-		return 0, err
-	}
+//Create func inserts a new score for a new user
+func (r *pgScoreRepository) Create(s *Score) error {
+	s.CreatedAt = time.Now()
 
-	s.ID, err = res.LastInsertId()
+	err := r.db.QueryRow("INSERT INTO scores(user_id, exp, coins, likes_remaining, created_at) VALUES($1,$2,$3,$4,$5) RETURNING id;",
+		s.UserID, s.Exp, s.Coins, s.LikesRemaining, s.CreatedAt).Scan(&s.ID)
 	if err != nil {
-		log.Printf("last insert id error: %v", err)
-		return 0, err
+		log.Printf("Create score: insert error: %v", err)
+		return dbError(err)
 	}
 
 	log.Printf("score successfully created with id %v", s.ID)
 
-	affected, err := res.RowsAffected()
-	if err != nil {
-		log.Printf("rows effected error: %v", err)
-		return 0, err
-	}
-	if affected == 0 {
-		log.Printf("rows effected -> %v", affected)
-		return 500, errors.New("Server error")
-	}
-
-	return 0, nil
+	return nil
 }
 
-//AddExp func updates the experience points in db
-func (s *Score) AddExp(amount int) (int, error) {
-	count, err := s.Count("WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL", s.ID, s.UserID)
+//add func increments one of the counters of the score of the user
+func (r *pgScoreRepository) add(s *Score, setClause string, amount int, e *AuditEvent) error {
+	count, err := r.count(s)
 	if err != nil {
 		log.Printf("Score count error: %v", err)
-		return 500, errors.New("Server error")
+		return Internal(err)
 	}
 
 	if count == 0 {
-		return 404, errors.New("Score not found")
+		return NotFound(CodeScoreNotFound, "Score not found")
 	}
 
 	if count != 1 {
-		return 409, errors.New("Conflict in records detected")
-	}
-
-	stmt, err := db.Prepare("UPDATE scores SET exp=exp+$1, updated_at=$2 WHERE id=$3 AND user_id=$4;")
-	if err != nil {
-		log.Printf("create prepare statement error: %v", err)
-		return 500, err
+		return Conflict(CodeMultipleFound, "Conflict in records detected")
 	}
 
-	now := time.Now()
-	s.UpdatedAt = &now
-
-	res, err := stmt.Exec(amount, s.UpdatedAt, s.ID, s.UserID)
-	if err != nil {
-		log.Printf("exec statement error: %v", err)
-		return 500, err
-	}
-
-	affected, err := res.RowsAffected()
-	if err != nil {
-		log.Printf("rows effected error: %v", err)
-		return 500, err
-	}
-	if affected == 0 {
-		log.Printf("rows effected -> %v", affected)
-		return 500, errors.New("Server error")
-	}
-	return 0, nil
-}
-
-//AddCoins func updates coins on db
-func (s *Score) AddCoins(amount int) (int, error) {
-	count, err := s.Count("WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL", s.ID, s.UserID)
-	if err != nil {
-		log.Printf("Score count error: %v", err)
-		return 500, errors.New("Server error")
-	}
-
-	if count == 0 {
-		return 404, errors.New("Score not found")
-	}
-
-	if count != 1 {
-		return 409, errors.New("Conflict in records detected")
-	}
+	return withAudit(r.db, e, auditScore, s.ID, func(q dbtx) error {
+		stmt, err := q.Prepare("UPDATE scores SET " + setClause + ", updated_at=$2 WHERE id=$3 AND user_id=$4;")
+		if err != nil {
+			log.Printf("create prepare statement error: %v", err)
+			return Internal(err)
+		}
+		defer stmt.Close()
 
-	stmt, err := db.Prepare("UPDATE scores SET coins=coins+($1), updated_at=$2  WHERE id=$3 AND user_id=$4;")
-	if err != nil {
-		log.Printf("create prepare statement error: %v", err)
-		return 500, err
-	}
+		now := time.Now()
+		s.UpdatedAt = &now
 
-	now := time.Now()
-	s.UpdatedAt = &now
+		res, err := stmt.Exec(amount, s.UpdatedAt, s.ID, s.UserID)
+		if err != nil {
+			log.Printf("exec statement error: %v", err)
+			return Internal(err)
+		}
 
-	res, err := stmt.Exec(amount, s.UpdatedAt, s.ID, s.UserID)
-	if err != nil {
-		log.Printf("exec statement error: %v", err)
-		return 500, err
-	}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			log.Printf("rows effected error: %v", err)
+			return Internal(err)
+		}
+		if affected == 0 {
+			log.Printf("rows effected -> %v", affected)
+			return NotFound(CodeScoreNotFound, "Score not found")
+		}
 
-	affected, err := res.RowsAffected()
-	if err != nil {
-		log.Printf("rows effected error: %v", err)
-		return 500, err
-	}
-	if affected == 0 {
-		log.Printf("rows effected -> %v", affected)
-		return 500, errors.New("Server error")
-	}
-	return 0, err
+		return nil
+	})
 }
 
-/*
-//ResetLikes func updates likes on db
-func (s *Score) ResetLikes(amount int) (int, error) {
-	count, err := s.Count("WHERE id=$1 AND user_id=$2 AND likes_remaining=0 AND likes_updated_at < NOW() - INTERVAL '1 hour'", s.ID, s.UserID)
-	if err != nil {
-		log.Printf("Score count error: %v", err)
-		return 500, errors.New("Server error")
-	}
-
-	if count == 0 {
-		return 405, errors.New("Not allowed")
-	}
-
-	if count != 1 {
-		return 409, errors.New("Conflict in records detected")
-	}
+//AddExp func updates the experience points in db
+func (r *pgScoreRepository) AddExp(s *Score, amount int, e *AuditEvent) error {
+	return r.add(s, "exp=exp+$1", amount, e)
+}
 
-	stmt, err := db.Prepare("UPDATE scores SET likes_remaining=$1 WHERE id=$2 AND user_id=$3;")
-	if err != nil {
-		log.Printf("create prepare statement error: %v", err)
-		return 500, err
-	}
+//AddCoins func updates coins on db
+func (r *pgScoreRepository) AddCoins(s *Score, amount int, e *AuditEvent) error {
+	return r.add(s, "coins=coins+($1)", amount, e)
+}
 
-	res, err := stmt.Exec(amount, s.ID, s.UserID)
+//RefillLikes func gives the amount of likes back to the users who ran out of likes before emptySince. It returns the
+//number of refilled scores.
+func (r *pgScoreRepository) RefillLikes(amount int, emptySince time.Time) (int64, error) {
+	res, err := r.db.Exec(`UPDATE scores SET likes_remaining=$1, likes_updated_at=NULL, updated_at=NOW()
+	WHERE likes_remaining<=0 AND COALESCE(likes_updated_at, updated_at, created_at)<$2;`, amount, emptySince)
 	if err != nil {
-		log.Printf("exec statement error: %v", err)
-		return 500, err
+		log.Printf("Refill likes: sql error %v", err)
+		return 0, err
 	}
 
-	affected, err := res.RowsAffected()
-	if err != nil {
-		log.Printf("rows effected error: %v", err)
-		return 500, err
-	}
-	if affected == 0 {
-		log.Printf("rows effected -> %v", affected)
-		return 500, errors.New("Server error")
-	}
-	return 0, err
+	return res.RowsAffected()
 }
-*/
 
 //AddLikes func updates likes on db
-func (s *Score) AddLikes(amount int) (int, error) {
-	count, err := s.Count("WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL", s.ID, s.UserID)
-	if err != nil {
-		log.Printf("Score count error: %v", err)
-		return 500, errors.New("Server error")
-	}
-
-	if count == 0 {
-		return 405, errors.New("Not allowed")
-	}
-
-	if count != 1 {
-		return 409, errors.New("Conflict in records detected")
-	}
-
-	stmt, err := db.Prepare("UPDATE scores SET likes_remaining=likes_remaining + $1 WHERE id=$2 AND user_id=$3;")
-	if err != nil {
-		log.Printf("create prepare statement error: %v", err)
-		return 500, err
-	}
-
-	res, err := stmt.Exec(amount, s.ID, s.UserID)
-	if err != nil {
-		log.Printf("exec statement error: %v", err)
-		return 500, err
-	}
-
-	affected, err := res.RowsAffected()
-	if err != nil {
-		log.Printf("rows effected error: %v", err)
-		return 500, err
-	}
-	if affected == 0 {
-		log.Printf("rows effected -> %v", affected)
-		return 500, errors.New("Server error")
-	}
-	return 0, err
+func (r *pgScoreRepository) AddLikes(s *Score, amount int, e *AuditEvent) error {
+	return r.add(s, "likes_remaining=likes_remaining+$1", amount, e)
 }
 
 /*
@@ -309,11 +201,11 @@ func (s *Score) UpdateLevel(levelID int64) (int, error) {
 }
 */
 
-//Count func counts the users from db
-func (s *Score) Count(whereClause string, args ...interface{}) (int64, error) {
+//count func counts the scores matching the id and the user of the given score
+func (r *pgScoreRepository) count(s *Score) (int64, error) {
 	var count int64
 
-	if err := db.QueryRow("SELECT COUNT(id) FROM scores "+whereClause+";", args...).Scan(&count); err != nil {
+	if err := r.db.QueryRow("SELECT COUNT(id) FROM scores WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL;", s.ID, s.UserID).Scan(&count); err != nil {
 		log.Printf("Count scores: sql error %v", err)
 		return count, err
 	}
@@ -0,0 +1,145 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+//types of the search results
+const (
+	SearchChallenge = "challenge"
+	SearchUser      = "user"
+)
+
+//SearchResult struct is a challenge or a user matching a search. The snippet is HTML escaped text with the matched words
+//wrapped in <mark> tags, the rank orders the results from the best match.
+type SearchResult struct {
+	Type    string  `json:"type"`
+	ID      int64   `json:"id"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+}
+
+//SearchPage struct is a page of search results. NextCursor is empty on the last page.
+type SearchPage struct {
+	Results    []*SearchResult `json:"results"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+//SearchCursor struct is the position of the last result of a page, the next page starts right after it
+type SearchCursor struct {
+	Rank float64 `json:"r"`
+	Type string  `json:"t"`
+	ID   int64   `json:"i"`
+}
+
+//Encode func turns the cursor into the opaque string handed to the clients
+func (c *SearchCursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+//ParseSearchCursor func reads a cursor made by Encode
+func ParseSearchCursor(s string) (*SearchCursor, error) {
+	c := SearchCursor{}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(b, &c)
+	}
+	if err != nil || (c.Type != SearchChallenge && c.Type != SearchUser) {
+		return nil, Validation(CodeInvalidQueryString, "Invalid query strings", "cursor")
+	}
+
+	return &c, nil
+}
+
+//after func tells if the result comes after the cursor in the rank desc, type, id desc order
+func (c *SearchCursor) after(r *SearchResult) bool {
+	if r.Rank != c.Rank {
+		return r.Rank < c.Rank
+	}
+	if r.Type != c.Type {
+		return r.Type > c.Type
+	}
+	return r.ID < c.ID
+}
+
+//searchableStates are the challenge states found by the search, drafts and archived challenges stay hidden
+var searchableStates = []string{ChallengeScheduled, ChallengeActive, ChallengeClosed}
+
+//searchHeadline is the ts_headline option list of the snippets
+const searchHeadline = `StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=" … "`
+
+//htmlEscaped func wraps a text expression so the headline only adds its own tags to it
+func htmlEscaped(expr string) string {
+	return "replace(replace(replace(" + expr + ", '&', '&amp;'), '<', '&lt;'), '>', '&gt;')"
+}
+
+//searchQuery ranks the challenges and the users matching the tsquery or similar enough to the raw query by trigrams.
+//The search_vector columns and the indexes are maintained by the 0014 migration.
+var searchQuery = `WITH query AS (SELECT websearch_to_tsquery('simple', $1) AS tsq)
+SELECT type, id, title, snippet, rank FROM (
+	SELECT 'challenge' AS type, c.id, c.name AS title,
+	ts_headline('simple', ` + htmlEscaped("c.name || COALESCE(' — ' || c.description, '')") + `, query.tsq, $2) AS snippet,
+	(ts_rank(c.search_vector, query.tsq) + similarity(c.name, $1))::float8 AS rank
+	FROM challenges c, query
+	WHERE c.deleted_at IS NULL AND c.status = ANY($3) AND (c.search_vector @@ query.tsq OR c.name % $1)
+	UNION ALL
+	SELECT 'user', u.id, u.name,
+	ts_headline('simple', ` + htmlEscaped("u.name") + `, query.tsq, $2),
+	(ts_rank(u.search_vector, query.tsq) + similarity(u.name, $1))::float8
+	FROM users u, query
+	WHERE u.deleted_at IS NULL AND (u.search_vector @@ query.tsq OR u.name % $1)
+) results `
+
+//afterCursor func matches the results after the cursor in the rank desc, type, id desc order
+func afterCursor(c *SearchCursor) Predicate {
+	return predicateFunc(func(q *Query) string {
+		rank, typ, id := q.arg(c.Rank), q.arg(c.Type), q.arg(c.ID)
+		return "(rank<" + rank + " OR (rank=" + rank + " AND (type>" + typ + " OR (type=" + typ + " AND id<" + id + "))))"
+	})
+}
+
+//pgSearchRepository struct is the postgres implementation of SearchRepository
+type pgSearchRepository struct {
+	db *sql.DB
+}
+
+//Search func fetches the results of the query, best match first
+func (r *pgSearchRepository) Search(filter SearchFilter) ([]*SearchResult, error) {
+	q := NewQuery()
+	q.arg(filter.Query)
+	q.arg(searchHeadline)
+	q.arg(pq.Array(searchableStates))
+
+	if filter.Type != "" {
+		q.Where(Eq("type", filter.Type))
+	}
+	if filter.Cursor != nil {
+		q.Where(afterCursor(filter.Cursor))
+	}
+	clause, args := q.Sort(SortKeys{"rank": "rank DESC, type, id DESC"}, "rank", "rank").Page(0, filter.Limit).Build()
+
+	resultList := []*SearchResult{}
+	rows, err := r.db.Query(searchQuery+clause+";", args...)
+	if err != nil {
+		log.Printf("Search: sql error %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		result := SearchResult{}
+		if err = rows.Scan(&result.Type, &result.ID, &result.Title, &result.Snippet, &result.Rank); err != nil {
+			log.Printf("scanning row to struct error: %v", err)
+			return nil, err
+		}
+		resultList = append(resultList, &result)
+	}
+	return resultList, nil
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"log"
+	"time"
+
+	"github.com/challengr/config"
+)
+
+//refreshTrending recounts the posts, the liked posts and the velocity of every active challenge in one pass, keeps them in
+//challenge_trends and copies the score into the indexed trending_score column the trending sort reads.
+//$1 is now, $2 the window in seconds and $3, $4, $5 the weights of the posts, liked posts and velocity.
+const refreshTrending = `WITH active AS (
+	SELECT id, weight FROM challenges WHERE deleted_at IS NULL AND status='active'
+), counts AS (
+	SELECT posts.challenge_id, COUNT(*) AS posts, COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM likes WHERE likes.post_id=posts.id)) AS liked_posts
+	FROM posts JOIN active ON active.id=posts.challenge_id WHERE posts.deleted_at IS NULL GROUP BY posts.challenge_id
+), events AS (
+	SELECT posts.challenge_id, posts.created_at FROM posts
+	WHERE posts.deleted_at IS NULL AND posts.created_at>$1::timestamptz-$2::float8*INTERVAL '1 second'
+	UNION ALL
+	SELECT posts.challenge_id, likes.created_at FROM likes JOIN posts ON posts.id=likes.post_id
+	WHERE posts.deleted_at IS NULL AND likes.created_at>$1::timestamptz-$2::float8*INTERVAL '1 second'
+), velocities AS (
+	SELECT challenge_id, SUM(GREATEST(1-EXTRACT(EPOCH FROM $1::timestamptz-created_at)::float8/$2::float8, 0)) AS velocity
+	FROM events GROUP BY challenge_id
+), trends AS (
+	SELECT active.id AS challenge_id, COALESCE(counts.posts, 0) AS posts, COALESCE(counts.liked_posts, 0) AS liked_posts,
+	COALESCE(velocities.velocity, 0) AS velocity,
+	($3::float8*COALESCE(counts.posts, 0) + $4::float8*COALESCE(counts.liked_posts, 0) + $5::float8*COALESCE(velocities.velocity, 0)) * (1 + active.weight) AS score
+	FROM active LEFT JOIN counts ON counts.challenge_id=active.id LEFT JOIN velocities ON velocities.challenge_id=active.id
+), saved AS (
+	INSERT INTO challenge_trends (challenge_id, posts, liked_posts, velocity, score, refreshed_at)
+	SELECT challenge_id, posts, liked_posts, velocity, score, $1 FROM trends
+	ON CONFLICT (challenge_id) DO UPDATE SET posts=EXCLUDED.posts, liked_posts=EXCLUDED.liked_posts, velocity=EXCLUDED.velocity,
+	score=EXCLUDED.score, refreshed_at=EXCLUDED.refreshed_at
+)
+UPDATE challenges SET trending_score=trends.score FROM trends
+WHERE challenges.id=trends.challenge_id AND challenges.trending_score IS DISTINCT FROM trends.score;`
+
+//trendScore func is the trending formula of the config, the velocity of a challenge sums its decayed posts and likes
+func trendScore(formula config.Trending, posts, likedPosts int, velocity float64, weight *float32) float64 {
+	score := formula.Posts*float64(posts) + formula.LikedPosts*float64(likedPosts) + formula.Velocity*velocity
+	if weight != nil {
+		score = score * (1 + float64(*weight))
+	}
+	return score
+}
+
+//decayed func returns the share of a post or like created at t still counting in the velocity: 1 right away, down to 0
+//at the end of the window
+func decayed(now, t time.Time, window time.Duration) float64 {
+	age := now.Sub(t)
+	if age >= window {
+		return 0
+	}
+	return 1 - age.Seconds()/window.Seconds()
+}
+
+//RefreshTrending func recounts the trending counters of the active challenges and their score. It returns the number of
+//challenges whose score changed.
+func (r *pgChallengeRepository) RefreshTrending(now time.Time, formula config.Trending) (int64, error) {
+	res, err := r.db.Exec(refreshTrending, now, formula.Window.Seconds(), formula.Posts, formula.LikedPosts, formula.Velocity)
+	if err != nil {
+		log.Printf("Refresh trending: sql error %v", err)
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
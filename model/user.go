@@ -1,15 +1,14 @@
 package model
 
 import (
+	"database/sql"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"log"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/challengr/middleware"
+	"github.com/challengr/keyset"
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
@@ -22,115 +21,207 @@ type User struct {
 	Role           string     `json:"role" sql:"role"`
 	Gender         string     `json:"gender" sql:"gender"`
 	DOB            string     `json:"date_of_birth" sql:"date_of_birth"`
-	Weight         *float32   `json:"weight" sql:"weight"`
+	Weight         *float32   `json:"weight" sql:"weight" bind:"weight"`
 	CreatedAt      *time.Time `json:"created_at" sql:"created_at"`
 	UpdatedAt      *time.Time `json:"updated_at" sql:"updated_at"`
 	DeletedAt      *time.Time `json:"deleted_at" sql:"deleted_at"`
+	DeletionDueAt  *time.Time `json:"deletion_due_at,omitempty" sql:"deletion_due_at"`
 
-	Token    string    `json:"token,omitempty" sql:"-"`
-	Location *geometry `json:"geo_coords" sql:"-"`
-	LevelID  int64     `json:"-" sql:"level_id"`
+	Token          string     `json:"token,omitempty" sql:"-"`
+	TokenExpiresAt *time.Time `json:"token_expires_at,omitempty" sql:"-"`
+	RefreshToken   string     `json:"refresh_token,omitempty" sql:"-"`
+	Location       *geometry  `json:"geo_coords" sql:"-"`
+	LevelID        int64      `json:"level_id,omitempty" sql:"level_id" bind:"level"`
 
 	//User        *User         `json:"user" sql:"-"`
 	TotalPost   int64         `json:"total_post" sql:"-"`
 	Level       *Level        `json:"level" sql:"-"`
 	BoughtItems []*BoughtItem `json:"bought_items" sql:"-"` //this will be fetched via user_id
 	Score       *Score        `json:"score" sql:"score"`
-
-	Payload map[string]interface{} `json:"-"`
 }
 
-//CreateTokenString func creates a new jwt token
-func (u *User) CreateTokenString() string {
-	// Embed User information to `token`
-	token := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), &middleware.JWTUser{
+//CreateTokenString func creates a new jwt access token of the session signed with the signing key of the keyset, it expires at expiresAt
+func (u *User) CreateTokenString(keys *keyset.Keyset, sessionID string, expiresAt time.Time) (string, error) {
+	claims := &JWTUser{
 		ID:             u.ID,
 		FacebookUserID: u.FacebookUserID,
-		Weight:         *u.Weight,
 		Role:           u.Role,
-	})
-	// token -> string. Only server knows this secret (foobar).
-	tokenstring, err := token.SignedString([]byte(JWTSecret))
+		SessionID:      sessionID,
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+	if u.Weight != nil {
+		claims.Weight = *u.Weight
+	}
+
+	// Embed User information to `token`
+	tokenstring, err := keys.Sign(claims)
 	if err != nil {
-		log.Fatalln(err)
+		log.Printf("token signing error: %v", err)
+		return "", err
 	}
-	return tokenstring
+	return tokenstring, nil
 }
 
-//ParseNotAllowedJSON unmarshalls JSON payload to struct payload and fields. Plus parses the JSON payload.
-func (u *User) ParseNotAllowedJSON() []string {
+
+//UserOpWeight and UserOpLevel are the operations of the endpoints which update a single field of the user
+const (
+	UserOpWeight = "weight"
+	UserOpLevel  = "level"
+)
+
+//Validate func validates the field of the operation
+func (u *User) Validate(op string) []string {
 	errSlice := []string{}
 
-	delete(u.Payload, "name")
-	delete(u.Payload, "role")
-	delete(u.Payload, "gender")
-	delete(u.Payload, "date_of_birth")
+	if op == UserOpWeight && (u.Weight == nil || *u.Weight <= 0) {
+		errSlice = append(errSlice, "weight")
+	}
 
-	for key := range u.Payload {
-		errSlice = append(errSlice, key)
+	if op == UserOpLevel && u.LevelID <= 0 {
+		errSlice = append(errSlice, "level_id")
 	}
 
 	return errSlice
 }
 
+//pgUserRepository struct is the postgres implementation of UserRepository
+type pgUserRepository struct {
+	db *sql.DB
+}
+
+//userSorts is the whitelist of the orderings of the users
+var userSorts = SortKeys{
+	"level": "users.level_id DESC NULLS LAST, users.id",
+}
+
+//query func translates the filter into a typed query
+func (r *pgUserRepository) query(filter UserFilter) *Query {
+	q := NewQuery().Where(IsNull("deleted_at"))
+
+	if len(filter.IDs) > 0 {
+		ids := make([]interface{}, len(filter.IDs))
+		for i, id := range filter.IDs {
+			ids[i] = id
+		}
+		q.Where(In("id", ids...))
+	}
+
+	if len(filter.FacebookUserIDs) > 0 {
+		fbIDs := make([]interface{}, len(filter.FacebookUserIDs))
+		for i, fbID := range filter.FacebookUserIDs {
+			fbIDs[i] = fbID
+		}
+		q.Where(In("facebook_user_id", fbIDs...))
+	}
+
+	if filter.Near != nil {
+		q.Where(WithinRadius("geometry", *filter.Near))
+	}
+
+	return q.Sort(userSorts, "level", "level").Page(filter.LastID, filter.Limit)
+}
+
 //Create func inserts a new user in db
-func (u *User) Create() error {
-	now := time.Now()
+func (r *pgUserRepository) Create(u *User) error {
+	if err := insertUser(r.db, u, time.Now()); err != nil {
+		log.Printf("Create user: insert error: %v", err)
+		return err
+	}
+
+	log.Printf("user successfully created with id %v", u.ID)
+
+	return nil
+}
+
+//insertUser func inserts the user with the user role and the default weight
+func insertUser(q dbtx, u *User, now time.Time) error {
 	u.CreatedAt = &now
 	u.Role = roleUser
+	if u.Weight == nil {
+		weight := float32(1)
+		u.Weight = &weight
+	}
 
-	stmt, err := db.Prepare("INSERT INTO users(name, email, facebook_user_id, role, gender, date_of_birth, created_at) VALUES($1,$2,$3,$4,$5,$6,$7);")
+	return q.QueryRow("INSERT INTO users(name, email, facebook_user_id, role, gender, date_of_birth, weight, created_at) VALUES($1,$2,$3,$4,$5,$6,$7,$8) RETURNING id;",
+		u.Name, u.Email, u.FacebookUserID, u.Role, u.Gender, u.DOB, u.Weight, u.CreatedAt).Scan(&u.ID)
+}
+
+//SignUp func inserts the user of a first login with its sign in identity and its score in one transaction. When a
+//concurrent login linked the identity first nothing is inserted and the error has the CodeIdentityInUse code.
+func (r *pgUserRepository) SignUp(u *User, i *UserIdentity, s *Score) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		log.Printf("create user prepare statement error: %v", err)
-		return err
+		log.Printf("SignUp: begin error: %v", err)
+		return Internal(err)
 	}
+	defer tx.Rollback()
 
-	res, err := stmt.Exec(u.Name, u.Email, u.FacebookUserID, u.Role, u.Gender, u.DOB, u.CreatedAt)
-	if err != nil {
-		log.Printf("Create user: exec statement error: %v", err)
-		return err
+	now := time.Now()
+	if err = insertUser(tx, u, now); err != nil {
+		log.Printf("SignUp: insert user error: %v", err)
+		return dbError(err)
 	}
 
-	u.ID, err = res.LastInsertId()
+	i.UserID = u.ID
+	i.CreatedAt = now
+	err = tx.QueryRow("INSERT INTO user_identities(user_id, provider, subject, email, created_at) VALUES($1,$2,$3,$4,$5) RETURNING id;", i.UserID, i.Provider, i.Subject, i.Email, i.CreatedAt).Scan(&i.ID)
 	if err != nil {
-		log.Printf("last insert id error: %v", err)
+		log.Printf("SignUp: insert identity error: %v", err)
+		if err = dbError(err); AsError(err).Kind == KindConflict {
+			return Conflict(CodeIdentityInUse, "Identity already linked to a user")
+		}
 		return err
 	}
 
-	log.Printf("user successfully created with id %v", u.ID)
-
-	affected, err := res.RowsAffected()
+	s.UserID = u.ID
+	s.CreatedAt = now
+	err = tx.QueryRow("INSERT INTO scores(user_id, exp, coins, likes_remaining, created_at) VALUES($1,$2,$3,$4,$5) RETURNING id;",
+		s.UserID, s.Exp, s.Coins, s.LikesRemaining, s.CreatedAt).Scan(&s.ID)
 	if err != nil {
-		log.Printf("rows effected error: %v", err)
-		return err
+		log.Printf("SignUp: insert score error: %v", err)
+		return dbError(err)
 	}
-	if affected == 0 {
-		log.Printf("rows effected -> %v", affected)
-		return errors.New("Server error")
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("SignUp: commit error: %v", err)
+		return Internal(err)
 	}
 
+	log.Printf("user successfully signed up with id %v", u.ID)
+
 	return nil
 }
 
 //Get func fetches the users from db
-func (u *User) Get(whereClause string, args ...interface{}) ([]*User, error) {
+func (r *pgUserRepository) Get(filter UserFilter) ([]*User, error) {
+	whereClause, args := r.query(filter).Build()
+
 	userList := []*User{}
-	rows, err := db.Query(`SELECT id, name, email, facebook_user_id, role, (SELECT COUNT(posts.id) FROM posts WHERE posts.user_id=users.user_id) AS total_post, (SELECT row_to_json(levels) FROM levels WHERE levels.id=users.level_id) as level, 
-	(SELECT COALESCE(array_to_json(array_agg(bought_items)), '[]') FROM bought_items WHERE bought_items.user_id=users.user_id) as bought_items, SELECT row_to_json(score) FROM 
-	(SELECT id, exp, coins, likes_remaining, created_at FROM scores WHERE scores.user_id=users.id) score, created_at, updated_at, deleted_at FROM users `+whereClause, args...)
+	rows, err := r.db.Query(`SELECT id, name, email, facebook_user_id, role, level_id, weight, (SELECT COUNT(posts.id) FROM posts WHERE posts.user_id=users.id AND posts.deleted_at IS NULL) AS total_post, 
+	COALESCE((SELECT row_to_json(levels) FROM levels WHERE levels.id=users.level_id), 'null') AS level, 
+	(SELECT COALESCE(array_to_json(array_agg(bought_items)), '[]') FROM bought_items WHERE bought_items.user_id=users.id) AS bought_items, 
+	COALESCE((SELECT row_to_json(score) FROM (SELECT id, exp, coins, likes_remaining AS like_remaining, created_at FROM scores WHERE scores.user_id=users.id) score), 'null') AS score, 
+	created_at, updated_at, deleted_at, deletion_due_at FROM users `+whereClause+";", args...)
 	if err != nil {
 		log.Printf("Get users: sql error %v", err)
 		return nil, err
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		user := User{}
+		levelID := sql.NullInt64{}
 		levelStr := ""
 		boughtItemsStr := ""
 		scoreStr := ""
-		if err = rows.Scan(&user.ID, &user.Name, &user.Email, &user.FacebookUserID, &user.Role, &user.TotalPost, &levelStr, &boughtItemsStr, &scoreStr, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt); err != nil {
+		if err = rows.Scan(&user.ID, &user.Name, &user.Email, &user.FacebookUserID, &user.Role, &levelID, &user.Weight, &user.TotalPost, &levelStr, &boughtItemsStr, &scoreStr, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt, &user.DeletionDueAt); err != nil {
 			log.Printf("scanning row to struct error: %v", err)
 			return nil, err
 		}
+		user.LevelID = levelID.Int64
 
 		if err = json.Unmarshal([]byte(scoreStr), &user.Score); err != nil {
 			log.Printf("Unmarshaling of score subquery error: %v", err)
@@ -152,10 +243,11 @@ func (u *User) Get(whereClause string, args ...interface{}) ([]*User, error) {
 }
 
 //Count func counts the users from db
-func (u *User) Count(whereClause string, args ...interface{}) (int64, error) {
+func (r *pgUserRepository) Count(filter UserFilter) (int64, error) {
 	var count int64
 
-	if err := db.QueryRow("SELECT COUNT(id) FROM users "+whereClause+";", args...).Scan(&count); err != nil {
+	whereClause, args := r.query(filter).WhereClause()
+	if err := r.db.QueryRow("SELECT COUNT(id) FROM users "+whereClause+";", args...).Scan(&count); err != nil {
 		log.Printf("Count users: sql error %v", err)
 		return count, err
 	}
@@ -163,35 +255,8 @@ func (u *User) Count(whereClause string, args ...interface{}) (int64, error) {
 	return count, nil
 }
 
-//UpdateFBFields func updates the name of the user
-func (u *User) UpdateFBFields(updateQuery string, args ...interface{}) error {
-	stmt, err := db.Prepare(updateQuery)
-	if err != nil {
-		log.Printf("UPDATE user prepare statement error: %v", err)
-		return err
-	}
-
-	res, err := stmt.Exec(args...)
-	if err != nil {
-		log.Printf("exec statement error: %v", err)
-		return err
-	}
-
-	affected, err := res.RowsAffected()
-	if err != nil {
-		log.Printf("rows effected error: %v", err)
-		return err
-	}
-	if affected == 0 {
-		log.Printf("rows effected -> %v", affected)
-		return errors.New("Server error")
-	}
-
-	return err
-}
-
-//Update func updates a user in db
-func (u *User) Update() error {
+//Update func updates a user in db. With an audit event the change is recorded.
+func (r *pgUserRepository) Update(u *User, e *AuditEvent) error {
 	sets := []string{}
 	values := make(map[int]interface{})
 	index := 0
@@ -250,54 +315,60 @@ func (u *User) Update() error {
 		sets = append(sets, "updated_at=$"+strconv.Itoa(index))
 	}
 
-	stmt, err := db.Prepare("UPDATE users SET " + strings.Join(sets, ", ") + " WHERE id=" + fmt.Sprintf("%v", u.ID) + " AND deleted IS NULL;")
-	if err != nil {
-		log.Printf("UPDATE user prepare statement error: %v", err)
-		return err
-	}
+	values[index] = u.ID
+	index = index + 1
 
-	argsValues := make([]interface{}, len(values))
-	for k, v := range values {
-		argsValues[k] = v
-	}
+	return withAudit(r.db, e, auditUser, u.ID, func(q dbtx) error {
+		stmt, err := q.Prepare("UPDATE users SET " + strings.Join(sets, ", ") + " WHERE id=$" + strconv.Itoa(index) + " AND deleted_at IS NULL;")
+		if err != nil {
+			log.Printf("UPDATE user prepare statement error: %v", err)
+			return err
+		}
+		defer stmt.Close()
 
-	res, err := stmt.Exec(argsValues...)
-	if err != nil {
-		log.Printf("exec statement error: %v", err)
-		return err
-	}
+		argsValues := make([]interface{}, len(values))
+		for k, v := range values {
+			argsValues[k] = v
+		}
 
-	affected, err := res.RowsAffected()
-	if err != nil {
-		log.Printf("rows effected error: %v", err)
-		return err
-	}
-	if affected == 0 {
-		log.Printf("rows effected -> %v", affected)
-		return errors.New("Server error")
-	}
+		res, err := stmt.Exec(argsValues...)
+		if err != nil {
+			log.Printf("exec statement error: %v", err)
+			return err
+		}
 
-	return nil
+		affected, err := res.RowsAffected()
+		if err != nil {
+			log.Printf("rows effected error: %v", err)
+			return err
+		}
+		if affected == 0 {
+			log.Printf("rows effected -> %v", affected)
+			return NotFound(CodeUserNotFound, "User not found")
+		}
+
+		return nil
+	})
 }
 
 //Delete func deletes the user. Delete meaning it doesnt purge it. Just hides it.
-func (u *User) Delete() error {
-	count, err := u.Count("WHERE id=$1 AND deleted_at IS NULL", u.ID)
+func (r *pgUserRepository) Delete(u *User) error {
+	count, err := r.Count(UserFilter{IDs: []int64{u.ID}})
 	if err != nil {
 		log.Printf("User delete: error on fetching User record count: %v", err)
 		return err
 	}
 
 	if count == 0 {
-		err = fmt.Errorf("User account not found-> id %v, total found %v", u.ID, count)
-		log.Printf("%v", err)
-		return err
+		log.Printf("User account not found-> id %v, total found %v", u.ID, count)
+		return NotFound(CodeUserNotFound, "User account not found")
 	} else if count == 1 {
-		stmt, err := db.Prepare("UPDATE users SET deleted_at=$1 WHERE id=$2;")
+		stmt, err := r.db.Prepare("UPDATE users SET deleted_at=$1 WHERE id=$2;")
 		if err != nil {
 			log.Printf("create prepare statement error: %v", err)
 			return err
 		}
+		defer stmt.Close()
 
 		res, err := stmt.Exec(time.Now(), u.ID)
 		if err != nil {
@@ -312,12 +383,11 @@ func (u *User) Delete() error {
 		}
 		if affected == 0 {
 			log.Printf("rows effected -> %v", affected)
-			return errors.New("Server error")
+			return NotFound(CodeUserNotFound, "User account not found")
 		}
 	} else {
-		err = fmt.Errorf("multiple Users found-> id %v, total found %v", u.ID, count)
-		log.Printf("%v", err)
-		return err
+		log.Printf("multiple Users found-> id %v, total found %v", u.ID, count)
+		return Conflict(CodeMultipleFound, "Multiple users detected")
 	}
 
 	return nil
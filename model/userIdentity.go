@@ -0,0 +1,163 @@
+package model
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+//providerFacebook is the provider whose subject is also kept in users.facebook_user_id, the fb_ids lookups use it
+const providerFacebook = "facebook"
+
+//UserIdentity struct is a model/schema for user_identities table. It links an account of a sign in provider to a user.
+type UserIdentity struct {
+	ID        int64     `json:"id" sql:"id"`
+	UserID    int64     `json:"user_id" sql:"user_id"`
+	Provider  string    `json:"provider" sql:"provider"`
+	Subject   string    `json:"subject" sql:"subject"`
+	Email     string    `json:"email" sql:"email"`
+	CreatedAt time.Time `json:"created_at" sql:"created_at"`
+}
+
+//IdentityLink struct is the payload for linking a provider account to the user. Token is the facebook access token or the id token.
+type IdentityLink struct {
+	Provider string `json:"provider" bind:"create"`
+	Token    string `json:"token" bind:"create"`
+}
+
+//Validate func validates an identity link payload data
+func (l *IdentityLink) Validate(op string) []string {
+	errSlice := []string{}
+
+	if l.Provider == "" {
+		errSlice = append(errSlice, "provider")
+	}
+
+	if l.Token == "" {
+		errSlice = append(errSlice, "token")
+	}
+
+	return errSlice
+}
+
+//pgUserIdentityRepository struct is the postgres implementation of UserIdentityRepository
+type pgUserIdentityRepository struct {
+	db *sql.DB
+}
+
+//Link func links the identity to its user. A facebook identity also becomes the facebook_user_id of the user.
+func (r *pgUserIdentityRepository) Link(i *UserIdentity) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Link identity: begin error: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	i.CreatedAt = time.Now()
+	err = tx.QueryRow("INSERT INTO user_identities(user_id, provider, subject, email, created_at) VALUES($1,$2,$3,$4,$5) RETURNING id;", i.UserID, i.Provider, i.Subject, i.Email, i.CreatedAt).Scan(&i.ID)
+	if err != nil {
+		log.Printf("Link identity: insert error: %v", err)
+		if err = dbError(err); AsError(err).Kind == KindConflict {
+			return Conflict(CodeIdentityInUse, "Identity already linked to a user")
+		}
+		return err
+	}
+
+	if i.Provider == providerFacebook {
+		if _, err = tx.Exec("UPDATE users SET facebook_user_id=$1 WHERE id=$2;", i.Subject, i.UserID); err != nil {
+			log.Printf("Link identity: update user error: %v", err)
+			return dbError(err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Link identity: commit error: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+//Get func fetches the identities passing the filter
+func (r *pgUserIdentityRepository) Get(filter UserIdentityFilter) ([]*UserIdentity, error) {
+	q := NewQuery()
+	if filter.UserID != 0 {
+		q.Where(Eq("user_id", filter.UserID))
+	}
+	if filter.Provider != "" {
+		q.Where(Eq("provider", filter.Provider))
+	}
+	if filter.Subject != "" {
+		q.Where(Eq("subject", filter.Subject))
+	}
+	whereClause, args := q.WhereClause()
+
+	identityList := []*UserIdentity{}
+	rows, err := r.db.Query("SELECT id, user_id, provider, subject, email, created_at FROM user_identities "+whereClause+" ORDER BY id;", args...)
+	if err != nil {
+		log.Printf("Get identities: sql error %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		identity := UserIdentity{}
+		if err = rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt); err != nil {
+			log.Printf("scanning row to struct error: %v", err)
+			return nil, err
+		}
+
+		identityList = append(identityList, &identity)
+	}
+	return identityList, nil
+}
+
+//Unlink func removes the identity of the provider from the user. The last identity of a user can not be unlinked,
+//the user could not sign in anymore.
+func (r *pgUserIdentityRepository) Unlink(i *UserIdentity) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Unlink identity: begin error: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int64
+	if err = tx.QueryRow("SELECT COUNT(id) FROM user_identities WHERE user_id=$1 AND provider<>$2;", i.UserID, i.Provider).Scan(&count); err != nil {
+		log.Printf("Unlink identity: count error: %v", err)
+		return err
+	}
+	if count == 0 {
+		return Forbidden(CodeLastIdentity, "The last sign in method can not be unlinked")
+	}
+
+	res, err := tx.Exec("DELETE FROM user_identities WHERE user_id=$1 AND provider=$2;", i.UserID, i.Provider)
+	if err != nil {
+		log.Printf("Unlink identity: delete error: %v", err)
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Printf("rows effected error: %v", err)
+		return err
+	}
+	if affected == 0 {
+		return NotFound(CodeNotFound, "Identity not found")
+	}
+
+	if i.Provider == providerFacebook {
+		if _, err = tx.Exec("UPDATE users SET facebook_user_id='' WHERE id=$1;", i.UserID); err != nil {
+			log.Printf("Unlink identity: update user error: %v", err)
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Unlink identity: commit error: %v", err)
+		return err
+	}
+
+	return nil
+}
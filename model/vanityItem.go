@@ -1,6 +1,9 @@
 package model
 
-import "log"
+import (
+	"database/sql"
+	"log"
+)
 
 //VanityItem struct is a model/schema for vanity_item table
 type VanityItem struct {
@@ -12,14 +15,33 @@ type VanityItem struct {
 	CreatedAt string `json:"created_at" sql:"created_at"`
 }
 
+//pgVanityItemRepository struct is the postgres implementation of VanityItemRepository
+type pgVanityItemRepository struct {
+	db *sql.DB
+}
+
+//Create func inserts a new vanity item in the db
+func (r *pgVanityItemRepository) Create(v *VanityItem) error {
+	err := r.db.QueryRow("INSERT INTO vanity_items (name, amount, currency, coins, created_at) VALUES($1,$2,$3,$4,NOW()) RETURNING id, created_at;",
+		v.Name, v.Amount, v.Currency, v.Coins).Scan(&v.ID, &v.CreatedAt)
+	if err != nil {
+		log.Printf("Create vanity item: insert error: %v", err)
+		return err
+	}
+
+	return nil
+}
+
 //Get func fetches the vanity items from the db
-func (v *VanityItem) Get(whereClause string, args ...interface{}) ([]*VanityItem, error) {
+func (r *pgVanityItemRepository) Get() ([]*VanityItem, error) {
 	vanityItemList := []*VanityItem{}
-	rows, err := db.Query("SELECT id, name, amount, currency, coins, created_at FROM vanity_items "+whereClause+" ORDER BY created_at DESC;", args...)
+	rows, err := r.db.Query("SELECT id, name, amount, currency, coins, created_at FROM vanity_items ORDER BY created_at DESC;")
 	if err != nil {
-		log.Printf("Get users: sql error %v", err)
+		log.Printf("Get vanity items: sql error %v", err)
 		return nil, err
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		vanityItem := VanityItem{}
 		if err = rows.Scan(&vanityItem.ID, &vanityItem.Name, &vanityItem.Amount, &vanityItem.Currency, &vanityItem.Coins, &vanityItem.CreatedAt); err != nil {
@@ -32,12 +54,12 @@ func (v *VanityItem) Get(whereClause string, args ...interface{}) ([]*VanityItem
 	return vanityItemList, nil
 }
 
-//Count func counts the total vanity_items in the database
-func (v *VanityItem) Count(whereClause string, args ...interface{}) (int64, error) {
+//Count func counts the vanity_items with the given id
+func (r *pgVanityItemRepository) Count(id int64) (int64, error) {
 	var count int64
 
-	if err := db.QueryRow("SELECT COUNT(id) FROM vanity_items "+whereClause+";", args...).Scan(&count); err != nil {
-		log.Printf("Count levels: sql error %v", err)
+	if err := r.db.QueryRow("SELECT COUNT(id) FROM vanity_items WHERE id=$1;", id).Scan(&count); err != nil {
+		log.Printf("Count vanity items: sql error %v", err)
 		return count, err
 	}
 
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"log"
+)
+
+//purgeUsage is printed when the purge command is called wrongly
+const purgeUsage = `usage: challengr purge
+
+Erases the users whose deletion grace period is over: their rows are deleted or anonymized and
+their uploaded files removed from s3. It is the erase_users job, which the server schedules.`
+
+//runPurge func handles the `challengr purge` sub command, a shorthand of `challengr jobs run erase_users`
+func runPurge(args []string) {
+	if len(args) != 0 {
+		log.Fatal(purgeUsage)
+	}
+
+	runJobs([]string{"run", "erase_users"})
+}
@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Rate struct is a token bucket of Limit tokens which refills completely in Period
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+//ParseRate func parses a rate written as "<requests>/<period>", e.g. "10/1m". The empty string is the zero rate, which disables the limit.
+func ParseRate(s string) (Rate, error) {
+	if s == "" {
+		return Rate{}, nil
+	}
+
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Rate{}, fmt.Errorf("rate %q is not <requests>/<period>", s)
+	}
+
+	limit, err := strconv.Atoi(parts[0])
+	if err != nil || limit <= 0 {
+		return Rate{}, fmt.Errorf("rate %q: invalid number of requests", s)
+	}
+
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return Rate{}, fmt.Errorf("rate %q: invalid period", s)
+	}
+
+	return Rate{Limit: limit, Period: period}, nil
+}
+
+//Disabled func tells if the rate is the zero rate
+func (r Rate) Disabled() bool {
+	return r.Limit <= 0 || r.Period <= 0
+}
+
+//perToken func returns how long the bucket takes to refill one token
+func (r Rate) perToken() time.Duration {
+	return r.Period / time.Duration(r.Limit)
+}
+
+//Result struct is the outcome of taking a token. Reset is when the bucket is full again, RetryAfter when the next token is available.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+//Store interface is implemented by the backends keeping the buckets. A shared backend, e.g. redis, lets several
+//instances enforce one limit.
+type Store interface {
+	Take(key string, rate Rate) (Result, error)
+}
+
+//bucket struct is the state of one key: the tokens left at last and the period of its rate
+type bucket struct {
+	tokens float64
+	last   time.Time
+	period time.Duration
+}
+
+//memory struct is the in-memory Store. Every instance of the server limits on its own.
+type memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	pruned  time.Time
+	now     func() time.Time
+}
+
+//pruneInterval is how often the full buckets are dropped from memory
+const pruneInterval = time.Minute
+
+//NewMemory func creates the in-memory Store
+func NewMemory() Store {
+	return NewMemoryClock(time.Now)
+}
+
+//NewMemoryClock func creates the in-memory Store which refills the buckets by the time of now, e.g. a fake clock
+func NewMemoryClock(now func() time.Time) Store {
+	return &memory{buckets: map[string]*bucket{}, now: now}
+}
+
+//Take func takes a token from the bucket of the key, refilled for the time passed since the last take
+func (m *memory) Take(key string, rate Rate) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.prune(now)
+
+	capacity := float64(rate.Limit)
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now, period: rate.Period}
+		m.buckets[key] = b
+	}
+
+	b.tokens += float64(now.Sub(b.last)) / float64(rate.perToken())
+	if b.tokens > capacity {
+		b.tokens = capacity
+	}
+	b.last = now
+
+	res := Result{Limit: rate.Limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - b.tokens) * float64(rate.perToken()))
+	}
+
+	res.Remaining = int(b.tokens)
+	res.Reset = time.Duration((capacity - b.tokens) * float64(rate.perToken()))
+	return res, nil
+}
+
+//prune func drops the buckets which have refilled completely, they are the same as a missing bucket. Caller must hold the lock.
+func (m *memory) prune(now time.Time) {
+	if now.Sub(m.pruned) < pruneInterval {
+		return
+	}
+	m.pruned = now
+
+	for key, b := range m.buckets {
+		if now.Sub(b.last) >= b.period {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+//clock struct is a fake clock which only moves when it is advanced
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestParseRate(t *testing.T) {
+	cases := []struct {
+		rate string
+		want Rate
+		ok   bool
+	}{
+		{"10/1m", Rate{Limit: 10, Period: time.Minute}, true},
+		{"1/500ms", Rate{Limit: 1, Period: 500 * time.Millisecond}, true},
+		{"", Rate{}, true},
+		{"10", Rate{}, false},
+		{"0/1m", Rate{}, false},
+		{"-1/1m", Rate{}, false},
+		{"ten/1m", Rate{}, false},
+		{"10/minute", Rate{}, false},
+		{"10/0s", Rate{}, false},
+	}
+
+	for _, tc := range cases {
+		rate, err := ParseRate(tc.rate)
+		if (err == nil) != tc.ok || rate != tc.want {
+			t.Errorf("ParseRate(%q) = %+v %v, want %+v ok %v", tc.rate, rate, err, tc.want, tc.ok)
+		}
+	}
+
+	if rate, _ := ParseRate(""); !rate.Disabled() {
+		t.Error("empty rate is not disabled")
+	}
+}
+
+//TestTakeRefill checks that the bucket empties, refills one token per Period/Limit and never holds more than Limit
+func TestTakeRefill(t *testing.T) {
+	c := &clock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewMemoryClock(c.Now)
+	rate := Rate{Limit: 3, Period: 3 * time.Second}
+
+	take := func() Result {
+		t.Helper()
+		res, err := store.Take("user:1", rate)
+		if err != nil {
+			t.Fatalf("take: %v", err)
+		}
+		return res
+	}
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		res := take()
+		if !res.Allowed || res.Remaining != remaining || res.Limit != 3 {
+			t.Fatalf("take = %+v, want allowed with %d remaining", res, remaining)
+		}
+	}
+	if res := take(); res.Allowed || res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+		t.Fatalf("take of empty bucket = %+v, want throttled for 1s, full in 3s", res)
+	}
+
+	c.advance(500 * time.Millisecond)
+	if res := take(); res.Allowed || res.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("take of half a token = %+v, want throttled for 500ms", res)
+	}
+
+	c.advance(500 * time.Millisecond)
+	if res := take(); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("take of a refilled token = %+v, want allowed with 0 remaining", res)
+	}
+
+	//a long pause refills the bucket up to its limit only
+	c.advance(time.Hour)
+	if res := take(); !res.Allowed || res.Remaining != 2 || res.Reset != time.Second {
+		t.Fatalf("take of a full bucket = %+v, want allowed with 2 remaining, full in 1s", res)
+	}
+
+	if res, _ := store.Take("user:2", rate); !res.Allowed || res.Remaining != 2 {
+		t.Errorf("take of other key = %+v, want its own bucket", res)
+	}
+}
+
+//TestPrune checks that the full buckets are dropped from memory and the others kept
+func TestPrune(t *testing.T) {
+	c := &clock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	m := NewMemoryClock(c.Now).(*memory)
+
+	m.Take("slow", Rate{Limit: 1, Period: time.Hour})
+	m.Take("fast", Rate{Limit: 1, Period: time.Second})
+
+	c.advance(pruneInterval)
+	m.Take("other", Rate{Limit: 1, Period: time.Second})
+
+	if _, ok := m.buckets["fast"]; ok {
+		t.Error("full bucket kept")
+	}
+	if _, ok := m.buckets["slow"]; !ok {
+		t.Error("refilling bucket dropped")
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+)
+
+//the actions of the audit events
+const (
+	auditChallengeUpdate = "challenge.admin_update"
+	auditChallengeStatus = "challenge.set_status"
+	auditChallengeDelete = "challenge.admin_delete"
+	auditPostDelete      = "post.admin_delete"
+	auditUserWeight      = "user.update_weight"
+	auditUserLevel       = "user.update_level"
+	auditScoreCoins      = "score.add_coins"
+	auditScoreExp        = "score.add_exp"
+	auditScoreLikes      = "score.add_likes"
+)
+
+//auditLimit is the default and the maximum page size of the audit log
+const auditLimit = 100
+
+//auditEvent func starts the audit event of the action, the actor is the user of the token. The repository of the change
+//completes it with the target and writes it in the transaction of the change.
+func auditEvent(c *gin.Context, action string) *model.AuditEvent {
+	e := model.AuditEvent{Action: action, RequestID: c.GetString("request_id")}
+	e.ActorID, _ = c.MustGet("user_id").(int64)
+	e.ActorRole, _ = c.MustGet("role").(string)
+	return &e
+}
+
+//GetAuditEvents handler func lists the audit log, newest first. It filters on the actor_id, action, target_type and target_id
+//query strings and the RFC 3339 from/to time range; before_id pages to older events.
+func (s *Service) GetAuditEvents(c *gin.Context) {
+	filter := model.AuditEventFilter{Action: c.Query("action"), TargetType: c.Query("target_type"), Limit: auditLimit}
+
+	ids := []struct {
+		name string
+		id   *int64
+	}{{"actor_id", &filter.ActorID}, {"target_id", &filter.TargetID}, {"before_id", &filter.BeforeID}}
+	for _, q := range ids {
+		if value := c.Query(q.name); value != "" {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query strings", q.name))
+				return
+			}
+			*q.id = n
+		}
+	}
+
+	times := []struct {
+		name string
+		t    **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}}
+	for _, q := range times {
+		if value := c.Query(q.name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query strings", q.name))
+				return
+			}
+			*q.t = &parsed
+		}
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > auditLimit {
+			c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query strings", "limit"))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	eventList, err := s.store.AuditEvents.Get(filter)
+	if err != nil {
+		log.Printf("audit events fetching error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, eventList)
+}
@@ -1,161 +1,141 @@
 package service
 
 import (
-	"encoding/json"
-	"io/ioutil"
 	"log"
 
 	"net/http"
 
+	"github.com/challengr/identity"
 	"github.com/challengr/model"
 	"github.com/gin-gonic/gin"
 )
 
-const fbGraphURI = "https://graph.facebook.com/me?fields=id,email&access_token="
-
-//LogIn func handler logs in a user based on a facebook token and email. Also updates or sets a onesignal detail.
-func LogIn(c *gin.Context) {
+//LogIn func handler logs in a user with the credential of a sign in provider and starts a session on the device.
+//The first login of a provider account creates the user, other providers are added by linking them.
+func (s *Service) LogIn(c *gin.Context) {
 	var logIn model.LogIn
-	if err := c.BindJSON(&logIn); err != nil {
-		log.Printf("LogIn struct JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: err.Error()})
+	if err := model.Bind(c.Request.Body, &logIn, model.OpCreate); err != nil {
+		log.Printf("LogIn bind error: %v", err)
+		c.Error(err)
 		return
 	}
 
-	if err := c.BindJSON(&logIn.Payload); err != nil {
-		log.Printf("LogIn Payload JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: err.Error()})
+	id, err := s.verifyIdentity(logIn.ProviderName(), logIn.Credential())
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	if errSlice := logIn.ParseNotAllowedJSON(); len(errSlice) > 0 {
-		log.Printf("LogIn not allowed fields detected: %v", errSlice)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Some fields are not allowed", Fields: &errSlice})
+	if id.Provider == identity.Facebook && (id.Email != logIn.Email || id.Subject != logIn.FacebookUserID) {
+		log.Printf("LogIn facebook email: %v != given login emai: %v", id.Email, logIn.Email)
+		c.Error(model.Validation(model.CodeInvalidFields, "Invalid fields detected", "email"))
 		return
 	}
 
-	if errSlice := logIn.PostValidate(); len(errSlice) > 0 {
-		log.Printf("LogIn post validate err: %v", errSlice)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid fields detected", Fields: &errSlice})
-		return
+	user, err := s.identityUser(id)
+	if err == nil && user == nil {
+		user, err = s.signUp(id)
+		if err != nil && model.AsError(err).Code == model.CodeIdentityInUse {
+			//a concurrent first login of the account created the user, log in as that one
+			user, err = s.identityUser(id)
+			if err == nil && user == nil {
+				err = model.NotFound(model.CodeUserNotFound, "User not found")
+			}
+		}
 	}
-
-	url := fbGraphURI + logIn.FacebookToken
-	res, err := http.Get(url)
 	if err != nil {
-		log.Printf("facebook req err: %q", err)
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Facebook server error"})
+		c.Error(err)
 		return
 	}
-	defer res.Body.Close()
 
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		log.Printf("facebook resp io read err: %q", err)
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
-		return
-	}
-	var facebookResp model.FacebookResp
-	if err = json.Unmarshal(body, &facebookResp); err != nil {
-		log.Printf("Facebook body byte unmarshalling err: %q", err)
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
+	if err = s.startSession(user, logIn.Imei); err != nil {
+		c.Error(err)
 		return
 	}
 
-	if facebookResp.Email != logIn.Email || facebookResp.ID != logIn.FacebookUserID {
-		log.Printf("LogIn facebook email: %v != given login emai: %v", facebookResp.Email, logIn.Email)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid fields detected", Fields: &[]string{"email"}})
-		return
+	c.JSON(http.StatusOK, user)
+}
+
+//identityUser func returns the user linked to the sign in identity, nil when there is none. The email and the name of
+//the user follow the ones of the provider.
+func (s *Service) identityUser(id *identity.Identity) (*model.User, error) {
+	identityList, err := s.store.UserIdentities.Get(model.UserIdentityFilter{Provider: id.Provider, Subject: id.Subject})
+	if err != nil {
+		return nil, err
 	}
 
-	//check via email or fb_user_id
-	user := model.User{Email: facebookResp.Email, FacebookUserID: facebookResp.ID, Name: facebookResp.Name}
+	if len(identityList) != 1 {
+		return nil, nil
+	}
 
-	userList, err := user.Get("WHERE facebook_user_id=$1", user.FacebookUserID)
+	userList, err := s.store.Users.Get(model.UserFilter{IDs: []int64{identityList[0].UserID}})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Server error"})
-		return
+		return nil, err
 	}
 
-	if len(userList) == 1 {
+	if len(userList) != 1 {
+		log.Printf("user of identity %v %v not found", id.Provider, id.Subject)
+		return nil, model.NotFound(model.CodeUserNotFound, "User not found")
+	}
+	existing := userList[0]
 
-		if userList[0].Email != user.Email && userList[0].Name != user.Name {
-			err = user.UpdateFBFields("UPDATE users SET email=$1, name=$2 WHERE id=$3", user.Email, user.Name, user.ID)
-		} else if userList[0].Email != user.Email {
-			err = user.UpdateFBFields("UPDATE users SET email=$1 WHERE id=$3", user.Email, user.ID)
-		} else if userList[0].Name != user.Name {
-			err = user.UpdateFBFields("UPDATE users SET name=$1 WHERE id=$3", user.Name, user.ID)
+	if (id.Email != "" && existing.Email != id.Email) || (id.Name != "" && existing.Name != id.Name) {
+		if err = s.store.Users.Update(&model.User{ID: existing.ID, Email: id.Email, Name: id.Name}, nil); err != nil {
+			return nil, err
 		}
-
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
-			return
+		if id.Email != "" {
+			existing.Email = id.Email
+		}
+		if id.Name != "" {
+			existing.Name = id.Name
 		}
-
-		user.Token = user.CreateTokenString()
-
-		c.JSON(http.StatusOK, &user)
-		return
 	}
 
-	if len(userList) != 0 {
-		log.Printf("multiple users detected: %v", userList)
-		c.JSON(http.StatusConflict, &model.ErrResp{Error: "Multiple users detected."})
-		return
-	}
+	return existing, nil
+}
 
-	if err = user.Create(); err != nil {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Server error"})
-		return
+//signUp func creates the user of the first login of the sign in identity, with the identity and a fresh score, at once
+func (s *Service) signUp(id *identity.Identity) (*model.User, error) {
+	user := model.User{Email: id.Email, Name: id.Name}
+	if id.Provider == identity.Facebook {
+		user.FacebookUserID = id.Subject
 	}
 
-	user.Token = user.CreateTokenString()
-	score := model.Score{UserID: user.ID, Exp: 0, Coins: 0, LikesRemaining: 20}
+	userIdentity := model.UserIdentity{Provider: id.Provider, Subject: id.Subject, Email: id.Email}
+	score := model.Score{Exp: 0, Coins: 0, LikesRemaining: constLikesRefill}
 
-	if status, err := score.Create(); err != nil {
-		c.JSON(status, &model.ErrResp{Error: err.Error()})
-		return
+	if err := s.store.Users.SignUp(&user, &userIdentity, &score); err != nil {
+		return nil, err
 	}
-
 	user.Score = &score
 
-	c.JSON(http.StatusOK, &user)
+	return &user, nil
 }
 
-//LogOut func handler logs out a user based on an user_id and imei
-func LogOut(c *gin.Context) {
+//LogOut func handler logs out a user based on an user_id and imei. The session of the token is revoked, so neither
+//its access tokens nor its refresh tokens work anymore.
+func (s *Service) LogOut(c *gin.Context) {
 	var logOut model.LogOut
-	if err := c.BindJSON(&logOut); err != nil {
-		log.Printf("logOut struct JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid logOut payload"})
+	if err := model.Bind(c.Request.Body, &logOut, model.OpCreate); err != nil {
+		log.Printf("logOut bind error: %v", err)
+		c.Error(err)
 		return
 	}
 
-	if err := c.BindJSON(&logOut.Payload); err != nil {
-		log.Printf("logOut Payload JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid logOut payload"})
-		return
-	}
-
-	if errSlice := logOut.ParseNotAllowedJSON(); len(errSlice) > 0 {
-		log.Printf("logOut not allowed fields detected: %v", errSlice)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Some fields are not allowed", Fields: &errSlice})
-		return
-	}
+	logOut.UserID = c.MustGet("user_id").(int64)
 
-	if errSlice := logOut.Validate(); len(errSlice) > 0 {
-		log.Printf("logOut post validate err: %v", errSlice)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid fields detected", Fields: &errSlice})
+	if err := s.store.RefreshTokens.RevokeFamily(c.MustGet("session_id").(string)); err != nil {
+		log.Printf("Revoke session error: %v", err)
+		c.Error(err)
 		return
 	}
 
-	logOut.UserID = c.MustGet("user_id").(int64)
-
 	oneSignal := model.OneSignal{UserID: logOut.UserID, Imei: logOut.Imei}
 
-	if err := oneSignal.Delete(); err != nil {
+	//a device which never registered for push has no onesignal record, the logout still succeeds
+	if err := s.store.OneSignals.Delete(&oneSignal); err != nil && model.AsError(err).Kind != model.KindNotFound {
 		log.Printf("Delete onesignal error: %v", err)
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
+		c.Error(err)
 		return
 	}
 
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/challengr/identity"
+	"github.com/challengr/keyset"
+	"github.com/challengr/model"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+func TestSignUp(t *testing.T) {
+	ts := newTestServer(t)
+	id := &identity.Identity{Provider: "google", Subject: "108", Email: "ada@example.com", Name: "Ada"}
+
+	user, err := ts.s.signUp(id)
+	if err != nil {
+		t.Fatalf("sign up: %v", err)
+	}
+	if user.Score == nil || user.Score.ID == 0 || user.Score.LikesRemaining != constLikesRefill {
+		t.Errorf("score = %+v, want a fresh score", user.Score)
+	}
+
+	identityList, err := ts.deps.Store.UserIdentities.Get(model.UserIdentityFilter{UserID: user.ID})
+	if err != nil || len(identityList) != 1 || identityList[0].Subject != id.Subject {
+		t.Errorf("identities = %+v %v, want the identity of the sign up", identityList, err)
+	}
+
+	//the loser of two first logins of the account finds the identity linked and logs in as the winner
+	if _, err = ts.s.signUp(id); model.AsError(err).Code != model.CodeIdentityInUse {
+		t.Fatalf("second sign up error = %v, want %v", err, model.CodeIdentityInUse)
+	}
+	if count, _ := ts.deps.Store.Users.Count(model.UserFilter{}); count != 1 {
+		t.Errorf("users = %v, want 1", count)
+	}
+
+	id.Name = "Ada L."
+	existing, err := ts.s.identityUser(id)
+	if err != nil || existing == nil || existing.ID != user.ID || existing.Name != id.Name {
+		t.Errorf("identity user = %+v %v, want user %d renamed", existing, err, user.ID)
+	}
+}
+
+//newIdentityServer func returns a test server whose facebook graph api and google JWKS are local stand-ins. The facebook
+//token "token of <name>" belongs to the facebook user <name>, the google id tokens are signed by the returned keyset.
+func newIdentityServer(t *testing.T) (*testServer, *keyset.Keyset) {
+	t.Helper()
+
+	graph := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Query().Get("access_token"), "token of ")
+		if name == r.URL.Query().Get("access_token") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id": "fb-" + name, "name": name, "email": name + "@example.com"})
+	}))
+	t.Cleanup(graph.Close)
+
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := keyset.ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	google, err := keyset.New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(google.JWKS())
+	}))
+	t.Cleanup(jwks.Close)
+
+	deps := newTestDeps(t)
+	deps.Config.Identity.FacebookGraphURL = graph.URL
+	deps.Config.Identity.GoogleJWKSURL = jwks.URL
+	deps.Config.Identity.GoogleIssuer = "https://accounts.example.com"
+	deps.Config.Identity.GoogleClientIDs = "challengr-app"
+
+	return &testServer{t: t, deps: deps, s: New(deps.Store, deps.Config, deps.Keys), router: NewRouter(deps)}, google
+}
+
+//googleIDToken func signs an id token of the google account with the subject
+func googleIDToken(t *testing.T, google *keyset.Keyset, subject string) string {
+	t.Helper()
+	token, err := google.Sign(jwt.MapClaims{
+		"iss":            "https://accounts.example.com",
+		"aud":            "challengr-app",
+		"sub":            subject,
+		"email":          subject + "@gmail.example.com",
+		"email_verified": true,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestLogInFacebook(t *testing.T) {
+	ts, _ := newIdentityServer(t)
+
+	cases := []struct {
+		name    string
+		login   model.LogIn
+		status  int
+		errCode string
+	}{
+		{"matching id and email", model.LogIn{Email: "ada@example.com", FacebookUserID: "fb-ada", FacebookToken: "token of ada", Imei: "phone"}, http.StatusOK, ""},
+		{"mismatched id", model.LogIn{Email: "ada@example.com", FacebookUserID: "fb-grace", FacebookToken: "token of ada", Imei: "phone"}, http.StatusBadRequest, model.CodeInvalidFields},
+		{"mismatched email", model.LogIn{Email: "grace@example.com", FacebookUserID: "fb-ada", FacebookToken: "token of ada", Imei: "phone"}, http.StatusBadRequest, model.CodeInvalidFields},
+		{"rejected token", model.LogIn{Email: "ada@example.com", FacebookUserID: "fb-ada", FacebookToken: "expired", Imei: "phone"}, http.StatusForbidden, model.CodeInvalidCredential},
+	}
+
+	for _, tc := range cases {
+		if code, errCode := ts.errorCode(nil, "POST", "/login", tc.login); code != tc.status || errCode != tc.errCode {
+			t.Errorf("%s login = %v %q, want %v %q", tc.name, code, errCode, tc.status, tc.errCode)
+		}
+	}
+
+	if count, _ := ts.deps.Store.Users.Count(model.UserFilter{}); count != 1 {
+		t.Errorf("users = %v, want only the one of the matching login", count)
+	}
+}
+
+//TestLinkIdentity checks that a second provider linked to a user logs in as that user
+func TestLinkIdentity(t *testing.T) {
+	ts, google := newIdentityServer(t)
+
+	var user model.User
+	login := model.LogIn{Email: "ada@example.com", FacebookUserID: "fb-ada", FacebookToken: "token of ada", Imei: "phone"}
+	if code := ts.do(nil, "POST", "/login", login, &user); code != http.StatusOK {
+		t.Fatalf("facebook login status = %v", code)
+	}
+
+	path := fmt.Sprintf("/user/%d/identities", user.ID)
+	link := model.IdentityLink{Provider: identity.Google, Token: googleIDToken(t, google, "g-ada")}
+	if code := ts.do(&user, "POST", path, link, nil); code != http.StatusOK {
+		t.Fatalf("link status = %v", code)
+	}
+
+	var identityList []*model.UserIdentity
+	if code := ts.do(&user, "GET", path, nil, &identityList); code != http.StatusOK {
+		t.Fatalf("identities status = %v", code)
+	}
+	if len(identityList) != 2 {
+		t.Errorf("identities = %+v, want facebook and google", identityList)
+	}
+
+	var googleUser model.User
+	login = model.LogIn{Provider: identity.Google, IDToken: googleIDToken(t, google, "g-ada"), Imei: "tablet"}
+	if code := ts.do(nil, "POST", "/login", login, &googleUser); code != http.StatusOK {
+		t.Fatalf("google login status = %v", code)
+	}
+	if googleUser.ID != user.ID {
+		t.Errorf("google login user = %v, want the linked user %v", googleUser.ID, user.ID)
+	}
+
+	//an account linked to one user can not be linked to another
+	var other model.User
+	login = model.LogIn{Email: "grace@example.com", FacebookUserID: "fb-grace", FacebookToken: "token of grace", Imei: "laptop"}
+	if code := ts.do(nil, "POST", "/login", login, &other); code != http.StatusOK {
+		t.Fatalf("other login status = %v", code)
+	}
+	path = fmt.Sprintf("/user/%d/identities", other.ID)
+	if code, errCode := ts.errorCode(&other, "POST", path, link); code != http.StatusConflict || errCode != model.CodeIdentityInUse {
+		t.Errorf("link of linked account = %v %q, want %v %q", code, errCode, http.StatusConflict, model.CodeIdentityInUse)
+	}
+}
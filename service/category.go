@@ -0,0 +1,99 @@
+package service
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+)
+
+//GetCategories handler func lists the categories of the challenges, ordered by name
+func (s *Service) GetCategories(c *gin.Context) {
+	categoryList, err := s.store.Categories.Get(model.CategoryFilter{})
+	if err != nil {
+		log.Printf("categories fetch error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &categoryList)
+}
+
+//PostCategory handler func creates a category
+func (s *Service) PostCategory(c *gin.Context) {
+	var category model.Category
+	if err := model.Bind(c.Request.Body, &category, model.OpCreate); err != nil {
+		log.Printf("category bind error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	if err := s.store.Categories.Create(&category); err != nil {
+		log.Printf("category create error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &category)
+}
+
+//PutCategory handler func renames a category or changes its slug
+func (s *Service) PutCategory(c *gin.Context) {
+	categoryID, err := strconv.ParseInt(c.Param("category_id"), 10, 64)
+	if err != nil {
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "category_id"))
+		return
+	}
+
+	category := model.Category{ID: categoryID}
+	if err := model.Bind(c.Request.Body, &category, model.OpUpdate); err != nil {
+		log.Printf("category bind error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	if err := s.store.Categories.Update(&category); err != nil {
+		log.Printf("category update error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.SuccessResp{Message: "Category successfuly updated", Status: http.StatusOK})
+}
+
+//DeleteCategory handler func deletes a category, its challenges are left without a category
+func (s *Service) DeleteCategory(c *gin.Context) {
+	categoryID, err := strconv.ParseInt(c.Param("category_id"), 10, 64)
+	if err != nil {
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "category_id"))
+		return
+	}
+
+	if err := s.store.Categories.Delete(&model.Category{ID: categoryID}); err != nil {
+		log.Printf("category delete error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.SuccessResp{Message: "Category successfuly deleted", Status: http.StatusOK})
+}
+
+//categoryFilter func resolves the category query string, a category id or slug, into the id of the category
+func (s *Service) categoryFilter(query string) (int64, error) {
+	filter := model.CategoryFilter{Slug: query}
+	if id, err := strconv.ParseInt(query, 10, 64); err == nil {
+		filter = model.CategoryFilter{ID: id}
+	}
+
+	categoryList, err := s.store.Categories.Get(filter)
+	if err != nil {
+		return 0, err
+	}
+	if len(categoryList) != 1 {
+		return 0, model.Validation(model.CodeInvalidQueryString, "Unknown category", "category")
+	}
+
+	return categoryList[0].ID, nil
+}
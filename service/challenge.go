@@ -1,215 +1,216 @@
 package service
 
 import (
+	"math"
 	"net/http"
 	"strconv"
-
 	"strings"
+	"time"
 
 	"log"
 
+	"github.com/challengr/middleware"
 	"github.com/challengr/model"
 	"github.com/gin-gonic/gin"
 )
 
-//GetChellenge func handler fetches challenges
-func GetChellenge(c *gin.Context) {
-	whereClause := []string{}
-	preparedValues := make(map[int]interface{})
+//GetChellenge func handler fetches the active challenges. They can be narrowed down by the category id or slug and by tags,
+//any of them or with tags_match=all every one of them, and by location: near a point, sorted by distance, or in a bbox.
+func (s *Service) GetChellenge(c *gin.Context) {
+	filter := model.ChallengeFilter{Status: model.ChallengeActive, Limit: 20}
 
 	queryUserID := c.Query("user_id")
 	if queryUserID != "" {
 		userID, err := strconv.ParseInt(queryUserID, 10, 64)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid query strings", Fields: &[]string{"user_id"}})
+			c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query strings", "user_id"))
 			return
 		}
-		preparedValues[len(whereClause)] = userID
-		whereClause = append(whereClause, "user_id=$"+strconv.Itoa(len(whereClause)+1))
+		filter.UserID = userID
 	}
 
 	queryLastID := c.Query("last_id")
 	if queryLastID != "" {
 		lastID, err := strconv.ParseInt(queryLastID, 10, 64)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid query strings", Fields: &[]string{"last_id"}})
+			c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query strings", "last_id"))
 			return
 		}
-		preparedValues[len(whereClause)] = lastID
-		whereClause = append(whereClause, "last_id>$"+strconv.Itoa(len(whereClause)+1))
-	} else {
-		preparedValues[len(whereClause)] = 0
-		whereClause = append(whereClause, "last_id>$"+strconv.Itoa(len(whereClause)+1))
+		filter.LastID = lastID
 	}
 
-	whereQueryStr := ""
-	status := "active"
-	queryType := c.Query("type")
-	switch queryType {
-	case "hot":
-		orderByStr := "(((SELECT COUNT(posts.id) FROM posts WHERE posts.challenge_id=challenges.id AND (SELECT COUNT(likes.id) FROM likes WHERE likes.post_id=posts.id)) / (SELECT COUNT(posts.id) FROM posts WHERE posts.challenge_id=id)) * (SELECT COUNT(posts.id) FROM posts WHERE posts.challenge_id=id) / 100)"
-		orderByStr = "(" + orderByStr + " + " + orderByStr + " * challenges.weight) DESC"
-		whereQueryStr = "WHERE " + strings.Join(whereClause, " AND ") + " AND status='" + status + "' AND deleted_at IS NULL ORDER BY " + orderByStr + " LIMIT 20;"
-	case "fresh":
-		orderByStr := "challenges.created_at DESC, (SELECT COUNT(posts.id) FROM posts WHERE posts.challenge_id=challenges.id) DESC"
-		whereQueryStr = "WHERE " + strings.Join(whereClause, " AND ") + " AND status='" + status + "' AND deleted_at IS NULL ORDER BY " + orderByStr + " LIMIT 20;"
+	if queryCategory := c.Query("category"); queryCategory != "" {
+		categoryID, err := s.categoryFilter(queryCategory)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		filter.CategoryID = categoryID
+	}
 
+	if queryTags := c.Query("tags"); queryTags != "" {
+		tags, ok := model.NormalizeTags(strings.Split(queryTags, ","))
+		if !ok {
+			c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query strings", "tags"))
+			return
+		}
+		filter.Tags = tags
+	}
+
+	if err := geoFilter(c, &filter); err != nil {
+		c.Error(err)
+		return
+	}
+
+	switch c.DefaultQuery("tags_match", "any") {
+	case "any":
+	case "all":
+		filter.AllTags = true
 	default:
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid query strings", Fields: &[]string{"type"}})
+		c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query strings", "tags_match"))
+		return
+	}
+
+	//challenges near a point are sorted by their distance, the type is optional then
+	queryType := c.Query("type")
+	if (filter.Near == nil || queryType != "") && !model.ChallengeSorts.Has(queryType) {
+		c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query strings", "type"))
 		return
 	}
+	filter.Sort = queryType
 
-	args := make([]interface{}, len(preparedValues))
-	for i, val := range preparedValues {
-		args[i] = val
+	if err := sortCursor(c, &filter); err != nil {
+		c.Error(err)
+		return
 	}
 
-	challengeList, err := (&model.Challenge{}).Get(whereQueryStr, args)
+	challengeList, err := s.store.Challenges.Get(filter)
 	if err != nil {
 		log.Printf("Fetch challenge error: %v", err)
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, &challengeList)
 }
 
-//PostChallenge func handler creates a new challenge
-func PostChallenge(c *gin.Context) {
+//sortCursor func reads the sort key of the last challenge of the previous page, which the trending and fresh sorts page
+//on next to the last_id: its last_trending_score or its RFC 3339 last_created_at
+func sortCursor(c *gin.Context, filter *model.ChallengeFilter) error {
+	if filter.Near != nil || filter.LastID == 0 {
+		return nil
+	}
+
+	switch filter.Sort {
+	case "trending", "hot":
+		score, err := strconv.ParseFloat(c.Query("last_trending_score"), 64)
+		if err != nil || math.IsNaN(score) || math.IsInf(score, 0) {
+			return model.Validation(model.CodeInvalidQueryString, "Invalid query strings", "last_trending_score")
+		}
+		filter.LastTrendingScore = score
+	case "fresh":
+		createdAt, err := time.Parse(time.RFC3339Nano, c.Query("last_created_at"))
+		if err != nil {
+			return model.Validation(model.CodeInvalidQueryString, "Invalid query strings", "last_created_at")
+		}
+		filter.LastCreatedAt = createdAt
+	}
+
+	return nil
+}
+
+//PostChallenge func handler creates a new challenge. Without a status it is scheduled when starts_at is in the future, active otherwise.
+func (s *Service) PostChallenge(c *gin.Context) {
 	userID, ok := c.MustGet("user_id").(int64)
 	if !ok {
 		log.Println("invalid token, user_id error")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token."})
-		return
-	}
-	userRole, ok := c.MustGet("role").(string)
-	if !ok {
-		log.Println("invalid token, role error")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token"})
+		c.Error(model.Forbidden(model.CodeInvalidToken, "Invalid token"))
 		return
 	}
 	weight, ok := c.MustGet("weight").(float32)
 	if !ok {
 		log.Println("invalid token, wight error")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token"})
+		c.Error(model.Forbidden(model.CodeInvalidToken, "Invalid token"))
 		return
 	}
 
 	var challenge model.Challenge
-	if err := c.BindJSON(&challenge); err != nil {
-		log.Printf("challenge struct JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: err.Error()})
-		return
-	}
-
-	if err := c.BindJSON(&challenge.Payload); err != nil {
-		log.Printf("challenge Payload JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: err.Error()})
-		return
-	}
-
-	if errSlice := challenge.ParseNotAllowedJSON(); len(errSlice) > 0 {
-		log.Printf("challenge not allowed fields detected: %v", errSlice)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Some fields are not allowed", Fields: &errSlice})
+	if err := model.Bind(c.Request.Body, &challenge, model.OpCreate); err != nil {
+		log.Printf("challenge bind error: %v", err)
+		c.Error(err)
 		return
 	}
 
-	if userRole != constAdminRole {
-		usersList, err := (&model.User{}).Get("WHERE id=$1", userID)
+	if !middleware.IsAdmin(c) {
+		usersList, err := s.store.Users.Get(model.UserFilter{IDs: []int64{userID}})
 		if err != nil {
 			log.Printf("User fetch error: %v", err)
-			c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
+			c.Error(err)
+			return
+		}
+		if len(usersList) != 1 {
+			log.Printf("User not found -> id %v", userID)
+			c.Error(model.Forbidden(model.CodeInvalidToken, "Invalid token"))
 			return
 		}
 		if usersList[0].LevelID < 5 {
 			log.Printf("User levelID: %v, must be 5 or bigger", usersList[0].LevelID)
-			c.JSON(http.StatusMethodNotAllowed, &model.ErrResp{Error: "Not allowed. Need level 5 or more."})
+			c.Error(model.Forbidden(model.CodeLevelTooLow, "Not allowed. Need level 5 or more."))
 			return
 		}
 	}
 
-	if errSlice := challenge.PostValidate(); len(errSlice) > 0 {
-		log.Printf("challenge post validate err: %v", errSlice)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid fields detected", Fields: &errSlice})
-		return
-	}
-
 	challenge.UserID = userID
 	challenge.Weight = &weight
 
-	if err := challenge.Create(); err != nil {
+	if err := s.store.Challenges.Create(&challenge); err != nil {
 		log.Printf("challenge create err: %v", err)
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, &challenge)
 }
 
-//PutChallenge func handler updates a challenge. PS: It cant update 'name'.
-func PutChallenge(c *gin.Context) {
+//PutChallenge func handler updates a challenge. PS: It cant update 'name'. Admins may update the weight of any challenge.
+//Status changes must be transitions of the state machine, a closed challenge can not be reopened.
+func (s *Service) PutChallenge(c *gin.Context) {
 	userID, ok := c.MustGet("user_id").(int64)
 	if !ok {
 		log.Println("invalid token, user_id error")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token."})
+		c.Error(model.Forbidden(model.CodeInvalidToken, "Invalid token"))
 		return
 	}
 	paramChallengeID := c.Param("challenge_id")
 	challengeID, err := strconv.ParseInt(paramChallengeID, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path params", Fields: &[]string{"challenge_id"}})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "challenge_id"))
 		return
 	}
 
-	challenge := model.Challenge{ID: challengeID}
-	if err := c.BindJSON(&challenge); err != nil {
-		log.Printf("challenge struct JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: err.Error()})
-		return
+	op := model.OpUpdate
+	if middleware.IsAdmin(c) {
+		op = model.OpAdminUpdate
 	}
 
-	if err := c.BindJSON(&challenge.Payload); err != nil {
-		log.Printf("challenge Payload JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: err.Error()})
-		return
-	}
-
-	if errSlice := challenge.ParseNotAllowedJSON(); len(errSlice) > 0 {
-		log.Printf("challenge not allowed fields detected: %v", errSlice)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Some fields are not allowed", Fields: &errSlice})
+	challenge := model.Challenge{ID: challengeID}
+	if err := model.Bind(c.Request.Body, &challenge, op); err != nil {
+		log.Printf("challenge bind error: %v", err)
+		c.Error(err)
 		return
 	}
 
-	notAllowedFields := []string{}
-	if challenge.Status != "" {
-		notAllowedFields = append(notAllowedFields, "status")
-	}
-	if challenge.Name != "" {
-		notAllowedFields = append(notAllowedFields, "name")
-	}
-	if challenge.LikesNeededPerPost != 0 {
-		notAllowedFields = append(notAllowedFields, "likes_needed_per_post")
-	}
-	if challenge.Weight != nil {
-		notAllowedFields = append(notAllowedFields, "weight")
-	}
-
-	if len(notAllowedFields) > 0 {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Some fields are not allowed", Fields: &notAllowedFields})
-		return
-	}
+	challenge.UserID = userID
 
-	if challenge.Description == nil && challenge.Location == nil {
-		log.Printf("challenge Payload invalid json: %v", challenge.Payload)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "No valid payload detected"})
-		return
+	if op == model.OpAdminUpdate {
+		err = s.store.Challenges.AdminUpdate(&challenge, auditEvent(c, auditChallengeUpdate))
+	} else {
+		err = s.store.Challenges.Update(&challenge)
 	}
 
-	challenge.UserID = userID
-
-	if errStatus, err := challenge.Update(); err != nil {
+	if err != nil {
 		log.Printf("challenge update error: %v", err)
-		c.JSON(errStatus, &model.ErrResp{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -217,91 +218,65 @@ func PutChallenge(c *gin.Context) {
 }
 
 //DeleteChallenge func handler deletes a challenge, if there is no post made.
-func DeleteChallenge(c *gin.Context) {
+func (s *Service) DeleteChallenge(c *gin.Context) {
 	userID, ok := c.MustGet("user_id").(int64)
 	if !ok {
 		log.Println("invalid token, user_id error")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token."})
-		return
-	}
-	role, ok := c.MustGet("role").(string)
-	if !ok {
-		log.Println("invalid token, role error")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token."})
+		c.Error(model.Forbidden(model.CodeInvalidToken, "Invalid token"))
 		return
 	}
 
 	paramChallengeID := c.Param("challenge_id")
 	challengeID, err := strconv.ParseInt(paramChallengeID, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path params", Fields: &[]string{"challenge_id"}})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "challenge_id"))
 		return
 	}
 
-	errStatus := 0
-	switch role {
-	case constAdminRole:
-		errStatus, err = (&model.Challenge{ID: challengeID}).Delete()
-	case constUserRole:
-		errStatus, err = (&model.Challenge{ID: challengeID, UserID: userID}).AdminDelete()
-	default:
-		c.JSON(http.StatusForbidden, &model.ErrResp{Error: "Check token"})
-		return
+	if middleware.IsAdmin(c) {
+		err = s.store.Challenges.AdminDelete(&model.Challenge{ID: challengeID}, auditEvent(c, auditChallengeDelete))
+	} else {
+		err = s.store.Challenges.Delete(&model.Challenge{ID: challengeID, UserID: userID})
 	}
 
 	if err != nil {
-		c.JSON(errStatus, err.Error())
+		c.Error(err)
 		return
 	}
+	s.leaderboards.invalidate(challengeID)
 
 	c.JSON(http.StatusOK, &model.SuccessResp{Message: "Challenge successfuly deleted", Status: http.StatusOK})
 }
 
-func activeDeactiveChallenge(val string, c *gin.Context) {
-	userID, ok := c.MustGet("user_id").(int64)
-	if !ok {
-		log.Println("invalid token, user_id error")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token."})
-		return
-	}
-	role, ok := c.MustGet("role").(string)
-	if !ok {
-		log.Println("invalid token, role error")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token."})
-		return
-	}
+//activeDeactiveChallenge func sets the status of any challenge, its routes are admin only
+func (s *Service) activeDeactiveChallenge(val string, c *gin.Context) {
 	paramChallengeID := c.Param("challenge_id")
 	challengeID, err := strconv.ParseInt(paramChallengeID, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path params", Fields: &[]string{"challenge_id"}})
-		return
-	}
-
-	errStatus := 0
-	switch role {
-	case constAdminRole:
-		errStatus, err = (&model.Challenge{ID: challengeID, Status: val}).AdminUpdate()
-	case constUserRole:
-		errStatus, err = (&model.Challenge{ID: challengeID, UserID: userID, Status: val}).Update()
-	default:
-		c.JSON(http.StatusForbidden, &model.ErrResp{Error: "Check token"})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "challenge_id"))
 		return
 	}
 
-	if err != nil {
-		c.JSON(errStatus, err.Error())
+	if err = s.store.Challenges.AdminUpdate(&model.Challenge{ID: challengeID, Status: val}, auditEvent(c, auditChallengeStatus)); err != nil {
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, &model.SuccessResp{Message: "Challenge successfuly updated", Status: http.StatusOK})
 }
 
-//DeActivateChallenge func handler de-activates challenges which are not being used for a while.
-func DeActivateChallenge(c *gin.Context) {
-	activeDeactiveChallenge("inactive", c)
+//DeActivateChallenge func handler closes challenges which are not being used for a while.
+func (s *Service) DeActivateChallenge(c *gin.Context) {
+	s.activeDeactiveChallenge(model.ChallengeClosed, c)
+}
+
+//ActivateChallenge func handler activates draft or scheduled challenges right away.
+func (s *Service) ActivateChallenge(c *gin.Context) {
+	s.activeDeactiveChallenge(model.ChallengeActive, c)
 }
 
-//ActivateChallenge func handler de-activates challenges which are not being used for a while.
-func ActivateChallenge(c *gin.Context) {
-	activeDeactiveChallenge("active", c)
+//AdvanceChallenges func starts the scheduled challenges whose starts_at has come and closes the active ones whose ends_at
+//has passed. It returns the number of moved challenges.
+func (s *Service) AdvanceChallenges() (int64, error) {
+	return s.store.Challenges.Advance(time.Now())
 }
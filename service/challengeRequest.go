@@ -11,24 +11,11 @@ import (
 )
 
 //GetChallengeRequest handler func fetches the challenge request to or from the user depending on the query parameters
-func GetChallengeRequest(c *gin.Context) {
-	userID, ok := c.MustGet("user_id").(int64)
-	if !ok {
-		log.Println("invalid token, user_id error")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token."})
-		return
-	}
-	userRole, ok := c.MustGet("role").(string)
-	if !ok {
-		log.Println("invalid token, role error")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token"})
-		return
-	}
-
+func (s *Service) GetChallengeRequest(c *gin.Context) {
 	paramUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
 	if err != nil {
 		log.Printf("Invalid path param user_id: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path param user_id"})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "user_id"))
 		return
 	}
 
@@ -38,31 +25,28 @@ func GetChallengeRequest(c *gin.Context) {
 	if queryLastID != "" {
 		lastID, err = strconv.ParseInt(queryLastID, 10, 64)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path params", Fields: &[]string{"last_id"}})
+			c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query string", "last_id"))
 			return
 		}
 	}
 
 	queryType := c.Query("type")
 	if queryType != "sent" && queryType != "recieved" {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid query string", Fields: &[]string{"type"}})
+		c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query string", "type"))
 		return
 	}
 
-	if userID != paramUserID && userRole != constAdminRole {
-		c.JSON(http.StatusMethodNotAllowed, &model.ErrResp{Error: "Not allowed"})
-		return
-	}
-
-	challengeRequestList := []*model.ChallengeRequest{}
+	filter := model.ChallengeRequestFilter{LastID: lastID, Limit: 20}
 	if queryType == "sent" {
-		challengeRequestList, err = (&model.ChallengeRequest{}).Get("WHERE from_id=$1 AND last_id>$2 ORDER BY DESC created_at LIMIT 20", paramUserID, lastID)
+		filter.FromID = paramUserID
 	} else {
-		challengeRequestList, err = (&model.ChallengeRequest{}).Get("WHERE to_id=$1 AND last_id>$2 ORDER BY DESC created_at LIMIT 20", paramUserID, lastID)
+		filter.ToID = paramUserID
 	}
+
+	challengeRequestList, err := s.store.ChallengeRequests.Get(filter)
 	if err != nil {
 		log.Printf("Challenge request fetching err: %v", err)
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
+		c.Error(err)
 		return
 	}
 
@@ -70,86 +54,50 @@ func GetChallengeRequest(c *gin.Context) {
 }
 
 //PostChallengeRequest handler func sends challenge to the user who is in friendlist in fb
-func PostChallengeRequest(c *gin.Context) {
-	userID, ok := c.MustGet("user_id").(int64)
-	if !ok {
-		log.Println("invalid token, user_id error")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token."})
-		return
-	}
-	userRole, ok := c.MustGet("role").(string)
-	if !ok {
-		log.Println("invalid token, role error")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token"})
-		return
-	}
-
+func (s *Service) PostChallengeRequest(c *gin.Context) {
 	paramUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
 	if err != nil {
 		log.Printf("Invalid path param user_id: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path param user_id"})
-		return
-	}
-
-	if userID != paramUserID && userRole != constAdminRole {
-		c.JSON(http.StatusMethodNotAllowed, &model.ErrResp{Error: "Not allowed"})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "user_id"))
 		return
 	}
 
 	var challengeRequest model.ChallengeRequest
-	if err := c.BindJSON(&challengeRequest); err != nil {
-		log.Printf("challenge struct JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: err.Error()})
-		return
-	}
-
-	if err := c.BindJSON(&challengeRequest.Payload); err != nil {
-		log.Printf("challenge Payload JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: err.Error()})
-		return
-	}
-
-	if errSlice := challengeRequest.ParseNotAllowedJSON(); len(errSlice) > 0 {
-		log.Printf("challenge not allowed fields detected: %v", errSlice)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Some fields are not allowed", Fields: &errSlice})
-		return
-	}
-
-	if errSlice := challengeRequest.PostValidate(); len(errSlice) > 0 {
-		log.Printf("challenge not allowed fields detected: %v", errSlice)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Some fields are invalid", Fields: &errSlice})
+	if err := model.Bind(c.Request.Body, &challengeRequest, model.OpCreate); err != nil {
+		log.Printf("challenge bind error: %v", err)
+		c.Error(err)
 		return
 	}
 
-	count, err := (&model.User{}).Count("WHERE id=$1", challengeRequest.ToID)
+	count, err := s.store.Users.Count(model.UserFilter{IDs: []int64{challengeRequest.ToID}})
 	if err != nil {
 		log.Printf("User count error: %v", err)
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
+		c.Error(err)
 		return
 	}
 
 	if count != 1 {
-		c.JSON(http.StatusNotFound, &model.ErrResp{Error: "to_id, user not found"})
+		c.Error(model.NotFound(model.CodeUserNotFound, "to_id, user not found"))
 		return
 	}
 
-	count, err = (&model.Challenge{}).Count("WHERE id=$1", challengeRequest.ToID)
+	count, err = s.store.Challenges.Count(model.ChallengeFilter{ID: challengeRequest.ChallengeID})
 	if err != nil {
 		log.Printf("Challenge count error: %v", err)
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
+		c.Error(err)
 		return
 	}
 
 	if count != 1 {
-		c.JSON(http.StatusNotFound, &model.ErrResp{Error: "challeng_id, challenge not found"})
+		c.Error(model.NotFound(model.CodeChallengeNotFound, "challeng_id, challenge not found"))
 		return
 	}
 
 	challengeRequest.FromID = paramUserID
 	challengeRequest.Status = "open"
-	if err = challengeRequest.Create(); err != nil {
+	if err = s.store.ChallengeRequests.Create(&challengeRequest); err != nil {
 		log.Printf("Challenge request create error: %v", err)
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
+		c.Error(err)
 		return
 	}
 
@@ -157,50 +105,32 @@ func PostChallengeRequest(c *gin.Context) {
 }
 
 //PutChallengeRequest handler func updates the challenge request, used basically for updating the status
-func PutChallengeRequest(c *gin.Context) {
-	userID, ok := c.MustGet("user_id").(int64)
-	if !ok {
-		log.Println("invalid token, user_id error")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token."})
-		return
-	}
-	userRole, ok := c.MustGet("role").(string)
-	if !ok {
-		log.Println("invalid token, role error")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token"})
-		return
-	}
-
+func (s *Service) PutChallengeRequest(c *gin.Context) {
 	paramUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
 	if err != nil {
 		log.Printf("Invalid path param user_id: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path param user_id"})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "user_id"))
 		return
 	}
 
 	challengeStatus := c.Query("status")
 	if challengeStatus != "rejected" && challengeStatus != "accepted" {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid query string", Fields: &[]string{"status"}})
-		return
-	}
-
-	if userID != paramUserID && userRole != constAdminRole {
-		c.JSON(http.StatusMethodNotAllowed, &model.ErrResp{Error: "Not allowed"})
+		c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query string", "status"))
 		return
 	}
 
 	paramChallengeRequestID, err := strconv.ParseInt(c.Param("challenge_request_id"), 10, 64)
 	if err != nil {
 		log.Printf("Invalid path param user_id: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path param challenge_request_id"})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "challenge_request_id"))
 		return
 	}
 
 	challengeRequest := model.ChallengeRequest{ID: paramChallengeRequestID, ToID: paramUserID}
-	status, err := challengeRequest.UpdateStatus(challengeStatus)
+	err = s.store.ChallengeRequests.UpdateStatus(&challengeRequest, challengeStatus)
 	if err != nil {
 		log.Printf("Challenge request update status error: %v", err)
-		c.JSON(status, &model.ErrResp{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -208,44 +138,26 @@ func PutChallengeRequest(c *gin.Context) {
 }
 
 //DeleteChallengeRequest handler func updates the challenge request, used basically for updating the status
-func DeleteChallengeRequest(c *gin.Context) {
-	userID, ok := c.MustGet("user_id").(int64)
-	if !ok {
-		log.Println("invalid token, user_id error")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token."})
-		return
-	}
-	userRole, ok := c.MustGet("role").(string)
-	if !ok {
-		log.Println("invalid token, role error")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token"})
-		return
-	}
-
+func (s *Service) DeleteChallengeRequest(c *gin.Context) {
 	paramUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
 	if err != nil {
 		log.Printf("Invalid path param user_id: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path param user_id"})
-		return
-	}
-
-	if userID != paramUserID && userRole != constAdminRole {
-		c.JSON(http.StatusMethodNotAllowed, &model.ErrResp{Error: "Not allowed"})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "user_id"))
 		return
 	}
 
 	paramChallengeRequestID, err := strconv.ParseInt(c.Param("challenge_request_id"), 10, 64)
 	if err != nil {
 		log.Printf("Invalid path param user_id: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path param challenge_request_id"})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "challenge_request_id"))
 		return
 	}
 
 	challengeRequest := model.ChallengeRequest{ID: paramChallengeRequestID, FromID: paramUserID}
-	status, err := challengeRequest.Delete()
+	err = s.store.ChallengeRequests.Delete(&challengeRequest)
 	if err != nil {
 		log.Printf("Challenge request update status error: %v", err)
-		c.JSON(status, &model.ErrResp{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+)
+
+//completionLimit is the default and the maximum page size of the completion events
+const completionLimit = 100
+
+//GetCompletions handler func lists the completion events of the user, oldest first. Clients poll with after_id, the id of
+//the last event they saw, and get only the newer ones. challenge_id keeps the events of one challenge.
+func (s *Service) GetCompletions(c *gin.Context) {
+	paramUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		log.Printf("path parm user_id err: %v", err)
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "user_id"))
+		return
+	}
+
+	filter := model.CompletionFilter{UserID: paramUserID, Limit: completionLimit}
+
+	ids := []struct {
+		name string
+		id   *int64
+	}{{"after_id", &filter.AfterID}, {"challenge_id", &filter.ChallengeID}}
+	for _, q := range ids {
+		if value := c.Query(q.name); value != "" {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n < 0 {
+				c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query strings", q.name))
+				return
+			}
+			*q.id = n
+		}
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > completionLimit {
+			c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query strings", "limit"))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	completionList, err := s.store.Completions.Get(filter)
+	if err != nil {
+		log.Printf("completions fetching error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, completionList)
+}
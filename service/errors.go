@@ -0,0 +1,27 @@
+package service
+
+import (
+	"log"
+
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+)
+
+//RenderErrors func middleware renders the last error a handler attached with c.Error as an ErrResp.
+//Handlers return right after attaching the error and never write the error response themselves.
+func RenderErrors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := model.AsError(c.Errors.Last().Err)
+		if err.Kind == model.KindInternal {
+			log.Printf("%s %s internal error: %v", c.Request.Method, c.Request.URL.Path, err.Err)
+		}
+
+		c.JSON(err.Status(), err.Resp())
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+)
+
+//radius limits of the near query string, in meters
+const (
+	nearRadius    = 10000
+	nearMaxRadius = 500000
+)
+
+//parseFloats func reads a comma separated list of exactly n numbers
+func parseFloats(value string, n int) ([]float64, bool) {
+	parts := strings.Split(value, ",")
+	if len(parts) != n {
+		return nil, false
+	}
+
+	floats := make([]float64, n)
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, false
+		}
+		floats[i] = f
+	}
+	return floats, true
+}
+
+//validLongLat func checks the long/lat ranges
+func validLongLat(long, lat float64) bool {
+	return long >= -180 && long <= 180 && lat >= -90 && lat <= 90
+}
+
+//geoFilter func reads the near=lng,lat point with its radius in meters, the last_distance of the previous page and the
+//bbox=min_lng,min_lat,max_lng,max_lat box of the challenge query strings into the filter
+func geoFilter(c *gin.Context, filter *model.ChallengeFilter) error {
+	invalid := func(name string) error {
+		return model.Validation(model.CodeInvalidQueryString, "Invalid query strings", name)
+	}
+
+	if value := c.Query("near"); value != "" {
+		point, ok := parseFloats(value, 2)
+		if !ok || !validLongLat(point[0], point[1]) {
+			return invalid("near")
+		}
+		filter.Near = &model.GeoRadius{Long: point[0], Lat: point[1], Radius: nearRadius}
+
+		if value := c.Query("radius"); value != "" {
+			radius, err := strconv.Atoi(value)
+			if err != nil || radius <= 0 || radius > nearMaxRadius {
+				return invalid("radius")
+			}
+			filter.Near.Radius = radius
+		}
+
+		if value := c.Query("last_distance"); value != "" {
+			distance, err := strconv.ParseFloat(value, 64)
+			if err != nil || distance < 0 {
+				return invalid("last_distance")
+			}
+			filter.LastDistance = distance
+		} else if filter.LastID > 0 {
+			return invalid("last_distance")
+		}
+	} else if c.Query("radius") != "" || c.Query("last_distance") != "" {
+		return invalid("near")
+	}
+
+	if value := c.Query("bbox"); value != "" {
+		box, ok := parseFloats(value, 4)
+		if !ok || !validLongLat(box[0], box[1]) || !validLongLat(box[2], box[3]) || box[1] > box[3] {
+			return invalid("bbox")
+		}
+		filter.Box = &model.GeoBox{MinLong: box[0], MinLat: box[1], MaxLong: box[2], MaxLat: box[3]}
+	}
+
+	return nil
+}
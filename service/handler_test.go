@@ -0,0 +1,365 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+)
+
+//testServer struct is the router of the api on a memory store with the service which signs the users in
+type testServer struct {
+	t      *testing.T
+	deps   Deps
+	s      *Service
+	router *gin.Engine
+}
+
+//newTestServer func builds the router on an empty memory store
+func newTestServer(t *testing.T) *testServer {
+	deps := newTestDeps(t)
+	return &testServer{t: t, deps: deps, s: New(deps.Store, deps.Config, deps.Keys), router: NewRouter(deps)}
+}
+
+//signIn func creates a user with a score and starts a session of it, the user carries the access token
+func (ts *testServer) signIn(name, role string, levelID int64) *model.User {
+	ts.t.Helper()
+
+	u := model.User{Name: name, LevelID: levelID}
+	if err := ts.deps.Store.Users.Create(&u); err != nil {
+		ts.t.Fatalf("user create: %v", err)
+	}
+	if err := ts.deps.Store.Scores.Create(&model.Score{UserID: u.ID}); err != nil {
+		ts.t.Fatalf("score create: %v", err)
+	}
+	if role != "" {
+		u.Role = role
+	}
+	if err := ts.s.startSession(&u, "imei-"+name); err != nil {
+		ts.t.Fatalf("session: %v", err)
+	}
+
+	return &u
+}
+
+//do func sends the request as the user, nil for none, and decodes the response into out when it is not nil
+func (ts *testServer) do(u *model.User, method, path string, body, out interface{}) int {
+	ts.t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			ts.t.Fatalf("request body: %v", err)
+		}
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if u != nil {
+		req.Header.Set("access-token", u.Token)
+	}
+
+	w := httptest.NewRecorder()
+	ts.router.ServeHTTP(w, req)
+
+	if out != nil && w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			ts.t.Fatalf("%s %s response %q: %v", method, path, w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+//errorCode func sends the request as the user and returns the status with the code of the error response
+func (ts *testServer) errorCode(u *model.User, method, path string, body interface{}) (int, string) {
+	ts.t.Helper()
+
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	if u != nil {
+		req.Header.Set("access-token", u.Token)
+	}
+
+	w := httptest.NewRecorder()
+	ts.router.ServeHTTP(w, req)
+
+	var resp model.ErrResp
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp.Code
+}
+
+//createChallenge func creates an active challenge of the owner which completes posts at likesNeeded likes
+func (ts *testServer) createChallenge(owner *model.User, likesNeeded int) *model.Challenge {
+	ts.t.Helper()
+
+	var challenge model.Challenge
+	body := map[string]interface{}{
+		"name":                  "street art",
+		"likes_needed_per_post": likesNeeded,
+		"geo_coords":            map[string]interface{}{"type": "Point", "coordinates": []float64{13.4, 52.5}},
+	}
+	if code := ts.do(owner, "POST", "/challenge", body, &challenge); code != http.StatusOK {
+		ts.t.Fatalf("challenge create status = %v", code)
+	}
+	return &challenge
+}
+
+//createPost func posts to the challenge as the user
+func (ts *testServer) createPost(u *model.User, challengeID int64) *model.Post {
+	ts.t.Helper()
+
+	var post model.Post
+	body := map[string]interface{}{"file_url": "https://cdn.example.com/wall.jpg", "content_type": "image/jpeg", "content_size": 1024}
+	if code := ts.do(u, "POST", fmt.Sprintf("/challenge/%d/post", challengeID), body, &post); code != http.StatusOK {
+		ts.t.Fatalf("post create status = %v", code)
+	}
+	return &post
+}
+
+func TestAuthentication(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signIn("ada", "", 1)
+
+	path := fmt.Sprintf("/user?ids=%d", user.ID)
+	if code := ts.do(nil, "GET", path, nil, nil); code != http.StatusForbidden {
+		t.Errorf("without token status = %v, want %v", code, http.StatusForbidden)
+	}
+
+	var userList []*model.User
+	if code := ts.do(user, "GET", path, nil, &userList); code != http.StatusOK {
+		t.Fatalf("get user status = %v", code)
+	}
+	if len(userList) != 1 || userList[0].Name != "ada" || userList[0].Score == nil {
+		t.Errorf("users = %+v, want ada with the score", userList)
+	}
+
+	if code := ts.do(user, "POST", "/logout", map[string]string{"imei": "imei-ada"}, nil); code != http.StatusOK {
+		t.Fatalf("logout status = %v", code)
+	}
+	if code := ts.do(user, "GET", path, nil, nil); code != http.StatusForbidden {
+		t.Errorf("after logout status = %v, want %v", code, http.StatusForbidden)
+	}
+}
+
+func TestAdminRoutes(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signIn("ada", "", 1)
+	admin := ts.signIn("grace", "admin", 1)
+
+	weight := map[string]float32{"weight": 3}
+	path := fmt.Sprintf("/user/%d/weight", user.ID)
+	if code, errCode := ts.errorCode(user, "PUT", path, weight); code != http.StatusForbidden || errCode != model.CodeNotAllowed {
+		t.Errorf("own weight update = %v %q, want %v %q", code, errCode, http.StatusForbidden, model.CodeNotAllowed)
+	}
+	if code := ts.do(admin, "PUT", path, weight, nil); code != http.StatusOK {
+		t.Errorf("admin weight update status = %v, want %v", code, http.StatusOK)
+	}
+
+	path = fmt.Sprintf("/user/%d/score/%d/add_coins", user.ID, 1)
+	if code := ts.do(user, "PUT", path, map[string]int{"amount": 100}, nil); code != http.StatusForbidden {
+		t.Errorf("own add coins status = %v, want %v", code, http.StatusForbidden)
+	}
+}
+
+func TestChallengeCompletion(t *testing.T) {
+	ts := newTestServer(t)
+	owner := ts.signIn("owner", "", 5)
+	poster := ts.signIn("poster", "", 1)
+	likers := []*model.User{ts.signIn("liker1", "", 1), ts.signIn("liker2", "", 1)}
+
+	challenge := ts.createChallenge(owner, 2)
+	post := ts.createPost(poster, challenge.ID)
+	if post.LikesNeeded != 2 {
+		t.Errorf("likes needed = %v, want 2", post.LikesNeeded)
+	}
+
+	likePath := fmt.Sprintf("/challenge/%d/post/%d/like", challenge.ID, post.ID)
+	if code, errCode := ts.errorCode(poster, "PUT", likePath, nil); code != http.StatusForbidden || errCode != model.CodeNotAllowed {
+		t.Errorf("own like = %v %q, want %v %q", code, errCode, http.StatusForbidden, model.CodeNotAllowed)
+	}
+
+	completionsPath := fmt.Sprintf("/user/%d/completions", poster.ID)
+	for i, liker := range likers {
+		if code := ts.do(liker, "PUT", likePath, nil, nil); code != http.StatusOK {
+			t.Fatalf("like status = %v", code)
+		}
+		//a like again changes nothing
+		if code := ts.do(liker, "PUT", likePath, nil, nil); code != http.StatusOK {
+			t.Fatalf("like again status = %v", code)
+		}
+
+		var completions []*model.Completion
+		if code := ts.do(poster, "GET", completionsPath, nil, &completions); code != http.StatusOK {
+			t.Fatalf("completions status = %v", code)
+		}
+		if want := i; len(completions) != want {
+			t.Fatalf("completions after %d likes = %d, want %d", i+1, len(completions), want)
+		}
+	}
+
+	var completions []*model.Completion
+	ts.do(poster, "GET", completionsPath, nil, &completions)
+	if c := completions[0]; c.ChallengeID != challenge.ID || c.PostID != post.ID || c.Exp != 100 || c.Coins != 10 {
+		t.Errorf("completion = %+v, want challenge %d post %d with 100 exp and 10 coins", c, challenge.ID, post.ID)
+	}
+
+	var leaderboard model.Leaderboard
+	if code := ts.do(likers[0], "GET", fmt.Sprintf("/challenge/%d/leaderboard", challenge.ID), nil, &leaderboard); code != http.StatusOK {
+		t.Fatalf("leaderboard status = %v", code)
+	}
+	if leaderboard.Total != 1 || leaderboard.Entries[0].UserID != poster.ID || leaderboard.Entries[0].Likes != 2 || leaderboard.Me != nil {
+		t.Errorf("leaderboard = %+v, want the poster first with 2 likes", leaderboard)
+	}
+}
+
+func TestInviteRedemption(t *testing.T) {
+	ts := newTestServer(t)
+	owner := ts.signIn("owner", "", 5)
+	friend := ts.signIn("friend", "", 1)
+	stranger := ts.signIn("stranger", "", 1)
+
+	challenge := ts.createChallenge(owner, 1)
+	invitePath := fmt.Sprintf("/challenge/%d/invite", challenge.ID)
+
+	if code, errCode := ts.errorCode(friend, "POST", invitePath, map[string]int{"max_uses": 1}); code != http.StatusForbidden || errCode != model.CodeNotAllowed {
+		t.Errorf("invite of other challenge = %v %q, want %v %q", code, errCode, http.StatusForbidden, model.CodeNotAllowed)
+	}
+
+	var invite model.Invite
+	if code := ts.do(owner, "POST", invitePath, map[string]int{"max_uses": 1}, &invite); code != http.StatusOK {
+		t.Fatalf("invite create status = %v", code)
+	}
+	if invite.Link != ts.deps.Config.Invites.LinkBase+invite.Code {
+		t.Errorf("invite link = %q", invite.Link)
+	}
+
+	redeemPath := fmt.Sprintf("/invite/%s/redeem", invite.Code)
+	cases := []struct {
+		user    *model.User
+		status  int
+		errCode string
+	}{
+		{owner, http.StatusConflict, model.CodeConflict},
+		{friend, http.StatusOK, ""},
+		{friend, http.StatusConflict, model.CodeInviteUsedUp},
+		{stranger, http.StatusConflict, model.CodeInviteUsedUp},
+	}
+	for _, tc := range cases {
+		if code, errCode := ts.errorCode(tc.user, "POST", redeemPath, nil); code != tc.status || errCode != tc.errCode {
+			t.Errorf("redeem by %s = %v %q, want %v %q", tc.user.Name, code, errCode, tc.status, tc.errCode)
+		}
+	}
+
+	if code, errCode := ts.errorCode(friend, "POST", "/invite/NOPE2345/redeem", nil); code != http.StatusNotFound || errCode != model.CodeInviteNotFound {
+		t.Errorf("redeem of unknown code = %v %q, want %v %q", code, errCode, http.StatusNotFound, model.CodeInviteNotFound)
+	}
+
+	var invites []*model.Invite
+	if code := ts.do(owner, "GET", invitePath, nil, &invites); code != http.StatusOK {
+		t.Fatalf("invites status = %v", code)
+	}
+	if len(invites) != 1 || invites[0].Uses != 1 || invites[0].LastRedeemedAt == nil {
+		t.Errorf("invites = %+v, want one invite used once", invites)
+	}
+}
+
+func TestChallengePaging(t *testing.T) {
+	ts := newTestServer(t)
+	owner := ts.signIn("owner", "", 5)
+	for i := 0; i < 3; i++ {
+		ts.createChallenge(owner, 1)
+	}
+
+	var challengeList []*model.Challenge
+	if code := ts.do(owner, "GET", "/challenge?type=fresh", nil, &challengeList); code != http.StatusOK {
+		t.Fatalf("challenges status = %v", code)
+	}
+	if len(challengeList) != 3 || challengeList[0].ID != 3 || challengeList[2].ID != 1 {
+		t.Fatalf("fresh challenges = %+v, want newest first", challengeList)
+	}
+
+	last := challengeList[1]
+	path := fmt.Sprintf("/challenge?type=fresh&last_id=%d", last.ID)
+	if code, errCode := ts.errorCode(owner, "GET", path, nil); code != http.StatusBadRequest || errCode != model.CodeInvalidQueryString {
+		t.Errorf("page without last_created_at = %v %q, want %v %q", code, errCode, http.StatusBadRequest, model.CodeInvalidQueryString)
+	}
+
+	path = path + "&last_created_at=" + url.QueryEscape(last.CreatedAt.Format(time.RFC3339Nano))
+	if code := ts.do(owner, "GET", path, nil, &challengeList); code != http.StatusOK {
+		t.Fatalf("next page status = %v", code)
+	}
+	if len(challengeList) != 1 || challengeList[0].ID != 1 {
+		t.Errorf("next page = %+v, want the oldest challenge", challengeList)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signIn("ada", "", 1)
+	other := ts.signIn("grace", "", 1)
+
+	path := fmt.Sprintf("/user/%d/sessions", user.ID)
+	if code := ts.do(other, "GET", path, nil, nil); code != http.StatusForbidden {
+		t.Errorf("sessions of other user status = %v, want %v", code, http.StatusForbidden)
+	}
+
+	//the device never registered for push
+	var sessionList []*model.Session
+	if code := ts.do(user, "GET", path, nil, &sessionList); code != http.StatusOK {
+		t.Fatalf("sessions status = %v", code)
+	}
+	if len(sessionList) != 1 || sessionList[0].Imei != "imei-ada" || sessionList[0].PlayerID != "" || sessionList[0].LastSeenAt == nil {
+		t.Fatalf("sessions = %+v, want the device of the login", sessionList)
+	}
+
+	if code := ts.do(other, "DELETE", path+"/imei-ada", nil, nil); code != http.StatusForbidden {
+		t.Errorf("sign out of other user status = %v, want %v", code, http.StatusForbidden)
+	}
+
+	second := *user
+	if err := ts.s.startSession(&second, "imei-tablet"); err != nil {
+		t.Fatalf("session: %v", err)
+	}
+	if code := ts.do(&second, "DELETE", path+"/imei-ada", nil, nil); code != http.StatusOK {
+		t.Fatalf("sign out status = %v", code)
+	}
+	if code := ts.do(user, "GET", path, nil, nil); code != http.StatusForbidden {
+		t.Errorf("signed out device status = %v, want %v", code, http.StatusForbidden)
+	}
+
+	sessionList = nil
+	ts.do(&second, "GET", path, nil, &sessionList)
+	if len(sessionList) != 1 || sessionList[0].Imei != "imei-tablet" {
+		t.Errorf("sessions = %+v, want only the tablet", sessionList)
+	}
+}
+
+//TestPreSignS3 checks that the content types without a file extension are rejected before anything is signed
+func TestPreSignS3(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signIn("ada", "", 1)
+
+	cases := []string{
+		"file-name=wall",
+		"file-name=wall&content-type=not+a+type",
+		"file-name=wall&content-type=application%2Fx-challengr-unregistered",
+		"content-type=image%2Fjpeg",
+	}
+	for _, query := range cases {
+		if code, errCode := ts.errorCode(user, "GET", "/s3Sign?"+query, nil); code != http.StatusBadRequest || errCode != model.CodeInvalidQueryString {
+			t.Errorf("presign of %s = %v %q, want %v %q", query, code, errCode, http.StatusBadRequest, model.CodeInvalidQueryString)
+		}
+	}
+}
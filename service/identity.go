@@ -0,0 +1,102 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/challengr/identity"
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+)
+
+//verifyIdentity func asks the provider for the identity behind the credential
+func (s *Service) verifyIdentity(provider, credential string) (*identity.Identity, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, model.Validation(model.CodeInvalidFields, "Unknown provider", "provider")
+	}
+
+	id, err := p.Verify(credential)
+	if errors.Is(err, identity.ErrInvalidCredential) {
+		log.Printf("%v credential rejected: %v", provider, err)
+		return nil, model.Forbidden(model.CodeInvalidCredential, "Invalid "+provider+" credential")
+	}
+	if err != nil {
+		log.Printf("%v verify error: %v", provider, err)
+		return nil, err
+	}
+
+	return id, nil
+}
+
+//GetIdentities handler func fetches the sign in providers linked to the user
+func (s *Service) GetIdentities(c *gin.Context) {
+	paramUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		log.Printf("path parm user_id err: %v", err)
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "user_id"))
+		return
+	}
+
+	identityList, err := s.store.UserIdentities.Get(model.UserIdentityFilter{UserID: paramUserID})
+	if err != nil {
+		log.Printf("identities fetching error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, identityList)
+}
+
+//LinkIdentity handler func links the provider account of the credential to the user, who can sign in with it afterwards.
+//Only the user can link, since the credential must be theirs.
+func (s *Service) LinkIdentity(c *gin.Context) {
+	paramUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		log.Printf("path parm user_id err: %v", err)
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "user_id"))
+		return
+	}
+
+	var link model.IdentityLink
+	if err := model.Bind(c.Request.Body, &link, model.OpCreate); err != nil {
+		log.Printf("identity link bind error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	id, err := s.verifyIdentity(link.Provider, link.Token)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	userIdentity := model.UserIdentity{UserID: paramUserID, Provider: id.Provider, Subject: id.Subject, Email: id.Email}
+	if err := s.store.UserIdentities.Link(&userIdentity); err != nil {
+		log.Printf("identity link error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &userIdentity)
+}
+
+//UnlinkIdentity handler func removes a sign in provider from the user. The last one can not be removed.
+func (s *Service) UnlinkIdentity(c *gin.Context) {
+	paramUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		log.Printf("path parm user_id err: %v", err)
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "user_id"))
+		return
+	}
+
+	if err := s.store.UserIdentities.Unlink(&model.UserIdentity{UserID: paramUserID, Provider: c.Param("provider")}); err != nil {
+		log.Printf("identity unlink error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.SuccessResp{Message: "Identity successfully unlinked", Status: http.StatusOK})
+}
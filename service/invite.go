@@ -0,0 +1,102 @@
+package service
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+)
+
+//inviteLimit is the page size of the invites of a challenge
+const inviteLimit = 20
+
+//inviteLink func returns the deep link of the invite code
+func (s *Service) inviteLink(code string) string {
+	return s.cfg.Invites.LinkBase + code
+}
+
+//PostInvite handler func creates an invite code of the challenge, the caller is the inviter. expires_at and max_uses are optional.
+func (s *Service) PostInvite(c *gin.Context) {
+	challengeID, err := strconv.ParseInt(c.Param("challenge_id"), 10, 64)
+	if err != nil {
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "challenge_id"))
+		return
+	}
+
+	userID, ok := c.MustGet("user_id").(int64)
+	if !ok {
+		log.Println("token parsing not ok")
+		c.Error(model.Forbidden(model.CodeInvalidToken, "Invalid token"))
+		return
+	}
+
+	var invite model.Invite
+	if err := model.Bind(c.Request.Body, &invite, model.OpCreate); err != nil {
+		log.Printf("invite bind error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	invite.ChallengeID = challengeID
+	invite.InviterID = userID
+
+	if err := s.store.Invites.Create(&invite); err != nil {
+		c.Error(err)
+		return
+	}
+	invite.Link = s.inviteLink(invite.Code)
+
+	c.JSON(http.StatusOK, &invite)
+}
+
+//GetInvites handler func lists the invites of the challenge, newest first, with their uses, how many of the users who
+//redeemed them posted in the challenge and the time of the last redemption. before_id pages to older invites.
+func (s *Service) GetInvites(c *gin.Context) {
+	challengeID, err := strconv.ParseInt(c.Param("challenge_id"), 10, 64)
+	if err != nil {
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "challenge_id"))
+		return
+	}
+
+	filter := model.InviteFilter{ChallengeID: challengeID, Limit: inviteLimit}
+	if value := c.Query("before_id"); value != "" {
+		filter.BeforeID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query string", "before_id"))
+			return
+		}
+	}
+
+	inviteList, err := s.store.Invites.Get(filter)
+	if err != nil {
+		log.Printf("invites fetching error: %v", err)
+		c.Error(err)
+		return
+	}
+	for _, invite := range inviteList {
+		invite.Link = s.inviteLink(invite.Code)
+	}
+
+	c.JSON(http.StatusOK, inviteList)
+}
+
+//RedeemInvite handler func joins the caller to the challenge of the invite code and returns the accepted challenge
+//request from the inviter
+func (s *Service) RedeemInvite(c *gin.Context) {
+	userID, ok := c.MustGet("user_id").(int64)
+	if !ok {
+		log.Println("token parsing not ok")
+		c.Error(model.Forbidden(model.CodeInvalidToken, "Invalid token"))
+		return
+	}
+
+	challengeRequest, err := s.store.Invites.Redeem(c.Param("code"), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, challengeRequest)
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/challengr/jobs"
+	"github.com/gin-gonic/gin"
+)
+
+//constLikesRefill is the number of likes a user gets at sign up and at every refill
+const constLikesRefill = 20
+
+//likesRefillAfter is how long a user who ran out of likes waits for the refill
+const likesRefillAfter = time.Hour
+
+//jobList func returns the maintenance jobs with their schedules from the config
+func (s *Service) jobList() []jobs.Job {
+	return []jobs.Job{
+		{Name: "advance_challenges", Spec: s.cfg.Jobs.AdvanceChallenges, Run: s.advanceChallengesJob},
+		{Name: "refill_likes", Spec: s.cfg.Jobs.RefillLikes, Run: s.refillLikesJob},
+		{Name: "close_idle_challenges", Spec: s.cfg.Jobs.CloseIdleChallenges, Run: s.closeIdleChallengesJob},
+		{Name: "purge_deleted", Spec: s.cfg.Jobs.PurgeDeleted, Run: s.purgeDeletedJob},
+		{Name: "erase_users", Spec: s.cfg.Jobs.EraseUsers, Run: s.eraseUsersJob},
+		{Name: "refresh_trending", Spec: s.cfg.Jobs.RefreshTrending, Run: s.refreshTrendingJob},
+	}
+}
+
+//newScheduler func registers the jobs of the service in a new scheduler. The specs were checked by the config validation.
+func (s *Service) newScheduler() *jobs.Scheduler {
+	scheduler := jobs.New(s.store.JobRuns)
+	for _, job := range s.jobList() {
+		if err := scheduler.Add(job); err != nil {
+			log.Printf("job %v not added: %v", job.Name, err)
+		}
+	}
+	return scheduler
+}
+
+//Jobs func returns the scheduler of the maintenance jobs
+func (s *Service) Jobs() *jobs.Scheduler {
+	return s.jobs
+}
+
+//advanceChallengesJob func starts and closes the challenges whose dates have come
+func (s *Service) advanceChallengesJob() error {
+	moved, err := s.AdvanceChallenges()
+	if moved > 0 {
+		log.Printf("%d challenges advanced", moved)
+	}
+	return err
+}
+
+//refillLikesJob func gives the likes back to the users who ran out of them at least likesRefillAfter ago
+func (s *Service) refillLikesJob() error {
+	refilled, err := s.store.Scores.RefillLikes(constLikesRefill, time.Now().Add(-likesRefillAfter))
+	if refilled > 0 {
+		log.Printf("likes of %d users refilled", refilled)
+	}
+	return err
+}
+
+//closeIdleChallengesJob func closes the active challenges which got no post for the idle period
+func (s *Service) closeIdleChallengesJob() error {
+	closed, err := s.store.Challenges.CloseIdle(time.Now().Add(-s.cfg.Jobs.IdleAfter))
+	if closed > 0 {
+		log.Printf("%d idle challenges closed", closed)
+	}
+	return err
+}
+
+//purgeDeletedJob func removes the posts, with their uploads, and the challenges deleted longer than the retention ago,
+//and the job runs older than it
+func (s *Service) purgeDeletedJob() error {
+	before := time.Now().Add(-s.cfg.Jobs.Retention)
+
+	urls, err := s.store.Posts.Purge(before)
+	if err != nil {
+		return err
+	}
+	log.Printf("%d deleted posts purged", len(urls))
+
+	//the rows are gone, a file which could not be removed stays orphaned in the bucket
+	if err = s.deleteObjects(urls); err != nil {
+		return err
+	}
+
+	purged, err := s.store.Challenges.Purge(before)
+	if err != nil {
+		return err
+	}
+	log.Printf("%d deleted challenges purged", purged)
+
+	_, err = s.store.JobRuns.Prune(before)
+	return err
+}
+
+//eraseUsersJob func erases the users whose deletion grace period is over
+func (s *Service) eraseUsersJob() error {
+	erased, err := s.EraseDueUsers()
+	if erased > 0 {
+		log.Printf("%d users erased", erased)
+	}
+	return err
+}
+
+//refreshTrendingJob func recounts the trending counters and scores of the active challenges
+func (s *Service) refreshTrendingJob() error {
+	changed, err := s.store.Challenges.RefreshTrending(time.Now(), s.cfg.Trending)
+	if changed > 0 {
+		log.Printf("trending scores of %d challenges changed", changed)
+	}
+	return err
+}
+
+//GetJobs handler func lists the maintenance jobs with their schedule, next run, last run and last failure
+func (s *Service) GetJobs(c *gin.Context) {
+	statusList, err := s.jobs.Status()
+	if err != nil {
+		log.Printf("job status error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &statusList)
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+)
+
+//leaderboardLimit is the maximum page size of a leaderboard, leaderboardDefaultLimit the page size without a limit
+const (
+	leaderboardLimit        = 100
+	leaderboardDefaultLimit = 20
+)
+
+//leaderboardCache struct keeps the rankings of the challenges until they expire or are invalidated. A ranking whose
+//challenge is invalidated while it loads is not cached. Expired rankings are evicted when a new one is cached, so the
+//cache only holds the challenges asked for within the ttl.
+type leaderboardCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	boards  map[int64]*cachedLeaderboard
+	loading map[int64]*leaderboardLoad
+}
+
+//cachedLeaderboard struct is a cached ranking with its expiry
+type cachedLeaderboard struct {
+	entries   []*model.LeaderboardEntry
+	expiresAt time.Time
+}
+
+//leaderboardLoad struct counts the loads in flight of the ranking of a challenge, stale once it is invalidated meanwhile
+type leaderboardLoad struct {
+	count int
+	stale bool
+}
+
+//newLeaderboardCache func creates an empty cache whose rankings live for ttl
+func newLeaderboardCache(ttl time.Duration) *leaderboardCache {
+	return &leaderboardCache{ttl: ttl, boards: make(map[int64]*cachedLeaderboard), loading: make(map[int64]*leaderboardLoad)}
+}
+
+//get func returns the cached ranking of the challenge, loading it when it is missing or expired. The entries are shared
+//and must not be changed.
+func (l *leaderboardCache) get(challengeID int64, load func() ([]*model.LeaderboardEntry, error)) ([]*model.LeaderboardEntry, error) {
+	l.mu.Lock()
+	if board, ok := l.boards[challengeID]; ok && time.Now().Before(board.expiresAt) {
+		l.mu.Unlock()
+		return board.entries, nil
+	}
+	loading, ok := l.loading[challengeID]
+	if !ok {
+		loading = &leaderboardLoad{}
+		l.loading[challengeID] = loading
+	}
+	loading.count = loading.count + 1
+	l.mu.Unlock()
+
+	entries, err := load()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	loading.count = loading.count - 1
+	if loading.count == 0 {
+		delete(l.loading, challengeID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !loading.stale {
+		now := time.Now()
+		l.evictExpired(now)
+		l.boards[challengeID] = &cachedLeaderboard{entries: entries, expiresAt: now.Add(l.ttl)}
+	}
+
+	return entries, nil
+}
+
+//evictExpired func drops the rankings which expired by now. Caller must hold the lock.
+func (l *leaderboardCache) evictExpired(now time.Time) {
+	for challengeID, board := range l.boards {
+		if !now.Before(board.expiresAt) {
+			delete(l.boards, challengeID)
+		}
+	}
+}
+
+//invalidate func drops the cached ranking of the challenge after a change of its likes or posts, or its deletion
+func (l *leaderboardCache) invalidate(challengeID int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.boards, challengeID)
+	if loading, ok := l.loading[challengeID]; ok {
+		loading.stale = true
+	}
+}
+
+//GetLeaderboard handler func returns a page of the ranking of the participants of the challenge, from offset and up to
+//limit entries, with the entry of the caller wherever it ranks
+func (s *Service) GetLeaderboard(c *gin.Context) {
+	challengeID, err := strconv.ParseInt(c.Param("challenge_id"), 10, 64)
+	if err != nil {
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "challenge_id"))
+		return
+	}
+
+	userID, ok := c.MustGet("user_id").(int64)
+	if !ok {
+		log.Println("token parsing not ok")
+		c.Error(model.Forbidden(model.CodeInvalidToken, "Invalid token"))
+		return
+	}
+
+	offset := 0
+	if value := c.Query("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query strings", "offset"))
+			return
+		}
+	}
+
+	limit := leaderboardDefaultLimit
+	if value := c.Query("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > leaderboardLimit {
+			c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query strings", "limit"))
+			return
+		}
+	}
+
+	entries, err := s.leaderboards.get(challengeID, func() ([]*model.LeaderboardEntry, error) {
+		return s.store.Leaderboards.Get(challengeID)
+	})
+	if err != nil {
+		log.Printf("leaderboard fetching error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	leaderboard := model.Leaderboard{ChallengeID: challengeID, Total: len(entries), Entries: []*model.LeaderboardEntry{}}
+	if offset < len(entries) {
+		end := offset + limit
+		if end > len(entries) {
+			end = len(entries)
+		}
+		leaderboard.Entries = entries[offset:end]
+	}
+	for _, entry := range entries {
+		if entry.UserID == userID {
+			leaderboard.Me = entry
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, &leaderboard)
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/challengr/model"
+)
+
+func TestLeaderboardCache(t *testing.T) {
+	cache := newLeaderboardCache(time.Minute)
+	loads := 0
+	load := func() ([]*model.LeaderboardEntry, error) {
+		loads = loads + 1
+		return []*model.LeaderboardEntry{{Rank: 1, UserID: 7}}, nil
+	}
+
+	cache.get(1, load)
+	cache.get(1, load)
+	if loads != 1 {
+		t.Errorf("loads = %v, want the second get served from the cache", loads)
+	}
+
+	//an invalidation while the ranking loads keeps the loaded ranking out of the cache
+	cache.get(2, func() ([]*model.LeaderboardEntry, error) {
+		cache.invalidate(2)
+		return load()
+	})
+	if _, ok := cache.boards[2]; ok {
+		t.Error("ranking loaded before the invalidation is cached")
+	}
+	if len(cache.loading) != 0 {
+		t.Errorf("loads in flight = %v, want none", len(cache.loading))
+	}
+
+	cache.invalidate(1)
+	cache.invalidate(3)
+	if len(cache.boards) != 0 || len(cache.loading) != 0 {
+		t.Errorf("boards = %v, loads in flight = %v, want the invalidated challenges forgotten", len(cache.boards), len(cache.loading))
+	}
+}
+
+func TestLeaderboardCacheEvictsExpired(t *testing.T) {
+	cache := newLeaderboardCache(time.Minute)
+	load := func() ([]*model.LeaderboardEntry, error) { return []*model.LeaderboardEntry{}, nil }
+
+	for challengeID := int64(1); challengeID <= 3; challengeID++ {
+		cache.get(challengeID, load)
+	}
+	for _, board := range cache.boards {
+		board.expiresAt = time.Now().Add(-time.Second)
+	}
+
+	cache.get(4, load)
+	if len(cache.boards) != 1 || cache.boards[4] == nil {
+		t.Errorf("boards = %v, want only the new ranking", cache.boards)
+	}
+}
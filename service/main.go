@@ -4,74 +4,90 @@ import (
 	"log"
 	"mime"
 	"net/http"
-	"os"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/challengr/config"
+	"github.com/challengr/identity"
+	"github.com/challengr/jobs"
+	"github.com/challengr/keyset"
+	"github.com/challengr/model"
+	"github.com/challengr/ratelimit"
 	"github.com/gin-gonic/gin"
 	uuid "github.com/satori/go.uuid"
 )
 
-//svc is s3 service which would be used for signing stuff
-var svc *s3.S3
+//Service struct holds the dependencies of the http handlers
+type Service struct {
+	store *model.Store
+	cfg   *config.Config
+	keys  *keyset.Keyset
 
-//bucketName const is used as a bucket name for s3
-const bucketName = "challengrPost"
+	//providers verify the credentials of the sign in providers
+	providers identity.Providers
 
-//adminRole const is used as a role string for admin
-const constAdminRole = "admin"
+	//svc is s3 service which would be used for signing stuff
+	svc *s3.S3
 
-//userRole const is used as a role string for admin
-const constUserRole = "user"
+	//limits keeps the rate limit buckets
+	limits ratelimit.Store
 
-//constPostActive const us used as a status for a challenge
-const constChallengeActive = "active"
+	//jobs runs the maintenance jobs
+	jobs *jobs.Scheduler
 
-//constChallengeInActive const us used as a status for a challenge
-const constChallengeInActive = "inactive"
+	//leaderboards caches the rankings of the challenges
+	leaderboards *leaderboardCache
+}
+
+//New func creates the http handlers on top of the given data store, config and token keyset
+func New(store *model.Store, cfg *config.Config, keys *keyset.Keyset) *Service {
+	awsConfig := aws.NewConfig().WithRegion(cfg.AWS.Region)
+	if cfg.AWS.AccessKeyID != "" {
+		awsConfig.WithCredentials(credentials.NewStaticCredentials(cfg.AWS.AccessKeyID, cfg.AWS.SecretAccessKey, ""))
+	}
 
-func init() {
-	config := aws.NewConfig().WithRegion(os.Getenv("PORT"))
-	config.WithCredentials(credentials.NewStaticCredentials(os.Getenv("AWS-ID"), os.Getenv("AWS-KEY"), ""))
-	svc = s3.New(session.New(config))
+	s := &Service{store: store, cfg: cfg, keys: keys, providers: identity.New(cfg.Identity), svc: s3.New(session.New(awsConfig)),
+		leaderboards: newLeaderboardCache(cfg.Leaderboard.TTL)}
+	s.jobs = s.newScheduler()
+
+	return s
 }
 
 //PreSignS3 func is a handler for pres signing the put object url for direct s3 upload
-func PreSignS3(c *gin.Context) {
+func (s *Service) PreSignS3(c *gin.Context) {
 	fileName := c.Query("file-name")
 	if fileName == "" {
-		c.JSON(http.StatusBadRequest, []string{"Invalid file-name"})
+		c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid file-name", "file-name"))
 		return
 	}
 	fileName = fileName + "-" + uuid.NewV4().String()
 
 	contentType := c.Query("content-type")
 	if contentType == "" {
-		c.JSON(http.StatusBadRequest, []string{"Invalid content-type"})
+		c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid content-type", "content-type"))
 		return
 	}
+	//a valid content type may still have no registered extension
 	ext, err := mime.ExtensionsByType(contentType)
-	if err != nil {
-		log.Printf("unknown mimetype err :%v", err)
-		c.JSON(http.StatusBadRequest, []string{"Invalid content-type"})
+	if err != nil || len(ext) == 0 {
+		log.Printf("unknown mimetype: %v, err: %v", contentType, err)
+		c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid content-type", "content-type"))
 		return
 	}
-	log.Printf("ext: %v", ext)
 	fileName = fileName + ext[0]
 	//id := uuid.NewV4().String()
-	req, _ := svc.PutObjectRequest(&s3.PutObjectInput{
-		Bucket:      aws.String(bucketName),
+	req, _ := s.svc.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:      aws.String(s.cfg.AWS.Bucket),
 		Key:         aws.String(fileName),
 		ContentType: aws.String(contentType),
 		ACL:         aws.String("public-read"),
 	})
-	signedURL, headers, err := req.PresignRequest(1 * time.Minute)
+	signedURL, headers, err := req.PresignRequest(s.cfg.AWS.PresignTTL)
 	if err != nil {
 		log.Printf("error: %v", err)
-		c.JSON(http.StatusInternalServerError, []string{"error"})
+		c.Error(err)
 		return
 	}
 	m := make(map[string]interface{})
@@ -82,8 +98,7 @@ func PreSignS3(c *gin.Context) {
 		}
 	}
 	m["headers"] = heads
-	log.Printf("url: %v", signedURL)
-	url := "https://" + bucketName + ".s3.amazonaws.com/" + fileName
+	url := s.objectURL(fileName)
 
 	m["signedRequest"] = signedURL
 	m["url"] = url
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/challengr/config"
+	"github.com/challengr/keyset"
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+)
+
+//testEnv is the env of the smallest valid config without the job scheduler, the store of the tests is in memory
+var testEnv = map[string]string{
+	"DB_HOST":      "localhost",
+	"DB_NAME":      "challengr",
+	"DB_USER":      "challengr",
+	"DB_PASSWORD":  "challengr",
+	"AWS_REGION":   "eu-west-1",
+	"S3_BUCKET":    "challengr",
+	"JWT_SECRET":   "0123456789abcdef",
+	"JOBS_ENABLED": "false",
+}
+
+//newTestDeps func returns the dependencies of a router on an empty memory store
+func newTestDeps(t *testing.T) Deps {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	for name, value := range testEnv {
+		t.Setenv(name, value)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config: %v", err)
+	}
+
+	keys, err := keyset.Load(cfg.JWT)
+	if err != nil {
+		t.Fatalf("keyset: %v", err)
+	}
+
+	return Deps{Store: model.NewMemoryStore(), Config: cfg, Keys: keys}
+}
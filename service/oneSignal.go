@@ -9,35 +9,25 @@ import (
 )
 
 //UpdateOneSignal func is a handler for updating onesignal account info of an user
-func UpdateOneSignal(c *gin.Context) {
-	var oneSignal model.OneSignal
-	if err := c.BindJSON(&oneSignal); err != nil {
-		log.Printf("oneSignal struct JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: err.Error()})
-		return
-	}
-
-	if err := c.BindJSON(&oneSignal.Payload); err != nil {
-		log.Printf("oneSignal Payload JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: err.Error()})
+func (s *Service) UpdateOneSignal(c *gin.Context) {
+	userID, ok := c.MustGet("user_id").(int64)
+	if !ok {
+		log.Printf("invalid userid in token, userid: %v", c.MustGet("user_id"))
+		c.Error(model.Forbidden(model.CodeInvalidToken, "Invalid token"))
 		return
 	}
 
-	if errSlice := oneSignal.ParseNotAllowedJSON(); len(errSlice) > 0 {
-		log.Printf("LogIn not allowed fields detected: %v", errSlice)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Some fields are not allowed", Fields: &errSlice})
-		return
-	}
-
-	if errSlice := oneSignal.Validate(); len(errSlice) > 0 {
-		log.Printf("LogIn post validate err: %v", errSlice)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid fields detected", Fields: &errSlice})
+	var oneSignal model.OneSignal
+	if err := model.Bind(c.Request.Body, &oneSignal, model.OpUpdate); err != nil {
+		log.Printf("oneSignal bind error: %v", err)
+		c.Error(err)
 		return
 	}
+	oneSignal.UserID = userID
 
-	if err := oneSignal.Upsert(); err != nil {
+	if err := s.store.OneSignals.Upsert(&oneSignal); err != nil {
 		log.Printf("oneSignal upsert error: %v", err)
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server errror"})
+		c.Error(err)
 		return
 	}
 
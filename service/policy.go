@@ -0,0 +1,65 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+)
+
+//guard func runs the handler only if every policy lets the request through. The policies of a route are listed next
+//to it in the route table, handlers never check the token themselves.
+func guard(handler gin.HandlerFunc, policies ...gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, policy := range policies {
+			if policy(c); c.IsAborted() {
+				return
+			}
+		}
+
+		handler(c)
+	}
+}
+
+//challengeOwner func is the middleware.OwnerLoader of the :challenge_id challenge
+func (s *Service) challengeOwner(c *gin.Context) (int64, error) {
+	challengeID, err := strconv.ParseInt(c.Param("challenge_id"), 10, 64)
+	if err != nil {
+		return 0, model.Validation(model.CodeInvalidPathParam, "Invalid path params", "challenge_id")
+	}
+
+	challengeList, err := s.store.Challenges.Get(model.ChallengeFilter{ID: challengeID, Limit: 1})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(challengeList) != 1 {
+		return 0, model.NotFound(model.CodeChallengeNotFound, "Challenge not found")
+	}
+
+	return challengeList[0].UserID, nil
+}
+
+//postOwner func is the middleware.OwnerLoader of the :post_id post of the :challenge_id challenge
+func (s *Service) postOwner(c *gin.Context) (int64, error) {
+	challengeID, err := strconv.ParseInt(c.Param("challenge_id"), 10, 64)
+	if err != nil {
+		return 0, model.Validation(model.CodeInvalidPathParam, "Invalid path params", "challenge_id")
+	}
+
+	postID, err := strconv.ParseInt(c.Param("post_id"), 10, 64)
+	if err != nil {
+		return 0, model.Validation(model.CodeInvalidPathParam, "Invalid path params", "post_id")
+	}
+
+	postList, err := s.store.Posts.Get(model.PostFilter{ID: postID, ChallengeID: challengeID, Limit: 1})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(postList) != 1 {
+		return 0, model.NotFound(model.CodePostNotFound, "Post not found")
+	}
+
+	return postList[0].UserID, nil
+}
@@ -5,68 +5,52 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/challengr/middleware"
 	"github.com/challengr/model"
 	"github.com/gin-gonic/gin"
 )
 
 //PostPost func is a handler for creating a new post
-func PostPost(c *gin.Context) {
+func (s *Service) PostPost(c *gin.Context) {
 	paramChallengeID := c.Param("challenge_id")
 	challengeID, err := strconv.ParseInt(paramChallengeID, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path params", Fields: &[]string{"challenge_id"}})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "challenge_id"))
 		return
 	}
 
 	userID, ok := c.MustGet("user_id").(int64)
 	if !ok {
 		log.Println("token parsing not ok")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token"})
+		c.Error(model.Forbidden(model.CodeInvalidToken, "Invalid token"))
 		return
 	}
 
 	var post model.Post
-	if err := c.BindJSON(&post); err != nil {
-		log.Printf("post struct JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: err.Error()})
-		return
-	}
-
-	if err := c.BindJSON(&post.Payload); err != nil {
-		log.Printf("post Payload JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: err.Error()})
-		return
-	}
-
-	if errSlice := post.ParseNotAllowedJSON(); len(errSlice) > 0 {
-		log.Printf("post not allowed fields detected: %v", errSlice)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Some fields are not allowed", Fields: &errSlice})
-		return
-	}
-
-	if errSlice := post.PostValidate(); len(errSlice) > 0 {
-		log.Printf("post validate err: %v", errSlice)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid fields detected", Fields: &errSlice})
+	if err := model.Bind(c.Request.Body, &post, model.OpCreate); err != nil {
+		log.Printf("post bind error: %v", err)
+		c.Error(err)
 		return
 	}
 
 	post.UserID = userID
 	post.ChallengeID = challengeID
 
-	if err := post.Create(); err != nil {
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
+	if err := s.store.Posts.Create(&post); err != nil {
+		c.Error(err)
 		return
 	}
+	s.leaderboards.invalidate(challengeID)
 
 	c.JSON(http.StatusOK, &post)
 }
 
 //GetPost func is a handler for fetching posts
-func GetPost(c *gin.Context) {
+func (s *Service) GetPost(c *gin.Context) {
 	paramChallengeID := c.Param("challenge_id")
 	challengeID, err := strconv.ParseInt(paramChallengeID, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path params", Fields: &[]string{"challenge_id"}})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "challenge_id"))
 		return
 	}
 
@@ -76,14 +60,14 @@ func GetPost(c *gin.Context) {
 	if queryLastID != "" {
 		lastID, err = strconv.ParseInt(queryLastID, 10, 64)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path params", Fields: &[]string{"last_id"}})
+			c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query string", "last_id"))
 			return
 		}
 	}
 
-	postList, err := (&model.Post{}).Get("WHERE challenge_id=$1 AND deleted_at IS NULL AND id > $2 ORDER BY created_at DESC LIMIT 30", challengeID, lastID)
+	postList, err := s.store.Posts.Get(model.PostFilter{ChallengeID: challengeID, LastID: lastID, Limit: 30})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
+		c.Error(err)
 		return
 	}
 
@@ -91,83 +75,69 @@ func GetPost(c *gin.Context) {
 }
 
 //DeletePost func is a handler for deleting a post
-func DeletePost(c *gin.Context) {
+func (s *Service) DeletePost(c *gin.Context) {
 	paramChallengeID := c.Param("challenge_id")
 	challengeID, err := strconv.ParseInt(paramChallengeID, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path params", Fields: &[]string{"challenge_id"}})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "challenge_id"))
 		return
 	}
 
-	paramPostID := c.Param("challenge_id")
+	paramPostID := c.Param("post_id")
 	postID, err := strconv.ParseInt(paramPostID, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path params", Fields: &[]string{"post_id"}})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "post_id"))
 		return
 	}
 
 	userID, ok := c.MustGet("user_id").(int64)
 	if !ok {
 		log.Println("token parsing not ok")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token"})
+		c.Error(model.Forbidden(model.CodeInvalidToken, "Invalid token"))
 		return
 	}
 
-	userRole, ok := c.MustGet("user_role").(string)
-	if !ok {
-		log.Println("token parsing not ok")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token"})
-		return
+	if middleware.IsAdmin(c) {
+		err = s.store.Posts.AdminDelete(&model.Post{ID: postID, ChallengeID: challengeID}, auditEvent(c, auditPostDelete))
+	} else {
+		err = s.store.Posts.Delete(&model.Post{ID: postID, ChallengeID: challengeID, UserID: userID})
 	}
 
-	switch userRole {
-	case constAdminRole:
-		status, err := (&model.Post{ID: postID, ChallengeID: challengeID}).Delete()
-		if err != nil {
-			c.JSON(status, &model.ErrResp{Error: err.Error()})
-			return
-		}
-	case constUserRole:
-		status, err := (&model.Post{ID: postID, ChallengeID: challengeID, UserID: userID}).Delete()
-		if err != nil {
-			c.JSON(status, &model.ErrResp{Error: err.Error()})
-			return
-		}
-	default:
-		log.Printf("token parsing invalid role -> %v", userRole)
-		c.JSON(http.StatusForbidden, &model.ErrResp{Error: "Invalid token"})
+	if err != nil {
+		c.Error(err)
 		return
 	}
+	s.leaderboards.invalidate(challengeID)
 
 	c.JSON(http.StatusOK, &model.SuccessResp{Message: "Post successfully deleted", Status: http.StatusOK})
 }
 
 //FlagPost func is a handler for flagging a post
-func FlagPost(c *gin.Context) {
+func (s *Service) FlagPost(c *gin.Context) {
 	paramChallengeID := c.Param("challenge_id")
 	challengeID, err := strconv.ParseInt(paramChallengeID, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path params", Fields: &[]string{"challenge_id"}})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "challenge_id"))
 		return
 	}
 
-	paramPostID := c.Param("challenge_id")
+	paramPostID := c.Param("post_id")
 	postID, err := strconv.ParseInt(paramPostID, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path params", Fields: &[]string{"post_id"}})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "post_id"))
 		return
 	}
 
 	userID, ok := c.MustGet("user_id").(int64)
 	if !ok {
 		log.Println("token parsing not ok")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token"})
+		c.Error(model.Forbidden(model.CodeInvalidToken, "Invalid token"))
 		return
 	}
 
-	status, err := (&model.Post{ID: postID, ChallengeID: challengeID}).Flag(userID)
+	err = s.store.Posts.Flag(&model.Post{ID: postID, ChallengeID: challengeID}, userID)
 	if err != nil {
-		c.JSON(status, &model.ErrResp{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -175,31 +145,31 @@ func FlagPost(c *gin.Context) {
 }
 
 //UnFlagPost func is a handler or unflagging a post
-func UnFlagPost(c *gin.Context) {
+func (s *Service) UnFlagPost(c *gin.Context) {
 	paramChallengeID := c.Param("challenge_id")
 	challengeID, err := strconv.ParseInt(paramChallengeID, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path params", Fields: &[]string{"challenge_id"}})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "challenge_id"))
 		return
 	}
 
-	paramPostID := c.Param("challenge_id")
+	paramPostID := c.Param("post_id")
 	postID, err := strconv.ParseInt(paramPostID, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path params", Fields: &[]string{"post_id"}})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "post_id"))
 		return
 	}
 
 	userID, ok := c.MustGet("user_id").(int64)
 	if !ok {
 		log.Println("token parsing not ok")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token"})
+		c.Error(model.Forbidden(model.CodeInvalidToken, "Invalid token"))
 		return
 	}
 
-	status, err := (&model.Post{ID: postID, ChallengeID: challengeID}).UnFlag(userID)
+	err = s.store.Posts.UnFlag(&model.Post{ID: postID, ChallengeID: challengeID}, userID)
 	if err != nil {
-		c.JSON(status, &model.ErrResp{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -207,33 +177,34 @@ func UnFlagPost(c *gin.Context) {
 }
 
 //LikePost func is a handler for licking a post
-func LikePost(c *gin.Context) {
+func (s *Service) LikePost(c *gin.Context) {
 	paramChallengeID := c.Param("challenge_id")
 	challengeID, err := strconv.ParseInt(paramChallengeID, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path params", Fields: &[]string{"challenge_id"}})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "challenge_id"))
 		return
 	}
 
-	paramPostID := c.Param("challenge_id")
+	paramPostID := c.Param("post_id")
 	postID, err := strconv.ParseInt(paramPostID, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path params", Fields: &[]string{"post_id"}})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path params", "post_id"))
 		return
 	}
 
 	userID, ok := c.MustGet("user_id").(int64)
 	if !ok {
 		log.Println("token parsing not ok")
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid token"})
+		c.Error(model.Forbidden(model.CodeInvalidToken, "Invalid token"))
 		return
 	}
 
-	status, err := (&model.Post{ID: postID, ChallengeID: challengeID}).Like(userID)
+	err = s.store.Posts.Like(&model.Post{ID: postID, ChallengeID: challengeID}, userID, s.cfg.Rewards)
 	if err != nil {
-		c.JSON(status, &model.ErrResp{Error: err.Error()})
+		c.Error(err)
 		return
 	}
+	s.leaderboards.invalidate(challengeID)
 
 	c.JSON(http.StatusOK, &model.SuccessResp{Message: "Post successfully liked", Status: http.StatusOK})
 }
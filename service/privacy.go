@@ -0,0 +1,169 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+)
+
+//ExportUser handler func returns a zip archive of everything stored about the user: a JSON file per table
+//and media.json with the urls of the uploaded files
+func (s *Service) ExportUser(c *gin.Context) {
+	paramUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		log.Printf("path parm user_id err: %v", err)
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "user_id"))
+		return
+	}
+
+	data, err := s.store.PersonalData.Export(paramUserID)
+	if err != nil {
+		log.Printf("personal data export error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	archive, err := zipPersonalData(data)
+	if err != nil {
+		log.Printf("personal data archive error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	fileName := fmt.Sprintf("challengr-export-%d-%s.zip", paramUserID, time.Now().UTC().Format("20060102"))
+	c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+//zipPersonalData func writes the tables and the media urls of the export into a zip archive
+func zipPersonalData(data *model.PersonalData) ([]byte, error) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	for _, table := range data.Tables {
+		f, err := w.Create(table.Name + ".json")
+		if err != nil {
+			return nil, err
+		}
+		if _, err = f.Write(table.Rows); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := w.Create("media.json")
+	if err != nil {
+		return nil, err
+	}
+	if err = json.NewEncoder(f).Encode(data.Media); err != nil {
+		return nil, err
+	}
+
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+//RequestUserDeletion handler func schedules the erasure of the user after the grace period. The user keeps
+//using the account meanwhile and can cancel it.
+func (s *Service) RequestUserDeletion(c *gin.Context) {
+	paramUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		log.Printf("path parm user_id err: %v", err)
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "user_id"))
+		return
+	}
+
+	user := model.User{ID: paramUserID}
+	if err := s.store.PersonalData.ScheduleDeletion(&user, time.Now().Add(s.cfg.Privacy.DeletionGrace)); err != nil {
+		log.Printf("schedule deletion error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, &model.SuccessResp{Status: http.StatusAccepted, Message: "Deletion scheduled", Response: map[string]interface{}{"deletion_due_at": user.DeletionDueAt}})
+}
+
+//CancelUserDeletion handler func cancels the pending deletion of the user
+func (s *Service) CancelUserDeletion(c *gin.Context) {
+	paramUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		log.Printf("path parm user_id err: %v", err)
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "user_id"))
+		return
+	}
+
+	if err := s.store.PersonalData.CancelDeletion(&model.User{ID: paramUserID}); err != nil {
+		log.Printf("cancel deletion error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.SuccessResp{Status: http.StatusOK, Message: "Deletion cancelled"})
+}
+
+//EraseDueUsers func erases the users whose grace period is over. The uploaded files are removed from s3 first,
+//a user whose files could not be removed is left for the next run. It returns the number of erased users.
+func (s *Service) EraseDueUsers() (int, error) {
+	ids, err := s.store.PersonalData.DueDeletions(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	erased := 0
+	for _, id := range ids {
+		urls, err := s.store.PersonalData.Media(id)
+		if err != nil {
+			return erased, err
+		}
+
+		if err := s.deleteObjects(urls); err != nil {
+			log.Printf("user %v media delete error, retrying next run: %v", id, err)
+			continue
+		}
+
+		if err := s.store.PersonalData.Erase(id); err != nil {
+			return erased, err
+		}
+		erased++
+	}
+
+	return erased, nil
+}
+
+//objectURL func returns the public url of the object of the bucket
+func (s *Service) objectURL(key string) string {
+	return "https://" + s.cfg.AWS.Bucket + ".s3.amazonaws.com/" + key
+}
+
+//deleteObjects func removes the objects of our bucket behind the urls. Urls of other hosts are skipped.
+func (s *Service) deleteObjects(urls []string) error {
+	prefix := s.objectURL("")
+	for _, url := range urls {
+		if !strings.HasPrefix(url, prefix) {
+			log.Printf("skipping media outside of the bucket: %v", url)
+			continue
+		}
+
+		_, err := s.svc.DeleteObject(&s3.DeleteObjectInput{
+			Bucket: aws.String(s.cfg.AWS.Bucket),
+			Key:    aws.String(strings.TrimPrefix(url, prefix)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
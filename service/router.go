@@ -0,0 +1,178 @@
+package service
+
+import (
+	"log"
+
+	"github.com/challengr/config"
+	"github.com/challengr/keyset"
+	"github.com/challengr/middleware"
+	"github.com/challengr/model"
+	"github.com/challengr/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+//Access type tells which route group a route is mounted on
+type Access int
+
+const (
+	//Public routes need no token
+	Public Access = iota
+	//Authenticated routes need a valid token
+	Authenticated
+	//Admin routes need a valid token with the admin role
+	Admin
+)
+
+//String func returns the name of the access level
+func (a Access) String() string {
+	switch a {
+	case Public:
+		return "public"
+	case Authenticated:
+		return "authenticated"
+	case Admin:
+		return "admin"
+	}
+	return "unknown"
+}
+
+//Route struct is an entry of the route table
+type Route struct {
+	Method  string
+	Path    string
+	Access  Access
+	Handler gin.HandlerFunc
+}
+
+//Deps struct holds the dependencies needed to build the router. Limits is the rate limit backend, in-memory when nil.
+type Deps struct {
+	Store  *model.Store
+	Config *config.Config
+	Keys   *keyset.Keyset
+	Limits ratelimit.Store
+}
+
+//Routes func returns the route table of the api. Every handler is mounted from here, guarded by the policies which decide
+//who may call it.
+func (s *Service) Routes() []Route {
+	self := middleware.RequireSelf(":user_id")
+	selfOrAdmin := middleware.RequireSelfOrAdmin(":user_id")
+	challengeOwner := middleware.RequireOwner(s.challengeOwner)
+	postOwner := middleware.RequireOwner(s.postOwner)
+
+	authLimit := s.rateLimit("auth", s.cfg.RateLimit.Auth)
+	likeLimit := s.rateLimit("like", s.cfg.RateLimit.Like)
+	uploadLimit := s.rateLimit("upload", s.cfg.RateLimit.Upload)
+
+	return []Route{
+		{"POST", "/login", Public, guard(s.LogIn, authLimit)},
+		{"POST", "/token/refresh", Public, guard(s.RefreshToken, authLimit)},
+		{"GET", "/.well-known/jwks.json", Public, s.GetJWKS},
+		{"GET", "/vanity_item", Public, s.GetVanityItem},
+
+		{"GET", "/s3Sign", Authenticated, guard(s.PreSignS3, uploadLimit)},
+
+		{"PUT", "/onesignal", Authenticated, s.UpdateOneSignal},
+		{"POST", "/logout", Authenticated, s.LogOut},
+
+		{"GET", "/user", Authenticated, s.GetUser},
+		{"PUT", "/user/:user_id/weight", Admin, s.UpdateUserWeight},
+		{"PUT", "/user/:user_id/level", Admin, s.UpdateUserLevel},
+
+		{"GET", "/user/:user_id/identities", Authenticated, guard(s.GetIdentities, selfOrAdmin)},
+		{"POST", "/user/:user_id/identities", Authenticated, guard(s.LinkIdentity, self)},
+		{"DELETE", "/user/:user_id/identities/:provider", Authenticated, guard(s.UnlinkIdentity, selfOrAdmin)},
+
+		{"GET", "/user/:user_id/sessions", Authenticated, guard(s.GetSessions, selfOrAdmin)},
+		{"DELETE", "/user/:user_id/sessions", Authenticated, guard(s.DeleteSessions, selfOrAdmin)},
+		{"DELETE", "/user/:user_id/sessions/:imei", Authenticated, guard(s.DeleteSession, selfOrAdmin)},
+
+		{"POST", "/user/:user_id/export", Authenticated, guard(s.ExportUser, selfOrAdmin)},
+		{"POST", "/user/:user_id/deletion", Authenticated, guard(s.RequestUserDeletion, selfOrAdmin)},
+		{"DELETE", "/user/:user_id/deletion", Authenticated, guard(s.CancelUserDeletion, selfOrAdmin)},
+
+		{"PUT", "/user/:user_id/score/:score_id/add_coins", Admin, s.AddCoins},
+		{"PUT", "/user/:user_id/score/:score_id/add_exp", Admin, s.AddExp},
+		{"PUT", "/user/:user_id/score/:score_id/add_likes", Admin, s.AddLikes},
+
+		{"GET", "/user/:user_id/completions", Authenticated, guard(s.GetCompletions, selfOrAdmin)},
+
+		{"GET", "/user/:user_id/bought_item", Authenticated, guard(s.GetBoughtItem, selfOrAdmin)},
+		{"POST", "/user/:user_id/bought_item", Authenticated, guard(s.Purchase, selfOrAdmin)},
+
+		{"GET", "/user/:user_id/challenge_request", Authenticated, guard(s.GetChallengeRequest, selfOrAdmin)},
+		{"POST", "/user/:user_id/challenge_request", Authenticated, guard(s.PostChallengeRequest, selfOrAdmin)},
+		{"PUT", "/user/:user_id/challenge_request/:challenge_request_id", Authenticated, guard(s.PutChallengeRequest, selfOrAdmin)},
+		{"DELETE", "/user/:user_id/challenge_request/:challenge_request_id", Authenticated, guard(s.DeleteChallengeRequest, selfOrAdmin)},
+
+		{"GET", "/search", Authenticated, s.GetSearch},
+
+		{"GET", "/category", Public, s.GetCategories},
+		{"POST", "/category", Admin, s.PostCategory},
+		{"PUT", "/category/:category_id", Admin, s.PutCategory},
+		{"DELETE", "/category/:category_id", Admin, s.DeleteCategory},
+
+		{"GET", "/challenge", Authenticated, s.GetChellenge},
+		{"POST", "/challenge", Authenticated, s.PostChallenge},
+		{"PUT", "/challenge/:challenge_id", Authenticated, guard(s.PutChallenge, challengeOwner)},
+		{"DELETE", "/challenge/:challenge_id", Authenticated, guard(s.DeleteChallenge, challengeOwner)},
+		{"PUT", "/challenge/:challenge_id/activate", Admin, s.ActivateChallenge},
+		{"PUT", "/challenge/:challenge_id/deactivate", Admin, s.DeActivateChallenge},
+		{"GET", "/challenge/:challenge_id/leaderboard", Authenticated, s.GetLeaderboard},
+		{"GET", "/challenge/:challenge_id/invite", Authenticated, guard(s.GetInvites, challengeOwner)},
+		{"POST", "/challenge/:challenge_id/invite", Authenticated, guard(s.PostInvite, challengeOwner)},
+		{"POST", "/invite/:code/redeem", Authenticated, guard(s.RedeemInvite, authLimit)},
+
+		{"GET", "/admin/audit", Admin, s.GetAuditEvents},
+		{"GET", "/admin/jobs", Admin, s.GetJobs},
+
+		{"GET", "/challenge/:challenge_id/post", Authenticated, s.GetPost},
+		{"POST", "/challenge/:challenge_id/post", Authenticated, s.PostPost},
+		{"PUT", "/challenge/:challenge_id/post/:post_id/like", Authenticated, guard(s.LikePost, likeLimit)},
+		{"PUT", "/challenge/:challenge_id/post/:post_id/flag", Authenticated, guard(s.FlagPost, likeLimit)},
+		{"PUT", "/challenge/:challenge_id/post/:post_id/unflag", Authenticated, guard(s.UnFlagPost, likeLimit)},
+		{"DELETE", "/challenge/:challenge_id/post/:post_id", Authenticated, guard(s.DeletePost, postOwner)},
+	}
+}
+
+//rateLimit func returns the rate limit middleware of the group, the rate was checked by the config validation
+func (s *Service) rateLimit(group, rate string) gin.HandlerFunc {
+	r, _ := ratelimit.ParseRate(rate)
+	return middleware.RateLimit(s.limits, group, r)
+}
+
+//NewRouter func builds the gin engine with the public, authenticated and admin route groups. Every group is rate limited
+//with the default limit, by client ip and, past the authentication, by user as well. The job scheduler is started when
+//the config enables it.
+func NewRouter(deps Deps) *gin.Engine {
+	s := New(deps.Store, deps.Config, deps.Keys)
+	s.limits = deps.Limits
+	if s.limits == nil {
+		s.limits = ratelimit.NewMemory()
+	}
+	if deps.Config.Jobs.Enabled {
+		s.jobs.Start()
+	}
+	defaultLimit := s.rateLimit("default", deps.Config.RateLimit.Default)
+
+	router := gin.New()
+	//the client ip keys the rate limits of every route, only the configured proxies may set it
+	if err := router.SetTrustedProxies(deps.Config.TrustedProxyList()); err != nil {
+		log.Printf("trusted proxies error: %v", err)
+	}
+	router.Use(gin.Logger())
+	router.Use(gin.Recovery())
+	router.Use(middleware.RequestID())
+	router.Use(RenderErrors())
+
+	groups := map[Access]*gin.RouterGroup{}
+	groups[Public] = router.Group("/", defaultLimit)
+	groups[Authenticated] = router.Group("/", middleware.Authenticate(deps.Keys, deps.Store.RefreshTokens), defaultLimit)
+	groups[Admin] = groups[Authenticated].Group("/", middleware.RequireAdmin())
+
+	for _, route := range s.Routes() {
+		groups[route.Access].Handle(route.Method, route.Path, route.Handler)
+	}
+
+	return router
+}
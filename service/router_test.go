@@ -0,0 +1,173 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+//routeAccess struct is an expected entry of the route table
+type routeAccess struct {
+	method string
+	path   string
+	access Access
+}
+
+//expectedRoutes is the route table with the access level each route must be mounted with. A route which moves to a
+//weaker group fails the test.
+var expectedRoutes = []routeAccess{
+	{"POST", "/login", Public},
+	{"POST", "/token/refresh", Public},
+	{"GET", "/.well-known/jwks.json", Public},
+	{"GET", "/vanity_item", Public},
+
+	{"GET", "/s3Sign", Authenticated},
+
+	{"PUT", "/onesignal", Authenticated},
+	{"POST", "/logout", Authenticated},
+
+	{"GET", "/user", Authenticated},
+	{"PUT", "/user/:user_id/weight", Admin},
+	{"PUT", "/user/:user_id/level", Admin},
+
+	{"GET", "/user/:user_id/identities", Authenticated},
+	{"POST", "/user/:user_id/identities", Authenticated},
+	{"DELETE", "/user/:user_id/identities/:provider", Authenticated},
+
+	{"GET", "/user/:user_id/sessions", Authenticated},
+	{"DELETE", "/user/:user_id/sessions", Authenticated},
+	{"DELETE", "/user/:user_id/sessions/:imei", Authenticated},
+
+	{"POST", "/user/:user_id/export", Authenticated},
+	{"POST", "/user/:user_id/deletion", Authenticated},
+	{"DELETE", "/user/:user_id/deletion", Authenticated},
+
+	{"PUT", "/user/:user_id/score/:score_id/add_coins", Admin},
+	{"PUT", "/user/:user_id/score/:score_id/add_exp", Admin},
+	{"PUT", "/user/:user_id/score/:score_id/add_likes", Admin},
+
+	{"GET", "/user/:user_id/completions", Authenticated},
+
+	{"GET", "/user/:user_id/bought_item", Authenticated},
+	{"POST", "/user/:user_id/bought_item", Authenticated},
+
+	{"GET", "/user/:user_id/challenge_request", Authenticated},
+	{"POST", "/user/:user_id/challenge_request", Authenticated},
+	{"PUT", "/user/:user_id/challenge_request/:challenge_request_id", Authenticated},
+	{"DELETE", "/user/:user_id/challenge_request/:challenge_request_id", Authenticated},
+
+	{"GET", "/search", Authenticated},
+
+	{"GET", "/category", Public},
+	{"POST", "/category", Admin},
+	{"PUT", "/category/:category_id", Admin},
+	{"DELETE", "/category/:category_id", Admin},
+
+	{"GET", "/challenge", Authenticated},
+	{"POST", "/challenge", Authenticated},
+	{"PUT", "/challenge/:challenge_id", Authenticated},
+	{"DELETE", "/challenge/:challenge_id", Authenticated},
+	{"PUT", "/challenge/:challenge_id/activate", Admin},
+	{"PUT", "/challenge/:challenge_id/deactivate", Admin},
+	{"GET", "/challenge/:challenge_id/leaderboard", Authenticated},
+	{"GET", "/challenge/:challenge_id/invite", Authenticated},
+	{"POST", "/challenge/:challenge_id/invite", Authenticated},
+	{"POST", "/invite/:code/redeem", Authenticated},
+
+	{"GET", "/admin/audit", Admin},
+	{"GET", "/admin/jobs", Admin},
+
+	{"GET", "/challenge/:challenge_id/post", Authenticated},
+	{"POST", "/challenge/:challenge_id/post", Authenticated},
+	{"PUT", "/challenge/:challenge_id/post/:post_id/like", Authenticated},
+	{"PUT", "/challenge/:challenge_id/post/:post_id/flag", Authenticated},
+	{"PUT", "/challenge/:challenge_id/post/:post_id/unflag", Authenticated},
+	{"DELETE", "/challenge/:challenge_id/post/:post_id", Authenticated},
+}
+
+func TestRoutes(t *testing.T) {
+	deps := newTestDeps(t)
+	s := New(deps.Store, deps.Config, deps.Keys)
+
+	expected := make(map[string]Access)
+	for _, route := range expectedRoutes {
+		expected[route.method+" "+route.path] = route.access
+	}
+
+	seen := make(map[string]bool)
+	for _, route := range s.Routes() {
+		key := route.Method + " " + route.Path
+		if seen[key] {
+			t.Errorf("%s is in the route table twice", key)
+		}
+		seen[key] = true
+
+		if route.Handler == nil {
+			t.Errorf("%s has no handler", key)
+		}
+
+		access, ok := expected[key]
+		if !ok {
+			t.Errorf("%s is not expected, add it with its access level", key)
+			continue
+		}
+		if route.Access != access {
+			t.Errorf("%s access = %v, want %v", key, route.Access, access)
+		}
+	}
+
+	for key := range expected {
+		if !seen[key] {
+			t.Errorf("%s is missing from the route table", key)
+		}
+	}
+}
+
+//TestNewRouterMountsRoutes checks that every route of the table is served by the router
+func TestNewRouterMountsRoutes(t *testing.T) {
+	router := NewRouter(newTestDeps(t))
+
+	mounted := make(map[string]bool)
+	for _, info := range router.Routes() {
+		mounted[info.Method+" "+info.Path] = true
+	}
+
+	for _, route := range expectedRoutes {
+		if key := route.method + " " + route.path; !mounted[key] {
+			t.Errorf("%s is not mounted", key)
+		}
+	}
+}
+
+//TestClientIP checks that only the trusted proxies tell the client ip which keys the rate limits
+func TestClientIP(t *testing.T) {
+	cases := []struct {
+		name    string
+		proxies string
+		want    string
+	}{
+		{"no proxy is trusted by default", "", "192.0.2.1"},
+		{"trusted proxy", "192.0.2.0/24", "203.0.113.7"},
+		{"other proxy", "10.0.0.1", "192.0.2.1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			deps := newTestDeps(t)
+			deps.Config.TrustedProxies = tc.proxies
+			router := NewRouter(deps)
+			router.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+			req := httptest.NewRequest("GET", "/ip", nil)
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if got := w.Body.String(); got != tc.want {
+				t.Errorf("client ip = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
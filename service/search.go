@@ -0,0 +1,74 @@
+package service
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+)
+
+//limits of the search
+const (
+	searchMinQuery = 2
+	searchMaxQuery = 100
+	searchLimit    = 20
+	searchMaxLimit = 50
+)
+
+//GetSearch handler func searches the challenge names and descriptions and the user names for the q query string.
+//type=challenge|user keeps one type of results, limit sets the page size and cursor is the next_cursor of the previous page.
+func (s *Service) GetSearch(c *gin.Context) {
+	filter := model.SearchFilter{Query: strings.TrimSpace(c.Query("q")), Type: c.Query("type"), Limit: searchLimit}
+
+	if n := utf8.RuneCountInString(filter.Query); n < searchMinQuery || n > searchMaxQuery {
+		c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query strings", "q"))
+		return
+	}
+
+	if filter.Type != "" && filter.Type != model.SearchChallenge && filter.Type != model.SearchUser {
+		c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query strings", "type"))
+		return
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > searchMaxLimit {
+			c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query strings", "limit"))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := model.ParseSearchCursor(value)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		filter.Cursor = cursor
+	}
+
+	//one more result than asked tells if there is a next page
+	limit := filter.Limit
+	filter.Limit = limit + 1
+
+	resultList, err := s.store.Search.Search(filter)
+	if err != nil {
+		log.Printf("search error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	page := model.SearchPage{Results: resultList}
+	if len(resultList) > limit {
+		page.Results = resultList[:limit]
+		last := page.Results[limit-1]
+		page.NextCursor = (&model.SearchCursor{Rank: last.Rank, Type: last.Type, ID: last.ID}).Encode()
+	}
+
+	c.JSON(http.StatusOK, &page)
+}
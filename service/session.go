@@ -0,0 +1,89 @@
+package service
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+)
+
+//touchDevice func records that the device of the user was just seen. It runs on sign in and token refresh,
+//a failure is only logged since the session itself is fine.
+func (s *Service) touchDevice(userID int64, imei string) {
+	if err := s.store.OneSignals.Touch(&model.OneSignal{UserID: userID, Imei: imei}); err != nil {
+		log.Printf("touch device error: %v", err)
+	}
+}
+
+//GetSessions handler func lists the devices of the user with an active session, with their push player id and last seen
+//time. Devices which never registered for push are listed too, so they can be signed out.
+func (s *Service) GetSessions(c *gin.Context) {
+	paramUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		log.Printf("path parm user_id err: %v", err)
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "user_id"))
+		return
+	}
+
+	sessionList, err := s.store.RefreshTokens.Sessions(paramUserID)
+	if err != nil {
+		log.Printf("sessions fetching error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sessionList)
+}
+
+//DeleteSession handler func signs a device of the user out remotely. Its tokens stop working and it gets no more push notifications.
+func (s *Service) DeleteSession(c *gin.Context) {
+	paramUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		log.Printf("path parm user_id err: %v", err)
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "user_id"))
+		return
+	}
+
+	imei := c.Param("imei")
+
+	if err := s.store.RefreshTokens.RevokeDevice(paramUserID, imei); err != nil {
+		log.Printf("Revoke device sessions error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	//like on logout, a device which never registered for push has no onesignal record
+	if err := s.store.OneSignals.Delete(&model.OneSignal{UserID: paramUserID, Imei: imei}); err != nil && model.AsError(err).Kind != model.KindNotFound {
+		log.Printf("Delete onesignal error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.SuccessResp{Status: http.StatusOK, Message: "Device successfully logged out"})
+}
+
+//DeleteSessions handler func logs the user out everywhere. Every device loses its tokens and its push registration.
+func (s *Service) DeleteSessions(c *gin.Context) {
+	paramUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		log.Printf("path parm user_id err: %v", err)
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "user_id"))
+		return
+	}
+
+	if err := s.store.RefreshTokens.RevokeUser(paramUserID); err != nil {
+		log.Printf("Revoke user sessions error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	if err := s.store.OneSignals.DeleteAll(paramUserID); err != nil {
+		log.Printf("Delete all onesignal error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &model.SuccessResp{Status: http.StatusOK, Message: "Successfully logged out everywhere"})
+}
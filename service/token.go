@@ -0,0 +1,139 @@
+package service
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/challengr/model"
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+//startSession func starts a new session of the user on the device. Older sessions of the device are revoked.
+func (s *Service) startSession(u *model.User, imei string) error {
+	if err := s.store.RefreshTokens.RevokeDevice(u.ID, imei); err != nil {
+		log.Printf("revoke device sessions error: %v", err)
+		return err
+	}
+
+	refreshToken, hash, err := model.NewRefreshTokenString()
+	if err != nil {
+		log.Printf("refresh token generation error: %v", err)
+		return err
+	}
+
+	now := time.Now()
+	session := model.RefreshToken{UserID: u.ID, Imei: imei, FamilyID: uuid.NewV4().String(), TokenHash: hash, ExpiresAt: now.Add(s.cfg.JWT.RefreshTTL)}
+	if err := s.store.RefreshTokens.Create(&session); err != nil {
+		log.Printf("refresh token create error: %v", err)
+		return err
+	}
+
+	expiresAt := now.Add(s.cfg.JWT.AccessTTL)
+	u.Token, err = u.CreateTokenString(s.keys, session.FamilyID, expiresAt)
+	if err != nil {
+		return err
+	}
+	u.TokenExpiresAt = &expiresAt
+	u.RefreshToken = refreshToken
+
+	s.touchDevice(u.ID, imei)
+	return nil
+}
+
+//RefreshToken func handler exchanges a refresh token for a new access token and a new refresh token.
+//A refresh token works once, presenting a rotated one again revokes the whole session.
+func (s *Service) RefreshToken(c *gin.Context) {
+	var refresh model.TokenRefresh
+	if err := model.Bind(c.Request.Body, &refresh, model.OpCreate); err != nil {
+		log.Printf("token refresh bind error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	current, err := s.store.RefreshTokens.GetByHash(model.HashRefreshToken(refresh.RefreshToken))
+	if err != nil {
+		if model.AsError(err).Kind == model.KindNotFound {
+			err = model.Forbidden(model.CodeInvalidToken, "Invalid refresh token")
+		}
+		c.Error(err)
+		return
+	}
+
+	if current.RevokedAt != nil {
+		c.Error(model.Forbidden(model.CodeTokenRevoked, "Session revoked"))
+		return
+	}
+
+	if current.RotatedAt != nil {
+		s.revokeReusedSession(current)
+		c.Error(model.Forbidden(model.CodeTokenReused, "Refresh token reused, session revoked"))
+		return
+	}
+
+	if current.Imei != refresh.Imei {
+		log.Printf("refresh token of imei %v presented by imei %v", current.Imei, refresh.Imei)
+		c.Error(model.Forbidden(model.CodeInvalidToken, "Invalid refresh token"))
+		return
+	}
+
+	now := time.Now()
+	if now.After(current.ExpiresAt) {
+		c.Error(model.Forbidden(model.CodeTokenExpired, "Refresh token expired"))
+		return
+	}
+
+	userList, err := s.store.Users.Get(model.UserFilter{IDs: []int64{current.UserID}})
+	if err != nil {
+		log.Printf("User fetching error %v", err)
+		c.Error(err)
+		return
+	}
+
+	if len(userList) != 1 {
+		c.Error(model.Forbidden(model.CodeInvalidToken, "Invalid refresh token"))
+		return
+	}
+
+	refreshToken, hash, err := model.NewRefreshTokenString()
+	if err != nil {
+		log.Printf("refresh token generation error: %v", err)
+		c.Error(err)
+		return
+	}
+
+	next := model.RefreshToken{TokenHash: hash, ExpiresAt: now.Add(s.cfg.JWT.RefreshTTL)}
+	if err := s.store.RefreshTokens.Rotate(current, &next); err != nil {
+		if model.AsError(err).Code == model.CodeTokenReused {
+			s.revokeReusedSession(current)
+		}
+		c.Error(err)
+		return
+	}
+
+	pair := model.TokenPair{TokenExpiresAt: now.Add(s.cfg.JWT.AccessTTL), RefreshToken: refreshToken}
+	pair.Token, err = userList[0].CreateTokenString(s.keys, next.FamilyID, pair.TokenExpiresAt)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	s.touchDevice(next.UserID, next.Imei)
+	c.JSON(http.StatusOK, &pair)
+}
+
+//revokeReusedSession func revokes the session of a refresh token which was presented after its rotation.
+//Either the client or an attacker holds a stolen copy, so neither keeps the session.
+func (s *Service) revokeReusedSession(t *model.RefreshToken) {
+	log.Printf("refresh token reuse detected, user_id: %v, imei: %v, session: %v", t.UserID, t.Imei, t.FamilyID)
+	if err := s.store.RefreshTokens.RevokeFamily(t.FamilyID); err != nil {
+		log.Printf("revoke session error: %v", err)
+	}
+}
+
+//GetJWKS func handler publishes the public verification keys, so other services can verify the access tokens
+func (s *Service) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, s.keys.JWKS())
+}
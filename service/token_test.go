@@ -0,0 +1,75 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/challengr/keyset"
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+//TestJWKSHandler checks that the published keys verify the access tokens and hold no private member
+func TestJWKSHandler(t *testing.T) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "signing.pem")
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("JWT_ALGORITHM", keyset.ES256)
+	t.Setenv("JWT_KEY_FILE", keyFile)
+
+	ts := newTestServer(t)
+	user := ts.signIn("ada", "", 1)
+
+	w := httptest.NewRecorder()
+	ts.router.ServeHTTP(w, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") == "" {
+		t.Fatalf("jwks = %v %v, want a cacheable response", w.Code, w.Header())
+	}
+
+	var members struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &members); err != nil {
+		t.Fatalf("jwks body %q: %v", w.Body.String(), err)
+	}
+	if len(members.Keys) != 1 {
+		t.Fatalf("jwks = %v, want the signing key", members.Keys)
+	}
+	if _, ok := members.Keys[0]["d"]; ok {
+		t.Error("jwks publishes the private key")
+	}
+
+	var jwks keyset.JWKS
+	json.Unmarshal(w.Body.Bytes(), &jwks)
+	public, err := jwks.Keys[0].PublicKey()
+	if err != nil {
+		t.Fatalf("jwk: %v", err)
+	}
+
+	token, err := jwt.Parse(user.Token, func(token *jwt.Token) (interface{}, error) {
+		if token.Header["kid"] != jwks.Keys[0].Kid || token.Method.Alg() != jwks.Keys[0].Alg {
+			t.Errorf("token header = %v, want the kid and alg of the jwk", token.Header)
+		}
+		return public, nil
+	})
+	if err != nil || !token.Valid {
+		t.Errorf("access token does not verify with the published key: %v", err)
+	}
+}
@@ -15,7 +15,7 @@ import (
 )
 
 //GetUser func is a handler for fetching user list. The level of detials depends on the role of the user.
-func GetUser(c *gin.Context) {
+func (s *Service) GetUser(c *gin.Context) {
 	var (
 		err      error
 		userList []*model.User
@@ -28,7 +28,7 @@ func GetUser(c *gin.Context) {
 		if queryLastID != "" {
 			lastID, err = strconv.ParseInt(queryLastID, 10, 64)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid query string", Fields: &[]string{"last_id"}})
+				c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query string", "last_id"))
 				return
 			}
 		} else {
@@ -36,101 +36,64 @@ func GetUser(c *gin.Context) {
 		}
 
 		queryRadius := strings.TrimSpace(c.Query("radius"))
-		radius, err := strconv.Atoi(queryRadius)
+		var radius int
+		radius, err = strconv.Atoi(queryRadius)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid query string", Fields: &[]string{"radius"}})
+			c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query string", "radius"))
 			return
 		}
 		if radius <= 0 {
-			c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid query string", Fields: &[]string{"radius"}})
+			c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query string", "radius"))
 			return
 		}
 
 		queryLong := strings.TrimSpace(c.Query("longitude"))
 		if r := regexp.MustCompile("^[-+]?(180(\\.0+)?|((1[0-7]\\d)|([1-9]?\\d))(\\.\\d+)?)$"); !r.MatchString(queryLong) {
-			c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid query string", Fields: &[]string{"longitude"}})
+			c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query string", "longitude"))
 			return
 		}
 
 		queryLat := strings.TrimSpace(c.Query("latitude"))
 		if r := regexp.MustCompile("^[-+]?([1-8]?\\d(\\.\\d+)?|90(\\.0+)?)$"); !r.MatchString(queryLat) {
-			c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid query string", Fields: &[]string{"latitude"}})
+			c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query string", "latitude"))
 			return
 		}
-		geomQ := string("ST_Distance_Sphere(geometry, ST_MakePoint(" + queryLong + "," + queryLat + ")) <= " + queryRadius)
-		userList, err = (&model.User{}).Get("WHERE last_id>$1 AND deleted_at IS NULL AND "+geomQ+" ORDER BY users.level_id DESC LIMIT 100", lastID)
+		long, _ := strconv.ParseFloat(queryLong, 64)
+		lat, _ := strconv.ParseFloat(queryLat, 64)
+		userList, err = s.store.Users.Get(model.UserFilter{LastID: lastID, Near: &model.GeoRadius{Long: long, Lat: lat, Radius: radius}, Limit: 100})
 	default:
 		IDs := []int64{}
-		queryIDs := strings.Split(c.Query("ids"), ",")
-		for i, v := range queryIDs {
-			queryIDs[i] = strings.TrimSpace(v)
-			if queryIDs[i] != "" {
-				ID, err := strconv.ParseInt(queryIDs[i], 10, 64)
-				if err != nil {
-					c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid query string", Fields: &[]string{"ids"}})
-					return
-				}
-				IDs = append(IDs, ID)
-			} else if len(queryIDs) == 1 {
-				queryIDs = []string{}
-			} else {
-				c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid query string", Fields: &[]string{"ids"}})
+		for _, v := range strings.Split(c.Query("ids"), ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			ID, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query string", "ids"))
 				return
 			}
+			IDs = append(IDs, ID)
 		}
 
-		fbIDs := strings.Split(c.Query("fb_ids"), ",")
-		for i, v := range queryIDs {
-			queryIDs[i] = strings.TrimSpace(v)
-			if queryIDs[i] == "" {
-				if len(queryIDs) == 1 {
-					fbIDs = []string{}
-				} else {
-					c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid query string", Fields: &[]string{"fb_ids"}})
-					return
-				}
+		fbIDs := []string{}
+		for _, v := range strings.Split(c.Query("fb_ids"), ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				fbIDs = append(fbIDs, v)
 			}
 		}
 
 		if len(IDs) == 0 && len(fbIDs) == 0 {
-			c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid query string"})
+			c.Error(model.Validation(model.CodeInvalidQueryString, "Invalid query string"))
 			return
 		}
 
-		args := make([]interface{}, len(IDs)+len(fbIDs))
-		idBuilder := []string{}
-		fbIDQBuilder := []string{}
-		index := 0
-		for _, v := range IDs {
-			index = index + 1
-			args[index] = v
-			idBuilder = append(idBuilder, "users.id == $"+strconv.Itoa(index))
-		}
-		for _, v := range fbIDs {
-			index = index + 1
-			args[index] = v
-			fbIDQBuilder = append(fbIDQBuilder, "users.facebook_user_id = $"+strconv.Itoa(index))
-		}
-		idQ := strings.Join(idBuilder, " OR ")
-		fbIDQ := strings.Join(fbIDQBuilder, " OR ")
-
-		q := ""
-		if idQ != "" {
-			q = "(" + idQ + ")"
-		}
-		if fbIDQ != "" {
-			if q != "" {
-				q = q + " AND " + "(" + fbIDQ + ")"
-			} else {
-				q = "(" + fbIDQ + ")"
-			}
-		}
-		userList, err = (&model.User{}).Get("WHERE deleted_at IS NULL AND "+q+" ORDER BY users.level_id DESC", args)
+		userList, err = s.store.Users.Get(model.UserFilter{IDs: IDs, FacebookUserIDs: fbIDs})
 	}
 
 	if err != nil {
 		log.Printf("User fetching error %v", err)
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
+		c.Error(err)
 		return
 	}
 
@@ -138,60 +101,29 @@ func GetUser(c *gin.Context) {
 }
 
 //UpdateUserWeight func is a handler for updateing a user info.
-func UpdateUserWeight(c *gin.Context) {
-	userID, ok := c.MustGet("user_id").(int64)
-	if !ok {
-		log.Printf("invalid userid in token, userid: %v", c.MustGet("user_id"))
-		c.JSON(http.StatusForbidden, &model.ErrResp{Error: "Invalid token"})
-		return
-	}
-
-	userRole, ok := c.MustGet("role").(string)
-	if !ok {
-		log.Printf("invalid userole in token, userid: %v", c.MustGet("role"))
-		c.JSON(http.StatusForbidden, &model.ErrResp{Error: "Invalid token"})
-		return
-	}
-
+func (s *Service) UpdateUserWeight(c *gin.Context) {
 	paramUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
 	if err != nil {
 		log.Printf("path parm user_id err: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path param", Fields: &[]string{"user_id"}})
-		return
-	}
-
-	if userID != paramUserID && userRole != constAdminRole {
-		c.JSON(http.StatusMethodNotAllowed, &model.ErrResp{Error: "Not allowed please check token"})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "user_id"))
 		return
 	}
 
 	var user model.User
-	if err := c.BindJSON(&user); err != nil {
-		log.Printf("user struct JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: err.Error()})
-		return
-	}
-
-	if err := c.BindJSON(&user.Payload); err != nil {
-		log.Printf("user Payload JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: err.Error()})
-		return
-	}
-
-	if errSlice := user.ParseNotAllowedJSON(); len(errSlice) > 0 {
-		log.Printf("user not allowed fields detected: %v", errSlice)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Some fields are not allowed", Fields: &errSlice})
+	if err := model.Bind(c.Request.Body, &user, model.UserOpWeight); err != nil {
+		log.Printf("user bind error: %v", err)
+		c.Error(err)
 		return
 	}
 
 	if user.Weight == nil {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid payload", Fields: &[]string{"weight"}})
+		c.Error(model.Validation(model.CodeInvalidFields, "Invalid payload", "weight"))
 		return
 	}
 
-	if err := (&model.User{ID: paramUserID, Weight: user.Weight}).Update(); err != nil {
+	if err := s.store.Users.Update(&model.User{ID: paramUserID, Weight: user.Weight}, auditEvent(c, auditUserWeight)); err != nil {
 		log.Printf("Error user weight update: %v", err)
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
+		c.Error(err)
 		return
 	}
 
@@ -199,60 +131,29 @@ func UpdateUserWeight(c *gin.Context) {
 }
 
 //UpdateUserLevel func handler updates the user level
-func UpdateUserLevel(c *gin.Context) {
-	userID, ok := c.MustGet("user_id").(int64)
-	if !ok {
-		log.Printf("invalid userid in token, userid: %v", c.MustGet("user_id"))
-		c.JSON(http.StatusForbidden, &model.ErrResp{Error: "Invalid token"})
-		return
-	}
-
-	userRole, ok := c.MustGet("role").(string)
-	if !ok {
-		log.Printf("invalid userole in token, userid: %v", c.MustGet("role"))
-		c.JSON(http.StatusForbidden, &model.ErrResp{Error: "Invalid token"})
-		return
-	}
-
+func (s *Service) UpdateUserLevel(c *gin.Context) {
 	paramUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
 	if err != nil {
 		log.Printf("path parm user_id err: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid path param", Fields: &[]string{"user_id"}})
-		return
-	}
-
-	if userID != paramUserID && userRole != constAdminRole {
-		c.JSON(http.StatusMethodNotAllowed, &model.ErrResp{Error: "Not allowed please check token"})
+		c.Error(model.Validation(model.CodeInvalidPathParam, "Invalid path param", "user_id"))
 		return
 	}
 
 	var user model.User
-	if err := c.BindJSON(&user); err != nil {
-		log.Printf("user struct JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: err.Error()})
-		return
-	}
-
-	if err := c.BindJSON(&user.Payload); err != nil {
-		log.Printf("user Payload JSON bind error: %v", err)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: err.Error()})
-		return
-	}
-
-	if errSlice := user.ParseNotAllowedJSON(); len(errSlice) > 0 {
-		log.Printf("user not allowed fields detected: %v", errSlice)
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Some fields are not allowed", Fields: &errSlice})
+	if err := model.Bind(c.Request.Body, &user, model.UserOpLevel); err != nil {
+		log.Printf("user bind error: %v", err)
+		c.Error(err)
 		return
 	}
 
 	if user.LevelID == 0 {
-		c.JSON(http.StatusBadRequest, &model.ErrResp{Error: "Invalid payload", Fields: &[]string{"level_id"}})
+		c.Error(model.Validation(model.CodeInvalidFields, "Invalid payload", "level_id"))
 		return
 	}
 
-	if err := (&model.User{ID: paramUserID, LevelID: user.LevelID}).Update(); err != nil {
+	if err := s.store.Users.Update(&model.User{ID: paramUserID, LevelID: user.LevelID}, auditEvent(c, auditUserLevel)); err != nil {
 		log.Printf("Error user weight update: %v", err)
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
+		c.Error(err)
 		return
 	}
 
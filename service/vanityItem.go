@@ -5,16 +5,15 @@ import (
 
 	"net/http"
 
-	"github.com/challengr/model"
 	"github.com/gin-gonic/gin"
 )
 
 //GetVanityItem handler func fetches the list of
-func GetVanityItem(c *gin.Context) {
-	vanityItemList, err := (&model.VanityItem{}).Get("")
+func (s *Service) GetVanityItem(c *gin.Context) {
+	vanityItemList, err := s.store.VanityItems.Get()
 	if err != nil {
 		log.Printf("db fetching vanityItmeList error: %v", err)
-		c.JSON(http.StatusInternalServerError, &model.ErrResp{Error: "Server error"})
+		c.Error(err)
 		return
 	}
 